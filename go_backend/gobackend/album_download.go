@@ -0,0 +1,478 @@
+package gobackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBatchConcurrency  = 3
+	maxBatchConcurrency      = 8
+	defaultAlbumFolderFormat = "{artist} - {album}"
+	albumCoverFileName       = "cover.jpg"
+)
+
+// AlbumDownloadRequest describes an album to resolve and download
+type AlbumDownloadRequest struct {
+	Source               string   `json:"source"`   // "spotify", "deezer" or an extension ID
+	AlbumID              string   `json:"album_id"` // ID or URL (Spotify/Deezer) or extension album ID
+	OutputDir            string   `json:"output_dir"`
+	AlbumFolderFormat    string   `json:"album_folder_format,omitempty"` // {artist}, {album}, {year}; "-" disables the album folder
+	FilenameFormat       string   `json:"filename_format"`
+	Quality              string   `json:"quality"`
+	Service              string   `json:"service,omitempty"` // Preferred built-in service
+	Mode                 string   `json:"mode,omitempty"`    // Same modes as the download queue
+	EmbedLyrics          bool     `json:"embed_lyrics"`
+	EmbedMaxQualityCover bool     `json:"embed_max_quality_cover"`
+	LyricsMode           string   `json:"lyrics_mode,omitempty"`
+	Concurrency          int      `json:"concurrency,omitempty"`
+	SkipCoverFile        bool     `json:"skip_cover_file,omitempty"`
+	PlaylistFormats      []string `json:"playlist_formats,omitempty"` // "m3u8", "pls", "xspf"; written in track order as tracks finish
+	ItemIDPrefix         string   `json:"item_id_prefix,omitempty"`   // Per-track item IDs are "<prefix>:<index>"; cancelling the prefix stops the album
+}
+
+// TrackDownloadReport is the outcome for one track of a batch download
+type TrackDownloadReport struct {
+	Index       int    `json:"index"`
+	ItemID      string `json:"item_id,omitempty"`
+	Title       string `json:"title"`
+	Artist      string `json:"artist"`
+	ISRC        string `json:"isrc,omitempty"`
+	TrackNumber int    `json:"track_number,omitempty"`
+	DiscNumber  int    `json:"disc_number,omitempty"`
+	DurationMS  int    `json:"duration_ms,omitempty"`
+	Status      string `json:"status"` // "downloaded", "skipped", "failed", "cancelled"
+	Service     string `json:"service,omitempty"`
+	FilePath    string `json:"file_path,omitempty"`
+	BitDepth    int    `json:"bit_depth,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+	Error       string `json:"error,omitempty"`
+	ErrorType   string `json:"error_type,omitempty"`
+}
+
+// AlbumDownloadReport summarizes an album download
+type AlbumDownloadReport struct {
+	Success       bool                  `json:"success"`
+	AlbumName     string                `json:"album_name"`
+	AlbumArtist   string                `json:"album_artist"`
+	ReleaseDate   string                `json:"release_date,omitempty"`
+	AlbumDir      string                `json:"album_dir"`
+	CoverPath     string                `json:"cover_path,omitempty"`
+	PlaylistFiles []string              `json:"playlist_files,omitempty"`
+	Total         int                   `json:"total"`
+	Downloaded    int                   `json:"downloaded"`
+	Skipped       int                   `json:"skipped"`
+	Failed        int                   `json:"failed"`
+	Cancelled     int                   `json:"cancelled"`
+	DurationMS    int64                 `json:"duration_ms"`
+	Tracks        []TrackDownloadReport `json:"tracks"`
+	Error         string                `json:"error,omitempty"`
+}
+
+// resolvedAlbum is an album normalized from any metadata source
+type resolvedAlbum struct {
+	Name        string
+	Artist      string
+	ReleaseDate string
+	CoverURL    string
+	Genre       string
+	Label       string
+	Copyright   string
+	Tracks      []DownloadRequest
+}
+
+// resolveAlbum fetches album tracks from Spotify (with Deezer fallback), Deezer or an extension
+func resolveAlbum(source, albumID string) (*resolvedAlbum, error) {
+	switch source {
+	case "", "spotify":
+		albumURL := albumID
+		if !strings.Contains(albumID, "spotify") {
+			albumURL = "https://open.spotify.com/album/" + albumID
+		}
+		payloadJSON, err := GetSpotifyMetadataWithDeezerFallback(albumURL)
+		if err != nil {
+			return nil, err
+		}
+		var payload AlbumResponsePayload
+		if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+			return nil, fmt.Errorf("unexpected album payload: %w", err)
+		}
+		return albumFromPayload(&payload), nil
+	case "deezer":
+		deezerID := albumID
+		if strings.Contains(albumID, "deezer") {
+			resourceType, id, err := parseDeezerURL(albumID)
+			if err != nil {
+				return nil, err
+			}
+			if resourceType != "album" {
+				return nil, fmt.Errorf("not a Deezer album URL: %s", albumID)
+			}
+			deezerID = id
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		payload, err := GetDeezerClient().GetAlbum(ctx, deezerID)
+		if err != nil {
+			return nil, err
+		}
+		return albumFromPayload(payload), nil
+	default:
+		payloadJSON, err := GetAlbumWithExtensionJSON(source, albumID)
+		if err != nil {
+			return nil, err
+		}
+		return albumFromExtensionJSON(source, payloadJSON)
+	}
+}
+
+// trackMetadataToRequest maps a Spotify/Deezer track listing entry to a download request
+func trackMetadataToRequest(t AlbumTrackMetadata) DownloadRequest {
+	return DownloadRequest{
+		ISRC:        t.ISRC,
+		SpotifyID:   t.SpotifyID,
+		TrackName:   t.Name,
+		ArtistName:  t.Artists,
+		AlbumName:   t.AlbumName,
+		AlbumArtist: t.AlbumArtist,
+		CoverURL:    t.Images,
+		TrackNumber: t.TrackNumber,
+		DiscNumber:  t.DiscNumber,
+		TotalTracks: t.TotalTracks,
+		ReleaseDate: t.ReleaseDate,
+		DurationMS:  t.DurationMS,
+	}
+}
+
+func albumFromPayload(payload *AlbumResponsePayload) *resolvedAlbum {
+	album := &resolvedAlbum{
+		Name:        payload.AlbumInfo.Name,
+		Artist:      payload.AlbumInfo.Artists,
+		ReleaseDate: payload.AlbumInfo.ReleaseDate,
+		CoverURL:    payload.AlbumInfo.Images,
+		Genre:       payload.AlbumInfo.Genre,
+		Label:       payload.AlbumInfo.Label,
+		Copyright:   payload.AlbumInfo.Copyright,
+	}
+
+	for _, t := range payload.TrackList {
+		track := trackMetadataToRequest(t)
+		if track.AlbumArtist == "" {
+			track.AlbumArtist = album.Artist
+		}
+		if track.CoverURL == "" {
+			track.CoverURL = album.CoverURL
+		}
+		if track.TotalTracks == 0 {
+			track.TotalTracks = payload.AlbumInfo.TotalTracks
+		}
+		track.Genre = album.Genre
+		track.Label = album.Label
+		track.Copyright = album.Copyright
+		album.Tracks = append(album.Tracks, track)
+	}
+	return album
+}
+
+// extensionTrackJSON is a track as returned by the extension album/playlist exports
+type extensionTrackJSON struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Artists     string `json:"artists"`
+	AlbumName   string `json:"album_name"`
+	AlbumArtist string `json:"album_artist"`
+	DurationMS  int    `json:"duration_ms"`
+	CoverURL    string `json:"cover_url"`
+	ReleaseDate string `json:"release_date"`
+	TrackNumber int    `json:"track_number"`
+	DiscNumber  int    `json:"disc_number"`
+	ISRC        string `json:"isrc"`
+}
+
+func (t extensionTrackJSON) toDownloadRequest(extensionID string) DownloadRequest {
+	return DownloadRequest{
+		ISRC:        t.ISRC,
+		SpotifyID:   t.ID,
+		TrackName:   t.Name,
+		ArtistName:  t.Artists,
+		AlbumName:   t.AlbumName,
+		AlbumArtist: t.AlbumArtist,
+		CoverURL:    t.CoverURL,
+		TrackNumber: t.TrackNumber,
+		DiscNumber:  t.DiscNumber,
+		ReleaseDate: t.ReleaseDate,
+		DurationMS:  t.DurationMS,
+		Source:      extensionID,
+	}
+}
+
+func albumFromExtensionJSON(extensionID, payloadJSON string) (*resolvedAlbum, error) {
+	var payload struct {
+		Name        string               `json:"name"`
+		Artists     string               `json:"artists"`
+		CoverURL    string               `json:"cover_url"`
+		ReleaseDate string               `json:"release_date"`
+		TotalTracks int                  `json:"total_tracks"`
+		Tracks      []extensionTrackJSON `json:"tracks"`
+	}
+	if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+		return nil, fmt.Errorf("unexpected album payload: %w", err)
+	}
+
+	album := &resolvedAlbum{
+		Name:        payload.Name,
+		Artist:      payload.Artists,
+		ReleaseDate: payload.ReleaseDate,
+		CoverURL:    payload.CoverURL,
+	}
+
+	for _, t := range payload.Tracks {
+		track := t.toDownloadRequest(extensionID)
+		if track.AlbumName == "" {
+			track.AlbumName = album.Name
+		}
+		if track.AlbumArtist == "" {
+			track.AlbumArtist = album.Artist
+		}
+		if track.ReleaseDate == "" {
+			track.ReleaseDate = album.ReleaseDate
+		}
+		track.TotalTracks = payload.TotalTracks
+		album.Tracks = append(album.Tracks, track)
+	}
+	return album, nil
+}
+
+// albumDirectory builds the album folder path from the folder template
+func albumDirectory(req AlbumDownloadRequest, album *resolvedAlbum) string {
+	if req.AlbumFolderFormat == "-" {
+		return req.OutputDir
+	}
+
+	format := req.AlbumFolderFormat
+	if format == "" {
+		format = defaultAlbumFolderFormat
+	}
+
+	folder := buildFilenameFromTemplate(format, map[string]interface{}{
+		"artist": album.Artist,
+		"album":  album.Name,
+		"year":   extractYear(album.ReleaseDate),
+	})
+	return filepath.Join(req.OutputDir, sanitizeFilename(folder))
+}
+
+// saveAlbumCover writes cover.jpg into the album folder unless it already exists
+func saveAlbumCover(albumDir, coverURL string, maxQuality bool) (string, error) {
+	coverPath := filepath.Join(albumDir, albumCoverFileName)
+	if info, err := os.Stat(coverPath); err == nil && info.Size() > 0 {
+		return coverPath, nil
+	}
+
+	data, err := downloadCoverToMemory(coverURL, maxQuality)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(coverPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write cover: %w", err)
+	}
+	return coverPath, nil
+}
+
+// DownloadAlbum resolves an album and downloads its tracks with bounded parallelism
+func DownloadAlbum(req AlbumDownloadRequest) (*AlbumDownloadReport, error) {
+	startTime := time.Now()
+
+	req.OutputDir = strings.TrimSpace(req.OutputDir)
+	if req.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if req.AlbumID == "" {
+		return nil, fmt.Errorf("album ID is required")
+	}
+
+	album, err := resolveAlbum(req.Source, req.AlbumID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve album: %w", err)
+	}
+	if len(album.Tracks) == 0 {
+		return nil, fmt.Errorf("album has no tracks")
+	}
+
+	albumDir := albumDirectory(req, album)
+	if err := os.MkdirAll(albumDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	AddAllowedDownloadDir(albumDir)
+
+	GoLog("[AlbumDownload] '%s' by '%s': %d tracks into %s\n", album.Name, album.Artist, len(album.Tracks), albumDir)
+
+	report := &AlbumDownloadReport{
+		AlbumName:   album.Name,
+		AlbumArtist: album.Artist,
+		ReleaseDate: album.ReleaseDate,
+		AlbumDir:    albumDir,
+		Total:       len(album.Tracks),
+	}
+
+	if !req.SkipCoverFile && album.CoverURL != "" {
+		coverPath, coverErr := saveAlbumCover(albumDir, album.CoverURL, req.EmbedMaxQualityCover)
+		if coverErr != nil {
+			GoLog("[AlbumDownload] Failed to save cover: %v\n", coverErr)
+		} else {
+			report.CoverPath = coverPath
+		}
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = defaultBatchMode(req.Source)
+	}
+
+	tracks := make([]DownloadRequest, len(album.Tracks))
+	for i, track := range album.Tracks {
+		track.OutputDir = albumDir
+		track.FilenameFormat = req.FilenameFormat
+		track.Quality = req.Quality
+		track.Service = req.Service
+		track.EmbedLyrics = req.EmbedLyrics
+		track.EmbedMaxQualityCover = req.EmbedMaxQualityCover
+		track.LyricsMode = req.LyricsMode
+		tracks[i] = track
+	}
+
+	var onTrackDone func(int, DownloadRequest, *DownloadResponse)
+	if len(req.PlaylistFormats) > 0 {
+		writer := newPlaylistFileWriter(albumDir, album.Name, req.PlaylistFormats, len(tracks))
+		for i, track := range tracks {
+			writer.Fill(i, playlistEntryFromResponse(track, nil))
+		}
+		writer.Write()
+		report.PlaylistFiles = writer.Paths()
+		onTrackDone = func(index int, track DownloadRequest, resp *DownloadResponse) {
+			writer.Set(index, playlistEntryFromResponse(track, resp))
+		}
+	}
+
+	report.Tracks = downloadTracksConcurrently(tracks, mode, req.Concurrency, req.ItemIDPrefix, "AlbumDownload", onTrackDone)
+
+	report.Downloaded, report.Skipped, report.Failed, report.Cancelled = tallyTrackReports(report.Tracks)
+	report.Success = report.Failed == 0 && report.Cancelled == 0
+	report.DurationMS = time.Since(startTime).Milliseconds()
+
+	GoLog("[AlbumDownload] Done: %d downloaded, %d skipped, %d failed, %d cancelled in %v\n",
+		report.Downloaded, report.Skipped, report.Failed, report.Cancelled, time.Since(startTime).Round(time.Second))
+
+	return report, nil
+}
+
+// defaultBatchMode picks the queue mode for a metadata source: extension
+// sources go through the extension fallback, everything else through the
+// built-in services.
+func defaultBatchMode(source string) string {
+	if source != "" && source != "spotify" && source != "deezer" {
+		return QueueModeExtensions
+	}
+	return QueueModeFallback
+}
+
+// downloadTracksConcurrently downloads tracks with at most concurrency
+// downloads in flight. When itemIDPrefix is set, each track gets the item ID
+// "<prefix>:<index>" and cancelling the prefix skips tracks not yet started.
+// onDone, if set, is called as each started track finishes.
+func downloadTracksConcurrently(tracks []DownloadRequest, mode string, concurrency int, itemIDPrefix, logTag string, onDone func(index int, track DownloadRequest, resp *DownloadResponse)) []TrackDownloadReport {
+	if concurrency <= 0 {
+		concurrency = defaultBatchConcurrency
+	}
+	if concurrency > maxBatchConcurrency {
+		concurrency = maxBatchConcurrency
+	}
+
+	reports := make([]TrackDownloadReport, len(tracks))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, track := range tracks {
+		if itemIDPrefix != "" {
+			track.ItemID = fmt.Sprintf("%s:%d", itemIDPrefix, i)
+		}
+
+		reports[i] = TrackDownloadReport{
+			Index:       i,
+			ItemID:      track.ItemID,
+			Title:       track.TrackName,
+			Artist:      track.ArtistName,
+			ISRC:        track.ISRC,
+			TrackNumber: track.TrackNumber,
+			DiscNumber:  track.DiscNumber,
+			DurationMS:  track.DurationMS,
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(index int, track DownloadRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			entry := &reports[index]
+			if isDownloadCancelled(itemIDPrefix) {
+				entry.Status = "cancelled"
+				return
+			}
+
+			resp := runDownloadMode(mode, track)
+			entry.Service = resp.Service
+			entry.FilePath = resp.FilePath
+			entry.BitDepth = resp.ActualBitDepth
+			entry.SampleRate = resp.ActualSampleRate
+
+			switch {
+			case resp.Success && resp.AlreadyExists:
+				entry.Status = "skipped"
+			case resp.Success:
+				entry.Status = "downloaded"
+			case resp.ErrorType == "cancelled":
+				entry.Status = "cancelled"
+			default:
+				entry.Status = "failed"
+				entry.Error = resp.Error
+				entry.ErrorType = resp.ErrorType
+			}
+			GoLog("[%s] Track %d/%d '%s': %s\n", logTag, index+1, len(tracks), track.TrackName, entry.Status)
+
+			if onDone != nil {
+				onDone(index, track, resp)
+			}
+		}(i, track)
+	}
+	wg.Wait()
+
+	if itemIDPrefix != "" {
+		clearDownloadCancel(itemIDPrefix)
+	}
+
+	return reports
+}
+
+// tallyTrackReports counts track reports by status
+func tallyTrackReports(reports []TrackDownloadReport) (downloaded, skipped, failed, cancelled int) {
+	for _, r := range reports {
+		switch r.Status {
+		case "downloaded":
+			downloaded++
+		case "skipped":
+			skipped++
+		case "cancelled":
+			cancelled++
+		default:
+			failed++
+		}
+	}
+	return
+}
@@ -1,17 +1,13 @@
 package gobackend
 
 import (
-	"bufio"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,7 +15,6 @@ import (
 
 type AmazonDownloader struct {
 	client           *http.Client
-	regions          []string
 	lastAPICallTime  time.Time
 	apiCallCount     int
 	apiCallResetTime time.Time
@@ -47,60 +42,10 @@ type DoubleDoubleStatusResponse struct {
 	} `json:"current"`
 }
 
-func amazonArtistsMatch(expectedArtist, foundArtist string) bool {
-	normExpected := strings.ToLower(strings.TrimSpace(expectedArtist))
-	normFound := strings.ToLower(strings.TrimSpace(foundArtist))
-
-	if normExpected == normFound {
-		return true
-	}
-
-	if strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected) {
-		return true
-	}
-
-	expectedFirst := strings.Split(normExpected, ",")[0]
-	expectedFirst = strings.Split(expectedFirst, " feat")[0]
-	expectedFirst = strings.Split(expectedFirst, " ft.")[0]
-	expectedFirst = strings.TrimSpace(expectedFirst)
-
-	foundFirst := strings.Split(normFound, ",")[0]
-	foundFirst = strings.Split(foundFirst, " feat")[0]
-	foundFirst = strings.Split(foundFirst, " ft.")[0]
-	foundFirst = strings.TrimSpace(foundFirst)
-
-	if expectedFirst == foundFirst {
-		return true
-	}
-
-	if strings.Contains(expectedFirst, foundFirst) || strings.Contains(foundFirst, expectedFirst) {
-		return true
-	}
-
-	expectedASCII := amazonIsASCIIString(expectedArtist)
-	foundASCII := amazonIsASCIIString(foundArtist)
-	if expectedASCII != foundASCII {
-		GoLog("[Amazon] Artist names in different scripts, assuming match: '%s' vs '%s'\n", expectedArtist, foundArtist)
-		return true
-	}
-
-	return false
-}
-
-func amazonIsASCIIString(s string) bool {
-	for _, r := range s {
-		if r > 127 {
-			return false
-		}
-	}
-	return true
-}
-
 func NewAmazonDownloader() *AmazonDownloader {
 	amazonDownloaderOnce.Do(func() {
 		globalAmazonDownloader = &AmazonDownloader{
 			client:           NewHTTPClientWithTimeout(120 * time.Second), // 120s timeout like PC
-			regions:          []string{"us", "eu"},                        // Same regions as PC
 			apiCallResetTime: time.Now(),
 		}
 	})
@@ -143,15 +88,10 @@ func (a *AmazonDownloader) waitForRateLimit() {
 	a.apiCallCount++
 }
 
-// Uses same service as PC version (doubledouble.top)
+// GetAvailableAPIs returns the DoubleDouble service regions from the endpoint
+// config. Defaults are the same regions as PC version.
 func (a *AmazonDownloader) GetAvailableAPIs() []string {
-	// DoubleDouble service regions (same as PC)
-	// Format: https://{region}.doubledouble.top
-	var apis []string
-	for _, region := range a.regions {
-		apis = append(apis, fmt.Sprintf("https://%s.doubledouble.top", region))
-	}
-	return apis
+	return Endpoints().Amazon
 }
 
 // downloadFromDoubleDoubleService downloads a track using DoubleDouble service (same as PC)
@@ -160,12 +100,8 @@ func (a *AmazonDownloader) GetAvailableAPIs() []string {
 func (a *AmazonDownloader) downloadFromDoubleDoubleService(amazonURL, _ string) (string, string, string, error) {
 	var lastError error
 
-	for _, region := range a.regions {
-		GoLog("[Amazon] Trying region: %s...\n", region)
-
-		serviceBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly8=")               // https://
-		serviceDomain, _ := base64.StdEncoding.DecodeString("LmRvdWJsZWRvdWJsZS50b3A=") // .doubledouble.top
-		baseURL := fmt.Sprintf("%s%s%s", string(serviceBase), region, string(serviceDomain))
+	for _, baseURL := range a.GetAvailableAPIs() {
+		GoLog("[Amazon] Trying region: %s...\n", baseURL)
 
 		encodedURL := url.QueryEscape(amazonURL)
 		submitURL := fmt.Sprintf("%s/dl?url=%s", baseURL, encodedURL)
@@ -311,19 +247,19 @@ func (a *AmazonDownloader) downloadFromDoubleDoubleService(amazonURL, _ string)
 
 		if elapsed >= maxWait {
 			lastError = fmt.Errorf("download timeout")
-			fmt.Printf("\n[Amazon] Error with %s region: %v\n", region, lastError)
+			fmt.Printf("\n[Amazon] Error with %s: %v\n", baseURL, lastError)
 			continue
 		}
 
 		if lastError != nil {
-			fmt.Printf("\n[Amazon] Error with %s region: %v\n", region, lastError)
+			fmt.Printf("\n[Amazon] Error with %s: %v\n", baseURL, lastError)
 		}
 	}
 
 	return "", "", "", fmt.Errorf("all regions failed. Last error: %v", lastError)
 }
 
-func (a *AmazonDownloader) DownloadFile(downloadURL, outputPath, itemID string) error {
+func (a *AmazonDownloader) DownloadFile(downloadURL, outputPath, itemID string, keepPartial bool, expectedDurationMS int) error {
 	ctx := context.Background()
 
 	// Initialize item progress (required for all downloads)
@@ -338,71 +274,10 @@ func (a *AmazonDownloader) DownloadFile(downloadURL, outputPath, itemID string)
 		return ErrDownloadCancelled
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	written, err := downloadToFileResumable(ctx, a.client, downloadURL, outputPath, itemID, keepPartial, expectedDurationMS)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("User-Agent", getRandomUserAgent())
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
 		return err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
-	}
-
-	expectedSize := resp.ContentLength
-	if expectedSize > 0 && itemID != "" {
-		SetItemBytesTotal(itemID, expectedSize)
-	}
-
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-
-	bufWriter := bufio.NewWriterSize(out, 256*1024)
-
-	var written int64
-	if itemID != "" {
-		pw := NewItemProgressWriter(bufWriter, itemID)
-		written, err = io.Copy(pw, resp.Body)
-	} else {
-		written, err = io.Copy(bufWriter, resp.Body)
-	}
-
-	// Flush buffer before checking for errors
-	flushErr := bufWriter.Flush()
-	closeErr := out.Close()
-
-	if err != nil {
-		os.Remove(outputPath)
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
-		return fmt.Errorf("download interrupted: %w", err)
-	}
-	if flushErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to flush buffer: %w", flushErr)
-	}
-	if closeErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to close file: %w", closeErr)
-	}
-
-	// Verify file size if Content-Length was provided
-	if expectedSize > 0 && written != expectedSize {
-		os.Remove(outputPath)
-		return fmt.Errorf("incomplete download: expected %d bytes, got %d bytes", expectedSize, written)
-	}
 
 	fmt.Printf("\r[Amazon] Downloaded: %.2f MB (Complete)\n", float64(written)/(1024*1024))
 	return nil
@@ -410,26 +285,27 @@ func (a *AmazonDownloader) DownloadFile(downloadURL, outputPath, itemID string)
 
 // AmazonDownloadResult contains download result with quality info
 type AmazonDownloadResult struct {
-	FilePath    string
-	BitDepth    int
-	SampleRate  int
-	Title       string
-	Artist      string
-	Album       string
-	ReleaseDate string
-	TrackNumber int
-	DiscNumber  int
-	ISRC        string
+	FilePath        string
+	BitDepth        int
+	SampleRate      int
+	Title           string
+	Artist          string
+	Album           string
+	ReleaseDate     string
+	TrackNumber     int
+	DiscNumber      int
+	ISRC            string
+	Genre           string
+	Label           string
+	Copyright       string
+	MetadataSources map[string]string // Field -> source of the tag written
+	MatchedID       string            // Provider's ID of the track it downloaded
+	Matched         TrackMatchInfo    // Provider's own details of that track, checked by verifyDownload
 }
 
 // Uses DoubleDouble service (same as PC version)
-func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
-	downloader := NewAmazonDownloader()
-
-	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
-		return AmazonDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
-	}
-
+// resolveAmazonURL looks up the Amazon Music URL for a request through SongLink
+func resolveAmazonURL(req DownloadRequest) (string, error) {
 	songlink := NewSongLinkClient()
 	var availability *TrackAvailability
 	var err error
@@ -441,17 +317,56 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 	} else if req.SpotifyID != "" {
 		availability, err = songlink.CheckTrackAvailability(req.SpotifyID, req.ISRC)
 	} else {
-		return AmazonDownloadResult{}, fmt.Errorf("no valid Spotify or Deezer ID provided for Amazon lookup")
+		return "", stageError(StageSongLink, "", fmt.Errorf("no valid Spotify or Deezer ID provided for Amazon lookup"))
 	}
 
 	if err != nil {
-		return AmazonDownloadResult{}, fmt.Errorf("failed to check Amazon availability via SongLink: %w", err)
+		return "", stageError(StageSongLink, "", fmt.Errorf("failed to check Amazon availability via SongLink: %w", err))
 	}
 
 	if !availability.Amazon || availability.AmazonURL == "" {
-		return AmazonDownloadResult{}, fmt.Errorf("track not available on Amazon Music (SongLink returned no Amazon URL)")
+		return "", stageError(StageSongLink, RejectNotAvailable, fmt.Errorf("track not available on Amazon Music (SongLink returned no Amazon URL)"))
+	}
+	return availability.AmazonURL, nil
+}
+
+// probeAmazonQuality resolves the Amazon URL. Amazon's stream quality is only
+// known after downloading, so BitDepth/SampleRate stay zero.
+func probeAmazonQuality(req DownloadRequest) (QualityProbe, error) {
+	amazonURL, err := resolveAmazonURL(req)
+	if err != nil {
+		return QualityProbe{}, err
+	}
+	return QualityProbe{TrackID: amazonURL}, nil
+}
+
+func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
+	downloader := NewAmazonDownloader()
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return AmazonDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
 	}
 
+	amazonURL, err := resolveAmazonURL(req)
+	if err != nil {
+		return AmazonDownloadResult{}, err
+	}
+	return downloadAmazonURL(downloader, req, amazonURL)
+}
+
+// downloadProbedAmazon downloads the Amazon URL probeAmazonQuality resolved
+func downloadProbedAmazon(req DownloadRequest, probe QualityProbe) (AmazonDownloadResult, error) {
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return AmazonDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+	}
+	if probe.TrackID == "" {
+		return AmazonDownloadResult{}, fmt.Errorf("quality probe has no Amazon URL")
+	}
+	return downloadAmazonURL(NewAmazonDownloader(), req, probe.TrackID)
+}
+
+// downloadAmazonURL downloads and tags the track at an Amazon Music URL
+func downloadAmazonURL(downloader *AmazonDownloader, req DownloadRequest, amazonURL string) (AmazonDownloadResult, error) {
 	if req.OutputDir != "." {
 		if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
 			return AmazonDownloadResult{}, fmt.Errorf("failed to create output directory: %w", err)
@@ -459,34 +374,28 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 	}
 
 	// Download using DoubleDouble service (same as PC)
-	downloadURL, trackName, artistName, err := downloader.downloadFromDoubleDoubleService(availability.AmazonURL, req.OutputDir)
+	downloadURL, trackName, artistName, err := downloader.downloadFromDoubleDoubleService(amazonURL, req.OutputDir)
 	if err != nil {
-		return AmazonDownloadResult{}, fmt.Errorf("failed to get download URL: %w", err)
+		return AmazonDownloadResult{}, stageError(StageDownloadURL, "", fmt.Errorf("failed to get download URL: %w", err))
 	}
 
-	// Verify artist matches
-	if artistName != "" && !amazonArtistsMatch(req.ArtistName, artistName) {
-		GoLog("[Amazon] Artist mismatch: expected '%s', got '%s'. Rejecting.\n", req.ArtistName, artistName)
-		return AmazonDownloadResult{}, fmt.Errorf("artist mismatch: expected '%s', got '%s'", req.ArtistName, artistName)
+	// DoubleDouble only reports the title and artist of what it will download
+	if artistName != "" {
+		if match := matchRequest("amazon", "DoubleDouble", req, TrackMatchInfo{Title: trackName, Artist: artistName}); !match.Accepted {
+			return AmazonDownloadResult{}, stageError(StageDownloadURL, match.Rejection, fmt.Errorf("track mismatch: expected '%s' by '%s', got '%s' by '%s'", req.TrackName, req.ArtistName, trackName, artistName))
+		}
 	}
 
 	GoLog("[Amazon] Match found: '%s' by '%s'\n", trackName, artistName)
 
-	filename := buildFilenameFromTemplate(req.FilenameFormat, map[string]interface{}{
-		"title":  req.TrackName,
-		"artist": req.ArtistName,
-		"album":  req.AlbumName,
-		"track":  req.TrackNumber,
-		"year":   extractYear(req.ReleaseDate),
-		"disc":   req.DiscNumber,
-	})
-	filename = sanitizeFilename(filename) + ".flac"
-	outputPath := filepath.Join(req.OutputDir, filename)
+	outputPath := buildTrackOutputPath(req)
 
 	if fileInfo, statErr := os.Stat(outputPath); statErr == nil && fileInfo.Size() > 0 {
 		return AmazonDownloadResult{FilePath: "EXISTS:" + outputPath}, nil
 	}
 
+	musicBrainzResult := startMusicBrainzLookup(req)
+
 	// START PARALLEL: Fetch cover and lyrics while downloading audio
 	var parallelResult *ParallelDownloadResult
 	parallelDone := make(chan struct{})
@@ -504,11 +413,11 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 	}()
 
 	// Download audio file with item ID for progress tracking
-	if err := downloader.DownloadFile(downloadURL, outputPath, req.ItemID); err != nil {
+	if err := downloader.DownloadFile(downloadURL, outputPath, req.ItemID, req.KeepPartial, req.DurationMS); err != nil {
 		if errors.Is(err, ErrDownloadCancelled) {
 			return AmazonDownloadResult{}, ErrDownloadCancelled
 		}
-		return AmazonDownloadResult{}, fmt.Errorf("download failed: %w", err)
+		return AmazonDownloadResult{}, stageError(StageDownload, "", fmt.Errorf("download failed: %w", err))
 	}
 
 	// Wait for parallel operations to complete
@@ -524,37 +433,18 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 		GoLog("[Amazon] DoubleDouble returned: %s - %s\n", artistName, trackName)
 	}
 
-	existingMeta, metaErr := ReadMetadata(outputPath)
-	actualTrackNum := req.TrackNumber
-	actualDiscNum := req.DiscNumber
-
-	if metaErr == nil && existingMeta != nil {
-		if existingMeta.TrackNumber > 0 && (req.TrackNumber == 0 || req.TrackNumber == 1) {
-			actualTrackNum = existingMeta.TrackNumber
-			GoLog("[Amazon] Using track number from file: %d (request had: %d)\n", actualTrackNum, req.TrackNumber)
-		}
-		if existingMeta.DiscNumber > 0 && (req.DiscNumber == 0 || req.DiscNumber == 1) {
-			actualDiscNum = existingMeta.DiscNumber
-			GoLog("[Amazon] Using disc number from file: %d (request had: %d)\n", actualDiscNum, req.DiscNumber)
-		}
+	// DoubleDouble tags the file with Amazon's own track and disc numbers
+	amazonValues := MetadataValues{Title: trackName, Artist: artistName}
+	matched := TrackMatchInfo{Title: trackName, Artist: artistName}
+	if existingMeta, err := ReadMetadata(outputPath); err == nil && existingMeta != nil {
+		amazonValues.TrackNumber = existingMeta.TrackNumber
+		amazonValues.DiscNumber = existingMeta.DiscNumber
+		matched.ISRC = existingMeta.ISRC
 	}
 
-	// Embed metadata using Spotify data (more accurate than DoubleDouble)
-	// But preserve track/disc numbers from file if they were better
-	metadata := Metadata{
-		Title:       req.TrackName,
-		Artist:      req.ArtistName,
-		Album:       req.AlbumName,
-		AlbumArtist: req.AlbumArtist,
-		Date:        req.ReleaseDate,
-		TrackNumber: actualTrackNum,
-		TotalTracks: req.TotalTracks,
-		DiscNumber:  actualDiscNum,
-		ISRC:        req.ISRC,
-		Genre:       req.Genre,     // From Deezer album metadata
-		Label:       req.Label,     // From Deezer album metadata
-		Copyright:   req.Copyright, // From Deezer album metadata
-	}
+	merged := mergeRequestMetadata(req, MetadataCandidate{Source: "amazon", Values: amazonValues})
+	metadata := merged.toMetadata()
+	applyMusicBrainzMetadata(&metadata, musicBrainzResult())
 
 	// Use cover data from parallel fetch
 	var coverData []byte
@@ -607,11 +497,6 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 	if metaReadErr == nil && finalMeta != nil {
 		GoLog("[Amazon] Final metadata from file - Track: %d, Disc: %d, Date: %s\n",
 			finalMeta.TrackNumber, finalMeta.DiscNumber, finalMeta.Date)
-		actualTrackNum = finalMeta.TrackNumber
-		actualDiscNum = finalMeta.DiscNumber
-		if finalMeta.Date != "" {
-			req.ReleaseDate = finalMeta.Date
-		}
 	}
 
 	// Add to ISRC index for fast duplicate checking
@@ -624,16 +509,7 @@ func downloadFromAmazon(req DownloadRequest) (AmazonDownloadResult, error) {
 		sampleRate = quality.SampleRate
 	}
 
-	return AmazonDownloadResult{
-		FilePath:    outputPath,
-		BitDepth:    bitDepth,
-		SampleRate:  sampleRate,
-		Title:       req.TrackName,
-		Artist:      req.ArtistName,
-		Album:       req.AlbumName,
-		ReleaseDate: req.ReleaseDate,
-		TrackNumber: actualTrackNum,
-		DiscNumber:  actualDiscNum,
-		ISRC:        req.ISRC,
-	}, nil
+	result := merged.downloadResult(outputPath, bitDepth, sampleRate)
+	result.Matched = matched
+	return AmazonDownloadResult(result), nil
 }
@@ -0,0 +1,275 @@
+package gobackend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QualityProbe is the matched track and the stream quality a provider expects
+// to deliver for a request. BitDepth/SampleRate are zero when the provider
+// only knows the quality after downloading.
+type QualityProbe struct {
+	TrackID    string `json:"track_id,omitempty"`
+	Title      string `json:"title,omitempty"`
+	Artist     string `json:"artist,omitempty"`
+	BitDepth   int    `json:"bit_depth"`
+	SampleRate int    `json:"sample_rate"`
+}
+
+// QualityProber is implemented by download providers that can match a track
+// and report its available quality without downloading audio
+type QualityProber interface {
+	ProbeQuality(req DownloadRequest) (QualityProbe, error)
+	// DownloadProbed downloads the track a ProbeQuality call matched
+	// without matching it again
+	DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error)
+}
+
+// ServiceQualityDecision explains what best-quality mode did with a service
+type ServiceQualityDecision struct {
+	Service    string `json:"service"`
+	Status     string `json:"status"` // "chosen", "skipped", "failed", "rejected", "not_tried"
+	Reason     string `json:"reason"`
+	TrackID    string `json:"track_id,omitempty"`
+	BitDepth   int    `json:"bit_depth,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+}
+
+// qualityCandidate is a provider that passed probing, in download order
+type qualityCandidate struct {
+	service string
+	probe   QualityProbe
+	probed  bool // ProbeQuality matched the track
+	known   bool // false when the provider cannot report the quality
+}
+
+// betterQuality reports whether quality a ranks above b
+func betterQuality(a, b QualityProbe) bool {
+	if a.BitDepth != b.BitDepth {
+		return a.BitDepth > b.BitDepth
+	}
+	return a.SampleRate > b.SampleRate
+}
+
+// meetsQualityFloor reports whether bitDepth/sampleRate satisfy the request's minimum
+func meetsQualityFloor(req DownloadRequest, bitDepth, sampleRate int) bool {
+	return bitDepth >= req.MinBitDepth && sampleRate >= req.MinSampleRate
+}
+
+func formatQuality(bitDepth, sampleRate int) string {
+	return fmt.Sprintf("%d-bit/%.1fkHz", bitDepth, float64(sampleRate)/1000)
+}
+
+// qualityFloorRejection returns why a finished download fails the request's
+// minimum quality, or "" if it passes. A file whose quality cannot be read
+// does not pass a floor it cannot be shown to meet.
+func qualityFloorRejection(req DownloadRequest, resp DownloadResponse) string {
+	if resp.AlreadyExists || (req.MinBitDepth == 0 && req.MinSampleRate == 0) {
+		return ""
+	}
+	if resp.ActualBitDepth == 0 {
+		return "delivered a file of unknown quality, which cannot be checked against the minimum quality"
+	}
+	if !meetsQualityFloor(req, resp.ActualBitDepth, resp.ActualSampleRate) {
+		return fmt.Sprintf("delivered %s, below the minimum quality", formatQuality(resp.ActualBitDepth, resp.ActualSampleRate))
+	}
+	return ""
+}
+
+// rankQualityCandidates orders probed providers from best to worst quality.
+// Providers that cannot probe go last; ties keep priority order.
+func rankQualityCandidates(candidates []qualityCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].known != candidates[j].known {
+			return candidates[i].known
+		}
+		return betterQuality(candidates[i].probe, candidates[j].probe)
+	})
+}
+
+// bestQualityServiceOrder returns the enabled providers in fallback policy order
+// with the preferred service first
+func bestQualityServiceOrder(preferred string) []string {
+	return GetFallbackPolicyStore().Get().serviceOrder(preferred)
+}
+
+// DownloadWithBestQuality probes every built-in provider for the quality it
+// can deliver, then downloads from the best one, falling back to the next
+// best on failure. Results below MinBitDepth/MinSampleRate are rejected.
+func DownloadWithBestQuality(req DownloadRequest) (result *DownloadResponse, err error) {
+	req.TrackName = strings.TrimSpace(req.TrackName)
+	req.ArtistName = strings.TrimSpace(req.ArtistName)
+	req.OutputDir = strings.TrimSpace(req.OutputDir)
+	defer func() { recordDownloadHistoryResult(req, result, err) }()
+	if req.OutputDir != "" {
+		AddAllowedDownloadDir(req.OutputDir)
+	}
+	if req.Quality == "" {
+		req.Quality = "HI_RES_LOSSLESS"
+	}
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		resp := buildDownloadResponse(DownloadResult{FilePath: "EXISTS:" + existingFile}, "", "")
+		return &resp, nil
+	}
+
+	services := bestQualityServiceOrder(req.Service)
+	GoLog("[BestQuality] Probing %s for '%s' by '%s'\n", strings.Join(services, ", "), req.TrackName, req.ArtistName)
+
+	decisions := make([]ServiceQualityDecision, len(services))
+	candidates := make([]*qualityCandidate, len(services))
+
+	var wg sync.WaitGroup
+	for i, service := range services {
+		provider, _ := GetDownloadProvider(service)
+		decisions[i] = ServiceQualityDecision{Service: service}
+
+		prober, ok := provider.(QualityProber)
+		if !ok {
+			candidates[i] = &qualityCandidate{service: service}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, service string, prober QualityProber) {
+			defer wg.Done()
+			probeReq := req
+			probeReq.Service = service
+			probeReq.Quality = capQuality(probeReq.Quality, GetFallbackPolicyStore().Get().lookup(service).MaxQuality)
+			probe, err := prober.ProbeQuality(probeReq)
+			if err != nil {
+				decisions[i].Status = "skipped"
+				decisions[i].Reason = "probe failed: " + err.Error()
+				return
+			}
+
+			decisions[i].TrackID = probe.TrackID
+			decisions[i].BitDepth = probe.BitDepth
+			decisions[i].SampleRate = probe.SampleRate
+			if probe.BitDepth == 0 {
+				candidates[i] = &qualityCandidate{service: service, probe: probe, probed: true}
+				return
+			}
+			if !meetsQualityFloor(req, probe.BitDepth, probe.SampleRate) {
+				decisions[i].Status = "skipped"
+				decisions[i].Reason = fmt.Sprintf("%s is below the minimum quality", formatQuality(probe.BitDepth, probe.SampleRate))
+				return
+			}
+			candidates[i] = &qualityCandidate{service: service, probe: probe, probed: true, known: true}
+		}(i, service, prober)
+	}
+	wg.Wait()
+
+	decisionFor := func(service string) *ServiceQualityDecision {
+		for i := range decisions {
+			if decisions[i].Service == service {
+				return &decisions[i]
+			}
+		}
+		return nil
+	}
+
+	var ranked []qualityCandidate
+	for _, c := range candidates {
+		if c != nil {
+			ranked = append(ranked, *c)
+		}
+	}
+	rankQualityCandidates(ranked)
+
+	var lastErr error
+	var attempts []DownloadAttempt
+	for rank, candidate := range ranked {
+		decision := decisionFor(candidate.service)
+
+		if isDownloadCancelled(req.ItemID) {
+			return nil, ErrDownloadCancelled
+		}
+
+		if candidate.known {
+			GoLog("[BestQuality] Trying %s (%s)\n", candidate.service, formatQuality(candidate.probe.BitDepth, candidate.probe.SampleRate))
+		} else {
+			GoLog("[BestQuality] Trying %s (quality unknown)\n", candidate.service)
+		}
+
+		// Download the track the probe matched rather than searching again
+		var probe *QualityProbe
+		if candidate.probed {
+			probe = &candidate.probe
+		}
+
+		start := time.Now()
+		result, err := downloadAndVerify(candidate.service, req, probe)
+		if err != nil {
+			attempts = append(attempts, newDownloadAttempt(candidate.service, start, err))
+			if errors.Is(err, ErrDownloadCancelled) {
+				return nil, ErrDownloadCancelled
+			}
+			decision.Status = "failed"
+			decision.Reason = "download failed: " + err.Error()
+			lastErr = err
+			continue
+		}
+
+		resp := buildDownloadResponse(result, candidate.service, "Downloaded from "+candidate.service)
+		if reason := qualityFloorRejection(req, resp); reason != "" {
+			GoLog("[BestQuality] %s %s; removing\n", candidate.service, reason)
+			os.Remove(resp.FilePath)
+			InvalidateISRCCache(req.OutputDir)
+			decision.Status = "rejected"
+			decision.Reason = reason
+			lastErr = fmt.Errorf("%s %s", candidate.service, reason)
+			attempts = append(attempts, newDownloadAttempt(candidate.service, start, stageError(StageDownload, "", lastErr)))
+			continue
+		}
+		attempts = append(attempts, newDownloadAttempt(candidate.service, start, nil))
+
+		decision.Status = "chosen"
+		switch {
+		case resp.AlreadyExists:
+			decision.Reason = "file already exists"
+		case !candidate.known:
+			decision.Reason = "no service with known quality succeeded"
+		case rank == 0:
+			decision.Reason = fmt.Sprintf("highest available quality (%s)", formatQuality(candidate.probe.BitDepth, candidate.probe.SampleRate))
+		default:
+			decision.Reason = fmt.Sprintf("best remaining quality (%s) after higher-ranked services failed", formatQuality(candidate.probe.BitDepth, candidate.probe.SampleRate))
+		}
+		for i := range decisions {
+			if decisions[i].Status != "" {
+				continue
+			}
+			decisions[i].Status = "not_tried"
+			if decisions[i].BitDepth == 0 {
+				decisions[i].Reason = "quality unknown, only tried after probed services"
+			} else {
+				decisions[i].Reason = "lower quality than " + candidate.service
+			}
+		}
+		resp.QualityDecisions = decisions
+		resp.Attempts = attempts
+		return &resp, nil
+	}
+
+	errMsg := "No service could deliver the minimum quality"
+	errorType := classifyErrorType(errMsg)
+	if lastErr != nil {
+		errMsg = "All services failed. Last error: " + lastErr.Error()
+		errorType = classifyErrorType(lastErr.Error())
+	} else if len(ranked) == 0 && req.MinBitDepth == 0 && req.MinSampleRate == 0 {
+		errMsg = "All services failed to find the track"
+		errorType = classifyErrorType(errMsg)
+	}
+	return &DownloadResponse{
+		Success:          false,
+		Error:            errMsg,
+		ErrorType:        errorType,
+		QualityDecisions: decisions,
+		Attempts:         attempts,
+	}, nil
+}
@@ -0,0 +1,122 @@
+package gobackend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRankQualityCandidates(t *testing.T) {
+	candidates := []qualityCandidate{
+		{service: "amazon"},
+		{service: "tidal", known: true, probe: QualityProbe{BitDepth: 16, SampleRate: 44100}},
+		{service: "qobuz", known: true, probe: QualityProbe{BitDepth: 24, SampleRate: 192000}},
+		{service: "other", known: true, probe: QualityProbe{BitDepth: 24, SampleRate: 96000}},
+	}
+
+	rankQualityCandidates(candidates)
+
+	want := []string{"qobuz", "other", "tidal", "amazon"}
+	for i, c := range candidates {
+		if c.service != want[i] {
+			t.Fatalf("position %d: expected %s, got %s", i, want[i], c.service)
+		}
+	}
+}
+
+func TestMeetsQualityFloor(t *testing.T) {
+	req := DownloadRequest{MinBitDepth: 24, MinSampleRate: 88200}
+	if meetsQualityFloor(req, 16, 44100) {
+		t.Error("16/44.1 should be below a 24/88.2 floor")
+	}
+	if meetsQualityFloor(req, 24, 48000) {
+		t.Error("24/48 should be below a 24/88.2 floor")
+	}
+	if !meetsQualityFloor(req, 24, 96000) {
+		t.Error("24/96 should meet a 24/88.2 floor")
+	}
+	if !meetsQualityFloor(DownloadRequest{}, 16, 44100) {
+		t.Error("no floor should accept anything")
+	}
+}
+
+func TestQualityFloorRejection(t *testing.T) {
+	floor := DownloadRequest{MinBitDepth: 24}
+	tests := []struct {
+		name   string
+		req    DownloadRequest
+		resp   DownloadResponse
+		reject bool
+	}{
+		{"meets floor", floor, DownloadResponse{ActualBitDepth: 24, ActualSampleRate: 96000}, false},
+		{"below floor", floor, DownloadResponse{ActualBitDepth: 16, ActualSampleRate: 44100}, true},
+		{"unknown quality", floor, DownloadResponse{}, true},
+		{"unknown quality without floor", DownloadRequest{}, DownloadResponse{}, false},
+		{"existing file", floor, DownloadResponse{AlreadyExists: true}, false},
+	}
+	for _, tt := range tests {
+		if got := qualityFloorRejection(tt.req, tt.resp); (got != "") != tt.reject {
+			t.Errorf("%s: qualityFloorRejection = %q", tt.name, got)
+		}
+	}
+}
+
+// matchProvider probes a track it cannot rate and writes a file whose quality
+// cannot be read, recording which track each download was asked for
+type matchProvider struct {
+	id      string
+	matches *[]string
+}
+
+func (p matchProvider) ID() string { return p.id }
+
+func (p matchProvider) ProbeQuality(req DownloadRequest) (QualityProbe, error) {
+	return QualityProbe{TrackID: p.id + "-1"}, nil
+}
+
+func (p matchProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	return p.DownloadProbed(req, QualityProbe{})
+}
+
+func (p matchProvider) DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error) {
+	*p.matches = append(*p.matches, probe.TrackID)
+	path := filepath.Join(req.OutputDir, p.id+".flac")
+	if err := os.WriteFile(path, []byte("not audio"), 0644); err != nil {
+		return DownloadResult{}, err
+	}
+	return DownloadResult{FilePath: path}, nil
+}
+
+func TestDownloadWithBestQuality_ProbeMatchAndUnknownQuality(t *testing.T) {
+	var matches []string
+	saved, _ := GetDownloadProvider("tidal")
+	t.Cleanup(func() { RegisterDownloadProvider(saved) })
+	RegisterDownloadProvider(matchProvider{id: "tidal", matches: &matches})
+
+	setTestFallbackPolicy(t, FallbackPolicy{Services: []ServicePolicy{
+		{Service: "tidal", Enabled: true},
+		{Service: "qobuz", Enabled: false},
+		{Service: "amazon", Enabled: false},
+	}})
+
+	dir := t.TempDir()
+	resp, err := DownloadWithBestQuality(DownloadRequest{TrackName: "Song", ArtistName: "Artist", OutputDir: dir})
+	if err != nil || !resp.Success {
+		t.Fatalf("download without a floor failed: %+v, %v", resp, err)
+	}
+	if len(matches) != 1 || matches[0] != "tidal-1" {
+		t.Fatalf("downloads were asked for %q, want the probed track", matches)
+	}
+	os.Remove(resp.FilePath)
+
+	resp, err = DownloadWithBestQuality(DownloadRequest{TrackName: "Song", ArtistName: "Artist", OutputDir: dir, MinBitDepth: 24})
+	if err != nil || resp.Success {
+		t.Fatalf("a file of unknown quality passed the minimum quality: %+v, %v", resp, err)
+	}
+	if _, statErr := os.Stat(filepath.Join(dir, "tidal.flac")); !os.IsNotExist(statErr) {
+		t.Error("rejected file was not removed")
+	}
+	if len(resp.QualityDecisions) != 1 || resp.QualityDecisions[0].Status != "rejected" {
+		t.Errorf("decisions = %+v, want tidal rejected", resp.QualityDecisions)
+	}
+}
@@ -17,6 +17,10 @@ type cancelEntry struct {
 var (
 	cancelMu  sync.Mutex
 	cancelMap = make(map[string]*cancelEntry)
+	// abortingItems records user cancels that arrive while a timed-out
+	// attempt is being aborted. Providers replace cancelMap entries when they
+	// start, so the user's cancel cannot be read back from there.
+	abortingItems = make(map[string]*bool)
 )
 
 func initDownloadCancel(itemID string) context.Context {
@@ -50,6 +54,9 @@ func cancelDownload(itemID string) {
 	} else {
 		cancelMap[itemID] = &cancelEntry{canceled: true}
 	}
+	if userCancelled, aborting := abortingItems[itemID]; aborting {
+		*userCancelled = true
+	}
 	cancelMu.Unlock()
 
 	RemoveItemProgress(itemID)
@@ -76,3 +83,51 @@ func clearDownloadCancel(itemID string) {
 	delete(cancelMap, itemID)
 	cancelMu.Unlock()
 }
+
+// abortDownloadAttempt cancels the in-flight download for itemID without
+// touching its progress entry, so another provider can retry the same item
+// after clearDownloadCancel
+func abortDownloadAttempt(itemID string) {
+	cancelMu.Lock()
+	defer cancelMu.Unlock()
+
+	if entry, ok := cancelMap[itemID]; ok {
+		entry.canceled = true
+		if entry.cancel != nil {
+			entry.cancel()
+		}
+	} else {
+		cancelMap[itemID] = &cancelEntry{canceled: true}
+	}
+}
+
+// beginDownloadAbort starts aborting the attempt for itemID, after which
+// endDownloadAbort reports whether the user cancelled the item meanwhile
+func beginDownloadAbort(itemID string) {
+	cancelMu.Lock()
+	defer cancelMu.Unlock()
+
+	userCancelled := false
+	abortingItems[itemID] = &userCancelled
+}
+
+// endDownloadAbort finishes an abort started by beginDownloadAbort. The
+// cancel flag is cleared for the next provider unless the user cancelled the
+// item, in which case it stays set and true is returned.
+func endDownloadAbort(itemID string) bool {
+	cancelMu.Lock()
+	defer cancelMu.Unlock()
+
+	userCancelled := abortingItems[itemID]
+	delete(abortingItems, itemID)
+	if userCancelled != nil && *userCancelled {
+		if entry, ok := cancelMap[itemID]; ok {
+			entry.canceled = true
+		} else {
+			cancelMap[itemID] = &cancelEntry{canceled: true}
+		}
+		return true
+	}
+	delete(cancelMap, itemID)
+	return false
+}
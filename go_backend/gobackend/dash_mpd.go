@@ -0,0 +1,401 @@
+package gobackend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MPD is an MPEG-DASH manifest. Only the parts needed to list the segments
+// of a static audio presentation are modelled.
+type MPD struct {
+	XMLName                   xml.Name    `xml:"MPD"`
+	Type                      string      `xml:"type,attr"`
+	MediaPresentationDuration string      `xml:"mediaPresentationDuration,attr"`
+	BaseURLs                  []string    `xml:"BaseURL"`
+	Periods                   []MPDPeriod `xml:"Period"`
+}
+
+type MPDPeriod struct {
+	ID             string             `xml:"id,attr"`
+	Start          string             `xml:"start,attr"`
+	Duration       string             `xml:"duration,attr"`
+	BaseURLs       []string           `xml:"BaseURL"`
+	AdaptationSets []MPDAdaptationSet `xml:"AdaptationSet"`
+}
+
+type MPDAdaptationSet struct {
+	ContentType     string              `xml:"contentType,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	BaseURLs        []string            `xml:"BaseURL"`
+	SegmentTemplate *MPDSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *MPDSegmentList     `xml:"SegmentList"`
+	Representations []MPDRepresentation `xml:"Representation"`
+}
+
+type MPDRepresentation struct {
+	ID              string              `xml:"id,attr"`
+	Bandwidth       int64               `xml:"bandwidth,attr"`
+	Codecs          string              `xml:"codecs,attr"`
+	MimeType        string              `xml:"mimeType,attr"`
+	BaseURLs        []string            `xml:"BaseURL"`
+	SegmentTemplate *MPDSegmentTemplate `xml:"SegmentTemplate"`
+	SegmentList     *MPDSegmentList     `xml:"SegmentList"`
+}
+
+type MPDSegmentTemplate struct {
+	Initialization string              `xml:"initialization,attr"`
+	Media          string              `xml:"media,attr"`
+	StartNumber    *int64              `xml:"startNumber,attr"`
+	Timescale      int64               `xml:"timescale,attr"`
+	Duration       int64               `xml:"duration,attr"`
+	Timeline       *MPDSegmentTimeline `xml:"SegmentTimeline"`
+}
+
+type MPDSegmentTimeline struct {
+	Segments []MPDTimelineSegment `xml:"S"`
+}
+
+type MPDTimelineSegment struct {
+	Time     *int64 `xml:"t,attr"`
+	Duration int64  `xml:"d,attr"`
+	Repeat   int64  `xml:"r,attr"` // -1 repeats until the next S or the period end
+}
+
+type MPDSegmentList struct {
+	Initialization *struct {
+		SourceURL string `xml:"sourceURL,attr"`
+	} `xml:"Initialization"`
+	SegmentURLs []struct {
+		Media string `xml:"media,attr"`
+	} `xml:"SegmentURL"`
+}
+
+// DASHPeriodSegments is the segment list of the representation chosen for
+// one period
+type DASHPeriodSegments struct {
+	RepresentationID string
+	Codecs           string
+	Bandwidth        int64
+	InitURL          string // Empty for self-initializing segments
+	MediaURLs        []string
+}
+
+// maxMPDSegments guards against malformed manifests expanding to huge lists
+const maxMPDSegments = 100000
+
+// parseMPD lists the audio segments of every period of a manifest.
+// manifestURL is the location the manifest was fetched from and is used to
+// resolve relative URLs; it may be empty.
+func parseMPD(data []byte, manifestURL string) ([]DASHPeriodSegments, error) {
+	var mpd MPD
+	if err := xml.Unmarshal(data, &mpd); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest XML: %w", err)
+	}
+	if mpd.Type == "dynamic" {
+		return nil, fmt.Errorf("live (dynamic) manifests are not supported")
+	}
+	if len(mpd.Periods) == 0 {
+		return nil, fmt.Errorf("no periods in manifest")
+	}
+
+	totalDuration, _ := parseISODuration(mpd.MediaPresentationDuration)
+	base := resolveMPDBaseURL(manifestURL, mpd.BaseURLs)
+
+	var result []DASHPeriodSegments
+	for i, period := range mpd.Periods {
+		periodDuration, ok := parseISODuration(period.Duration)
+		if !ok {
+			// The last period runs to the end of the presentation
+			start, _ := parseISODuration(period.Start)
+			if i == len(mpd.Periods)-1 && totalDuration > start {
+				periodDuration = totalDuration - start
+			} else if nextStart, ok := nextPeriodStart(mpd.Periods, i); ok && nextStart > start {
+				periodDuration = nextStart - start
+			}
+		}
+
+		segments, err := parseMPDPeriod(period, resolveMPDBaseURL(base, period.BaseURLs), periodDuration)
+		if err != nil {
+			return nil, fmt.Errorf("period %d: %w", i+1, err)
+		}
+		result = append(result, segments)
+	}
+	return result, nil
+}
+
+func nextPeriodStart(periods []MPDPeriod, i int) (float64, bool) {
+	if i+1 >= len(periods) {
+		return 0, false
+	}
+	return parseISODuration(periods[i+1].Start)
+}
+
+func parseMPDPeriod(period MPDPeriod, base string, periodDuration float64) (DASHPeriodSegments, error) {
+	set, rep, ok := selectMPDRepresentation(period.AdaptationSets)
+	if !ok {
+		return DASHPeriodSegments{}, fmt.Errorf("no audio representation found")
+	}
+
+	base = resolveMPDBaseURL(resolveMPDBaseURL(base, set.BaseURLs), rep.BaseURLs)
+	codecs := rep.Codecs
+	if codecs == "" {
+		codecs = set.Codecs
+	}
+	segments := DASHPeriodSegments{RepresentationID: rep.ID, Codecs: codecs, Bandwidth: rep.Bandwidth}
+
+	if list := firstSegmentList(rep.SegmentList, set.SegmentList); list != nil {
+		if list.Initialization != nil && list.Initialization.SourceURL != "" {
+			segments.InitURL = resolveMPDURL(base, list.Initialization.SourceURL)
+		}
+		for _, s := range list.SegmentURLs {
+			segments.MediaURLs = append(segments.MediaURLs, resolveMPDURL(base, s.Media))
+		}
+		if len(segments.MediaURLs) == 0 {
+			return segments, fmt.Errorf("empty segment list")
+		}
+		return segments, nil
+	}
+
+	tmpl := mergeSegmentTemplates(set.SegmentTemplate, rep.SegmentTemplate)
+	if tmpl == nil || tmpl.Media == "" {
+		return segments, fmt.Errorf("representation %q has no segment template or list", rep.ID)
+	}
+
+	if tmpl.Initialization != "" {
+		segments.InitURL = resolveMPDURL(base, expandMPDTemplate(tmpl.Initialization, rep, 0, 0))
+	}
+
+	number := int64(1)
+	if tmpl.StartNumber != nil {
+		number = *tmpl.StartNumber
+	}
+
+	switch {
+	case tmpl.Timeline != nil && len(tmpl.Timeline.Segments) > 0:
+		timescale := tmpl.Timescale
+		if timescale <= 0 {
+			timescale = 1
+		}
+		periodEnd := int64(periodDuration * float64(timescale))
+
+		var t int64
+		entries := tmpl.Timeline.Segments
+		for i, s := range entries {
+			if s.Time != nil {
+				t = *s.Time
+			}
+			if s.Duration <= 0 {
+				return segments, fmt.Errorf("invalid segment duration %d", s.Duration)
+			}
+			repeat := s.Repeat
+			if repeat < 0 {
+				// Repeat until the next explicit start time or the end of the period
+				end := periodEnd
+				if i+1 < len(entries) && entries[i+1].Time != nil {
+					end = *entries[i+1].Time
+				}
+				repeat = 0
+				if end > t {
+					repeat = (end-t+s.Duration-1)/s.Duration - 1
+				}
+			}
+			for r := int64(0); r <= repeat; r++ {
+				if len(segments.MediaURLs) >= maxMPDSegments {
+					return segments, fmt.Errorf("manifest lists more than %d segments", maxMPDSegments)
+				}
+				segments.MediaURLs = append(segments.MediaURLs, resolveMPDURL(base, expandMPDTemplate(tmpl.Media, rep, number, t)))
+				number++
+				t += s.Duration
+			}
+		}
+	case tmpl.Duration > 0:
+		if periodDuration <= 0 {
+			return segments, fmt.Errorf("segment count unknown: manifest has no duration")
+		}
+		timescale := tmpl.Timescale
+		if timescale <= 0 {
+			timescale = 1
+		}
+		segmentSeconds := float64(tmpl.Duration) / float64(timescale)
+		count := int64(periodDuration / segmentSeconds)
+		if float64(count)*segmentSeconds < periodDuration-1e-9 {
+			count++
+		}
+		if count > maxMPDSegments {
+			return segments, fmt.Errorf("manifest lists more than %d segments", maxMPDSegments)
+		}
+		for i := int64(0); i < count; i++ {
+			segments.MediaURLs = append(segments.MediaURLs, resolveMPDURL(base, expandMPDTemplate(tmpl.Media, rep, number+i, i*tmpl.Duration)))
+		}
+	default:
+		return segments, fmt.Errorf("segment template has neither a timeline nor a duration")
+	}
+
+	return segments, nil
+}
+
+// selectMPDRepresentation picks the audio representation to download: FLAC
+// when offered, otherwise the highest bandwidth
+func selectMPDRepresentation(sets []MPDAdaptationSet) (MPDAdaptationSet, MPDRepresentation, bool) {
+	var bestSet MPDAdaptationSet
+	var best MPDRepresentation
+	found := false
+	bestLossless := false
+
+	for _, set := range sets {
+		if !isAudioAdaptationSet(set) {
+			continue
+		}
+		for _, rep := range set.Representations {
+			codecs := rep.Codecs
+			if codecs == "" {
+				codecs = set.Codecs
+			}
+			lossless := strings.HasPrefix(strings.ToLower(codecs), "flac") || strings.HasPrefix(strings.ToLower(codecs), "alac")
+			better := !found ||
+				(lossless && !bestLossless) ||
+				(lossless == bestLossless && rep.Bandwidth > best.Bandwidth)
+			if better {
+				bestSet, best, found, bestLossless = set, rep, true, lossless
+			}
+		}
+	}
+	return bestSet, best, found
+}
+
+func isAudioAdaptationSet(set MPDAdaptationSet) bool {
+	if set.ContentType != "" {
+		return set.ContentType == "audio"
+	}
+	if set.MimeType != "" {
+		return strings.HasPrefix(set.MimeType, "audio/")
+	}
+	for _, rep := range set.Representations {
+		if rep.MimeType != "" && !strings.HasPrefix(rep.MimeType, "audio/") {
+			return false
+		}
+	}
+	return true
+}
+
+func firstSegmentList(lists ...*MPDSegmentList) *MPDSegmentList {
+	for _, l := range lists {
+		if l != nil {
+			return l
+		}
+	}
+	return nil
+}
+
+// mergeSegmentTemplates applies representation-level attributes over the
+// adaptation set's template
+func mergeSegmentTemplates(parent, child *MPDSegmentTemplate) *MPDSegmentTemplate {
+	if parent == nil {
+		return child
+	}
+	if child == nil {
+		return parent
+	}
+	merged := *parent
+	if child.Initialization != "" {
+		merged.Initialization = child.Initialization
+	}
+	if child.Media != "" {
+		merged.Media = child.Media
+	}
+	if child.StartNumber != nil {
+		merged.StartNumber = child.StartNumber
+	}
+	if child.Timescale > 0 {
+		merged.Timescale = child.Timescale
+	}
+	if child.Duration > 0 {
+		merged.Duration = child.Duration
+	}
+	if child.Timeline != nil {
+		merged.Timeline = child.Timeline
+	}
+	return &merged
+}
+
+var mpdTemplatePattern = regexp.MustCompile(`\$(RepresentationID|Number|Time|Bandwidth)(%0(\d+)d)?\$|\$\$`)
+
+// expandMPDTemplate substitutes $RepresentationID$, $Number$, $Time$ and
+// $Bandwidth$, including width formats such as $Number%05d$
+func expandMPDTemplate(template string, rep MPDRepresentation, number, time int64) string {
+	return mpdTemplatePattern.ReplaceAllStringFunc(template, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+		m := mpdTemplatePattern.FindStringSubmatch(match)
+		var value int64
+		switch m[1] {
+		case "RepresentationID":
+			return rep.ID
+		case "Number":
+			value = number
+		case "Time":
+			value = time
+		case "Bandwidth":
+			value = rep.Bandwidth
+		}
+		if m[3] != "" {
+			width, _ := strconv.Atoi(m[3])
+			return fmt.Sprintf("%0*d", width, value)
+		}
+		return strconv.FormatInt(value, 10)
+	})
+}
+
+// resolveMPDBaseURL applies the first BaseURL element to base
+func resolveMPDBaseURL(base string, baseURLs []string) string {
+	if len(baseURLs) == 0 {
+		return base
+	}
+	return resolveMPDURL(base, strings.TrimSpace(baseURLs[0]))
+}
+
+// resolveMPDURL resolves ref against base; without a base, ref is returned as-is
+func resolveMPDURL(base, ref string) string {
+	if base == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+(?:\.\d+)?)Y)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)D)?(?:T(?:(\d+(?:\.\d+)?)H)?(?:(\d+(?:\.\d+)?)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// parseISODuration parses an xs:duration such as "PT3M25.5S" into seconds.
+// Years and months are approximated as 365 and 30 days.
+func parseISODuration(s string) (float64, bool) {
+	m := isoDurationPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil || s == "P" {
+		return 0, false
+	}
+	units := []float64{365 * 86400, 30 * 86400, 86400, 3600, 60, 1}
+	var total float64
+	for i, unit := range units {
+		if m[i+1] == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(m[i+1], 64)
+		if err != nil {
+			return 0, false
+		}
+		total += v * unit
+	}
+	return total, true
+}
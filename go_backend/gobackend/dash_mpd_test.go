@@ -0,0 +1,207 @@
+package gobackend
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// loadMPDFixture reads a manifest from testdata/mpd. The fixtures are
+// hand-written: tidal_flac.mpd follows the layout of Tidal's FLAC manifests
+// with a made-up host path and token, the others cover MPD features. Captured
+// manifests go in testdata/mpd/captured; see TestParseManifest_CapturedTidal.
+func loadMPDFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", "mpd", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestParseMPD_TidalFLAC(t *testing.T) {
+	data := loadMPDFixture(t, "tidal_flac.mpd")
+
+	directURL, initURL, mediaURLs, err := parseManifest(base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directURL != "" {
+		t.Errorf("unexpected direct URL %q", directURL)
+	}
+	const prefix = "https://sp-pr-fa.audio.tidal.com/mediatracks/GCkQAA/"
+	const query = ".mp4?token=1718000000~ZmFrZQ&sig=abc"
+	if initURL != prefix+"0"+query {
+		t.Errorf("init URL = %q", initURL)
+	}
+	if len(mediaURLs) != 53 {
+		t.Fatalf("got %d segments, want 53", len(mediaURLs))
+	}
+	if mediaURLs[0] != prefix+"1"+query || mediaURLs[52] != prefix+"53"+query {
+		t.Errorf("unexpected segment URLs %q ... %q", mediaURLs[0], mediaURLs[52])
+	}
+}
+
+// TestParseManifest_CapturedTidal runs the parser over manifests captured
+// from Tidal in testdata/mpd/captured. To add one, decode the "manifest" field
+// of a playbackinfo response whose manifestMimeType is application/dash+xml,
+// and replace the mediatracks path and the token and signature values with
+// placeholders before saving it as <quality>_<layout>.mpd.
+func TestParseManifest_CapturedTidal(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "mpd", "captured", "*.mpd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Skip("no captured Tidal manifests in testdata/mpd/captured")
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Base(file)
+		directURL, initURL, mediaURLs, err := parseManifest(base64.StdEncoding.EncodeToString(data))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if directURL != "" || initURL == "" || len(mediaURLs) == 0 {
+			t.Errorf("%s: direct %q, init %q, %d segments", name, directURL, initURL, len(mediaURLs))
+			continue
+		}
+		seen := make(map[string]bool, len(mediaURLs))
+		for _, u := range append([]string{initURL}, mediaURLs...) {
+			if !strings.HasPrefix(u, "https://") || seen[u] {
+				t.Errorf("%s: bad or repeated segment URL %q", name, u)
+				break
+			}
+			seen[u] = true
+		}
+	}
+}
+
+func TestParseMPD_SelectsRepresentationAndExpandsTime(t *testing.T) {
+	periods, err := parseMPD(loadMPDFixture(t, "multi_representation.mpd"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 1 {
+		t.Fatalf("got %d periods", len(periods))
+	}
+
+	p := periods[0]
+	if p.RepresentationID != "flac-hires" || p.Codecs != "flac" {
+		t.Errorf("selected %q (%s), want flac-hires", p.RepresentationID, p.Codecs)
+	}
+	const base = "https://cdn.example.com/audio/track-42/flac-hires/"
+	if p.InitURL != base+"init.mp4" {
+		t.Errorf("init URL = %q", p.InitURL)
+	}
+	var want []string
+	for _, ts := range []int{1024, 193024, 385024, 577024, 769024} {
+		want = append(want, fmt.Sprintf("%sseg-%d.m4s", base, ts))
+	}
+	if !reflect.DeepEqual(p.MediaURLs, want) {
+		t.Errorf("media URLs = %v", p.MediaURLs)
+	}
+}
+
+func TestParseMPD_SegmentListWithBaseURLs(t *testing.T) {
+	periods, err := parseMPD(loadMPDFixture(t, "segment_list.mpd"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := periods[0]
+	const base = "https://media.example.com/streams/album-7/lossless/"
+	if p.InitURL != base+"init.mp4" {
+		t.Errorf("init URL = %q", p.InitURL)
+	}
+	want := []string{base + "chunk-001.m4s", base + "chunk-002.m4s", "https://media.example.com/shared/chunk-003.m4s"}
+	if !reflect.DeepEqual(p.MediaURLs, want) {
+		t.Errorf("media URLs = %v", p.MediaURLs)
+	}
+}
+
+func TestParseMPD_MultiPeriodWithStartNumber(t *testing.T) {
+	data := loadMPDFixture(t, "multi_period.mpd")
+	periods, err := parseMPD(data, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(periods) != 2 {
+		t.Fatalf("got %d periods", len(periods))
+	}
+
+	wantIntro := []string{
+		"https://cdn.example.com/a1/00000.m4s",
+		"https://cdn.example.com/a1/00001.m4s",
+		"https://cdn.example.com/a1/00002.m4s",
+	}
+	if !reflect.DeepEqual(periods[0].MediaURLs, wantIntro) {
+		t.Errorf("intro segments = %v", periods[0].MediaURLs)
+	}
+	if n := len(periods[1].MediaURLs); n != 4 || periods[1].MediaURLs[0] != "https://cdn.example.com/a2/7.m4s" {
+		t.Errorf("main segments = %v", periods[1].MediaURLs)
+	}
+
+	_, initURL, mediaURLs, err := parseManifest(base64.StdEncoding.EncodeToString(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One init segment serves both periods
+	if initURL != "https://cdn.example.com/a1/init.mp4" || len(mediaURLs) != 7 || mediaURLs[3] != "https://cdn.example.com/a2/7.m4s" {
+		t.Errorf("flattened manifest: init=%q media=%v", initURL, mediaURLs)
+	}
+}
+
+func TestParseManifest_RejectsMixedCodecPeriods(t *testing.T) {
+	data := []byte(`<MPD type="static" mediaPresentationDuration="PT16S">
+		<Period duration="PT8S"><AdaptationSet mimeType="audio/mp4"><Representation id="r1" codecs="flac" bandwidth="1">
+			<SegmentTemplate initialization="https://cdn.example.com/r1/init.mp4" media="https://cdn.example.com/r1/$Number$.m4s" duration="4"/>
+		</Representation></AdaptationSet></Period>
+		<Period duration="PT8S"><AdaptationSet mimeType="audio/mp4"><Representation id="r2" codecs="mp4a.40.2" bandwidth="1">
+			<SegmentTemplate initialization="https://cdn.example.com/r2/init.mp4" media="https://cdn.example.com/r2/$Number$.m4s" duration="4"/>
+		</Representation></AdaptationSet></Period>
+	</MPD>`)
+	if _, _, _, err := parseManifest(base64.StdEncoding.EncodeToString(data)); err == nil {
+		t.Error("expected periods with different codecs to be rejected")
+	}
+}
+
+func TestParseMPD_RelativeToManifestURL(t *testing.T) {
+	data := []byte(`<MPD type="static" mediaPresentationDuration="PT8S"><Period><AdaptationSet mimeType="audio/mp4">
+		<Representation id="r1" bandwidth="1"><SegmentTemplate initialization="init-$RepresentationID$.mp4" media="$Number$.m4s" duration="4"/></Representation>
+	</AdaptationSet></Period></MPD>`)
+	periods, err := parseMPD(data, "https://host.example/path/manifest.mpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://host.example/path/1.m4s", "https://host.example/path/2.m4s"}
+	if periods[0].InitURL != "https://host.example/path/init-r1.mp4" || !reflect.DeepEqual(periods[0].MediaURLs, want) {
+		t.Errorf("got init=%q media=%v", periods[0].InitURL, periods[0].MediaURLs)
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := map[string]float64{
+		"PT3M30.023S": 210.023,
+		"PT1H":        3600,
+		"P1DT2S":      86402,
+	}
+	for in, want := range tests {
+		got, ok := parseISODuration(in)
+		if !ok || got < want-1e-6 || got > want+1e-6 {
+			t.Errorf("parseISODuration(%q) = %v, %v", in, got, ok)
+		}
+	}
+	if _, ok := parseISODuration("3 minutes"); ok {
+		t.Error("expected failure for invalid duration")
+	}
+}
@@ -0,0 +1,204 @@
+package gobackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const defaultSegmentConcurrency = 4
+
+// segmentRetryConfig is the backoff used for individual DASH segments. Segments
+// are small, so retries start quickly and never wait long.
+func segmentRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries:    DefaultMaxRetries,
+		InitialDelay:  500 * time.Millisecond,
+		MaxDelay:      8 * time.Second,
+		BackoffFactor: 2.0,
+	}
+}
+
+// segmentStatusError is a non-2xx segment response
+type segmentStatusError struct {
+	StatusCode int
+}
+
+func (e *segmentStatusError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// retryable reports whether a failed segment request is worth repeating
+func (e *segmentStatusError) retryable() bool {
+	return e.StatusCode == 429 || e.StatusCode >= 500
+}
+
+// fetchSegment downloads one segment, retrying network errors, truncated
+// bodies, 429 and 5xx responses with exponential backoff
+func fetchSegment(ctx context.Context, client *http.Client, segmentURL string, config RetryConfig) ([]byte, error) {
+	var lastErr error
+	delay := config.InitialDelay
+
+	for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+			delay = calculateNextDelay(delay, config)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", segmentURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", getRandomUserAgent())
+
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			if CheckAndLogISPBlocking(err, segmentURL, "Tidal") {
+				return nil, WrapErrorWithISPCheck(err, segmentURL, "Tidal")
+			}
+			lastErr = err
+			continue
+		}
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			statusErr := &segmentStatusError{StatusCode: resp.StatusCode}
+			if resp.StatusCode == 429 {
+				if retryAfter := getRetryAfterDuration(resp); retryAfter > 0 {
+					delay = min(retryAfter, config.MaxDelay)
+				}
+			}
+			resp.Body.Close()
+			if !statusErr.retryable() {
+				return nil, statusErr
+			}
+			lastErr = statusErr
+			continue
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			lastErr = err
+			continue
+		}
+		return data, nil
+	}
+
+	return nil, fmt.Errorf("failed after %d attempts: %w", config.MaxRetries+1, lastErr)
+}
+
+type fetchedSegment struct {
+	index int
+	data  []byte
+	err   error
+}
+
+// fetchSegmentsOrdered downloads segments with up to concurrency requests in
+// flight and writes them to w in manifest order. At most 2*concurrency
+// segments are buffered ahead of the writer. onWritten is called after each
+// segment is written with the number of segments written so far.
+func fetchSegmentsOrdered(ctx context.Context, client *http.Client, segmentURLs []string, w io.Writer, concurrency int, config RetryConfig, onWritten func(written int)) error {
+	if len(segmentURLs) == 0 {
+		return nil
+	}
+	if concurrency <= 0 {
+		concurrency = defaultSegmentConcurrency
+	}
+	concurrency = min(concurrency, len(segmentURLs))
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// window bounds how far fetching may run ahead of writing
+	window := make(chan struct{}, 2*concurrency)
+	indexes := make(chan int)
+	results := make(chan fetchedSegment, concurrency)
+
+	go func() {
+		defer close(indexes)
+		for i := range segmentURLs {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				data, err := fetchSegment(ctx, client, segmentURLs[i], config)
+				select {
+				case results <- fetchedSegment{index: i, data: data, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int][]byte)
+	next := 0
+	for next < len(segmentURLs) {
+		seg, ok := <-results
+		if !ok {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return fmt.Errorf("segment fetcher stopped at segment %d", next+1)
+		}
+		if seg.err != nil {
+			return fmt.Errorf("segment %d: %w", seg.index+1, seg.err)
+		}
+		pending[seg.index] = seg.data
+
+		for {
+			data, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if _, err := w.Write(data); err != nil {
+				return fmt.Errorf("failed to write segment %d: %w", next+1, err)
+			}
+			next++
+			<-window
+			if onWritten != nil {
+				onWritten(next)
+			}
+		}
+	}
+	return nil
+}
+
+// isSegmentCancelled reports whether a segment error came from cancellation
+func isSegmentCancelled(err error, itemID string) bool {
+	return errors.Is(err, ErrDownloadCancelled) || errors.Is(err, context.Canceled) || isDownloadCancelled(itemID)
+}
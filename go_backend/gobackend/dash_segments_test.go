@@ -0,0 +1,80 @@
+package gobackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testSegmentRetryConfig() RetryConfig {
+	return RetryConfig{MaxRetries: 2, InitialDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, BackoffFactor: 2}
+}
+
+func TestFetchSegmentsOrdered_ReordersAndRetries(t *testing.T) {
+	var mu sync.Mutex
+	failedOnce := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := strings.TrimPrefix(r.URL.Path, "/seg/")
+		// Later segments answer sooner so they arrive out of order
+		var idx int
+		fmt.Sscanf(n, "%d", &idx)
+		time.Sleep(time.Duration(20-idx) * time.Millisecond)
+
+		mu.Lock()
+		firstTry := !failedOnce[n]
+		failedOnce[n] = true
+		mu.Unlock()
+		if idx%3 == 0 && firstTry {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "[%s]", n)
+	}))
+	defer server.Close()
+
+	var urls []string
+	var want strings.Builder
+	for i := 1; i <= 12; i++ {
+		urls = append(urls, fmt.Sprintf("%s/seg/%d", server.URL, i))
+		fmt.Fprintf(&want, "[%d]", i)
+	}
+
+	var out bytes.Buffer
+	lastWritten := 0
+	err := fetchSegmentsOrdered(context.Background(), server.Client(), urls, &out, 4, testSegmentRetryConfig(), func(written int) {
+		lastWritten = written
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != want.String() {
+		t.Errorf("segments out of order:\n got %s\nwant %s", out.String(), want.String())
+	}
+	if lastWritten != len(urls) {
+		t.Errorf("onWritten reported %d, want %d", lastWritten, len(urls))
+	}
+}
+
+func TestFetchSegmentsOrdered_StopsOnPermanentFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/seg/2" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte("x"))
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/seg/1", server.URL + "/seg/2", server.URL + "/seg/3"}
+	var out bytes.Buffer
+	err := fetchSegmentsOrdered(context.Background(), server.Client(), urls, &out, 2, testSegmentRetryConfig(), nil)
+	if err == nil || !strings.Contains(err.Error(), "segment 2") {
+		t.Fatalf("expected segment 2 failure, got %v", err)
+	}
+}
@@ -20,18 +20,11 @@ const (
 	deezerArtistURL   = deezerBaseURL + "/artist/%s"
 	deezerPlaylistURL = deezerBaseURL + "/playlist/%s"
 
-	deezerCacheTTL = 10 * time.Minute
-
 	deezerMaxParallelISRC = 10
 )
 
 type DeezerClient struct {
-	httpClient  *http.Client
-	searchCache map[string]*cacheEntry
-	albumCache  map[string]*cacheEntry
-	artistCache map[string]*cacheEntry
-	isrcCache   map[string]string
-	cacheMu     sync.RWMutex
+	httpClient *http.Client
 }
 
 var (
@@ -42,11 +35,7 @@ var (
 func GetDeezerClient() *DeezerClient {
 	deezerClientOnce.Do(func() {
 		deezerClient = &DeezerClient{
-			httpClient:  NewHTTPClientWithTimeout(15 * time.Second),
-			searchCache: make(map[string]*cacheEntry),
-			albumCache:  make(map[string]*cacheEntry),
-			artistCache: make(map[string]*cacheEntry),
-			isrcCache:   make(map[string]string),
+			httpClient: NewHTTPClientWithTimeout(15 * time.Second),
 		}
 	})
 	return deezerClient
@@ -188,13 +177,11 @@ func (c *DeezerClient) SearchAll(ctx context.Context, query string, trackLimit,
 
 	cacheKey := fmt.Sprintf("deezer:all:%s:%d:%d", query, trackLimit, artistLimit)
 
-	c.cacheMu.RLock()
-	if entry, ok := c.searchCache[cacheKey]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
+	var cached SearchAllResult
+	if GetMetadataCache().Get(CacheDeezerSearch, cacheKey, &cached) {
 		GoLog("[Deezer] SearchAll: returning cached result\n")
-		return entry.data.(*SearchAllResult), nil
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	result := &SearchAllResult{
 		Tracks:  make([]TrackMetadata, 0, trackLimit),
@@ -261,12 +248,7 @@ func (c *DeezerClient) SearchAll(ctx context.Context, query string, trackLimit,
 
 	GoLog("[Deezer] SearchAll complete: %d tracks, %d artists\n", len(result.Tracks), len(result.Artists))
 
-	c.cacheMu.Lock()
-	c.searchCache[cacheKey] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(deezerCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheDeezerSearch, cacheKey, result)
 
 	return result, nil
 }
@@ -287,12 +269,10 @@ func (c *DeezerClient) GetTrack(ctx context.Context, trackID string) (*TrackResp
 
 // ISRC is fetched in parallel for better performance
 func (c *DeezerClient) GetAlbum(ctx context.Context, albumID string) (*AlbumResponsePayload, error) {
-	c.cacheMu.RLock()
-	if entry, ok := c.albumCache[albumID]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*AlbumResponsePayload), nil
+	var cached AlbumResponsePayload
+	if GetMetadataCache().Get(CacheDeezerAlbum, albumID, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	albumURL := fmt.Sprintf(deezerAlbumURL, albumID)
 
@@ -367,23 +347,16 @@ func (c *DeezerClient) GetAlbum(ctx context.Context, albumID string) (*AlbumResp
 		TrackList: tracks,
 	}
 
-	c.cacheMu.Lock()
-	c.albumCache[albumID] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(deezerCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheDeezerAlbum, albumID, result)
 
 	return result, nil
 }
 
 func (c *DeezerClient) GetArtist(ctx context.Context, artistID string) (*ArtistResponsePayload, error) {
-	c.cacheMu.RLock()
-	if entry, ok := c.artistCache[artistID]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*ArtistResponsePayload), nil
+	var cached ArtistResponsePayload
+	if GetMetadataCache().Get(CacheDeezerArtist, artistID, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	// Fetch artist info
 	artistURL := fmt.Sprintf(deezerArtistURL, artistID)
@@ -452,12 +425,7 @@ func (c *DeezerClient) GetArtist(ctx context.Context, artistID string) (*ArtistR
 		Albums:     albums,
 	}
 
-	c.cacheMu.Lock()
-	c.artistCache[artistID] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(deezerCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheDeezerArtist, artistID, result)
 
 	return result, nil
 }
@@ -563,35 +531,21 @@ func (c *DeezerClient) fetchISRCsParallel(ctx context.Context, tracks []deezerTr
 	result := make(map[string]string, len(tracks))
 	var resultMu sync.Mutex
 
+	cache := GetMetadataCache()
 	var tracksToFetch []deezerTrack
-	var directISRCs map[string]string
-	c.cacheMu.RLock()
 	for _, track := range tracks {
 		trackIDStr := fmt.Sprintf("%d", track.ID)
 		if track.ISRC != "" {
 			result[trackIDStr] = track.ISRC
-			if _, ok := c.isrcCache[trackIDStr]; !ok {
-				if directISRCs == nil {
-					directISRCs = make(map[string]string)
-				}
-				directISRCs[trackIDStr] = track.ISRC
-			}
 			continue
 		}
-		if isrc, ok := c.isrcCache[trackIDStr]; ok {
+		var isrc string
+		if cache.Get(CacheDeezerISRC, trackIDStr, &isrc) {
 			result[trackIDStr] = isrc
 		} else {
 			tracksToFetch = append(tracksToFetch, track)
 		}
 	}
-	c.cacheMu.RUnlock()
-	if len(directISRCs) > 0 {
-		c.cacheMu.Lock()
-		for trackIDStr, isrc := range directISRCs {
-			c.isrcCache[trackIDStr] = isrc
-		}
-		c.cacheMu.Unlock()
-	}
 
 	if len(tracksToFetch) == 0 {
 		return result
@@ -624,9 +578,7 @@ func (c *DeezerClient) fetchISRCsParallel(ctx context.Context, tracks []deezerTr
 			result[trackIDStr] = fullTrack.ISRC
 			resultMu.Unlock()
 
-			c.cacheMu.Lock()
-			c.isrcCache[trackIDStr] = fullTrack.ISRC
-			c.cacheMu.Unlock()
+			cache.Set(CacheDeezerISRC, trackIDStr, fullTrack.ISRC)
 		}(track)
 	}
 
@@ -636,21 +588,17 @@ func (c *DeezerClient) fetchISRCsParallel(ctx context.Context, tracks []deezerTr
 
 // Use this when you need ISRC for download
 func (c *DeezerClient) GetTrackISRC(ctx context.Context, trackID string) (string, error) {
-	c.cacheMu.RLock()
-	if isrc, ok := c.isrcCache[trackID]; ok {
-		c.cacheMu.RUnlock()
+	var isrc string
+	if GetMetadataCache().Get(CacheDeezerISRC, trackID, &isrc) {
 		return isrc, nil
 	}
-	c.cacheMu.RUnlock()
 
 	fullTrack, err := c.fetchFullTrack(ctx, trackID)
 	if err != nil {
 		return "", err
 	}
 
-	c.cacheMu.Lock()
-	c.isrcCache[trackID] = fullTrack.ISRC
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheDeezerISRC, trackID, fullTrack.ISRC)
 
 	return fullTrack.ISRC, nil
 }
@@ -705,13 +653,10 @@ func (c *DeezerClient) GetAlbumExtendedMetadata(ctx context.Context, albumID str
 		return nil, fmt.Errorf("empty album ID")
 	}
 
-	cacheKey := fmt.Sprintf("album_meta:%s", albumID)
-	c.cacheMu.RLock()
-	if entry, ok := c.searchCache[cacheKey]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*AlbumExtendedMetadata), nil
+	var cached AlbumExtendedMetadata
+	if GetMetadataCache().Get(CacheDeezerAlbumEx, albumID, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	albumURL := fmt.Sprintf(deezerAlbumURL, albumID)
 
@@ -732,12 +677,7 @@ func (c *DeezerClient) GetAlbumExtendedMetadata(ctx context.Context, albumID str
 		Label: album.Label,
 	}
 
-	c.cacheMu.Lock()
-	c.searchCache[cacheKey] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(deezerCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheDeezerAlbumEx, albumID, result)
 
 	GoLog("[Deezer] Album metadata fetched - Genre: %s, Label: %s\n", result.Genre, result.Label)
 
@@ -0,0 +1,148 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Matching and download stages reported in attempt trails
+const (
+	StageProviderID     = "provider_id"     // ID from Odesli enrichment or the track ID cache
+	StageISRCSearch     = "isrc_search"     // Search by ISRC
+	StageSongLink       = "songlink"        // ID lookup through SongLink
+	StageMetadataSearch = "metadata_search" // Search by title/artist
+	StageExtension      = "extension"       // Extension download provider
+	StageDownloadURL    = "download_url"    // Resolving the stream URL
+	StageDownload       = "download"        // Fetching and tagging the audio
+	StageVerify         = "verify"          // Checking the downloaded file
+)
+
+// Rejection reasons reported in attempt trails
+const (
+	RejectArtistMismatch   = "artist_mismatch"
+	RejectTitleMismatch    = "title_mismatch"
+	RejectISRCMismatch     = "isrc_mismatch"
+	RejectDurationMismatch = "duration_mismatch"
+	RejectIDMismatch       = "id_mismatch" // The provider downloaded another track than the requested ID
+	RejectPreview          = "preview"     // The source only delivered a preview clip
+	RejectNotAvailable     = "not_available"
+	RejectHTTPStatus       = "http_status"
+)
+
+// DownloadAttempt is one provider tried by a fallback download
+type DownloadAttempt struct {
+	Provider   string `json:"provider"`
+	Success    bool   `json:"success"`
+	Stage      string `json:"stage,omitempty"`     // Last stage reached
+	Rejection  string `json:"rejection,omitempty"` // Why the last candidate was rejected
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Error      string `json:"error,omitempty"`
+	ErrorType  string `json:"error_type,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// downloadStageError tags a provider error with the stage it failed at and
+// the last rejection reason seen while matching
+type downloadStageError struct {
+	Stage     string
+	Rejection string
+	Err       error
+}
+
+func (e *downloadStageError) Error() string { return e.Err.Error() }
+
+func (e *downloadStageError) Unwrap() error { return e.Err }
+
+// stageError wraps err with stage information for the attempt trail
+func stageError(stage, rejection string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &downloadStageError{Stage: stage, Rejection: rejection, Err: err}
+}
+
+var httpStatusPattern = regexp.MustCompile(`(?i)(?:HTTP|status(?: code)?)[: ]+(\d{3})\b`)
+
+// httpStatusFromError extracts an HTTP status code from an error message
+func httpStatusFromError(msg string) int {
+	m := httpStatusPattern.FindStringSubmatch(msg)
+	if m == nil {
+		return 0
+	}
+	status, _ := strconv.Atoi(m[1])
+	return status
+}
+
+// newDownloadAttempt builds the trail entry for a provider that ran since start
+func newDownloadAttempt(provider string, start time.Time, err error) DownloadAttempt {
+	attempt := DownloadAttempt{
+		Provider:   provider,
+		Success:    err == nil,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err == nil {
+		return attempt
+	}
+
+	attempt.Error = err.Error()
+	attempt.ErrorType = classifyErrorType(attempt.Error)
+	var stageErr *downloadStageError
+	if errors.As(err, &stageErr) {
+		attempt.Stage = stageErr.Stage
+		attempt.Rejection = stageErr.Rejection
+	}
+	if isPreviewError(err) {
+		attempt.Rejection = RejectPreview
+	}
+	if status := httpStatusFromError(attempt.Error); status > 0 {
+		attempt.HTTPStatus = status
+		if attempt.Rejection == "" {
+			attempt.Rejection = RejectHTTPStatus
+		}
+	}
+	return attempt
+}
+
+// extensionDownloadError turns an extension download outcome into an error
+// tagged with the extension stage, or nil on success
+func extensionDownloadError(result *ExtDownloadResult, err error) error {
+	switch {
+	case err != nil:
+		var stageErr *downloadStageError
+		if errors.As(err, &stageErr) {
+			// Verification already tagged its stage and rejection
+			return err
+		}
+		return stageError(StageExtension, "", err)
+	case result == nil:
+		return stageError(StageExtension, "", errors.New("extension returned no result"))
+	case !result.Success:
+		msg := result.ErrorMessage
+		if msg == "" {
+			msg = "extension download failed"
+		}
+		return stageError(StageExtension, "", errors.New(msg))
+	}
+	return nil
+}
+
+// attemptsErrorResponse is errorResponse with the attempt trail attached. The
+// error type is that of the last failed attempt, so a summary message does not
+// hide a retryable network or rate-limit failure.
+func attemptsErrorResponse(msg string, attempts []DownloadAttempt) (string, error) {
+	errorType := classifyErrorType(msg)
+	if n := len(attempts); n > 0 && !attempts[n-1].Success && attempts[n-1].ErrorType != "" {
+		errorType = attempts[n-1].ErrorType
+	}
+	resp := DownloadResponse{
+		Success:   false,
+		Error:     msg,
+		ErrorType: errorType,
+		Attempts:  attempts,
+	}
+	jsonBytes, _ := json.Marshal(resp)
+	return string(jsonBytes), nil
+}
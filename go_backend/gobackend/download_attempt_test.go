@@ -0,0 +1,51 @@
+package gobackend
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHTTPStatusFromError(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want int
+	}{
+		{"failed to get download URL: HTTP 403", 403},
+		{"API returned status 429", 429},
+		{"unexpected status code: 502 Bad Gateway", 502},
+		{"track not found", 0},
+		{"timeout after 30000ms", 0},
+	}
+	for _, tt := range tests {
+		if got := httpStatusFromError(tt.msg); got != tt.want {
+			t.Errorf("httpStatusFromError(%q) = %d, want %d", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestNewDownloadAttempt(t *testing.T) {
+	start := time.Now()
+
+	ok := newDownloadAttempt("tidal", start, nil)
+	if !ok.Success || ok.Error != "" || ok.Stage != "" {
+		t.Errorf("unexpected success attempt: %+v", ok)
+	}
+
+	err := fmt.Errorf("wrapped: %w", stageError(StageISRCSearch, RejectDurationMismatch, errors.New("tidal search failed: track not found")))
+	failed := newDownloadAttempt("tidal", start, err)
+	if failed.Success || failed.Stage != StageISRCSearch || failed.Rejection != RejectDurationMismatch || failed.ErrorType != "not_found" {
+		t.Errorf("unexpected failed attempt: %+v", failed)
+	}
+
+	status := newDownloadAttempt("qobuz", start, stageError(StageDownloadURL, "", errors.New("failed to get download URL: HTTP 401")))
+	if status.HTTPStatus != 401 || status.Rejection != RejectHTTPStatus || status.Stage != StageDownloadURL {
+		t.Errorf("unexpected HTTP attempt: %+v", status)
+	}
+
+	ext := newDownloadAttempt("ext", start, extensionDownloadError(&ExtDownloadResult{ErrorMessage: "blocked"}, nil))
+	if ext.Stage != StageExtension || ext.Error != "blocked" {
+		t.Errorf("unexpected extension attempt: %+v", ext)
+	}
+}
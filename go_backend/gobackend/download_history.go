@@ -0,0 +1,415 @@
+package gobackend
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const downloadHistoryFileName = "download_history.jsonl"
+
+// Download history statuses
+const (
+	HistoryStatusCompleted = "completed"
+	HistoryStatusSkipped   = "skipped"
+	HistoryStatusFailed    = "failed"
+)
+
+// DownloadHistoryRecord is one finished download attempt
+type DownloadHistoryRecord struct {
+	ID          string `json:"id"`
+	Status      string `json:"status"`
+	ISRC        string `json:"isrc,omitempty"`
+	SpotifyID   string `json:"spotify_id,omitempty"`
+	TidalID     string `json:"tidal_id,omitempty"`
+	QobuzID     string `json:"qobuz_id,omitempty"`
+	DeezerID    string `json:"deezer_id,omitempty"`
+	Source      string `json:"source,omitempty"`
+	Service     string `json:"service,omitempty"`
+	TrackName   string `json:"track_name"`
+	ArtistName  string `json:"artist_name"`
+	AlbumName   string `json:"album_name,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	BitDepth    int    `json:"bit_depth,omitempty"`
+	SampleRate  int    `json:"sample_rate,omitempty"`
+	FilePath    string `json:"file_path,omitempty"`
+	FileSize    int64  `json:"file_size,omitempty"`
+	FileHash    string `json:"file_hash,omitempty"` // SHA-256 of the audio file
+	Error       string `json:"error,omitempty"`
+	ErrorType   string `json:"error_type,omitempty"`
+	Timestamp   int64  `json:"timestamp"` // Unix milliseconds
+}
+
+// DownloadHistoryQuery filters history records. Text filters are
+// case-insensitive substring matches; empty fields match everything.
+type DownloadHistoryQuery struct {
+	Artist  string `json:"artist,omitempty"`
+	Album   string `json:"album,omitempty"`
+	Track   string `json:"track,omitempty"`
+	ISRC    string `json:"isrc,omitempty"`
+	Service string `json:"service,omitempty"`
+	Status  string `json:"status,omitempty"`
+	From    int64  `json:"from,omitempty"` // Unix milliseconds, inclusive
+	To      int64  `json:"to,omitempty"`   // Unix milliseconds, inclusive
+	Limit   int    `json:"limit,omitempty"`
+	Offset  int    `json:"offset,omitempty"`
+}
+
+// DownloadHistoryStore keeps download history in memory and appends every
+// record to a JSON Lines file so writes never rewrite the whole history
+type DownloadHistoryStore struct {
+	mu       sync.RWMutex
+	filePath string
+	records  []DownloadHistoryRecord
+	nextID   int64
+}
+
+var (
+	globalHistoryStore     *DownloadHistoryStore
+	globalHistoryStoreOnce sync.Once
+)
+
+// GetDownloadHistoryStore returns the global history store. Records are only
+// kept once SetDataDir has been called.
+func GetDownloadHistoryStore() *DownloadHistoryStore {
+	globalHistoryStoreOnce.Do(func() {
+		globalHistoryStore = &DownloadHistoryStore{}
+	})
+	return globalHistoryStore
+}
+
+// SetDataDir sets the directory of the history file and loads existing records
+func (s *DownloadHistoryStore) SetDataDir(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filePath = filepath.Join(dataDir, downloadHistoryFileName)
+	s.records = nil
+	return s.loadLocked()
+}
+
+func (s *DownloadHistoryStore) loadLocked() error {
+	f, err := os.Open(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	skipped := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var record DownloadHistoryRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			// A crash mid-append leaves at most one truncated line
+			skipped++
+			continue
+		}
+		s.records = append(s.records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	GoLog("[DownloadHistory] Loaded %d records (%d unreadable)\n", len(s.records), skipped)
+	return nil
+}
+
+// Add appends a record, assigning its ID and timestamp if missing
+func (s *DownloadHistoryStore) Add(record DownloadHistoryRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.filePath == "" {
+		return fmt.Errorf("download history not initialized")
+	}
+
+	if record.Timestamp == 0 {
+		record.Timestamp = time.Now().UnixMilli()
+	}
+	if record.ID == "" {
+		s.nextID++
+		record.ID = fmt.Sprintf("h-%d-%d", record.Timestamp, s.nextID)
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, writeErr := f.Write(append(data, '\n'))
+	closeErr := f.Close()
+	if writeErr != nil {
+		return writeErr
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+
+	s.records = append(s.records, record)
+	return nil
+}
+
+func (q DownloadHistoryQuery) matches(r DownloadHistoryRecord) bool {
+	contains := func(value, filter string) bool {
+		return filter == "" || strings.Contains(strings.ToLower(value), strings.ToLower(filter))
+	}
+
+	return contains(r.ArtistName, q.Artist) &&
+		contains(r.AlbumName, q.Album) &&
+		contains(r.TrackName, q.Track) &&
+		(q.ISRC == "" || strings.EqualFold(r.ISRC, q.ISRC)) &&
+		(q.Service == "" || strings.EqualFold(r.Service, q.Service)) &&
+		(q.Status == "" || r.Status == q.Status) &&
+		(q.From == 0 || r.Timestamp >= q.From) &&
+		(q.To == 0 || r.Timestamp <= q.To)
+}
+
+// Query returns matching records, newest first
+func (s *DownloadHistoryStore) Query(q DownloadHistoryQuery) []DownloadHistoryRecord {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := []DownloadHistoryRecord{}
+	for i := len(s.records) - 1; i >= 0; i-- {
+		if q.matches(s.records[i]) {
+			result = append(result, s.records[i])
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Timestamp > result[j].Timestamp
+	})
+
+	if q.Offset > 0 {
+		if q.Offset >= len(result) {
+			return []DownloadHistoryRecord{}
+		}
+		result = result[q.Offset:]
+	}
+	if q.Limit > 0 && q.Limit < len(result) {
+		result = result[:q.Limit]
+	}
+	return result
+}
+
+// Clear removes all records and the history file
+func (s *DownloadHistoryStore) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = nil
+	if s.filePath == "" {
+		return nil
+	}
+	if err := os.Remove(s.filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+var downloadHistoryCSVHeader = []string{
+	"id", "status", "timestamp", "isrc", "spotify_id", "tidal_id", "qobuz_id", "deezer_id",
+	"source", "service", "track_name", "artist_name", "album_name", "album_artist", "release_date",
+	"bit_depth", "sample_rate", "file_path", "file_size", "file_hash", "error", "error_type",
+}
+
+// writeDownloadHistoryCSV writes records as CSV with a header row
+func writeDownloadHistoryCSV(w io.Writer, records []DownloadHistoryRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(downloadHistoryCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range records {
+		row := []string{
+			r.ID, r.Status, time.UnixMilli(r.Timestamp).UTC().Format(time.RFC3339),
+			r.ISRC, r.SpotifyID, r.TidalID, r.QobuzID, r.DeezerID,
+			r.Source, r.Service, r.TrackName, r.ArtistName, r.AlbumName, r.AlbumArtist, r.ReleaseDate,
+			strconv.Itoa(r.BitDepth), strconv.Itoa(r.SampleRate), r.FilePath,
+			strconv.FormatInt(r.FileSize, 10), r.FileHash, r.Error, r.ErrorType,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// hashFile returns the hex SHA-256 and size of a file
+func hashFile(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// fileHash returns the hash and size of a record's file. A skipped download
+// reuses the hash of the newest record for the same path and size, so files
+// that already existed are not read again on every run.
+func (s *DownloadHistoryStore) fileHash(record DownloadHistoryRecord) (string, int64, error) {
+	if record.Status == HistoryStatusSkipped {
+		if info, err := os.Stat(record.FilePath); err == nil {
+			if hash := s.knownFileHash(record.FilePath, info.Size()); hash != "" {
+				return hash, info.Size(), nil
+			}
+		}
+	}
+	return hashFile(record.FilePath)
+}
+
+// knownFileHash returns the hash the newest record for path recorded when the
+// file had the given size, or ""
+func (s *DownloadHistoryStore) knownFileHash(path string, size int64) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.records) - 1; i >= 0; i-- {
+		r := s.records[i]
+		if r.FilePath == path && r.FileHash != "" {
+			if r.FileSize == size {
+				return r.FileHash
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// historyRecordFromResponse builds a history record from a finished download.
+// Cancelled downloads return ok=false and are not recorded.
+func historyRecordFromResponse(req DownloadRequest, resp *DownloadResponse) (DownloadHistoryRecord, bool) {
+	record := DownloadHistoryRecord{
+		ISRC:        req.ISRC,
+		SpotifyID:   req.SpotifyID,
+		TidalID:     req.TidalID,
+		QobuzID:     req.QobuzID,
+		DeezerID:    req.DeezerID,
+		Source:      req.Source,
+		Service:     req.Service,
+		TrackName:   req.TrackName,
+		ArtistName:  req.ArtistName,
+		AlbumName:   req.AlbumName,
+		AlbumArtist: req.AlbumArtist,
+		ReleaseDate: req.ReleaseDate,
+	}
+
+	switch {
+	case resp == nil:
+		return record, false
+	case !resp.Success && resp.ErrorType == "cancelled":
+		return record, false
+	case !resp.Success:
+		record.Status = HistoryStatusFailed
+		record.Error = resp.Error
+		record.ErrorType = resp.ErrorType
+		return record, true
+	case resp.AlreadyExists:
+		record.Status = HistoryStatusSkipped
+	default:
+		record.Status = HistoryStatusCompleted
+	}
+
+	if resp.Service != "" {
+		record.Service = resp.Service
+	}
+	// The track the service matched replaces the ID the request suggested
+	if resp.TrackID != "" {
+		switch record.Service {
+		case "tidal":
+			record.TidalID = resp.TrackID
+		case "qobuz":
+			record.QobuzID = resp.TrackID
+		case "deezer":
+			record.DeezerID = resp.TrackID
+		}
+	}
+	if record.ISRC == "" {
+		record.ISRC = resp.ISRC
+	}
+	record.BitDepth = resp.ActualBitDepth
+	record.SampleRate = resp.ActualSampleRate
+	record.FilePath = resp.FilePath
+	return record, true
+}
+
+// recordDownloadHistory stores the outcome of a download in the background;
+// hashing a large FLAC should not delay the response
+func recordDownloadHistory(req DownloadRequest, resp *DownloadResponse) {
+	store := GetDownloadHistoryStore()
+	store.mu.RLock()
+	enabled := store.filePath != ""
+	store.mu.RUnlock()
+	if !enabled {
+		return
+	}
+
+	record, ok := historyRecordFromResponse(req, resp)
+	if !ok {
+		return
+	}
+
+	go func() {
+		if record.FilePath != "" {
+			if hash, size, err := store.fileHash(record); err == nil {
+				record.FileHash = hash
+				record.FileSize = size
+			}
+		}
+		if err := store.Add(record); err != nil {
+			GoLog("[DownloadHistory] Failed to record download: %v\n", err)
+		}
+	}()
+}
+
+// recordDownloadHistoryResult records the outcome of an entry point returning (*DownloadResponse, error)
+func recordDownloadHistoryResult(req DownloadRequest, resp *DownloadResponse, err error) {
+	if err != nil {
+		resp = &DownloadResponse{Error: err.Error(), ErrorType: classifyErrorType(err.Error())}
+	}
+	recordDownloadHistory(req, resp)
+}
+
+// recordDownloadHistoryJSON records a JSON DownloadResponse
+func recordDownloadHistoryJSON(req DownloadRequest, respJSON string) {
+	var resp DownloadResponse
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		return
+	}
+	recordDownloadHistory(req, &resp)
+}
@@ -0,0 +1,114 @@
+package gobackend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadHistoryStore_PersistAndQuery(t *testing.T) {
+	dir := t.TempDir()
+	store := &DownloadHistoryStore{}
+	if err := store.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	records := []DownloadHistoryRecord{
+		{Status: HistoryStatusCompleted, Service: "tidal", TrackName: "One", ArtistName: "Alpha", AlbumName: "First", Timestamp: 1000},
+		{Status: HistoryStatusFailed, Service: "qobuz", TrackName: "Two", ArtistName: "Beta", AlbumName: "Second", Timestamp: 2000, Error: "not found"},
+		{Status: HistoryStatusSkipped, Service: "tidal", TrackName: "Three", ArtistName: "Alpha Beta", AlbumName: "First", Timestamp: 3000},
+	}
+	for _, r := range records {
+		if err := store.Add(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Simulate a crash mid-append
+	f, _ := os.OpenFile(filepath.Join(dir, downloadHistoryFileName), os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"id":"trunc`)
+	f.Close()
+
+	reloaded := &DownloadHistoryStore{}
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	all := reloaded.Query(DownloadHistoryQuery{})
+	if len(all) != 3 || all[0].TrackName != "Three" {
+		t.Fatalf("expected 3 records newest first, got %+v", all)
+	}
+
+	alpha := reloaded.Query(DownloadHistoryQuery{Artist: "alpha", Service: "tidal"})
+	if len(alpha) != 2 {
+		t.Errorf("expected 2 Alpha/tidal records, got %d", len(alpha))
+	}
+
+	ranged := reloaded.Query(DownloadHistoryQuery{From: 1500, To: 2500})
+	if len(ranged) != 1 || ranged[0].TrackName != "Two" {
+		t.Errorf("unexpected date range result: %+v", ranged)
+	}
+
+	var sb strings.Builder
+	if err := writeDownloadHistoryCSV(&sb, all); err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(sb.String(), "\n"); lines != 4 {
+		t.Errorf("expected header + 3 CSV rows, got %d lines", lines)
+	}
+}
+
+func TestHistoryRecordFromResponse(t *testing.T) {
+	req := DownloadRequest{TrackName: "Song", ArtistName: "Artist", ISRC: "USABC0000001"}
+
+	if _, ok := historyRecordFromResponse(req, &DownloadResponse{ErrorType: "cancelled"}); ok {
+		t.Error("cancelled downloads should not be recorded")
+	}
+
+	record, ok := historyRecordFromResponse(req, &DownloadResponse{Success: true, AlreadyExists: true, Service: "qobuz", FilePath: "/music/song.flac"})
+	if !ok || record.Status != HistoryStatusSkipped || record.Service != "qobuz" || record.FilePath != "/music/song.flac" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	// The downloaded track's ID wins over the one the request suggested
+	req.TidalID = "111"
+	req.QobuzID = "999"
+	record, _ = historyRecordFromResponse(req, &DownloadResponse{Success: true, Service: "tidal", TrackID: "222", FilePath: "/music/song.flac"})
+	if record.TidalID != "222" || record.QobuzID != "999" {
+		t.Errorf("tidal %s, qobuz %s: want the matched Tidal ID", record.TidalID, record.QobuzID)
+	}
+}
+
+func TestDownloadHistoryStore_ReusesHashOfUnchangedFile(t *testing.T) {
+	store := &DownloadHistoryStore{}
+	if err := store.SetDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "song.flac")
+	if err := os.WriteFile(path, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Add(DownloadHistoryRecord{Status: HistoryStatusCompleted, FilePath: path, FileSize: 5, FileHash: "recorded"}); err != nil {
+		t.Fatal(err)
+	}
+
+	skipped := DownloadHistoryRecord{Status: HistoryStatusSkipped, FilePath: path}
+	if hash, size, err := store.fileHash(skipped); err != nil || hash != "recorded" || size != 5 {
+		t.Errorf("unchanged file: hash %q, size %d, err %v", hash, size, err)
+	}
+
+	// A file whose size changed is hashed again
+	if err := os.WriteFile(path, []byte("new audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want, _, _ := hashFile(path)
+	if hash, _, _ := store.fileHash(skipped); hash != want {
+		t.Errorf("changed file: hash %q, want %q", hash, want)
+	}
+
+	// Completed downloads are always hashed
+	if hash, _, _ := store.fileHash(DownloadHistoryRecord{Status: HistoryStatusCompleted, FilePath: path}); hash != want {
+		t.Errorf("completed download: hash %q, want %q", hash, want)
+	}
+}
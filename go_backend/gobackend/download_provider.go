@@ -0,0 +1,133 @@
+package gobackend
+
+import (
+	"fmt"
+	"sync"
+)
+
+// DownloadProvider is a Go-native download source. Built-in services register
+// themselves here so the download entry points and fallback loops can iterate
+// them instead of switching on service names.
+type DownloadProvider interface {
+	// ID returns the service identifier used in requests and priority lists (e.g. "tidal")
+	ID() string
+	// Download fetches and tags the track. A FilePath prefixed with "EXISTS:"
+	// means the track was already present and nothing was downloaded.
+	Download(req DownloadRequest) (DownloadResult, error)
+}
+
+var (
+	downloadProviders      = make(map[string]DownloadProvider)
+	downloadProviderOrder  []string
+	downloadProvidersMutex sync.RWMutex
+)
+
+func init() {
+	RegisterDownloadProvider(tidalDownloadProvider{})
+	RegisterDownloadProvider(qobuzDownloadProvider{})
+	RegisterDownloadProvider(amazonDownloadProvider{})
+}
+
+// RegisterDownloadProvider adds a provider to the registry. Registering an ID
+// twice replaces the earlier provider but keeps its position in the order.
+func RegisterDownloadProvider(provider DownloadProvider) {
+	downloadProvidersMutex.Lock()
+	defer downloadProvidersMutex.Unlock()
+
+	id := provider.ID()
+	if _, exists := downloadProviders[id]; !exists {
+		downloadProviderOrder = append(downloadProviderOrder, id)
+	}
+	downloadProviders[id] = provider
+}
+
+// GetDownloadProvider returns the registered provider for id
+func GetDownloadProvider(id string) (DownloadProvider, bool) {
+	downloadProvidersMutex.RLock()
+	defer downloadProvidersMutex.RUnlock()
+
+	provider, ok := downloadProviders[id]
+	return provider, ok
+}
+
+// RegisteredDownloadProviderIDs returns provider IDs in registration order
+func RegisteredDownloadProviderIDs() []string {
+	downloadProvidersMutex.RLock()
+	defer downloadProvidersMutex.RUnlock()
+
+	result := make([]string, len(downloadProviderOrder))
+	copy(result, downloadProviderOrder)
+	return result
+}
+
+// downloadWithProvider runs a registered provider by ID. With a probe, the
+// provider downloads the track it matched while probing.
+func downloadWithProvider(providerID string, req DownloadRequest, probe *QualityProbe) (DownloadResult, error) {
+	provider, ok := GetDownloadProvider(providerID)
+	if !ok {
+		return DownloadResult{}, fmt.Errorf("unknown built-in provider: %s", providerID)
+	}
+	req.Service = providerID
+	if probe == nil {
+		return provider.Download(req)
+	}
+	prober, ok := provider.(QualityProber)
+	if !ok {
+		return DownloadResult{}, fmt.Errorf("provider %s cannot download a probed track", providerID)
+	}
+	return prober.DownloadProbed(req, *probe)
+}
+
+type tidalDownloadProvider struct{}
+
+func (tidalDownloadProvider) ID() string { return "tidal" }
+
+func (tidalDownloadProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	result, err := downloadFromTidal(req)
+	return DownloadResult(result), err
+}
+
+type qobuzDownloadProvider struct{}
+
+func (qobuzDownloadProvider) ID() string { return "qobuz" }
+
+func (qobuzDownloadProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	result, err := downloadFromQobuz(req)
+	return DownloadResult(result), err
+}
+
+type amazonDownloadProvider struct{}
+
+func (amazonDownloadProvider) ID() string { return "amazon" }
+
+func (amazonDownloadProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	result, err := downloadFromAmazon(req)
+	return DownloadResult(result), err
+}
+
+func (tidalDownloadProvider) ProbeQuality(req DownloadRequest) (QualityProbe, error) {
+	return probeTidalQuality(req)
+}
+
+func (qobuzDownloadProvider) ProbeQuality(req DownloadRequest) (QualityProbe, error) {
+	return probeQobuzQuality(req)
+}
+
+func (amazonDownloadProvider) ProbeQuality(req DownloadRequest) (QualityProbe, error) {
+	return probeAmazonQuality(req)
+}
+
+func (tidalDownloadProvider) DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error) {
+	result, err := downloadProbedTidal(req, probe)
+	return DownloadResult(result), err
+}
+
+func (qobuzDownloadProvider) DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error) {
+	result, err := downloadProbedQobuz(req, probe)
+	return DownloadResult(result), err
+}
+
+func (amazonDownloadProvider) DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error) {
+	result, err := downloadProbedAmazon(req, probe)
+	return DownloadResult(result), err
+}
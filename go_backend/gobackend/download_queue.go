@@ -0,0 +1,599 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Queue job states
+const (
+	QueueJobPending   = "pending"
+	QueueJobRunning   = "running"
+	QueueJobCompleted = "completed"
+	QueueJobFailed    = "failed"
+	QueueJobCancelled = "cancelled"
+)
+
+// Queue job modes select which download entry point runs the job
+const (
+	QueueModeSingle      = "single"       // DownloadTrack with req.Service only
+	QueueModeFallback    = "fallback"     // DownloadWithFallback (built-in services)
+	QueueModeExtensions  = "extensions"   // DownloadWithExtensionFallback
+	QueueModeBestQuality = "best_quality" // DownloadWithBestQuality (built-in services)
+)
+
+const (
+	queueJournalFileName = "download_queue.json"
+	queueJournalVersion  = 1
+	defaultQueueWorkers  = 3
+	maxQueueWorkers      = 8
+)
+
+// QueueRetryPolicy controls how failed jobs are retried
+type QueueRetryPolicy struct {
+	MaxAttempts    int      `json:"max_attempts"`
+	InitialDelayMS int      `json:"initial_delay_ms"`
+	MaxDelayMS     int      `json:"max_delay_ms"`
+	BackoffFactor  float64  `json:"backoff_factor"`
+	RetryOn        []string `json:"retry_on"` // DownloadResponse.ErrorType values worth retrying
+}
+
+// DefaultQueueRetryPolicy returns the retry policy used when none is configured
+func DefaultQueueRetryPolicy() QueueRetryPolicy {
+	return QueueRetryPolicy{
+		MaxAttempts:    3,
+		InitialDelayMS: 5000,
+		MaxDelayMS:     120000,
+		BackoffFactor:  2.0,
+		RetryOn:        []string{"network", "rate_limit", "unknown"},
+	}
+}
+
+func (p QueueRetryPolicy) shouldRetry(errorType string, attempts int) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+	for _, t := range p.RetryOn {
+		if t == errorType {
+			return true
+		}
+	}
+	return false
+}
+
+func (p QueueRetryPolicy) delayFor(attempts int) time.Duration {
+	delay := float64(p.InitialDelayMS)
+	for i := 1; i < attempts; i++ {
+		delay *= p.BackoffFactor
+	}
+	if p.MaxDelayMS > 0 && delay > float64(p.MaxDelayMS) {
+		delay = float64(p.MaxDelayMS)
+	}
+	return time.Duration(delay) * time.Millisecond
+}
+
+// QueueJob is a single download tracked by the queue
+type QueueJob struct {
+	ID            string            `json:"id"`
+	Mode          string            `json:"mode"`
+	Request       DownloadRequest   `json:"request"`
+	Status        string            `json:"status"`
+	Attempts      int               `json:"attempts"`
+	Interrupted   bool              `json:"interrupted,omitempty"` // Was running when the app was killed
+	Error         string            `json:"error,omitempty"`
+	ErrorType     string            `json:"error_type,omitempty"`
+	Result        *DownloadResponse `json:"result,omitempty"`
+	CreatedAt     int64             `json:"created_at"`
+	UpdatedAt     int64             `json:"updated_at"`
+	NextAttemptAt int64             `json:"next_attempt_at,omitempty"`
+}
+
+type queueJournal struct {
+	Version int              `json:"version"`
+	Workers int              `json:"workers"`
+	Paused  bool             `json:"paused"`
+	Policy  QueueRetryPolicy `json:"policy"`
+	Jobs    []*QueueJob      `json:"jobs"`
+}
+
+// DownloadQueue runs download jobs with a bounded number of workers and
+// persists every state change to a journal so the batch survives restarts
+type DownloadQueue struct {
+	mu          sync.Mutex
+	journalPath string
+	jobs        []*QueueJob
+	workers     int
+	running     int
+	paused      bool
+	policy      QueueRetryPolicy
+	nextID      int64
+}
+
+var (
+	globalDownloadQueue   *DownloadQueue
+	globalDownloadQueueMu sync.Mutex
+)
+
+// downloadModeExecutors run a request through the entry point for a mode
+var downloadModeExecutors = map[string]func(req DownloadRequest) (*DownloadResponse, error){
+	QueueModeSingle: func(req DownloadRequest) (*DownloadResponse, error) {
+		return runJSONDownload(DownloadTrack, req)
+	},
+	QueueModeFallback: func(req DownloadRequest) (*DownloadResponse, error) {
+		return runJSONDownload(DownloadWithFallback, req)
+	},
+	QueueModeExtensions:  DownloadWithExtensionFallback,
+	QueueModeBestQuality: DownloadWithBestQuality,
+}
+
+// runDownloadMode runs req with the given mode and always returns a response;
+// errors are converted the same way errorResponse classifies them
+func runDownloadMode(mode string, req DownloadRequest) *DownloadResponse {
+	executor, ok := downloadModeExecutors[mode]
+	if !ok {
+		executor = downloadModeExecutors[QueueModeFallback]
+	}
+
+	resp, err := executor(req)
+	if err != nil {
+		return &DownloadResponse{Error: err.Error(), ErrorType: classifyErrorType(err.Error())}
+	}
+	return resp
+}
+
+func runJSONDownload(fn func(string) (string, error), req DownloadRequest) (*DownloadResponse, error) {
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	respJSON, err := fn(string(reqJSON))
+	if err != nil {
+		return nil, err
+	}
+	var resp DownloadResponse
+	if err := json.Unmarshal([]byte(respJSON), &resp); err != nil {
+		return nil, fmt.Errorf("invalid download response: %w", err)
+	}
+	return &resp, nil
+}
+
+// InitDownloadQueue loads the queue journal from dataDir and resumes pending
+// and interrupted jobs. Calling it again returns the existing queue.
+func InitDownloadQueue(dataDir string) (*DownloadQueue, error) {
+	globalDownloadQueueMu.Lock()
+	defer globalDownloadQueueMu.Unlock()
+
+	if globalDownloadQueue != nil {
+		return globalDownloadQueue, nil
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	q := &DownloadQueue{
+		journalPath: filepath.Join(dataDir, queueJournalFileName),
+		workers:     defaultQueueWorkers,
+		policy:      DefaultQueueRetryPolicy(),
+	}
+	if err := q.load(); err != nil {
+		GoLog("[DownloadQueue] Failed to load journal, starting empty: %v\n", err)
+		q.jobs = nil
+	}
+
+	globalDownloadQueue = q
+	q.schedule()
+	return q, nil
+}
+
+// GetDownloadQueue returns the queue or nil if InitDownloadQueue was not called
+func GetDownloadQueue() *DownloadQueue {
+	globalDownloadQueueMu.Lock()
+	defer globalDownloadQueueMu.Unlock()
+	return globalDownloadQueue
+}
+
+func (q *DownloadQueue) load() error {
+	data, err := os.ReadFile(q.journalPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var journal queueJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return err
+	}
+
+	if journal.Workers > 0 {
+		q.workers = journal.Workers
+	}
+	if journal.Policy.MaxAttempts > 0 {
+		q.policy = journal.Policy
+	}
+	q.paused = journal.Paused
+
+	resumed := 0
+	for _, job := range journal.Jobs {
+		if job == nil || job.ID == "" {
+			continue
+		}
+		switch job.Status {
+		case QueueJobRunning:
+			job.Status = QueueJobPending
+			job.Interrupted = true
+			job.NextAttemptAt = 0
+			resumed++
+		case QueueJobPending:
+			job.NextAttemptAt = 0
+			resumed++
+		}
+		q.jobs = append(q.jobs, job)
+	}
+
+	GoLog("[DownloadQueue] Loaded %d jobs from journal (%d to resume)\n", len(q.jobs), resumed)
+	return nil
+}
+
+// persistLocked writes the journal atomically. Caller must hold q.mu.
+func (q *DownloadQueue) persistLocked() {
+	journal := queueJournal{
+		Version: queueJournalVersion,
+		Workers: q.workers,
+		Paused:  q.paused,
+		Policy:  q.policy,
+		Jobs:    q.jobs,
+	}
+
+	data, err := json.Marshal(journal)
+	if err != nil {
+		GoLog("[DownloadQueue] Failed to encode journal: %v\n", err)
+		return
+	}
+
+	tmpPath := q.journalPath + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		GoLog("[DownloadQueue] Failed to write journal: %v\n", err)
+		return
+	}
+	_, writeErr := f.Write(data)
+	syncErr := f.Sync()
+	closeErr := f.Close()
+	if writeErr != nil || syncErr != nil || closeErr != nil {
+		os.Remove(tmpPath)
+		GoLog("[DownloadQueue] Failed to write journal: %v %v %v\n", writeErr, syncErr, closeErr)
+		return
+	}
+
+	if err := os.Rename(tmpPath, q.journalPath); err != nil {
+		os.Remove(tmpPath)
+		GoLog("[DownloadQueue] Failed to replace journal: %v\n", err)
+	}
+}
+
+func (q *DownloadQueue) findLocked(jobID string) (int, *QueueJob) {
+	for i, job := range q.jobs {
+		if job.ID == jobID {
+			return i, job
+		}
+	}
+	return -1, nil
+}
+
+func (q *DownloadQueue) newJobIDLocked() string {
+	q.nextID++
+	return fmt.Sprintf("q-%d-%d", time.Now().UnixNano(), q.nextID)
+}
+
+// Enqueue adds a job to the end of the queue
+func (q *DownloadQueue) Enqueue(req DownloadRequest, mode string) (*QueueJob, error) {
+	if mode == "" {
+		mode = QueueModeFallback
+	}
+	if _, ok := downloadModeExecutors[mode]; !ok {
+		return nil, fmt.Errorf("unknown queue mode: %s", mode)
+	}
+
+	q.mu.Lock()
+	id := req.ItemID
+	if id == "" {
+		id = q.newJobIDLocked()
+	} else if _, existing := q.findLocked(id); existing != nil {
+		q.mu.Unlock()
+		return nil, fmt.Errorf("job '%s' already queued", id)
+	}
+	req.ItemID = id
+
+	now := time.Now().Unix()
+	job := &QueueJob{
+		ID:        id,
+		Mode:      mode,
+		Request:   req,
+		Status:    QueueJobPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	q.jobs = append(q.jobs, job)
+	q.persistLocked()
+	snapshot := *job
+	q.mu.Unlock()
+
+	q.schedule()
+	return &snapshot, nil
+}
+
+// schedule starts pending jobs while worker slots are free
+func (q *DownloadQueue) schedule() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.paused {
+		return
+	}
+
+	now := time.Now().UnixMilli()
+	started := false
+	for _, job := range q.jobs {
+		if q.running >= q.workers {
+			break
+		}
+		if job.Status != QueueJobPending || job.NextAttemptAt > now {
+			continue
+		}
+		job.Status = QueueJobRunning
+		job.Attempts++
+		job.UpdatedAt = time.Now().Unix()
+		q.running++
+		started = true
+
+		jobCopy := *job
+		go q.run(jobCopy)
+	}
+
+	if started {
+		q.persistLocked()
+	}
+}
+
+func (q *DownloadQueue) run(job QueueJob) {
+	GoLog("[DownloadQueue] Starting job %s (attempt %d, mode %s)\n", job.ID, job.Attempts, job.Mode)
+
+	resp := runDownloadMode(job.Mode, job.Request)
+
+	q.mu.Lock()
+	q.running--
+
+	_, current := q.findLocked(job.ID)
+	if current == nil || current.Status != QueueJobRunning {
+		// Removed or cancelled while running
+		q.persistLocked()
+		q.mu.Unlock()
+		q.schedule()
+		return
+	}
+
+	current.UpdatedAt = time.Now().Unix()
+	current.Result = resp
+
+	switch {
+	case resp.Success:
+		current.Status = QueueJobCompleted
+		current.Error = ""
+		current.ErrorType = ""
+		GoLog("[DownloadQueue] Job %s completed via %s\n", job.ID, resp.Service)
+	case resp.ErrorType == "cancelled":
+		current.Status = QueueJobCancelled
+		current.Error = resp.Error
+		current.ErrorType = resp.ErrorType
+	case q.policy.shouldRetry(resp.ErrorType, current.Attempts):
+		delay := q.policy.delayFor(current.Attempts)
+		current.Status = QueueJobPending
+		current.Error = resp.Error
+		current.ErrorType = resp.ErrorType
+		current.NextAttemptAt = time.Now().Add(delay).UnixMilli()
+		GoLog("[DownloadQueue] Job %s failed (%s), retrying in %v\n", job.ID, resp.ErrorType, delay)
+		time.AfterFunc(delay, q.schedule)
+	default:
+		current.Status = QueueJobFailed
+		current.Error = resp.Error
+		current.ErrorType = resp.ErrorType
+		GoLog("[DownloadQueue] Job %s failed: %s\n", job.ID, resp.Error)
+	}
+
+	q.persistLocked()
+	q.mu.Unlock()
+	q.schedule()
+}
+
+// Jobs returns a snapshot of all jobs in queue order
+func (q *DownloadQueue) Jobs() []QueueJob {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	result := make([]QueueJob, len(q.jobs))
+	for i, job := range q.jobs {
+		result[i] = *job
+	}
+	return result
+}
+
+// Job returns a snapshot of a single job
+func (q *DownloadQueue) Job(jobID string) (*QueueJob, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, job := q.findLocked(jobID)
+	if job == nil {
+		return nil, fmt.Errorf("job '%s' not found", jobID)
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// Move places a job at newIndex in the queue order
+func (q *DownloadQueue) Move(jobID string, newIndex int) error {
+	q.mu.Lock()
+	idx, job := q.findLocked(jobID)
+	if job == nil {
+		q.mu.Unlock()
+		return fmt.Errorf("job '%s' not found", jobID)
+	}
+
+	q.jobs = append(q.jobs[:idx], q.jobs[idx+1:]...)
+	if newIndex < 0 {
+		newIndex = 0
+	}
+	if newIndex > len(q.jobs) {
+		newIndex = len(q.jobs)
+	}
+	q.jobs = append(q.jobs, nil)
+	copy(q.jobs[newIndex+1:], q.jobs[newIndex:])
+	q.jobs[newIndex] = job
+
+	q.persistLocked()
+	q.mu.Unlock()
+
+	q.schedule()
+	return nil
+}
+
+// Remove drops a job from the queue, cancelling it if it is running
+func (q *DownloadQueue) Remove(jobID string) error {
+	q.mu.Lock()
+	idx, job := q.findLocked(jobID)
+	if job == nil {
+		q.mu.Unlock()
+		return fmt.Errorf("job '%s' not found", jobID)
+	}
+	wasRunning := job.Status == QueueJobRunning
+	q.jobs = append(q.jobs[:idx], q.jobs[idx+1:]...)
+	q.persistLocked()
+	q.mu.Unlock()
+
+	if wasRunning {
+		cancelDownload(jobID)
+	}
+	return nil
+}
+
+// Retry puts a failed or cancelled job back into the pending state
+func (q *DownloadQueue) Retry(jobID string) error {
+	q.mu.Lock()
+	_, job := q.findLocked(jobID)
+	if job == nil {
+		q.mu.Unlock()
+		return fmt.Errorf("job '%s' not found", jobID)
+	}
+	if job.Status != QueueJobFailed && job.Status != QueueJobCancelled {
+		q.mu.Unlock()
+		return fmt.Errorf("job '%s' is %s", jobID, job.Status)
+	}
+	job.Status = QueueJobPending
+	job.Attempts = 0
+	job.NextAttemptAt = 0
+	job.UpdatedAt = time.Now().Unix()
+	clearDownloadCancel(jobID)
+	q.persistLocked()
+	q.mu.Unlock()
+
+	q.schedule()
+	return nil
+}
+
+// ClearFinished removes completed, failed and cancelled jobs and returns how many were removed
+func (q *DownloadQueue) ClearFinished() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	kept := q.jobs[:0]
+	removed := 0
+	for _, job := range q.jobs {
+		switch job.Status {
+		case QueueJobCompleted, QueueJobFailed, QueueJobCancelled:
+			removed++
+		default:
+			kept = append(kept, job)
+		}
+	}
+	q.jobs = kept
+	if removed > 0 {
+		q.persistLocked()
+	}
+	return removed
+}
+
+// SetWorkers changes the number of concurrent downloads
+func (q *DownloadQueue) SetWorkers(workers int) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > maxQueueWorkers {
+		workers = maxQueueWorkers
+	}
+
+	q.mu.Lock()
+	q.workers = workers
+	q.persistLocked()
+	q.mu.Unlock()
+
+	q.schedule()
+}
+
+// SetRetryPolicy replaces the retry policy for future failures
+func (q *DownloadQueue) SetRetryPolicy(policy QueueRetryPolicy) {
+	if policy.MaxAttempts < 1 {
+		policy.MaxAttempts = 1
+	}
+	if policy.BackoffFactor < 1 {
+		policy.BackoffFactor = 1
+	}
+
+	q.mu.Lock()
+	q.policy = policy
+	q.persistLocked()
+	q.mu.Unlock()
+}
+
+// SetPaused stops or resumes scheduling of new jobs. Running jobs are not interrupted.
+func (q *DownloadQueue) SetPaused(paused bool) {
+	q.mu.Lock()
+	q.paused = paused
+	q.persistLocked()
+	q.mu.Unlock()
+
+	if !paused {
+		q.schedule()
+	}
+}
+
+// Status returns a summary of queue state
+func (q *DownloadQueue) Status() map[string]interface{} {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	counts := map[string]int{
+		QueueJobPending:   0,
+		QueueJobRunning:   0,
+		QueueJobCompleted: 0,
+		QueueJobFailed:    0,
+		QueueJobCancelled: 0,
+	}
+	for _, job := range q.jobs {
+		counts[job.Status]++
+	}
+
+	return map[string]interface{}{
+		"workers": q.workers,
+		"paused":  q.paused,
+		"policy":  q.policy,
+		"counts":  counts,
+		"total":   len(q.jobs),
+	}
+}
@@ -0,0 +1,155 @@
+package gobackend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDownloadQueue_LoadResumesInterruptedJobs(t *testing.T) {
+	dir := t.TempDir()
+	journal := `{
+		"version": 1,
+		"workers": 2,
+		"paused": true,
+		"policy": {"max_attempts": 5, "initial_delay_ms": 100, "backoff_factor": 2, "retry_on": ["network"]},
+		"jobs": [
+			{"id": "a", "mode": "fallback", "status": "running", "attempts": 1},
+			{"id": "b", "mode": "fallback", "status": "pending", "next_attempt_at": 99999999999999},
+			{"id": "c", "mode": "fallback", "status": "completed", "attempts": 1}
+		]
+	}`
+	if err := os.WriteFile(filepath.Join(dir, queueJournalFileName), []byte(journal), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	q := &DownloadQueue{
+		journalPath: filepath.Join(dir, queueJournalFileName),
+		workers:     defaultQueueWorkers,
+		policy:      DefaultQueueRetryPolicy(),
+	}
+	if err := q.load(); err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if q.workers != 2 || !q.paused || q.policy.MaxAttempts != 5 {
+		t.Errorf("settings not restored: workers=%d paused=%v policy=%+v", q.workers, q.paused, q.policy)
+	}
+
+	jobs := q.Jobs()
+	if len(jobs) != 3 {
+		t.Fatalf("expected 3 jobs, got %d", len(jobs))
+	}
+	if jobs[0].Status != QueueJobPending || !jobs[0].Interrupted {
+		t.Errorf("running job should resume as interrupted pending, got %+v", jobs[0])
+	}
+	if jobs[1].NextAttemptAt != 0 {
+		t.Errorf("pending job backoff should reset on load, got %d", jobs[1].NextAttemptAt)
+	}
+	if jobs[2].Status != QueueJobCompleted {
+		t.Errorf("completed job should stay completed, got %s", jobs[2].Status)
+	}
+}
+
+func TestDownloadQueue_MoveAndPersist(t *testing.T) {
+	dir := t.TempDir()
+	q := &DownloadQueue{
+		journalPath: filepath.Join(dir, queueJournalFileName),
+		workers:     1,
+		paused:      true,
+		policy:      DefaultQueueRetryPolicy(),
+	}
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := q.Enqueue(DownloadRequest{ItemID: id}, QueueModeFallback); err != nil {
+			t.Fatalf("enqueue %s: %v", id, err)
+		}
+	}
+	if err := q.Move("c", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := &DownloadQueue{journalPath: q.journalPath, policy: DefaultQueueRetryPolicy()}
+	if err := reloaded.load(); err != nil {
+		t.Fatal(err)
+	}
+	jobs := reloaded.Jobs()
+	order := ""
+	for _, job := range jobs {
+		order += job.ID
+	}
+	if order != "cab" {
+		t.Errorf("expected order cab, got %s", order)
+	}
+}
+
+func TestQueueRetryPolicy_Backoff(t *testing.T) {
+	p := QueueRetryPolicy{MaxAttempts: 3, InitialDelayMS: 1000, MaxDelayMS: 3000, BackoffFactor: 2, RetryOn: []string{"network"}}
+
+	if !p.shouldRetry("network", 1) || p.shouldRetry("network", 3) || p.shouldRetry("not_found", 1) {
+		t.Error("unexpected shouldRetry result")
+	}
+	if d := p.delayFor(1); d != time.Second {
+		t.Errorf("expected 1s, got %v", d)
+	}
+	if d := p.delayFor(3); d != 3*time.Second {
+		t.Errorf("expected delay capped at 3s, got %v", d)
+	}
+}
+
+// failingProvider fails every download with err and counts its calls
+type failingProvider struct {
+	id    string
+	err   error
+	calls *int32
+}
+
+func (p failingProvider) ID() string { return p.id }
+
+func (p failingProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	atomic.AddInt32(p.calls, 1)
+	return DownloadResult{}, p.err
+}
+
+func TestDownloadQueue_RetriesFallbackNetworkFailure(t *testing.T) {
+	var calls int32
+	saved, _ := GetDownloadProvider("tidal")
+	t.Cleanup(func() { RegisterDownloadProvider(saved) })
+	RegisterDownloadProvider(failingProvider{id: "tidal", err: errors.New("dial tcp: connection refused"), calls: &calls})
+	setTestFallbackPolicy(t, FallbackPolicy{Services: []ServicePolicy{
+		{Service: "tidal", Enabled: true},
+		{Service: "qobuz", Enabled: false},
+		{Service: "amazon", Enabled: false},
+	}})
+
+	q := &DownloadQueue{
+		journalPath: filepath.Join(t.TempDir(), queueJournalFileName),
+		workers:     1,
+		policy:      QueueRetryPolicy{MaxAttempts: 3, InitialDelayMS: 10, BackoffFactor: 1, RetryOn: []string{"network"}},
+	}
+	job, err := q.Enqueue(DownloadRequest{TrackName: "Song", ArtistName: "Artist", OutputDir: t.TempDir()}, QueueModeFallback)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		current := q.Jobs()[0]
+		if current.ID != job.ID {
+			t.Fatalf("unexpected job %s", current.ID)
+		}
+		if current.Status == QueueJobFailed {
+			if n := atomic.LoadInt32(&calls); current.Attempts != 3 || n != 3 || current.ErrorType != "network" {
+				t.Errorf("attempts = %d, provider calls = %d, error type = %q", current.Attempts, n, current.ErrorType)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job still %s after %d attempts", current.Status, current.Attempts)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
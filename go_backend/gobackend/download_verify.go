@@ -0,0 +1,163 @@
+package gobackend
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+const (
+	// A downloaded file may differ from the expected duration by this many
+	// seconds, or by verifyDurationToleranceRatio of it if that is larger
+	verifyDurationToleranceSec   = 5.0
+	verifyDurationToleranceRatio = 0.03
+)
+
+// fileDurationSeconds returns the playing time of a FLAC or M4A file from its
+// sample count
+func fileDurationSeconds(filePath string) (float64, bool) {
+	quality, err := GetAudioQuality(filePath)
+	if err != nil || quality.SampleRate <= 0 || quality.TotalSamples <= 0 {
+		return 0, false
+	}
+	return float64(quality.TotalSamples) / float64(quality.SampleRate), true
+}
+
+// checkDownloadedDuration compares the file's real duration with the
+// requested one. Files whose duration cannot be read pass.
+func checkDownloadedDuration(filePath string, expectedMS int) error {
+	if expectedMS <= 0 {
+		return nil
+	}
+	actual, ok := fileDurationSeconds(filePath)
+	if !ok {
+		GoLog("[Verify] Could not read duration of %s, skipping duration check\n", filePath)
+		return nil
+	}
+
+	expected := float64(expectedMS) / 1000
+	if looksLikePreview(actual, expected) {
+		return stageError(StageVerify, RejectPreview, &PreviewError{ActualSec: actual, ExpectedSec: expected})
+	}
+	tolerance := math.Max(verifyDurationToleranceSec, expected*verifyDurationToleranceRatio)
+	if math.Abs(actual-expected) > tolerance {
+		return stageError(StageVerify, RejectDurationMismatch,
+			fmt.Errorf("downloaded file is %.1fs, expected %.1fs", actual, expected))
+	}
+	return nil
+}
+
+// requestedTrackID returns the track ID the request named for a service, or ""
+func requestedTrackID(service string, req DownloadRequest) string {
+	switch service {
+	case "tidal":
+		return req.TidalID
+	case "qobuz":
+		return req.QobuzID
+	case "deezer":
+		return req.DeezerID
+	}
+	return ""
+}
+
+// checkMatchedTrack compares the track the provider reports it matched with
+// the request. Titles and durations must agree. A different track ID or ISRC
+// is accepted when the rest of the identity is confirmed, since a stale ID is
+// replaced by a search and the same recording is often released under several
+// ISRCs.
+func checkMatchedTrack(service string, result DownloadResult, req DownloadRequest, durationConfirmed bool) error {
+	matched := result.Matched
+
+	if matched.Title != "" && req.TrackName != "" && !titlesMatch(req.TrackName, matched.Title) {
+		return stageError(StageVerify, RejectTitleMismatch,
+			fmt.Errorf("%s matched %q, expected %q", service, matched.Title, req.TrackName))
+	}
+
+	if matched.DurationMS > 0 && req.DurationMS > 0 {
+		if !durationsMatch(service, req.DurationMS/1000, matched.DurationMS/1000) {
+			return stageError(StageVerify, RejectDurationMismatch,
+				fmt.Errorf("%s matched a %ds track, expected %ds", service, matched.DurationMS/1000, req.DurationMS/1000))
+		}
+		durationConfirmed = true
+	}
+
+	isrcKnown := matched.ISRC != "" && req.ISRC != ""
+	isrcMatches := isrcKnown && strings.EqualFold(matched.ISRC, req.ISRC)
+
+	if wantID := requestedTrackID(service, req); wantID != "" && result.MatchedID != "" && result.MatchedID != wantID {
+		if !isrcMatches && !durationConfirmed {
+			return stageError(StageVerify, RejectIDMismatch,
+				fmt.Errorf("%s matched track %s, expected %s", service, result.MatchedID, wantID))
+		}
+		GoLog("[Verify] %s matched track %s instead of %s, identity confirmed\n", service, result.MatchedID, wantID)
+	}
+
+	if !isrcKnown || isrcMatches {
+		return nil
+	}
+	if durationConfirmed {
+		GoLog("[Verify] %s matched ISRC %s instead of %s, title and duration match\n", service, matched.ISRC, req.ISRC)
+		return nil
+	}
+	return stageError(StageVerify, RejectISRCMismatch,
+		fmt.Errorf("%s matched ISRC %s, expected %s", service, matched.ISRC, req.ISRC))
+}
+
+// verifyDownload checks that a finished download is complete and is the
+// requested track. Files that already existed are not checked.
+func verifyDownload(service string, result DownloadResult, req DownloadRequest) error {
+	if result.FilePath == "" || strings.HasPrefix(result.FilePath, "EXISTS:") {
+		return nil
+	}
+
+	if err := checkDownloadedDuration(result.FilePath, req.DurationMS); err != nil {
+		return err
+	}
+	_, hasDuration := fileDurationSeconds(result.FilePath)
+	return checkMatchedTrack(service, result, req, hasDuration && req.DurationMS > 0)
+}
+
+// downloadAndVerify runs a built-in provider under the fallback policy and
+// verifies the file it produced. A file that fails verification is removed so
+// the next provider can write the same output path. With a probe, the
+// provider downloads the track it matched while probing.
+func downloadAndVerify(service string, req DownloadRequest, probe *QualityProbe) (DownloadResult, error) {
+	result, err := downloadWithServicePolicy(service, req, probe)
+	if err != nil {
+		return result, err
+	}
+
+	if verifyErr := verifyDownload(service, result, req); verifyErr != nil {
+		GoLog("[Verify] %s download rejected: %v\n", service, verifyErr)
+		removeRejectedDownload(result.FilePath, req)
+		return DownloadResult{}, verifyErr
+	}
+	return result, nil
+}
+
+// verifyExtensionDownload applies the same checks to a file an extension
+// provider wrote, against the metadata it returned, and removes it if it is
+// rejected. Formats whose duration cannot be read, such as MP3 or Opus, only
+// have their metadata checked.
+func verifyExtensionDownload(providerID string, result *ExtDownloadResult, req DownloadRequest) error {
+	downloaded := DownloadResult{
+		FilePath: result.FilePath,
+		Matched:  TrackMatchInfo{Title: result.Title, Artist: result.Artist, Album: result.Album, ISRC: result.ISRC},
+	}
+	if err := verifyDownload(providerID, downloaded, req); err != nil {
+		GoLog("[Verify] %s download rejected: %v\n", providerID, err)
+		removeRejectedDownload(result.FilePath, req)
+		return err
+	}
+	return nil
+}
+
+func removeRejectedDownload(filePath string, req DownloadRequest) {
+	if removeErr := os.Remove(filePath); removeErr != nil && !os.IsNotExist(removeErr) {
+		GoLog("[Verify] Failed to remove rejected file: %v\n", removeErr)
+	}
+	if req.ISRC != "" && req.OutputDir != "" {
+		GetISRCIndex(req.OutputDir).remove(req.ISRC)
+	}
+}
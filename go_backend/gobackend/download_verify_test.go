@@ -0,0 +1,199 @@
+package gobackend
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestFLAC writes a tagged 44.1kHz FLAC of the given length with no audio frames
+func writeTestFLAC(t *testing.T, seconds float64, title, isrc string) string {
+	t.Helper()
+	si := testStreamInfo()
+	si[0] &^= 0x80 // Not the last block; tags follow
+	totalSamples := uint64(seconds * 44100)
+	body := si[4:]
+	body[13] |= byte(totalSamples>>32) & 0x0F
+	binary.BigEndian.PutUint32(body[14:18], uint32(totalSamples))
+
+	data := append([]byte("fLaC"), si...)
+	data = append(data, 0x80|1, 0, 0, 4, 0, 0, 0, 0) // Last block: 4 bytes padding
+	data = append(data, 0xFF, 0xF8)                  // Frame sync code
+
+	path := filepath.Join(t.TempDir(), "track.flac")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := EmbedMetadata(path, Metadata{Title: title, ISRC: isrc}, ""); err != nil {
+		t.Fatalf("EmbedMetadata: %v", err)
+	}
+	return path
+}
+
+func verifyRejection(err error) string {
+	var stageErr *downloadStageError
+	if errors.As(err, &stageErr) && stageErr.Stage == StageVerify {
+		return stageErr.Rejection
+	}
+	return ""
+}
+
+func TestVerifyDownload(t *testing.T) {
+	song := TrackMatchInfo{Title: "Song", ISRC: "USAAA0000001", DurationMS: 200000}
+	tests := []struct {
+		name      string
+		seconds   float64
+		matchedID string
+		matched   TrackMatchInfo
+		req       DownloadRequest
+		rejection string
+	}{
+		{"match", 200, "", song,
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", DurationMS: 201000}, ""},
+		{"truncated", 120, "", song,
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", DurationMS: 200000}, RejectDurationMismatch},
+		{"preview", 30, "", song,
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", DurationMS: 200000}, RejectPreview},
+		{"wrong title", 200, "", TrackMatchInfo{Title: "Different Track", ISRC: "USAAA0000001"},
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", DurationMS: 200000}, RejectTitleMismatch},
+		{"wrong matched duration", 0, "", TrackMatchInfo{Title: "Song", DurationMS: 320000},
+			DownloadRequest{TrackName: "Song", DurationMS: 200000}, RejectDurationMismatch},
+		{"other ISRC, same recording", 200, "", TrackMatchInfo{Title: "Song", ISRC: "GBBBB0000002"},
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", DurationMS: 200000}, ""},
+		{"other ISRC, unknown duration", 200, "", TrackMatchInfo{Title: "Song", ISRC: "GBBBB0000002"},
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001"}, RejectISRCMismatch},
+		{"other ID, same ISRC", 200, "222", song,
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", TidalID: "111"}, ""},
+		{"other ID, unconfirmed", 200, "222", TrackMatchInfo{Title: "Song"},
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001", TidalID: "111"}, RejectIDMismatch},
+		{"nothing to compare", 200, "", TrackMatchInfo{},
+			DownloadRequest{TrackName: "Song", ISRC: "USAAA0000001"}, ""},
+	}
+
+	for _, tt := range tests {
+		path := writeTestFLAC(t, tt.seconds, "", "")
+		err := verifyDownload("tidal", DownloadResult{FilePath: path, MatchedID: tt.matchedID, Matched: tt.matched}, tt.req)
+		if got := verifyRejection(err); got != tt.rejection || (tt.rejection == "" && err != nil) {
+			t.Errorf("%s: err = %v (rejection %q), want rejection %q", tt.name, err, got, tt.rejection)
+		}
+	}
+
+	// Files that already existed are not checked
+	if err := verifyDownload("tidal", DownloadResult{FilePath: "EXISTS:/missing.flac"}, DownloadRequest{DurationMS: 1000}); err != nil {
+		t.Errorf("existing file: %v", err)
+	}
+}
+
+// wrongTrackProvider reports a complete file, tagged as requested, of
+// another track than the one requested
+type wrongTrackProvider struct {
+	path string
+}
+
+func (p wrongTrackProvider) ID() string { return "tidal" }
+
+func (p wrongTrackProvider) Download(req DownloadRequest) (DownloadResult, error) {
+	return DownloadResult{
+		FilePath:  p.path,
+		Title:     req.TrackName,
+		ISRC:      req.ISRC,
+		MatchedID: "222",
+		Matched:   TrackMatchInfo{Title: "Different Track", ISRC: "GBBBB0000002", DurationMS: 200000},
+	}, nil
+}
+
+func TestDownloadAndVerifyRejectsWrongMatch(t *testing.T) {
+	saved, _ := GetDownloadProvider("tidal")
+	t.Cleanup(func() { RegisterDownloadProvider(saved) })
+	path := writeTestFLAC(t, 200, "Song", "USAAA0000001")
+	RegisterDownloadProvider(wrongTrackProvider{path: path})
+
+	req := DownloadRequest{TrackName: "Song", ArtistName: "Artist", ISRC: "USAAA0000001", DurationMS: 200000, OutputDir: filepath.Dir(path)}
+	_, err := downloadAndVerify("tidal", req, nil)
+	if got := verifyRejection(err); got != RejectTitleMismatch {
+		t.Fatalf("err = %v (rejection %q), want title mismatch", err, got)
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Error("rejected file was not removed")
+	}
+}
+
+func TestVerifyExtensionDownload(t *testing.T) {
+	req := DownloadRequest{TrackName: "Song", DurationMS: 200000}
+	path := writeTestFLAC(t, 120, "Song", "")
+
+	err := verifyExtensionDownload("ext", &ExtDownloadResult{Success: true, FilePath: path, Title: "Song"}, req)
+	if got := verifyRejection(err); got != RejectDurationMismatch {
+		t.Fatalf("err = %v, want duration mismatch", err)
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Error("rejected file was not removed")
+	}
+
+	// The attempt trail keeps the verify stage rather than the extension one
+	attempt := newDownloadAttempt("ext", time.Now(), extensionDownloadError(nil, err))
+	if attempt.Stage != StageVerify || attempt.Rejection != RejectDurationMismatch {
+		t.Errorf("attempt = %+v", attempt)
+	}
+
+	if err := verifyExtensionDownload("ext", &ExtDownloadResult{Success: true, FilePath: writeTestFLAC(t, 200, "", ""), Title: "Song"}, req); err != nil {
+		t.Errorf("complete download rejected: %v", err)
+	}
+
+	// The extension's own metadata names the track it delivered
+	wrong := &ExtDownloadResult{Success: true, FilePath: writeTestFLAC(t, 200, "Song", ""), Title: "Different Track"}
+	if got := verifyRejection(verifyExtensionDownload("ext", wrong, req)); got != RejectTitleMismatch {
+		t.Errorf("wrong extension track: rejection %q, want title mismatch", got)
+	}
+}
+
+func TestGetM4AQualityDuration(t *testing.T) {
+	audioEntry := make([]byte, 28)
+	binary.BigEndian.PutUint16(audioEntry[16:], 2)  // Channels
+	binary.BigEndian.PutUint16(audioEntry[18:], 16) // Sample size
+	binary.BigEndian.PutUint32(audioEntry[24:], 44100<<16)
+	// mvhd has no duration and mehd claims 10s, as in fragmented files
+	moov := testBox("moov",
+		testBox("mvhd", testU32(0, 0, 0, 1000, 0)),
+		testBox("trak",
+			testBox("tkhd", testU32(0, 0, 0, 1, 0)),
+			testBox("mdia",
+				testBox("mdhd", testU32(0, 0, 0, 44100, 0)),
+				testBox("minf", testBox("stbl",
+					testBox("stsd", testU32(0, 1), testBox("mp4a", audioEntry)))))),
+		testBox("mvex",
+			testBox("mehd", testU32(0, 10000)),
+			testBox("trex", testU32(0, 1, 1, 44100, 4, 0)))) // 1s, 4 byte samples
+	data := append(testBox("ftyp", []byte("M4A "), testU32(0)), moov...)
+	for _, samples := range []uint32{3, 2} {
+		moof := testBox("moof", testBox("traf",
+			testBox("tfhd", testU32(0x020000, 1)),
+			testBox("trun", testU32(0x000001, samples, 0))))
+		binary.BigEndian.PutUint32(moof[len(moof)-4:], uint32(len(moof)+8)) // Data offset past the mdat header
+		data = append(data, moof...)
+		data = append(data, testBox("mdat", make([]byte, 4*samples))...)
+	}
+
+	path := filepath.Join(t.TempDir(), "track.m4a")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	seconds, ok := fileDurationSeconds(path)
+	if !ok || math.Abs(seconds-5) > 0.01 {
+		t.Errorf("duration = %v (ok %v), want 5 from the fragments", seconds, ok)
+	}
+
+	// A download that stopped inside the last mdat only counts the samples
+	// that arrived in full
+	if err := os.WriteFile(path, data[:len(data)-1], 0644); err != nil {
+		t.Fatal(err)
+	}
+	seconds, ok = fileDurationSeconds(path)
+	if !ok || math.Abs(seconds-4) > 0.01 {
+		t.Errorf("truncated duration = %v (ok %v), want 4", seconds, ok)
+	}
+}
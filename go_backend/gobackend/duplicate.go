@@ -125,6 +125,18 @@ func (idx *ISRCIndex) remove(isrc string) {
 	delete(idx.index, strings.ToUpper(isrc))
 }
 
+// snapshot returns a copy of the ISRC -> file path map (internal use)
+func (idx *ISRCIndex) snapshot() map[string]string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	result := make(map[string]string, len(idx.index))
+	for isrc, path := range idx.index {
+		result[isrc] = path
+	}
+	return result
+}
+
 // Lookup checks if an ISRC exists in the index (gomobile compatible)
 // Returns filepath if found, empty string if not found
 func (idx *ISRCIndex) Lookup(isrc string) (string, error) {
@@ -0,0 +1,519 @@
+package gobackend
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	endpointOverridesFileName = "endpoint_overrides.json"
+	endpointRemoteFileName    = "endpoints_remote.json"
+
+	maxEndpointsPerService  = 32
+	maxEndpointDocumentSize = 256 * 1024
+	endpointFetchTimeout    = 30 * time.Second
+)
+
+// Endpoint service keys, as used in EndpointConfig JSON and per-service overrides
+const (
+	EndpointTidal       = "tidal"
+	EndpointQobuz       = "qobuz"
+	EndpointQobuzAPI    = "qobuz_api"
+	EndpointAmazon      = "amazon"
+	EndpointSongLink    = "songlink"
+	EndpointLRCLIB      = "lrclib"
+	EndpointMusicBrainz = "musicbrainz"
+)
+
+var endpointServices = []string{
+	EndpointTidal, EndpointQobuz, EndpointQobuzAPI, EndpointAmazon, EndpointSongLink, EndpointLRCLIB,
+	EndpointMusicBrainz,
+}
+
+// endpointSigningKeys are the base64 ed25519 public keys trusted to sign
+// remote endpoint documents. The list ships empty, so every remote document
+// is rejected. A key may only be added by the maintainers, from a key pair
+// they generated and hold themselves (for example with
+// `openssl genpkey -algorithm ed25519`), together with a note in the
+// release notes naming who holds the private key.
+var endpointSigningKeys = []string{}
+
+// EndpointConfig lists the base URLs of the built-in services. In overrides
+// and remote documents an empty field keeps the endpoints of the layer below.
+type EndpointConfig struct {
+	Tidal       []string `json:"tidal,omitempty"`       // Tidal API mirrors
+	Qobuz       []string `json:"qobuz,omitempty"`       // Qobuz stream APIs; the track ID is appended
+	QobuzAPI    string   `json:"qobuz_api,omitempty"`   // Qobuz metadata API base
+	Amazon      []string `json:"amazon,omitempty"`      // DoubleDouble service regions
+	SongLink    string   `json:"songlink,omitempty"`    // SongLink API base
+	LRCLIB      string   `json:"lrclib,omitempty"`      // LRCLIB API base
+	MusicBrainz string   `json:"musicbrainz,omitempty"` // MusicBrainz web service base
+}
+
+// defaultEndpoints returns the compiled-in endpoints
+func defaultEndpoints() EndpointConfig {
+	httpsHosts := func(encoded ...string) []string {
+		var urls []string
+		for _, e := range encoded {
+			decoded, err := base64.StdEncoding.DecodeString(e)
+			if err != nil {
+				continue
+			}
+			urls = append(urls, "https://"+string(decoded))
+		}
+		return urls
+	}
+	decode := func(encoded string) string {
+		decoded, _ := base64.StdEncoding.DecodeString(encoded)
+		return string(decoded)
+	}
+
+	return EndpointConfig{
+		Tidal: httpsHosts(
+			"dGlkYWwua2lub3BsdXMub25saW5l",
+			"dGlkYWwtYXBpLmJpbmltdW0ub3Jn",
+			"dHJpdG9uLnNxdWlkLnd0Zg==",
+			"dm9nZWwucXFkbC5zaXRl",
+			"bWF1cy5xcWRsLnNpdGU=",
+			"aHVuZC5xcWRsLnNpdGU=",
+			"a2F0emUucXFkbC5zaXRl",
+			"d29sZi5xcWRsLnNpdGU=",
+		),
+		// Same stream APIs as PC version: dab.yeet.su, fallback dabmusic.xyz
+		Qobuz: httpsHosts(
+			"ZGFiLnllZXQuc3UvYXBpL3N0cmVhbT90cmFja0lkPQ==",
+			"ZGFibXVzaWMueHl6L2FwaS9zdHJlYW0/dHJhY2tJZD0=",
+		),
+		QobuzAPI: decode("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMg=="),
+		// Same DoubleDouble regions as PC version: us, eu
+		Amazon: httpsHosts(
+			"dXMuZG91YmxlZG91YmxlLnRvcA==",
+			"ZXUuZG91YmxlZG91YmxlLnRvcA==",
+		),
+		SongLink:    decode("aHR0cHM6Ly9hcGkuc29uZy5saW5rL3YxLWFscGhhLjE="),
+		LRCLIB:      "https://lrclib.net/api",
+		MusicBrainz: "https://musicbrainz.org/ws/2",
+	}
+}
+
+func isEndpointService(service string) bool {
+	return slices.Contains(endpointServices, service)
+}
+
+// isSingleEndpointService reports whether a service takes exactly one base URL
+func isSingleEndpointService(service string) bool {
+	return service == EndpointQobuzAPI || service == EndpointSongLink || service == EndpointLRCLIB ||
+		service == EndpointMusicBrainz
+}
+
+// urls returns the endpoints of one service
+func (c EndpointConfig) urls(service string) []string {
+	single := func(s string) []string {
+		if s == "" {
+			return nil
+		}
+		return []string{s}
+	}
+	switch service {
+	case EndpointTidal:
+		return c.Tidal
+	case EndpointQobuz:
+		return c.Qobuz
+	case EndpointQobuzAPI:
+		return single(c.QobuzAPI)
+	case EndpointAmazon:
+		return c.Amazon
+	case EndpointSongLink:
+		return single(c.SongLink)
+	case EndpointLRCLIB:
+		return single(c.LRCLIB)
+	case EndpointMusicBrainz:
+		return single(c.MusicBrainz)
+	}
+	return nil
+}
+
+// setURLs replaces the endpoints of one service; nil clears them
+func (c *EndpointConfig) setURLs(service string, urls []string) error {
+	if !isEndpointService(service) {
+		return fmt.Errorf("unknown endpoint service: %s", service)
+	}
+	if isSingleEndpointService(service) && len(urls) > 1 {
+		return fmt.Errorf("%s takes a single endpoint, got %d", service, len(urls))
+	}
+	first := ""
+	if len(urls) > 0 {
+		first = urls[0]
+	}
+	urls = slices.Clone(urls)
+
+	switch service {
+	case EndpointTidal:
+		c.Tidal = urls
+	case EndpointQobuz:
+		c.Qobuz = urls
+	case EndpointQobuzAPI:
+		c.QobuzAPI = first
+	case EndpointAmazon:
+		c.Amazon = urls
+	case EndpointSongLink:
+		c.SongLink = first
+	case EndpointLRCLIB:
+		c.LRCLIB = first
+	case EndpointMusicBrainz:
+		c.MusicBrainz = first
+	}
+	return nil
+}
+
+// validateEndpointURL accepts absolute https URLs without credentials
+func validateEndpointURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %q: %w", raw, err)
+	}
+	if u.Scheme != "https" {
+		return fmt.Errorf("endpoint %q must use https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("endpoint %q has no host", raw)
+	}
+	if u.User != nil {
+		return fmt.Errorf("endpoint %q must not contain credentials", raw)
+	}
+	return nil
+}
+
+// validate checks every endpoint, rejecting duplicates and oversized lists
+func (c EndpointConfig) validate() error {
+	for _, service := range endpointServices {
+		urls := c.urls(service)
+		if len(urls) > maxEndpointsPerService {
+			return fmt.Errorf("too many %s endpoints: %d (max %d)", service, len(urls), maxEndpointsPerService)
+		}
+		seen := make(map[string]bool)
+		for _, u := range urls {
+			if err := validateEndpointURL(u); err != nil {
+				return fmt.Errorf("%s: %w", service, err)
+			}
+			if seen[u] {
+				return fmt.Errorf("%s: endpoint listed twice: %s", service, u)
+			}
+			seen[u] = true
+		}
+	}
+	return nil
+}
+
+// overlay returns c with every service that layer sets replaced
+func (c EndpointConfig) overlay(layer EndpointConfig) EndpointConfig {
+	for _, service := range endpointServices {
+		if urls := layer.urls(service); len(urls) > 0 {
+			c.setURLs(service, urls)
+		}
+	}
+	return c
+}
+
+// SignedEndpointDocument is a remotely published endpoint list. Payload is
+// the base64 JSON of an endpoint document and Signature the base64 ed25519
+// signature of the decoded payload.
+type SignedEndpointDocument struct {
+	Payload   string `json:"payload"`
+	Signature string `json:"signature"`
+}
+
+// endpointDocument is the signed payload. Version must increase with every
+// published document so an older one cannot be replayed.
+type endpointDocument struct {
+	Version   int            `json:"version"`
+	Expires   int64          `json:"expires,omitempty"` // Unix seconds; 0 means no expiry
+	Endpoints EndpointConfig `json:"endpoints"`
+}
+
+// verifyEndpointDocument checks the signature, expiry and endpoints of a
+// signed document
+func verifyEndpointDocument(data []byte, now time.Time) (*endpointDocument, error) {
+	var signed SignedEndpointDocument
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("invalid endpoint document: %w", err)
+	}
+	payload, err := base64.StdEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint document payload: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint document signature: %w", err)
+	}
+
+	if len(endpointSigningKeys) == 0 {
+		return nil, fmt.Errorf("no endpoint signing keys configured; remote endpoint documents are disabled")
+	}
+
+	verified := false
+	for _, encoded := range endpointSigningKeys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			continue
+		}
+		if ed25519.Verify(ed25519.PublicKey(key), payload, signature) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("endpoint document signature is not trusted")
+	}
+
+	var doc endpointDocument
+	if err := json.Unmarshal(payload, &doc); err != nil {
+		return nil, fmt.Errorf("invalid endpoint document payload: %w", err)
+	}
+	if doc.Version <= 0 {
+		return nil, fmt.Errorf("endpoint document has no version")
+	}
+	if doc.Expires > 0 && now.Unix() > doc.Expires {
+		return nil, fmt.Errorf("endpoint document version %d has expired", doc.Version)
+	}
+	if err := doc.Endpoints.validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// EndpointStatus describes the active endpoints and where they come from
+type EndpointStatus struct {
+	Endpoints     EndpointConfig `json:"endpoints"`
+	Overrides     EndpointConfig `json:"overrides"`
+	RemoteVersion int            `json:"remote_version,omitempty"`
+}
+
+// EndpointStore layers the built-in service endpoints: compiled-in defaults,
+// then the latest verified remote document, then local overrides
+type EndpointStore struct {
+	mu        sync.RWMutex
+	dataDir   string
+	remote    *endpointDocument
+	overrides EndpointConfig
+}
+
+var (
+	globalEndpointStore     *EndpointStore
+	globalEndpointStoreOnce sync.Once
+)
+
+// GetEndpointStore returns the global endpoint store. Until a document or
+// override is loaded, the compiled-in endpoints are used.
+func GetEndpointStore() *EndpointStore {
+	globalEndpointStoreOnce.Do(func() {
+		globalEndpointStore = &EndpointStore{}
+	})
+	return globalEndpointStore
+}
+
+// Endpoints returns the active endpoints of the built-in services
+func Endpoints() EndpointConfig {
+	return GetEndpointStore().Get()
+}
+
+// SetDataDir sets the directory of the endpoint files and loads the saved
+// overrides and remote document. The remote document is verified again.
+func (s *EndpointStore) SetDataDir(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create endpoint directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dataDir = dataDir
+
+	if data, err := os.ReadFile(filepath.Join(dataDir, endpointOverridesFileName)); err == nil {
+		var overrides EndpointConfig
+		if err := json.Unmarshal(data, &overrides); err != nil {
+			GoLog("[Endpoints] Ignoring unreadable overrides file: %v\n", err)
+		} else if err := overrides.validate(); err != nil {
+			GoLog("[Endpoints] Ignoring invalid overrides file: %v\n", err)
+		} else {
+			s.overrides = overrides
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dataDir, endpointRemoteFileName)); err == nil {
+		doc, err := verifyEndpointDocument(data, time.Now())
+		if err != nil {
+			GoLog("[Endpoints] Ignoring saved endpoint document: %v\n", err)
+		} else {
+			s.remote = doc
+			GoLog("[Endpoints] Loaded endpoint document version %d\n", doc.Version)
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Get returns the active endpoints with all layers applied
+func (s *EndpointStore) Get() EndpointConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	config := defaultEndpoints()
+	if s.remote != nil {
+		config = config.overlay(s.remote.Endpoints)
+	}
+	return config.overlay(s.overrides)
+}
+
+// Status returns the active endpoints together with the local overrides and
+// the version of the applied remote document
+func (s *EndpointStore) Status() EndpointStatus {
+	status := EndpointStatus{Endpoints: s.Get()}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	status.Overrides = s.overrides
+	if s.remote != nil {
+		status.RemoteVersion = s.remote.Version
+	}
+	return status
+}
+
+// SetOverrides validates, stores and persists the local overrides for all
+// services. Services left empty use the remote or compiled-in endpoints.
+func (s *EndpointStore) SetOverrides(overrides EndpointConfig) error {
+	if err := overrides.validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides = overrides
+	return s.saveOverridesLocked()
+}
+
+// SetServiceOverride overrides the endpoints of one service. An empty list
+// removes the override.
+func (s *EndpointStore) SetServiceOverride(service string, urls []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overrides := s.overrides
+	if err := overrides.setURLs(service, urls); err != nil {
+		return err
+	}
+	if err := overrides.validate(); err != nil {
+		return err
+	}
+	s.overrides = overrides
+	return s.saveOverridesLocked()
+}
+
+// LoadOverridesFile sets the local overrides from an EndpointConfig JSON file
+func (s *EndpointStore) LoadOverridesFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read endpoint config: %w", err)
+	}
+	var overrides EndpointConfig
+	if err := json.Unmarshal(data, &overrides); err != nil {
+		return fmt.Errorf("invalid endpoint config: %w", err)
+	}
+	return s.SetOverrides(overrides)
+}
+
+func (s *EndpointStore) saveOverridesLocked() error {
+	if s.dataDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.overrides, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.dataDir, endpointOverridesFileName), data)
+}
+
+// ApplySignedDocument verifies a signed endpoint document and makes it the
+// remote layer. Documents older than the applied one are rejected.
+func (s *EndpointStore) ApplySignedDocument(data []byte) error {
+	doc, err := verifyEndpointDocument(data, time.Now())
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.remote != nil && doc.Version < s.remote.Version {
+		return fmt.Errorf("endpoint document version %d is older than applied version %d", doc.Version, s.remote.Version)
+	}
+	s.remote = doc
+	GoLog("[Endpoints] Applied endpoint document version %d\n", doc.Version)
+
+	if s.dataDir == "" {
+		return nil
+	}
+	return writeFileAtomic(filepath.Join(s.dataDir, endpointRemoteFileName), data)
+}
+
+// FetchSignedDocument downloads a signed endpoint document over https and
+// applies it
+func (s *EndpointStore) FetchSignedDocument(documentURL string) error {
+	if err := validateEndpointURL(documentURL); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest("GET", documentURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := DoRequestWithUserAgent(NewHTTPClientWithTimeout(endpointFetchTimeout), req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch endpoint document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("failed to fetch endpoint document: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxEndpointDocumentSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read endpoint document: %w", err)
+	}
+	if len(data) > maxEndpointDocumentSize {
+		return fmt.Errorf("endpoint document exceeds %d bytes", maxEndpointDocumentSize)
+	}
+	return s.ApplySignedDocument(data)
+}
+
+// Reset drops the overrides and the remote document, returning to the
+// compiled-in endpoints
+func (s *EndpointStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides = EndpointConfig{}
+	s.remote = nil
+	if s.dataDir == "" {
+		return nil
+	}
+	for _, name := range []string{endpointOverridesFileName, endpointRemoteFileName} {
+		if err := os.Remove(filepath.Join(s.dataDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package gobackend
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signEndpointDocument(t *testing.T, key ed25519.PrivateKey, doc endpointDocument) []byte {
+	t.Helper()
+	payload, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(SignedEndpointDocument{
+		Payload:   base64.StdEncoding.EncodeToString(payload),
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func useTestSigningKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := endpointSigningKeys
+	endpointSigningKeys = []string{base64.StdEncoding.EncodeToString(pub)}
+	t.Cleanup(func() { endpointSigningKeys = saved })
+	return priv
+}
+
+func TestDefaultEndpointsAreValid(t *testing.T) {
+	defaults := defaultEndpoints()
+	if err := defaults.validate(); err != nil {
+		t.Fatalf("compiled-in endpoints invalid: %v", err)
+	}
+	for _, service := range endpointServices {
+		if len(defaults.urls(service)) == 0 {
+			t.Errorf("no default endpoint for %s", service)
+		}
+	}
+}
+
+func TestEndpointConfigValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config EndpointConfig
+		errMsg string
+	}{
+		{"valid", EndpointConfig{Tidal: []string{"https://a.example"}, LRCLIB: "https://lrc.example/api"}, ""},
+		{"http", EndpointConfig{Tidal: []string{"http://a.example"}}, "must use https"},
+		{"no host", EndpointConfig{SongLink: "https:///links"}, "no host"},
+		{"credentials", EndpointConfig{Amazon: []string{"https://user:pw@a.example"}}, "credentials"},
+		{"duplicate", EndpointConfig{Qobuz: []string{"https://a.example", "https://a.example"}}, "listed twice"},
+	}
+	for _, tt := range tests {
+		err := tt.config.validate()
+		if tt.errMsg == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+			t.Errorf("%s: error = %v, want %q", tt.name, err, tt.errMsg)
+		}
+	}
+}
+
+func TestEndpointStoreLayers(t *testing.T) {
+	priv := useTestSigningKey(t)
+	dir := t.TempDir()
+	store := &EndpointStore{}
+	if err := store.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	remote := []string{"https://remote-1.example", "https://remote-2.example"}
+	doc := signEndpointDocument(t, priv, endpointDocument{
+		Version:   2,
+		Endpoints: EndpointConfig{Tidal: remote, SongLink: "https://songlink.example/v1"},
+	})
+	if err := store.ApplySignedDocument(doc); err != nil {
+		t.Fatalf("ApplySignedDocument: %v", err)
+	}
+	if err := store.SetServiceOverride(EndpointSongLink, []string{"https://local.example/v1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := store.Get()
+	if !reflect.DeepEqual(got.Tidal, remote) {
+		t.Errorf("Tidal = %v, want remote %v", got.Tidal, remote)
+	}
+	if got.SongLink != "https://local.example/v1" {
+		t.Errorf("SongLink = %q, want local override", got.SongLink)
+	}
+	if got.LRCLIB != defaultEndpoints().LRCLIB {
+		t.Errorf("LRCLIB = %q, want default", got.LRCLIB)
+	}
+
+	// Both layers survive a restart
+	reloaded := &EndpointStore{}
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Get(), got) {
+		t.Errorf("reloaded endpoints = %+v, want %+v", reloaded.Get(), got)
+	}
+	if reloaded.Status().RemoteVersion != 2 {
+		t.Errorf("RemoteVersion = %d, want 2", reloaded.Status().RemoteVersion)
+	}
+
+	// Removing the override falls back to the remote endpoint
+	if err := reloaded.SetServiceOverride(EndpointSongLink, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := reloaded.Get().SongLink; got != "https://songlink.example/v1" {
+		t.Errorf("SongLink = %q, want remote", got)
+	}
+
+	if err := reloaded.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reloaded.Get(), defaultEndpoints()) {
+		t.Errorf("Reset did not restore defaults: %+v", reloaded.Get())
+	}
+}
+
+func TestEndpointStoreRejectsBadOverrides(t *testing.T) {
+	store := &EndpointStore{}
+	if err := store.SetServiceOverride("spotify", []string{"https://a.example"}); err == nil {
+		t.Error("expected unknown service error")
+	}
+	if err := store.SetServiceOverride(EndpointLRCLIB, []string{"https://a.example", "https://b.example"}); err == nil {
+		t.Error("expected single endpoint error")
+	}
+	if err := store.SetServiceOverride(EndpointTidal, []string{"ftp://a.example"}); err == nil {
+		t.Error("expected https error")
+	}
+	if !reflect.DeepEqual(store.Get(), defaultEndpoints()) {
+		t.Errorf("rejected overrides changed endpoints: %+v", store.Get())
+	}
+}
+
+func TestEndpointDocumentsRejectedWithoutKeys(t *testing.T) {
+	if len(endpointSigningKeys) != 0 {
+		t.Fatalf("shipped signing keys = %v, want none", endpointSigningKeys)
+	}
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	doc := signEndpointDocument(t, priv, endpointDocument{Version: 1, Endpoints: EndpointConfig{Tidal: []string{"https://a.example"}}})
+	if _, err := verifyEndpointDocument(doc, time.Now()); err == nil || !strings.Contains(err.Error(), "no endpoint signing keys") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestVerifyEndpointDocument(t *testing.T) {
+	priv := useTestSigningKey(t)
+	now := time.Now()
+	endpoints := EndpointConfig{Tidal: []string{"https://a.example"}}
+
+	valid := signEndpointDocument(t, priv, endpointDocument{Version: 3, Endpoints: endpoints})
+	if _, err := verifyEndpointDocument(valid, now); err != nil {
+		t.Fatalf("valid document rejected: %v", err)
+	}
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	untrusted := signEndpointDocument(t, otherKey, endpointDocument{Version: 3, Endpoints: endpoints})
+	if _, err := verifyEndpointDocument(untrusted, now); err == nil || !strings.Contains(err.Error(), "not trusted") {
+		t.Errorf("untrusted signature: err = %v", err)
+	}
+
+	var tampered SignedEndpointDocument
+	json.Unmarshal(valid, &tampered)
+	payload, _ := json.Marshal(endpointDocument{Version: 3, Endpoints: EndpointConfig{Tidal: []string{"https://evil.example"}}})
+	tampered.Payload = base64.StdEncoding.EncodeToString(payload)
+	tamperedData, _ := json.Marshal(tampered)
+	if _, err := verifyEndpointDocument(tamperedData, now); err == nil {
+		t.Error("tampered payload accepted")
+	}
+
+	expired := signEndpointDocument(t, priv, endpointDocument{Version: 3, Expires: now.Add(-time.Hour).Unix(), Endpoints: endpoints})
+	if _, err := verifyEndpointDocument(expired, now); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("expired document: err = %v", err)
+	}
+
+	invalid := signEndpointDocument(t, priv, endpointDocument{Version: 3, Endpoints: EndpointConfig{Tidal: []string{"http://a.example"}}})
+	if _, err := verifyEndpointDocument(invalid, now); err == nil {
+		t.Error("document with invalid endpoint accepted")
+	}
+
+	store := &EndpointStore{}
+	if err := store.ApplySignedDocument(valid); err != nil {
+		t.Fatal(err)
+	}
+	older := signEndpointDocument(t, priv, endpointDocument{Version: 2, Endpoints: endpoints})
+	if err := store.ApplySignedDocument(older); err == nil || !strings.Contains(err.Error(), "older") {
+		t.Errorf("rollback: err = %v", err)
+	}
+}
@@ -46,7 +46,11 @@ func GetSpotifyMetadata(spotifyURL string) (string, error) {
 
 	client, err := NewSpotifyMetadataClient()
 	if err != nil {
-		return "", err
+		// A logged-in user with only a Client ID can still read playlists
+		if !errors.Is(err, ErrNoSpotifyCredentials) || !GetSpotifyAuthStore().Status().Authorized {
+			return "", err
+		}
+		client = newSpotifyUserClient()
 	}
 	data, err := client.GetFilteredData(ctx, spotifyURL, false, 0)
 	if err != nil {
@@ -103,6 +107,113 @@ func SearchSpotifyAll(query string, trackLimit, artistLimit int) (string, error)
 	return string(jsonBytes), nil
 }
 
+// InitSpotifyAuth loads the saved Spotify login from dataDir
+func InitSpotifyAuth(dataDir string) error {
+	return GetSpotifyAuthStore().SetDataDir(dataDir)
+}
+
+// StartSpotifyAuth starts an authorization code + PKCE login and returns the
+// URL to open in the browser together with its state
+func StartSpotifyAuth(redirectURI string) (string, error) {
+	start, err := GetSpotifyAuthStore().StartAuthorization(redirectURI)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(start)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// CompleteSpotifyAuth finishes the login with the redirect URL Spotify sent
+// the user back to and returns the new auth status
+func CompleteSpotifyAuth(callbackURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	store := GetSpotifyAuthStore()
+	if err := store.CompleteAuthorization(ctx, callbackURL); err != nil {
+		return "", err
+	}
+
+	return GetSpotifyAuthStatusJSON()
+}
+
+// GetSpotifyAuthStatusJSON reports whether a Spotify account is connected
+func GetSpotifyAuthStatusJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetSpotifyAuthStore().Status())
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// LogoutSpotify forgets the saved Spotify login
+func LogoutSpotify() error {
+	return GetSpotifyAuthStore().Logout()
+}
+
+// GetSpotifyLikedSongs returns the user's Liked Songs in the playlist payload
+// shape of GetSpotifyMetadata
+func GetSpotifyLikedSongs() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	data, err := newSpotifyUserClient().GetLikedSongs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// GetSpotifySavedAlbums returns the user's saved albums in the artist payload
+// shape of GetSpotifyMetadata
+func GetSpotifySavedAlbums() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	data, err := newSpotifyUserClient().GetSavedAlbums(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// GetSpotifyFollowedArtists returns the artists the user follows, each in the
+// artist shape of SearchSpotifyAll
+func GetSpotifyFollowedArtists() (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	data, err := newSpotifyUserClient().GetFollowedArtists(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
 func CheckAvailability(spotifyID, isrc string) (string, error) {
 	client := NewSongLinkClient()
 	availability, err := client.CheckTrackAvailability(spotifyID, isrc)
@@ -146,52 +257,68 @@ type DownloadRequest struct {
 	QobuzID              string `json:"qobuz_id,omitempty"`
 	DeezerID             string `json:"deezer_id,omitempty"`
 	LyricsMode           string `json:"lyrics_mode,omitempty"`
+	KeepPartial          bool   `json:"keep_partial,omitempty"`    // Keep .part file on cancel so a retry resumes it
+	MinBitDepth          int    `json:"min_bit_depth,omitempty"`   // Best-quality mode: reject results below this
+	MinSampleRate        int    `json:"min_sample_rate,omitempty"` // Best-quality mode: reject results below this (Hz)
+
+	MetadataSources map[string]string `json:"metadata_sources,omitempty"` // Field -> source of the request's values, "request" when unset
 }
 
 // DownloadResponse represents the result of a download
 type DownloadResponse struct {
-	Success                bool   `json:"success"`
-	Message                string `json:"message"`
-	FilePath               string `json:"file_path,omitempty"`
-	Error                  string `json:"error,omitempty"`
-	ErrorType              string `json:"error_type,omitempty"` // "not_found", "rate_limit", "network", "unknown"
-	AlreadyExists          bool   `json:"already_exists,omitempty"`
-	ActualBitDepth         int    `json:"actual_bit_depth,omitempty"`
-	ActualSampleRate       int    `json:"actual_sample_rate,omitempty"`
-	Service                string `json:"service,omitempty"` // Actual service used (for fallback)
-	Title                  string `json:"title,omitempty"`
-	Artist                 string `json:"artist,omitempty"`
-	Album                  string `json:"album,omitempty"`
-	AlbumArtist            string `json:"album_artist,omitempty"`
-	ReleaseDate            string `json:"release_date,omitempty"`
-	TrackNumber            int    `json:"track_number,omitempty"`
-	DiscNumber             int    `json:"disc_number,omitempty"`
-	ISRC                   string `json:"isrc,omitempty"`
-	CoverURL               string `json:"cover_url,omitempty"`
-	Genre                  string `json:"genre,omitempty"`
-	Label                  string `json:"label,omitempty"`
-	Copyright              string `json:"copyright,omitempty"`
-	SkipMetadataEnrichment bool   `json:"skip_metadata_enrichment,omitempty"`
+	Success                bool                     `json:"success"`
+	Message                string                   `json:"message"`
+	FilePath               string                   `json:"file_path,omitempty"`
+	Error                  string                   `json:"error,omitempty"`
+	ErrorType              string                   `json:"error_type,omitempty"` // "not_found", "rate_limit", "network", "preview", "unknown"
+	AlreadyExists          bool                     `json:"already_exists,omitempty"`
+	ActualBitDepth         int                      `json:"actual_bit_depth,omitempty"`
+	ActualSampleRate       int                      `json:"actual_sample_rate,omitempty"`
+	Service                string                   `json:"service,omitempty"`  // Actual service used (for fallback)
+	TrackID                string                   `json:"track_id,omitempty"` // The service's ID of the downloaded track
+	Title                  string                   `json:"title,omitempty"`
+	Artist                 string                   `json:"artist,omitempty"`
+	Album                  string                   `json:"album,omitempty"`
+	AlbumArtist            string                   `json:"album_artist,omitempty"`
+	ReleaseDate            string                   `json:"release_date,omitempty"`
+	TrackNumber            int                      `json:"track_number,omitempty"`
+	DiscNumber             int                      `json:"disc_number,omitempty"`
+	ISRC                   string                   `json:"isrc,omitempty"`
+	CoverURL               string                   `json:"cover_url,omitempty"`
+	Genre                  string                   `json:"genre,omitempty"`
+	Label                  string                   `json:"label,omitempty"`
+	Copyright              string                   `json:"copyright,omitempty"`
+	SkipMetadataEnrichment bool                     `json:"skip_metadata_enrichment,omitempty"`
+	QualityDecisions       []ServiceQualityDecision `json:"quality_decisions,omitempty"` // Best-quality mode: why each service was chosen or skipped
+	Attempts               []DownloadAttempt        `json:"attempts,omitempty"`          // Every provider tried, in order
+	MetadataSources        map[string]string        `json:"metadata_sources,omitempty"`  // Field -> provider that supplied the tag
 }
 
 type DownloadResult struct {
-	FilePath    string
-	BitDepth    int
-	SampleRate  int
-	Title       string
-	Artist      string
-	Album       string
-	ReleaseDate string
-	TrackNumber int
-	DiscNumber  int
-	ISRC        string
-}
-
-func DownloadTrack(requestJSON string) (string, error) {
+	FilePath        string
+	BitDepth        int
+	SampleRate      int
+	Title           string
+	Artist          string
+	Album           string
+	ReleaseDate     string
+	TrackNumber     int
+	DiscNumber      int
+	ISRC            string
+	Genre           string
+	Label           string
+	Copyright       string
+	MetadataSources map[string]string // Field -> source of the tag written
+	MatchedID       string            // Provider's ID of the track it downloaded
+	Matched         TrackMatchInfo    // Provider's own details of that track, checked by verifyDownload
+}
+
+func DownloadTrack(requestJSON string) (respJSON string, err error) {
 	var req DownloadRequest
 	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
 		return errorResponse("Invalid request: " + err.Error())
 	}
+	defer func() { recordDownloadHistoryJSON(req, respJSON) }()
 
 	// Trim whitespace from string fields to prevent filename/path issues
 	req.TrackName = strings.TrimSpace(req.TrackName)
@@ -204,112 +331,54 @@ func DownloadTrack(requestJSON string) (string, error) {
 		AddAllowedDownloadDir(req.OutputDir)
 	}
 
-	var result DownloadResult
-	var err error
-
-	switch req.Service {
-	case "tidal":
-		tidalResult, tidalErr := downloadFromTidal(req)
-		if tidalErr == nil {
-			result = DownloadResult{
-				FilePath:    tidalResult.FilePath,
-				BitDepth:    tidalResult.BitDepth,
-				SampleRate:  tidalResult.SampleRate,
-				Title:       tidalResult.Title,
-				Artist:      tidalResult.Artist,
-				Album:       tidalResult.Album,
-				ReleaseDate: tidalResult.ReleaseDate,
-				TrackNumber: tidalResult.TrackNumber,
-				DiscNumber:  tidalResult.DiscNumber,
-				ISRC:        tidalResult.ISRC,
-			}
-		}
-		err = tidalErr
-	case "qobuz":
-		qobuzResult, qobuzErr := downloadFromQobuz(req)
-		if qobuzErr == nil {
-			result = DownloadResult{
-				FilePath:    qobuzResult.FilePath,
-				BitDepth:    qobuzResult.BitDepth,
-				SampleRate:  qobuzResult.SampleRate,
-				Title:       qobuzResult.Title,
-				Artist:      qobuzResult.Artist,
-				Album:       qobuzResult.Album,
-				ReleaseDate: qobuzResult.ReleaseDate,
-				TrackNumber: qobuzResult.TrackNumber,
-				DiscNumber:  qobuzResult.DiscNumber,
-				ISRC:        qobuzResult.ISRC,
-			}
-		}
-		err = qobuzErr
-	case "amazon":
-		amazonResult, amazonErr := downloadFromAmazon(req)
-		if amazonErr == nil {
-			result = DownloadResult{
-				FilePath:    amazonResult.FilePath,
-				BitDepth:    amazonResult.BitDepth,
-				SampleRate:  amazonResult.SampleRate,
-				Title:       amazonResult.Title,
-				Artist:      amazonResult.Artist,
-				Album:       amazonResult.Album,
-				ReleaseDate: amazonResult.ReleaseDate,
-				TrackNumber: amazonResult.TrackNumber,
-				DiscNumber:  amazonResult.DiscNumber,
-				ISRC:        amazonResult.ISRC,
-			}
-		}
-		err = amazonErr
-	default:
+	if _, ok := GetDownloadProvider(req.Service); !ok {
 		return errorResponse("Unknown service: " + req.Service)
 	}
 
+	start := time.Now()
+	result, err := downloadWithProvider(req.Service, req, nil)
+	attempts := []DownloadAttempt{newDownloadAttempt(req.Service, start, err)}
 	if err != nil {
-		return errorResponse(err.Error())
+		return attemptsErrorResponse(err.Error(), attempts)
 	}
 
-	if len(result.FilePath) > 7 && result.FilePath[:7] == "EXISTS:" {
-		actualPath := result.FilePath[7:]
-		quality, qErr := GetAudioQuality(actualPath)
-		if qErr == nil {
-			result.BitDepth = quality.BitDepth
-			result.SampleRate = quality.SampleRate
-		}
-		resp := DownloadResponse{
-			Success:          true,
-			Message:          "File already exists",
-			FilePath:         actualPath,
-			AlreadyExists:    true,
-			ActualBitDepth:   result.BitDepth,
-			ActualSampleRate: result.SampleRate,
-			Service:          req.Service,
-			Title:            result.Title,
-			Artist:           result.Artist,
-			Album:            result.Album,
-			ReleaseDate:      result.ReleaseDate,
-			TrackNumber:      result.TrackNumber,
-			DiscNumber:       result.DiscNumber,
-			ISRC:             result.ISRC,
-		}
-		jsonBytes, _ := json.Marshal(resp)
-		return string(jsonBytes), nil
+	resp := buildDownloadResponse(result, req.Service, "Download complete")
+	resp.Attempts = attempts
+	jsonBytes, _ := json.Marshal(resp)
+	return string(jsonBytes), nil
+}
+
+// buildDownloadResponse converts a provider result into a DownloadResponse,
+// reading the actual quality from the file. Results with an "EXISTS:" path
+// are reported as already existing.
+func buildDownloadResponse(result DownloadResult, service, message string) DownloadResponse {
+	alreadyExists := false
+	if strings.HasPrefix(result.FilePath, "EXISTS:") {
+		result.FilePath = strings.TrimPrefix(result.FilePath, "EXISTS:")
+		alreadyExists = true
+		message = "File already exists"
 	}
 
 	quality, qErr := GetAudioQuality(result.FilePath)
 	if qErr == nil {
 		result.BitDepth = quality.BitDepth
 		result.SampleRate = quality.SampleRate
-		GoLog("[Download] Actual quality from file: %d-bit/%dHz\n", quality.BitDepth, quality.SampleRate)
-	} else {
+		if !alreadyExists {
+			GoLog("[Download] Actual quality from file: %d-bit/%dHz\n", quality.BitDepth, quality.SampleRate)
+		}
+	} else if !alreadyExists {
 		GoLog("[Download] Could not read quality from file: %v\n", qErr)
 	}
 
-	resp := DownloadResponse{
+	return DownloadResponse{
 		Success:          true,
-		Message:          "Download complete",
+		Message:          message,
 		FilePath:         result.FilePath,
+		AlreadyExists:    alreadyExists,
 		ActualBitDepth:   result.BitDepth,
 		ActualSampleRate: result.SampleRate,
-		Service:          req.Service,
+		Service:          service,
+		TrackID:          result.MatchedID,
 		Title:            result.Title,
 		Artist:           result.Artist,
 		Album:            result.Album,
@@ -317,17 +386,19 @@ func DownloadTrack(requestJSON string) (string, error) {
 		TrackNumber:      result.TrackNumber,
 		DiscNumber:       result.DiscNumber,
 		ISRC:             result.ISRC,
+		Genre:            result.Genre,
+		Label:            result.Label,
+		Copyright:        result.Copyright,
+		MetadataSources:  result.MetadataSources,
 	}
-
-	jsonBytes, _ := json.Marshal(resp)
-	return string(jsonBytes), nil
 }
 
-func DownloadWithFallback(requestJSON string) (string, error) {
+func DownloadWithFallback(requestJSON string) (respJSON string, err error) {
 	var req DownloadRequest
 	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
 		return errorResponse("Invalid request: " + err.Error())
 	}
+	defer func() { recordDownloadHistoryJSON(req, respJSON) }()
 
 	req.TrackName = strings.TrimSpace(req.TrackName)
 	req.ArtistName = strings.TrimSpace(req.ArtistName)
@@ -339,148 +410,37 @@ func DownloadWithFallback(requestJSON string) (string, error) {
 		AddAllowedDownloadDir(req.OutputDir)
 	}
 
-	allServices := []string{"tidal", "qobuz", "amazon"}
-	preferredService := req.Service
-	if preferredService == "" {
-		preferredService = "tidal"
-	}
-
 	GoLog("[DownloadWithFallback] Preferred service from request: '%s'\n", req.Service)
 
-	services := []string{preferredService}
-	for _, s := range allServices {
-		if s != preferredService {
-			services = append(services, s)
-		}
+	// Preferred service first, then the rest in fallback policy order
+	services := GetFallbackPolicyStore().Get().serviceOrder(req.Service)
+	if len(services) == 0 {
+		return errorResponse("All services are disabled by the fallback policy")
 	}
 
 	GoLog("[DownloadWithFallback] Service order: %v\n", services)
 
 	var lastErr error
+	var attempts []DownloadAttempt
 
 	for _, service := range services {
 		GoLog("[DownloadWithFallback] Trying service: %s\n", service)
 		req.Service = service
 
-		var result DownloadResult
-		var err error
-
-		switch service {
-		case "tidal":
-			tidalResult, tidalErr := downloadFromTidal(req)
-			if tidalErr == nil {
-				result = DownloadResult{
-					FilePath:    tidalResult.FilePath,
-					BitDepth:    tidalResult.BitDepth,
-					SampleRate:  tidalResult.SampleRate,
-					Title:       tidalResult.Title,
-					Artist:      tidalResult.Artist,
-					Album:       tidalResult.Album,
-					ReleaseDate: tidalResult.ReleaseDate,
-					TrackNumber: tidalResult.TrackNumber,
-					DiscNumber:  tidalResult.DiscNumber,
-					ISRC:        tidalResult.ISRC,
-				}
-			} else if !errors.Is(tidalErr, ErrDownloadCancelled) {
-				GoLog("[DownloadWithFallback] Tidal error: %v\n", tidalErr)
-			}
-			err = tidalErr
-		case "qobuz":
-			qobuzResult, qobuzErr := downloadFromQobuz(req)
-			if qobuzErr == nil {
-				result = DownloadResult{
-					FilePath:    qobuzResult.FilePath,
-					BitDepth:    qobuzResult.BitDepth,
-					SampleRate:  qobuzResult.SampleRate,
-					Title:       qobuzResult.Title,
-					Artist:      qobuzResult.Artist,
-					Album:       qobuzResult.Album,
-					ReleaseDate: qobuzResult.ReleaseDate,
-					TrackNumber: qobuzResult.TrackNumber,
-					DiscNumber:  qobuzResult.DiscNumber,
-					ISRC:        qobuzResult.ISRC,
-				}
-			} else if !errors.Is(qobuzErr, ErrDownloadCancelled) {
-				GoLog("[DownloadWithFallback] Qobuz error: %v\n", qobuzErr)
-			}
-			err = qobuzErr
-		case "amazon":
-			amazonResult, amazonErr := downloadFromAmazon(req)
-			if amazonErr == nil {
-				result = DownloadResult{
-					FilePath:    amazonResult.FilePath,
-					BitDepth:    amazonResult.BitDepth,
-					SampleRate:  amazonResult.SampleRate,
-					Title:       amazonResult.Title,
-					Artist:      amazonResult.Artist,
-					Album:       amazonResult.Album,
-					ReleaseDate: amazonResult.ReleaseDate,
-					TrackNumber: amazonResult.TrackNumber,
-					DiscNumber:  amazonResult.DiscNumber,
-					ISRC:        amazonResult.ISRC,
-				}
-			} else if !errors.Is(amazonErr, ErrDownloadCancelled) {
-				GoLog("[DownloadWithFallback] Amazon error: %v\n", amazonErr)
-			}
-			err = amazonErr
+		start := time.Now()
+		result, err := downloadAndVerify(service, req, nil)
+		attempts = append(attempts, newDownloadAttempt(service, start, err))
+		if err != nil && !errors.Is(err, ErrDownloadCancelled) {
+			GoLog("[DownloadWithFallback] %s error: %v\n", service, err)
 		}
 
 		if err != nil && errors.Is(err, ErrDownloadCancelled) {
-			return errorResponse("Download cancelled")
+			return attemptsErrorResponse("Download cancelled", attempts)
 		}
 
 		if err == nil {
-			if len(result.FilePath) > 7 && result.FilePath[:7] == "EXISTS:" {
-				actualPath := result.FilePath[7:]
-				quality, qErr := GetAudioQuality(actualPath)
-				if qErr == nil {
-					result.BitDepth = quality.BitDepth
-					result.SampleRate = quality.SampleRate
-				}
-				resp := DownloadResponse{
-					Success:          true,
-					Message:          "File already exists",
-					FilePath:         actualPath,
-					AlreadyExists:    true,
-					ActualBitDepth:   result.BitDepth,
-					ActualSampleRate: result.SampleRate,
-					Service:          service,
-					Title:            result.Title,
-					Artist:           result.Artist,
-					Album:            result.Album,
-					ReleaseDate:      result.ReleaseDate,
-					TrackNumber:      result.TrackNumber,
-					DiscNumber:       result.DiscNumber,
-					ISRC:             result.ISRC,
-				}
-				jsonBytes, _ := json.Marshal(resp)
-				return string(jsonBytes), nil
-			}
-
-			quality, qErr := GetAudioQuality(result.FilePath)
-			if qErr == nil {
-				result.BitDepth = quality.BitDepth
-				result.SampleRate = quality.SampleRate
-				GoLog("[Download] Actual quality from file: %d-bit/%dHz\n", quality.BitDepth, quality.SampleRate)
-			} else {
-				GoLog("[Download] Could not read quality from file: %v\n", qErr)
-			}
-
-			resp := DownloadResponse{
-				Success:          true,
-				Message:          "Downloaded from " + service,
-				FilePath:         result.FilePath,
-				ActualBitDepth:   result.BitDepth,
-				ActualSampleRate: result.SampleRate,
-				Service:          service,
-				Title:            result.Title,
-				Artist:           result.Artist,
-				Album:            result.Album,
-				ReleaseDate:      result.ReleaseDate,
-				TrackNumber:      result.TrackNumber,
-				DiscNumber:       result.DiscNumber,
-				ISRC:             result.ISRC,
-			}
+			resp := buildDownloadResponse(result, service, "Downloaded from "+service)
+			resp.Attempts = attempts
 			jsonBytes, _ := json.Marshal(resp)
 			return string(jsonBytes), nil
 		}
@@ -488,7 +448,124 @@ func DownloadWithFallback(requestJSON string) (string, error) {
 		lastErr = err
 	}
 
-	return errorResponse("All services failed. Last error: " + lastErr.Error())
+	return attemptsErrorResponse("All services failed. Last error: "+lastErr.Error(), attempts)
+}
+
+// DownloadWithBestQualityJSON downloads from the built-in service offering the best quality
+func DownloadWithBestQualityJSON(requestJSON string) (string, error) {
+	var req DownloadRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return errorResponse("Invalid request: " + err.Error())
+	}
+
+	resp, err := DownloadWithBestQuality(req)
+	if err != nil {
+		return errorResponse(err.Error())
+	}
+
+	jsonBytes, err := json.Marshal(resp)
+	if err != nil {
+		return errorResponse("Failed to encode response: " + err.Error())
+	}
+	return string(jsonBytes), nil
+}
+
+// ResolveDownloadJSON previews a download: chosen service, matched remote track,
+// expected quality, output path and duplicate check, without fetching audio
+func ResolveDownloadJSON(requestJSON string) (string, error) {
+	var req DownloadRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	jsonBytes, err := json.Marshal(ResolveDownload(req))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// ResolveDownloadsJSON previews a batch of downloads (JSON array of requests)
+func ResolveDownloadsJSON(requestsJSON string) (string, error) {
+	var reqs []DownloadRequest
+	if err := json.Unmarshal([]byte(requestsJSON), &reqs); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	jsonBytes, err := json.Marshal(ResolveDownloads(reqs))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// DownloadAlbumJSON resolves an album and downloads all of its tracks, returning a per-track report
+func DownloadAlbumJSON(requestJSON string) (string, error) {
+	var req AlbumDownloadRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	report, err := DownloadAlbum(req)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// SyncPlaylistJSON downloads the tracks of a playlist missing from the output directory and returns the diff summary
+func SyncPlaylistJSON(requestJSON string) (string, error) {
+	var req PlaylistSyncRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	result, err := SyncPlaylist(req)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// WritePlaylistFileJSON (re)writes playlist files for tracks downloaded outside the album/playlist APIs.
+// Call it again as items finish; entries without file_path are kept as gaps.
+func WritePlaylistFileJSON(requestJSON string) (string, error) {
+	var req struct {
+		OutputDir string              `json:"output_dir"`
+		Title     string              `json:"title"`
+		Formats   []string            `json:"formats"`
+		Entries   []PlaylistFileEntry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+	if req.OutputDir == "" || req.Title == "" {
+		return "", fmt.Errorf("output_dir and title are required")
+	}
+
+	writer := newPlaylistFileWriter(req.OutputDir, req.Title, req.Formats, len(req.Entries))
+	for i, entry := range req.Entries {
+		writer.Fill(i, entry)
+	}
+	if err := writer.Write(); err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(writer.Paths())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
 }
 
 func GetDownloadProgress() string {
@@ -521,6 +598,316 @@ func CleanupConnections() {
 	CloseIdleConnections()
 }
 
+// InitDownloadQueueJSON loads the persistent download queue from dataDir and resumes unfinished jobs
+func InitDownloadQueueJSON(dataDir string) error {
+	_, err := InitDownloadQueue(dataDir)
+	return err
+}
+
+func requireDownloadQueue() (*DownloadQueue, error) {
+	queue := GetDownloadQueue()
+	if queue == nil {
+		return nil, fmt.Errorf("download queue not initialized")
+	}
+	return queue, nil
+}
+
+// EnqueueDownloadJSON adds a DownloadRequest to the queue
+// mode: "single", "fallback" (default), "extensions" or "best_quality"
+func EnqueueDownloadJSON(requestJSON, mode string) (string, error) {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return "", err
+	}
+
+	var req DownloadRequest
+	if err := json.Unmarshal([]byte(requestJSON), &req); err != nil {
+		return "", fmt.Errorf("invalid request: %w", err)
+	}
+
+	job, err := queue.Enqueue(req, mode)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// GetDownloadQueueJSON returns all queued jobs in order plus a status summary
+func GetDownloadQueueJSON() (string, error) {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return "", err
+	}
+
+	result := map[string]interface{}{
+		"status": queue.Status(),
+		"jobs":   queue.Jobs(),
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// GetDownloadQueueJobJSON returns a single queued job
+func GetDownloadQueueJobJSON(jobID string) (string, error) {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return "", err
+	}
+
+	job, err := queue.Job(jobID)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
+// MoveDownloadQueueJob moves a job to a new position in the queue
+func MoveDownloadQueueJob(jobID string, newIndex int) error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	return queue.Move(jobID, newIndex)
+}
+
+// RemoveDownloadQueueJob removes a job, cancelling it if running
+func RemoveDownloadQueueJob(jobID string) error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	return queue.Remove(jobID)
+}
+
+// RetryDownloadQueueJob requeues a failed or cancelled job
+func RetryDownloadQueueJob(jobID string) error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	return queue.Retry(jobID)
+}
+
+// ClearFinishedDownloadQueueJobs removes completed, failed and cancelled jobs
+func ClearFinishedDownloadQueueJobs() int {
+	queue := GetDownloadQueue()
+	if queue == nil {
+		return 0
+	}
+	return queue.ClearFinished()
+}
+
+// SetDownloadQueueWorkers sets how many queued downloads run concurrently
+func SetDownloadQueueWorkers(workers int) error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	queue.SetWorkers(workers)
+	return nil
+}
+
+// SetDownloadQueueRetryPolicyJSON sets the retry policy for failed jobs
+func SetDownloadQueueRetryPolicyJSON(policyJSON string) error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+
+	policy := DefaultQueueRetryPolicy()
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return fmt.Errorf("invalid retry policy: %w", err)
+	}
+
+	queue.SetRetryPolicy(policy)
+	return nil
+}
+
+// PauseDownloadQueue stops starting new jobs; running jobs finish normally
+func PauseDownloadQueue() error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	queue.SetPaused(true)
+	return nil
+}
+
+// ResumeDownloadQueue resumes starting queued jobs
+func ResumeDownloadQueue() error {
+	queue, err := requireDownloadQueue()
+	if err != nil {
+		return err
+	}
+	queue.SetPaused(false)
+	return nil
+}
+
+// InitDownloadHistory loads the download history from dataDir and starts recording downloads
+func InitDownloadHistory(dataDir string) error {
+	return GetDownloadHistoryStore().SetDataDir(dataDir)
+}
+
+// QueryDownloadHistoryJSON returns history records matching a DownloadHistoryQuery, newest first
+func QueryDownloadHistoryJSON(queryJSON string) (string, error) {
+	var query DownloadHistoryQuery
+	if queryJSON != "" {
+		if err := json.Unmarshal([]byte(queryJSON), &query); err != nil {
+			return "", fmt.Errorf("invalid query: %w", err)
+		}
+	}
+
+	jsonBytes, err := json.Marshal(GetDownloadHistoryStore().Query(query))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// ExportDownloadHistory returns matching history records as "json" or "csv"
+func ExportDownloadHistory(format, queryJSON string) (string, error) {
+	var query DownloadHistoryQuery
+	if queryJSON != "" {
+		if err := json.Unmarshal([]byte(queryJSON), &query); err != nil {
+			return "", fmt.Errorf("invalid query: %w", err)
+		}
+	}
+	records := GetDownloadHistoryStore().Query(query)
+
+	switch strings.ToLower(format) {
+	case "", "json":
+		jsonBytes, err := json.MarshalIndent(records, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(jsonBytes), nil
+	case "csv":
+		var sb strings.Builder
+		if err := writeDownloadHistoryCSV(&sb, records); err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ClearDownloadHistory deletes all history records
+func ClearDownloadHistory() error {
+	return GetDownloadHistoryStore().Clear()
+}
+
+// InitMirrorHealth loads saved API mirror health scores from dataDir
+func InitMirrorHealth(dataDir string) error {
+	return GetMirrorHealthStore().SetDataDir(dataDir)
+}
+
+// GetMirrorHealthJSON lists the health of every Tidal API mirror, best first
+func GetMirrorHealthJSON() (string, error) {
+	apis := NewTidalDownloader().GetAvailableAPIs()
+	jsonBytes, err := json.Marshal(GetMirrorHealthStore().Snapshot(apis))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// ResetMirrorHealth forgets all recorded mirror health
+func ResetMirrorHealth() error {
+	return GetMirrorHealthStore().Reset()
+}
+
+// InitMetadataCache keeps the Spotify and Deezer metadata cache under
+// dataDir and loads what earlier sessions saved there
+func InitMetadataCache(dataDir string) error {
+	return GetMetadataCache().SetDataDir(dataDir)
+}
+
+// GetMetadataCacheStatsJSON returns entry counts, sizes and hit counts for
+// each metadata cache namespace
+func GetMetadataCacheStatsJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetMetadataCache().Stats())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// ClearMetadataCache empties one metadata cache namespace, or all of them
+// when namespace is empty
+func ClearMetadataCache(namespace string) error {
+	return GetMetadataCache().Clear(namespace)
+}
+
+// InitEndpointConfig loads saved endpoint overrides and the last verified
+// remote endpoint document from dataDir
+func InitEndpointConfig(dataDir string) error {
+	return GetEndpointStore().SetDataDir(dataDir)
+}
+
+// GetEndpointConfigJSON returns the active service endpoints, the local
+// overrides and the applied remote document version
+func GetEndpointConfigJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetEndpointStore().Status())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// SetEndpointOverridesJSON replaces the local endpoint overrides for all
+// services. Services left out use the remote or built-in endpoints.
+func SetEndpointOverridesJSON(configJSON string) error {
+	var overrides EndpointConfig
+	if err := json.Unmarshal([]byte(configJSON), &overrides); err != nil {
+		return err
+	}
+	return GetEndpointStore().SetOverrides(overrides)
+}
+
+// SetServiceEndpointsJSON overrides the endpoints of one service with a JSON
+// list of URLs. An empty list removes the override.
+func SetServiceEndpointsJSON(service, urlsJSON string) error {
+	var urls []string
+	if err := json.Unmarshal([]byte(urlsJSON), &urls); err != nil {
+		return err
+	}
+	return GetEndpointStore().SetServiceOverride(service, urls)
+}
+
+// LoadEndpointConfigFile sets the local endpoint overrides from a JSON file
+func LoadEndpointConfigFile(filePath string) error {
+	return GetEndpointStore().LoadOverridesFile(filePath)
+}
+
+// UpdateEndpointsFromURL fetches a signed endpoint document and applies it
+// once its signature is verified
+func UpdateEndpointsFromURL(documentURL string) error {
+	return GetEndpointStore().FetchSignedDocument(documentURL)
+}
+
+// ResetEndpointConfig returns all services to their built-in endpoints
+func ResetEndpointConfig() error {
+	return GetEndpointStore().Reset()
+}
+
 func ReadFileMetadata(filePath string) (string, error) {
 	metadata, err := ReadMetadata(filePath)
 	if err != nil {
@@ -811,6 +1198,48 @@ func GetDeezerExtendedMetadata(trackID string) (string, error) {
 	return string(jsonBytes), nil
 }
 
+// GetQobuzAlbum fetches a Qobuz album with disc layout and album-level tags
+func GetQobuzAlbum(albumID string) (string, error) {
+	album, err := NewQobuzDownloader().GetAlbum(albumID)
+	if err != nil {
+		return "", err
+	}
+
+	tracks := make([]map[string]interface{}, 0, len(album.Tracks.Items))
+	for _, t := range album.Tracks.Items {
+		tracks = append(tracks, map[string]interface{}{
+			"id":           t.ID,
+			"title":        t.Title,
+			"isrc":         t.ISRC,
+			"duration_ms":  t.Duration * 1000,
+			"track_number": t.TrackNumber,
+			"disc_number":  t.MediaNumber,
+		})
+	}
+
+	result := map[string]interface{}{
+		"id":           album.ID,
+		"title":        album.Title,
+		"album_artist": album.Artist.Name,
+		"upc":          album.UPC,
+		"label":        album.Label.Name,
+		"genre":        album.Genre.Name,
+		"copyright":    album.Copyright,
+		"release_date": album.ReleaseDate,
+		"total_discs":  album.MediaCount,
+		"total_tracks": album.TracksCount,
+		"cover_url":    album.Image.Large,
+		"tracks":       tracks,
+	}
+
+	jsonBytes, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
 // SearchDeezerByISRC searches for a track by ISRC on Deezer
 func SearchDeezerByISRC(isrc string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -976,6 +1405,17 @@ func GetAmazonURLFromDeezerTrack(deezerTrackID string) (string, error) {
 }
 
 func errorResponse(msg string) (string, error) {
+	resp := DownloadResponse{
+		Success:   false,
+		Error:     msg,
+		ErrorType: classifyErrorType(msg),
+	}
+	jsonBytes, _ := json.Marshal(resp)
+	return string(jsonBytes), nil
+}
+
+// classifyErrorType maps an error message to the ErrorType reported to the app
+func classifyErrorType(msg string) string {
 	errorType := "unknown"
 	lowerMsg := strings.ToLower(msg)
 
@@ -985,6 +1425,8 @@ func errorResponse(msg string) (string, error) {
 		errorType = "isp_blocked"
 	} else if strings.Contains(lowerMsg, "cancel") {
 		errorType = "cancelled"
+	} else if strings.Contains(lowerMsg, "preview") {
+		errorType = "preview"
 	} else if strings.Contains(lowerMsg, "permission") ||
 		strings.Contains(lowerMsg, "operation not permitted") ||
 		strings.Contains(lowerMsg, "access denied") ||
@@ -1008,13 +1450,7 @@ func errorResponse(msg string) (string, error) {
 		errorType = "network"
 	}
 
-	resp := DownloadResponse{
-		Success:   false,
-		Error:     msg,
-		ErrorType: errorType,
-	}
-	jsonBytes, _ := json.Marshal(resp)
-	return string(jsonBytes), nil
+	return errorType
 }
 
 // ==================== EXTENSION SYSTEM ====================
@@ -1166,6 +1602,41 @@ func GetProviderPriorityJSON() (string, error) {
 	return string(jsonBytes), nil
 }
 
+// SearchMusicBrainzByISRC looks up a track on MusicBrainz by ISRC
+func SearchMusicBrainzByISRC(isrc, trackName, artistName, albumName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	meta, err := GetMusicBrainzClient().GetTrackMetadata(ctx, isrc, trackName, artistName, albumName, "")
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// SearchMusicBrainzRelease finds the MusicBrainz entry for a track through its
+// release, by UPC or by album title and artist
+func SearchMusicBrainzRelease(upc, albumName, artistName, trackName string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	meta, err := GetMusicBrainzClient().GetTrackMetadata(ctx, "", trackName, artistName, albumName, upc)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
 // SetMetadataProviderPriorityJSON sets the metadata provider priority order from JSON array
 func SetMetadataProviderPriorityJSON(priorityJSON string) error {
 	var priority []string
@@ -1187,6 +1658,113 @@ func GetMetadataProviderPriorityJSON() (string, error) {
 	return string(jsonBytes), nil
 }
 
+// InitFallbackPolicy loads the saved built-in fallback policy from dataDir
+func InitFallbackPolicy(dataDir string) error {
+	return GetFallbackPolicyStore().SetDataDir(dataDir)
+}
+
+// SetFallbackPolicyJSON sets and saves the built-in fallback policy: service
+// order, enabled flags, quality caps and timeouts
+func SetFallbackPolicyJSON(policyJSON string) error {
+	var policy FallbackPolicy
+	if err := json.Unmarshal([]byte(policyJSON), &policy); err != nil {
+		return err
+	}
+	return GetFallbackPolicyStore().Set(policy)
+}
+
+// GetFallbackPolicyJSON returns the built-in fallback policy as JSON
+func GetFallbackPolicyJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetFallbackPolicyStore().Get())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// SetMatchThresholdsJSON sets the track matcher thresholds from JSON
+func SetMatchThresholdsJSON(configJSON string) error {
+	config := DefaultMatchConfig()
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return err
+	}
+	return SetMatchConfig(config)
+}
+
+// GetMatchThresholdsJSON returns the track matcher thresholds as JSON
+func GetMatchThresholdsJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetMatchConfig())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// ScoreTrackMatchJSON scores a candidate track against the expected one and
+// returns the score with its explanation as JSON
+func ScoreTrackMatchJSON(provider, expectedJSON, foundJSON string) (string, error) {
+	var expected, found TrackMatchInfo
+	if err := json.Unmarshal([]byte(expectedJSON), &expected); err != nil {
+		return "", fmt.Errorf("invalid expected track: %w", err)
+	}
+	if err := json.Unmarshal([]byte(foundJSON), &found); err != nil {
+		return "", fmt.Errorf("invalid found track: %w", err)
+	}
+
+	jsonBytes, err := json.Marshal(ScoreTrackMatch(provider, expected, found))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// SetMetadataMergeConfigJSON sets the per-field metadata source priority
+// from JSON, e.g. {"default": [...], "fields": {"genre": ["deezer"]}}
+func SetMetadataMergeConfigJSON(configJSON string) error {
+	var config MetadataMergeConfig
+	if err := json.Unmarshal([]byte(configJSON), &config); err != nil {
+		return err
+	}
+	return SetMetadataMergeConfig(config)
+}
+
+// GetMetadataMergeConfigJSON returns the per-field metadata source priority as JSON
+func GetMetadataMergeConfigJSON() (string, error) {
+	jsonBytes, err := json.Marshal(GetMetadataMergeConfig())
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// MergeMetadataJSON merges track metadata from several providers, given as
+// [{"source": "spotify", "track": {...}}], and returns the merged fields with
+// the source of each
+func MergeMetadataJSON(candidatesJSON string) (string, error) {
+	var inputs []struct {
+		Source string          `json:"source"`
+		Track  json.RawMessage `json:"track"`
+	}
+	if err := json.Unmarshal([]byte(candidatesJSON), &inputs); err != nil {
+		return "", err
+	}
+
+	candidates := make([]MetadataCandidate, 0, len(inputs))
+	for _, input := range inputs {
+		candidate, err := metadataCandidateFromJSON(input.Source, input.Track)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s metadata: %w", input.Source, err)
+		}
+		candidates = append(candidates, candidate)
+	}
+
+	jsonBytes, err := json.Marshal(MergeMetadata(candidates))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
 // GetExtensionSettingsJSON returns settings for an extension as JSON
 func GetExtensionSettingsJSON(extensionID string) (string, error) {
 	store := GetExtensionSettingsStore()
@@ -1656,6 +2234,28 @@ func HandleURLWithExtensionJSON(url string) (string, error) {
 	return string(jsonBytes), nil
 }
 
+// ResolveAnyURL resolves a music link from any platform (Spotify, Deezer,
+// Apple Music, YouTube Music, Tidal, Amazon, song.link, ...) through SongLink
+// and the extension URL handlers. Qobuz track links, which SongLink does not
+// cover, are matched by ISRC. The result names the source and carries that
+// source's usual payload under "data"
+func ResolveAnyURL(url string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	resolved, err := resolveAnyURL(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	jsonBytes, err := json.Marshal(resolved)
+	if err != nil {
+		return "", err
+	}
+
+	return string(jsonBytes), nil
+}
+
 // FindURLHandlerJSON finds an extension that can handle the given URL
 // Returns extension ID or empty string if none found
 func FindURLHandlerJSON(url string) string {
@@ -696,7 +696,7 @@ func GetProviderPriority() []string {
 
 	if len(providerPriority) == 0 {
 		// Default order: built-in providers first
-		return []string{"tidal", "qobuz", "amazon"}
+		return RegisteredDownloadProviderIDs()
 	}
 
 	result := make([]string, len(providerPriority))
@@ -705,7 +705,8 @@ func GetProviderPriority() []string {
 }
 
 // SetMetadataProviderPriority sets the order of metadata providers
-// providerIDs should include both built-in ("spotify", "deezer") and extension IDs
+// providerIDs should include both built-in ("spotify", "deezer") and extension IDs.
+// Including "musicbrainz" adds MusicBrainz IDs to downloaded files' tags.
 func SetMetadataProviderPriority(providerIDs []string) {
 	metadataProviderPriorityMu.Lock()
 	defer metadataProviderPriorityMu.Unlock()
@@ -730,12 +731,8 @@ func GetMetadataProviderPriority() []string {
 
 // isBuiltInProvider checks if a provider ID is a built-in provider
 func isBuiltInProvider(providerID string) bool {
-	switch providerID {
-	case "tidal", "qobuz", "amazon":
-		return true
-	default:
-		return false
-	}
+	_, ok := GetDownloadProvider(providerID)
+	return ok
 }
 
 // ==================== Download with Fallback ====================
@@ -743,12 +740,20 @@ func isBuiltInProvider(providerID string) bool {
 // DownloadWithExtensionFallback tries to download from providers in priority order
 // Includes both built-in providers and extension providers
 // If req.Source is set (extension ID), that extension is tried first
-func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, error) {
-	priority := GetProviderPriority()
+func DownloadWithExtensionFallback(req DownloadRequest) (result *DownloadResponse, err error) {
+	defer func() { recordDownloadHistoryResult(req, result, err) }()
+
+	priority := GetFallbackPolicyStore().applyToPriority(GetProviderPriority())
 	extManager := GetExtensionManager()
 
 	var lastErr error
 	var skipBuiltIn bool // If source extension has skipBuiltInFallback, don't try built-in providers
+	var attempts []DownloadAttempt
+	defer func() {
+		if result != nil {
+			result.Attempts = attempts
+		}
+	}()
 
 	// LAZY ENRICHMENT: If track came from an extension, try to enrich metadata (e.g., get real ISRC)
 	// This is done lazily at download time, not when playlist/album is loaded
@@ -773,10 +778,6 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 
 			enrichedTrack, err := provider.EnrichTrack(trackMeta)
 			if err == nil && enrichedTrack != nil {
-				if enrichedTrack.ISRC != "" && enrichedTrack.ISRC != req.ISRC {
-					GoLog("[DownloadWithExtensionFallback] ISRC enriched: %s -> %s\n", req.ISRC, enrichedTrack.ISRC)
-					req.ISRC = enrichedTrack.ISRC
-				}
 				if enrichedTrack.TidalID != "" {
 					GoLog("[DownloadWithExtensionFallback] Tidal ID from Odesli: %s\n", enrichedTrack.TidalID)
 					req.TidalID = enrichedTrack.TidalID
@@ -789,29 +790,10 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 					GoLog("[DownloadWithExtensionFallback] Deezer ID from Odesli: %s\n", enrichedTrack.DeezerID)
 					req.DeezerID = enrichedTrack.DeezerID
 				}
-				if enrichedTrack.Name != "" {
-					req.TrackName = enrichedTrack.Name
-				}
-				if enrichedTrack.Artists != "" {
-					req.ArtistName = enrichedTrack.Artists
-				}
-				// Copy extended metadata from enrichment (label, copyright, genre, release_date)
-				if enrichedTrack.Label != "" && req.Label == "" {
-					GoLog("[DownloadWithExtensionFallback] Label from enrichment: %s\n", enrichedTrack.Label)
-					req.Label = enrichedTrack.Label
-				}
-				if enrichedTrack.Copyright != "" && req.Copyright == "" {
-					GoLog("[DownloadWithExtensionFallback] Copyright from enrichment: %s\n", enrichedTrack.Copyright)
-					req.Copyright = enrichedTrack.Copyright
-				}
-				if enrichedTrack.Genre != "" && req.Genre == "" {
-					GoLog("[DownloadWithExtensionFallback] Genre from enrichment: %s\n", enrichedTrack.Genre)
-					req.Genre = enrichedTrack.Genre
-				}
-				if enrichedTrack.ReleaseDate != "" && req.ReleaseDate == "" {
-					GoLog("[DownloadWithExtensionFallback] ReleaseDate from enrichment: %s\n", enrichedTrack.ReleaseDate)
-					req.ReleaseDate = enrichedTrack.ReleaseDate
-				}
+				mergeRequestMetadata(req, MetadataCandidate{
+					Source: req.Source,
+					Values: metadataValuesFromExtTrack(enrichedTrack),
+				}).applyToRequest(&req)
 			}
 		}
 	}
@@ -835,11 +817,16 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 			outputPath := buildOutputPath(req)
 
 			// Download directly using the track ID from the extension
+			start := time.Now()
 			result, err := provider.Download(trackID, req.Quality, outputPath, func(percent int) {
 				if req.ItemID != "" {
 					SetItemProgress(req.ItemID, float64(percent), 0, 0)
 				}
 			})
+			if err == nil && result != nil && result.Success {
+				err = verifyExtensionDownload(req.Source, result, req)
+			}
+			attempts = append(attempts, newDownloadAttempt(req.Source, start, extensionDownloadError(result, err)))
 
 			if err == nil && result.Success {
 				resp := &DownloadResponse{
@@ -849,9 +836,11 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 					ActualBitDepth:   result.BitDepth,
 					ActualSampleRate: result.SampleRate,
 					Service:          req.Source,
+					TrackID:          trackID,
 					Genre:            req.Genre,
 					Label:            req.Label,
 					Copyright:        req.Copyright,
+					MetadataSources:  req.MetadataSources,
 				}
 
 				// Embed genre and label if provided (from Deezer metadata)
@@ -952,33 +941,21 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 				extMeta, err := deezerClient.GetExtendedMetadataByISRC(ctx, req.ISRC)
 				cancel()
 				if err == nil && extMeta != nil {
-					if req.Genre == "" && extMeta.Genre != "" {
-						req.Genre = extMeta.Genre
-						GoLog("[DownloadWithExtensionFallback] Genre from Deezer: %s\n", req.Genre)
-					}
-					if req.Label == "" && extMeta.Label != "" {
-						req.Label = extMeta.Label
-						GoLog("[DownloadWithExtensionFallback] Label from Deezer: %s\n", req.Label)
-					}
+					mergeRequestMetadata(req, MetadataCandidate{
+						Source: "deezer",
+						Values: MetadataValues{Genre: extMeta.Genre, Label: extMeta.Label},
+					}).applyToRequest(&req)
 				} else if err != nil {
 					GoLog("[DownloadWithExtensionFallback] Failed to get extended metadata from Deezer: %v\n", err)
 				}
 			}
 
 			// Use built-in provider
+			start := time.Now()
 			result, err := tryBuiltInProvider(providerID, req)
+			attempts = append(attempts, newDownloadAttempt(providerID, start, err))
 			if err == nil && result.Success {
 				result.Service = providerID
-				// Copy enriched metadata to response for Flutter (needed for M4A->FLAC conversion)
-				if req.Label != "" {
-					result.Label = req.Label
-				}
-				if req.Copyright != "" {
-					result.Copyright = req.Copyright
-				}
-				if req.Genre != "" {
-					result.Genre = req.Genre
-				}
 				if req.ReleaseDate != "" && result.ReleaseDate == "" {
 					result.ReleaseDate = req.ReleaseDate
 				}
@@ -1010,12 +987,16 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 
 			provider := NewExtensionProviderWrapper(ext)
 
+			start := time.Now()
 			availability, err := provider.CheckAvailability(req.ISRC, req.TrackName, req.ArtistName)
 			if err != nil || !availability.Available {
 				GoLog("[DownloadWithExtensionFallback] %s: not available\n", providerID)
 				if err != nil {
 					lastErr = err
+				} else {
+					err = fmt.Errorf("track not available on %s", providerID)
 				}
+				attempts = append(attempts, newDownloadAttempt(providerID, start, stageError(StageExtension, RejectNotAvailable, err)))
 				continue
 			}
 
@@ -1026,6 +1007,10 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 					SetItemProgress(req.ItemID, float64(percent), 0, 0)
 				}
 			})
+			if err == nil && result != nil && result.Success {
+				err = verifyExtensionDownload(providerID, result, req)
+			}
+			attempts = append(attempts, newDownloadAttempt(providerID, start, extensionDownloadError(result, err)))
 
 			if err == nil && result.Success {
 				resp := &DownloadResponse{
@@ -1035,9 +1020,11 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 					ActualBitDepth:   result.BitDepth,
 					ActualSampleRate: result.SampleRate,
 					Service:          providerID,
+					TrackID:          availability.TrackID,
 					Genre:            req.Genre,
 					Label:            req.Label,
 					Copyright:        req.Copyright,
+					MetadataSources:  req.MetadataSources,
 				}
 
 				// Embed genre and label if provided (from Deezer metadata)
@@ -1106,7 +1093,7 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 		return &DownloadResponse{
 			Success:   false,
 			Error:     fmt.Sprintf("All providers failed. Last error: %v", lastErr),
-			ErrorType: "not_found",
+			ErrorType: classifyErrorType(lastErr.Error()),
 		}, nil
 	}
 
@@ -1117,90 +1104,23 @@ func DownloadWithExtensionFallback(req DownloadRequest) (*DownloadResponse, erro
 	}, nil
 }
 
-// tryBuiltInProvider attempts download from a built-in provider
+// tryBuiltInProvider attempts download from a built-in provider,
+// using the fallback policy's limits for that service and verifying the file
 func tryBuiltInProvider(providerID string, req DownloadRequest) (*DownloadResponse, error) {
-	req.Service = providerID
-
-	var result DownloadResult
-	var err error
-
-	switch providerID {
-	case "tidal":
-		tidalResult, tidalErr := downloadFromTidal(req)
-		if tidalErr == nil {
-			result = DownloadResult{
-				FilePath:    tidalResult.FilePath,
-				BitDepth:    tidalResult.BitDepth,
-				SampleRate:  tidalResult.SampleRate,
-				Title:       tidalResult.Title,
-				Artist:      tidalResult.Artist,
-				Album:       tidalResult.Album,
-				ReleaseDate: tidalResult.ReleaseDate,
-				TrackNumber: tidalResult.TrackNumber,
-				DiscNumber:  tidalResult.DiscNumber,
-				ISRC:        tidalResult.ISRC,
-			}
-		}
-		err = tidalErr
-	case "qobuz":
-		qobuzResult, qobuzErr := downloadFromQobuz(req)
-		if qobuzErr == nil {
-			result = DownloadResult{
-				FilePath:    qobuzResult.FilePath,
-				BitDepth:    qobuzResult.BitDepth,
-				SampleRate:  qobuzResult.SampleRate,
-				Title:       qobuzResult.Title,
-				Artist:      qobuzResult.Artist,
-				Album:       qobuzResult.Album,
-				ReleaseDate: qobuzResult.ReleaseDate,
-				TrackNumber: qobuzResult.TrackNumber,
-				DiscNumber:  qobuzResult.DiscNumber,
-				ISRC:        qobuzResult.ISRC,
-			}
-		}
-		err = qobuzErr
-	case "amazon":
-		amazonResult, amazonErr := downloadFromAmazon(req)
-		if amazonErr == nil {
-			result = DownloadResult{
-				FilePath:    amazonResult.FilePath,
-				BitDepth:    amazonResult.BitDepth,
-				SampleRate:  amazonResult.SampleRate,
-				Title:       amazonResult.Title,
-				Artist:      amazonResult.Artist,
-				Album:       amazonResult.Album,
-				ReleaseDate: amazonResult.ReleaseDate,
-				TrackNumber: amazonResult.TrackNumber,
-				DiscNumber:  amazonResult.DiscNumber,
-				ISRC:        amazonResult.ISRC,
-			}
-		}
-		err = amazonErr
-	default:
-		return nil, fmt.Errorf("unknown built-in provider: %s", providerID)
-	}
-
+	result, err := downloadAndVerify(providerID, req, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	return &DownloadResponse{
-		Success:          true,
-		Message:          "Download complete",
-		FilePath:         result.FilePath,
-		ActualBitDepth:   result.BitDepth,
-		ActualSampleRate: result.SampleRate,
-		Title:            result.Title,
-		Artist:           result.Artist,
-		Album:            result.Album,
-		ReleaseDate:      result.ReleaseDate,
-		TrackNumber:      result.TrackNumber,
-		DiscNumber:       result.DiscNumber,
-		ISRC:             result.ISRC,
-		Genre:            req.Genre,
-		Label:            req.Label,
-		Copyright:        req.Copyright,
-	}, nil
+	resp := buildDownloadResponse(result, providerID, "Download complete")
+	// Existing files report no tags, so fall back to the request's
+	if resp.MetadataSources == nil {
+		resp.Genre = req.Genre
+		resp.Label = req.Label
+		resp.Copyright = req.Copyright
+		resp.MetadataSources = req.MetadataSources
+	}
+	return &resp, nil
 }
 
 // buildOutputPath builds the output file path from request
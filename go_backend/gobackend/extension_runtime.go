@@ -267,6 +267,7 @@ func (r *ExtensionRuntime) RegisterAPIs(vm *goja.Runtime) {
 	matchingObj.Set("compareStrings", r.matchingCompareStrings)
 	matchingObj.Set("compareDuration", r.matchingCompareDuration)
 	matchingObj.Set("normalizeString", r.matchingNormalizeString)
+	matchingObj.Set("scoreTrack", r.matchingScoreTrack)
 	vm.Set("matching", matchingObj)
 
 	utilsObj := vm.NewObject()
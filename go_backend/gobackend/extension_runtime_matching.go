@@ -36,8 +36,8 @@ func (r *ExtensionRuntime) matchingCompareDuration(call goja.FunctionCall) goja.
 	dur1 := int(call.Arguments[0].ToInteger())
 	dur2 := int(call.Arguments[1].ToInteger())
 
-	// Default tolerance: 3 seconds
-	tolerance := 3000 // milliseconds
+	// Default tolerance: the configured duration tolerance
+	tolerance := GetMatchConfig().Default.DurationToleranceSec * 1000 // milliseconds
 	if len(call.Arguments) > 2 && !goja.IsUndefined(call.Arguments[2]) {
 		tolerance = int(call.Arguments[2].ToInteger())
 	}
@@ -50,6 +50,60 @@ func (r *ExtensionRuntime) matchingCompareDuration(call goja.FunctionCall) goja.
 	return r.vm.ToValue(diff <= tolerance)
 }
 
+// matchingScoreTrack scores a candidate track against the expected one with the
+// shared matcher. Both arguments are objects with title, artist, album,
+// durationMs and isrc; the optional third argument picks a provider's
+// thresholds.
+func (r *ExtensionRuntime) matchingScoreTrack(call goja.FunctionCall) goja.Value {
+	if len(call.Arguments) < 2 {
+		return r.vm.ToValue(map[string]interface{}{
+			"accepted": false,
+			"score":    0.0,
+			"error":    "expected and found tracks are required",
+		})
+	}
+
+	provider := ""
+	if len(call.Arguments) > 2 && !goja.IsUndefined(call.Arguments[2]) {
+		provider = call.Arguments[2].String()
+	}
+
+	result := ScoreTrackMatch(provider, jsTrackMatchInfo(call.Arguments[0]), jsTrackMatchInfo(call.Arguments[1]))
+	components := make([]interface{}, 0, len(result.Components))
+	for _, c := range result.Components {
+		components = append(components, map[string]interface{}{
+			"name":   c.Name,
+			"score":  c.Score,
+			"weight": c.Weight,
+			"detail": c.Detail,
+		})
+	}
+	return r.vm.ToValue(map[string]interface{}{
+		"score":       result.Score,
+		"accepted":    result.Accepted,
+		"rejection":   result.Rejection,
+		"components":  components,
+		"explanation": result.Explanation,
+	})
+}
+
+// jsTrackMatchInfo reads a track object passed from JavaScript
+func jsTrackMatchInfo(arg goja.Value) TrackMatchInfo {
+	obj, _ := arg.Export().(map[string]interface{})
+	var info TrackMatchInfo
+	info.Title, _ = obj["title"].(string)
+	info.Artist, _ = obj["artist"].(string)
+	info.Album, _ = obj["album"].(string)
+	info.ISRC, _ = obj["isrc"].(string)
+	switch d := obj["durationMs"].(type) {
+	case int64:
+		info.DurationMS = int(d)
+	case float64:
+		info.DurationMS = int(d)
+	}
+	return info
+}
+
 // matchingNormalizeString normalizes a string for comparison
 func (r *ExtensionRuntime) matchingNormalizeString(call goja.FunctionCall) goja.Value {
 	if len(call.Arguments) < 1 {
@@ -61,62 +115,6 @@ func (r *ExtensionRuntime) matchingNormalizeString(call goja.FunctionCall) goja.
 	return r.vm.ToValue(normalized)
 }
 
-// calculateStringSimilarity calculates similarity between two strings (0-1)
-func calculateStringSimilarity(s1, s2 string) float64 {
-	if len(s1) == 0 && len(s2) == 0 {
-		return 1.0
-	}
-	if len(s1) == 0 || len(s2) == 0 {
-		return 0.0
-	}
-
-	// Use Levenshtein distance
-	distance := levenshteinDistance(s1, s2)
-	maxLen := len(s1)
-	if len(s2) > maxLen {
-		maxLen = len(s2)
-	}
-
-	return 1.0 - float64(distance)/float64(maxLen)
-}
-
-// levenshteinDistance calculates the Levenshtein distance between two strings
-func levenshteinDistance(s1, s2 string) int {
-	if len(s1) == 0 {
-		return len(s2)
-	}
-	if len(s2) == 0 {
-		return len(s1)
-	}
-
-	// Create matrix
-	matrix := make([][]int, len(s1)+1)
-	for i := range matrix {
-		matrix[i] = make([]int, len(s2)+1)
-		matrix[i][0] = i
-	}
-	for j := range matrix[0] {
-		matrix[0][j] = j
-	}
-
-	// Fill matrix
-	for i := 1; i <= len(s1); i++ {
-		for j := 1; j <= len(s2); j++ {
-			cost := 1
-			if s1[i-1] == s2[j-1] {
-				cost = 0
-			}
-			matrix[i][j] = min(
-				matrix[i-1][j]+1,      // deletion
-				matrix[i][j-1]+1,      // insertion
-				matrix[i-1][j-1]+cost, // substitution
-			)
-		}
-	}
-
-	return matrix[len(s1)][len(s2)]
-}
-
 // normalizeStringForMatching normalizes a string for comparison
 func normalizeStringForMatching(s string) string {
 	// Convert to lowercase
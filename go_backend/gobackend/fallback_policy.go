@@ -0,0 +1,335 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+const fallbackPolicyFileName = "fallback_policy.json"
+
+// Quality tiers in ascending order, as accepted in DownloadRequest.Quality
+var qualityTiers = []string{"LOSSLESS", "HI_RES", "HI_RES_LOSSLESS"}
+
+// ServicePolicy configures one built-in service in the fallback order
+type ServicePolicy struct {
+	Service        string `json:"service"`
+	Enabled        bool   `json:"enabled"`
+	MaxQuality     string `json:"max_quality,omitempty"`     // LOSSLESS, HI_RES or HI_RES_LOSSLESS; empty means no cap
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"` // Per-attempt limit; 0 means no limit
+}
+
+// UnmarshalJSON treats a missing "enabled" as true, so a policy that only
+// reorders services or sets limits does not disable them
+func (s *ServicePolicy) UnmarshalJSON(data []byte) error {
+	type plain ServicePolicy
+	p := plain{Enabled: true}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*s = ServicePolicy(p)
+	return nil
+}
+
+// FallbackPolicy is the ordered list of built-in services tried by the
+// fallback download entry points
+type FallbackPolicy struct {
+	Services []ServicePolicy `json:"services"`
+}
+
+func qualityTierRank(quality string) int {
+	for i, tier := range qualityTiers {
+		if tier == quality {
+			return i
+		}
+	}
+	return -1
+}
+
+// validate rejects unknown services, duplicates and malformed limits
+func (p FallbackPolicy) validate() error {
+	seen := make(map[string]bool)
+	for _, s := range p.Services {
+		if _, ok := GetDownloadProvider(s.Service); !ok {
+			return fmt.Errorf("unknown built-in service: %s", s.Service)
+		}
+		if seen[s.Service] {
+			return fmt.Errorf("service listed twice: %s", s.Service)
+		}
+		seen[s.Service] = true
+		if s.MaxQuality != "" && qualityTierRank(s.MaxQuality) < 0 {
+			return fmt.Errorf("invalid max quality for %s: %s", s.Service, s.MaxQuality)
+		}
+		if s.TimeoutSeconds < 0 {
+			return fmt.Errorf("invalid timeout for %s: %d", s.Service, s.TimeoutSeconds)
+		}
+	}
+	return nil
+}
+
+// normalized drops services that are no longer registered and appends newly
+// registered ones, enabled, at the end
+func (p FallbackPolicy) normalized() FallbackPolicy {
+	var result FallbackPolicy
+	seen := make(map[string]bool)
+	for _, s := range p.Services {
+		if _, ok := GetDownloadProvider(s.Service); ok && !seen[s.Service] {
+			result.Services = append(result.Services, s)
+			seen[s.Service] = true
+		}
+	}
+	for _, id := range RegisteredDownloadProviderIDs() {
+		if !seen[id] {
+			result.Services = append(result.Services, ServicePolicy{Service: id, Enabled: true})
+		}
+	}
+	return result
+}
+
+// lookup returns the policy entry for a service
+func (p FallbackPolicy) lookup(service string) ServicePolicy {
+	for _, s := range p.Services {
+		if s.Service == service {
+			return s
+		}
+	}
+	return ServicePolicy{Service: service, Enabled: true}
+}
+
+// serviceOrder returns the enabled services in policy order, with preferred
+// moved to the front if it is enabled
+func (p FallbackPolicy) serviceOrder(preferred string) []string {
+	services := []string{}
+	if s := p.lookup(preferred); s.Enabled {
+		if _, ok := GetDownloadProvider(preferred); ok {
+			services = append(services, preferred)
+		}
+	}
+	for _, s := range p.Services {
+		if s.Enabled && s.Service != preferred {
+			services = append(services, s.Service)
+		}
+	}
+	return services
+}
+
+// capQuality lowers the requested quality to maxQuality. An empty request
+// means the service's highest tier, so it becomes the cap itself.
+func capQuality(requested, maxQuality string) string {
+	capRank := qualityTierRank(maxQuality)
+	if capRank < 0 {
+		return requested
+	}
+	if requested == "" || qualityTierRank(requested) > capRank {
+		return maxQuality
+	}
+	return requested
+}
+
+// FallbackPolicyStore holds the fallback policy and persists it as JSON
+type FallbackPolicyStore struct {
+	mu       sync.RWMutex
+	filePath string
+	policy   FallbackPolicy
+}
+
+var (
+	globalFallbackPolicyStore     *FallbackPolicyStore
+	globalFallbackPolicyStoreOnce sync.Once
+)
+
+// GetFallbackPolicyStore returns the global fallback policy store. Until a
+// policy is set or loaded, every built-in service is enabled without limits.
+func GetFallbackPolicyStore() *FallbackPolicyStore {
+	globalFallbackPolicyStoreOnce.Do(func() {
+		globalFallbackPolicyStore = &FallbackPolicyStore{}
+	})
+	return globalFallbackPolicyStore
+}
+
+// SetDataDir sets the directory of the policy file and loads the saved policy
+func (s *FallbackPolicyStore) SetDataDir(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create policy directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filePath = filepath.Join(dataDir, fallbackPolicyFileName)
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var policy FallbackPolicy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		GoLog("[FallbackPolicy] Ignoring unreadable policy file: %v\n", err)
+		return nil
+	}
+	s.policy = policy
+	GoLog("[FallbackPolicy] Loaded policy for %d services\n", len(policy.Services))
+	return nil
+}
+
+// Get returns the current policy, covering every registered service
+func (s *FallbackPolicyStore) Get() FallbackPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.policy.normalized()
+}
+
+// Set validates, stores and persists a policy
+func (s *FallbackPolicyStore) Set(policy FallbackPolicy) error {
+	if err := policy.validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.policy = policy
+	if s.filePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(policy, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.filePath, data)
+}
+
+// applyToPriority adapts a mixed built-in/extension priority list to the
+// policy: disabled built-in services are removed and, once a policy has been
+// set, the remaining built-in services take the policy order within the
+// slots they already occupy
+func (s *FallbackPolicyStore) applyToPriority(priority []string) []string {
+	s.mu.RLock()
+	configured := len(s.policy.Services) > 0
+	s.mu.RUnlock()
+	policy := s.Get()
+
+	var builtIns []string
+	if configured {
+		builtIns = policy.serviceOrder("")
+	}
+
+	result := make([]string, 0, len(priority))
+	next := 0
+	for _, id := range priority {
+		if !isBuiltInProvider(id) {
+			result = append(result, id)
+			continue
+		}
+		if !policy.lookup(id).Enabled {
+			GoLog("[FallbackPolicy] Skipping %s (disabled)\n", id)
+			continue
+		}
+		if !configured {
+			result = append(result, id)
+			continue
+		}
+		// Fill this built-in slot with the next service in policy order
+		// that the priority list includes
+		for next < len(builtIns) && !slices.Contains(priority, builtIns[next]) {
+			next++
+		}
+		if next < len(builtIns) {
+			result = append(result, builtIns[next])
+			next++
+		}
+	}
+	return result
+}
+
+// downloadWithServicePolicy runs a built-in provider with the policy's
+// quality cap and timeout for that service applied
+func downloadWithServicePolicy(service string, req DownloadRequest, probe *QualityProbe) (DownloadResult, error) {
+	policy := GetFallbackPolicyStore().Get().lookup(service)
+	if !policy.Enabled {
+		return DownloadResult{}, fmt.Errorf("%s is disabled by the fallback policy", service)
+	}
+
+	if policy.MaxQuality != "" {
+		if capped := capQuality(req.Quality, policy.MaxQuality); capped != req.Quality {
+			GoLog("[FallbackPolicy] Capping %s quality at %s\n", service, capped)
+			req.Quality = capped
+		}
+	}
+
+	if policy.TimeoutSeconds <= 0 {
+		return downloadWithProvider(service, req, probe)
+	}
+	return downloadWithTimeout(service, req, probe, time.Duration(policy.TimeoutSeconds)*time.Second)
+}
+
+// downloadWithTimeout aborts a provider that runs longer than timeout. It
+// waits for the provider to stop so a later provider never writes the same
+// output file concurrently.
+func downloadWithTimeout(service string, req DownloadRequest, probe *QualityProbe, timeout time.Duration) (DownloadResult, error) {
+	if req.ItemID == "" {
+		// Cancellation is keyed by item ID
+		req.ItemID = fmt.Sprintf("policy-timeout:%s:%d", service, time.Now().UnixNano())
+		defer clearDownloadCancel(req.ItemID)
+	}
+
+	type outcome struct {
+		result DownloadResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := downloadWithProvider(service, req, probe)
+		done <- outcome{result, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-timer.C:
+	}
+
+	if isDownloadCancelled(req.ItemID) {
+		// Cancelled by the user; let the provider report it
+		o := <-done
+		return o.result, o.err
+	}
+
+	GoLog("[FallbackPolicy] %s exceeded %s, aborting\n", service, timeout)
+	// Providers reset the cancel flag when their download starts, so keep
+	// aborting until the provider returns
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	beginDownloadAbort(req.ItemID)
+	abortDownloadAttempt(req.ItemID)
+	var o outcome
+wait:
+	for {
+		select {
+		case o = <-done:
+			break wait
+		case <-ticker.C:
+			abortDownloadAttempt(req.ItemID)
+		}
+	}
+	if endDownloadAbort(req.ItemID) {
+		// The user cancelled while the attempt was being aborted; stop the
+		// fallback rather than starting the next provider
+		return DownloadResult{}, ErrDownloadCancelled
+	}
+
+	if o.err == nil {
+		// Finished before noticing the abort; keep the file
+		return o.result, nil
+	}
+	return DownloadResult{}, fmt.Errorf("%s download timeout after %s", service, timeout)
+}
@@ -0,0 +1,120 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// setTestFallbackPolicy replaces the global fallback policy for one test
+func setTestFallbackPolicy(t *testing.T, policy FallbackPolicy) {
+	t.Helper()
+	store := GetFallbackPolicyStore()
+	store.mu.Lock()
+	saved := store.policy
+	store.policy = policy
+	store.mu.Unlock()
+	t.Cleanup(func() {
+		store.mu.Lock()
+		store.policy = saved
+		store.mu.Unlock()
+	})
+}
+
+func TestCapQuality(t *testing.T) {
+	tests := []struct {
+		requested, cap, want string
+	}{
+		{"HI_RES_LOSSLESS", "LOSSLESS", "LOSSLESS"},
+		{"LOSSLESS", "HI_RES", "LOSSLESS"},
+		{"", "HI_RES", "HI_RES"},
+		{"HI_RES", "", "HI_RES"},
+	}
+	for _, tt := range tests {
+		if got := capQuality(tt.requested, tt.cap); got != tt.want {
+			t.Errorf("capQuality(%q, %q) = %q, want %q", tt.requested, tt.cap, got, tt.want)
+		}
+	}
+}
+
+func TestFallbackPolicyStore_OrderAndPersistence(t *testing.T) {
+	dir := t.TempDir()
+	store := &FallbackPolicyStore{}
+	if err := store.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.applyToPriority([]string{"tidal", "ext", "qobuz", "amazon"}); !reflect.DeepEqual(got, []string{"tidal", "ext", "qobuz", "amazon"}) {
+		t.Errorf("unset policy should keep priority order, got %v", got)
+	}
+
+	policy := FallbackPolicy{Services: []ServicePolicy{
+		{Service: "qobuz", Enabled: true, MaxQuality: "HI_RES"},
+		{Service: "tidal", Enabled: true, TimeoutSeconds: 60},
+		{Service: "amazon", Enabled: false},
+	}}
+	if err := store.Set(policy); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded := &FallbackPolicyStore{}
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	loaded := reloaded.Get()
+
+	if got := loaded.serviceOrder(""); !reflect.DeepEqual(got, []string{"qobuz", "tidal"}) {
+		t.Errorf("serviceOrder(\"\") = %v", got)
+	}
+	if got := loaded.serviceOrder("tidal"); !reflect.DeepEqual(got, []string{"tidal", "qobuz"}) {
+		t.Errorf("serviceOrder(\"tidal\") = %v", got)
+	}
+	if got := loaded.serviceOrder("amazon"); !reflect.DeepEqual(got, []string{"qobuz", "tidal"}) {
+		t.Errorf("disabled preferred service should be dropped, got %v", got)
+	}
+	if got := reloaded.applyToPriority([]string{"tidal", "ext", "qobuz", "amazon"}); !reflect.DeepEqual(got, []string{"qobuz", "ext", "tidal"}) {
+		t.Errorf("applyToPriority = %v", got)
+	}
+	if loaded.lookup("qobuz").MaxQuality != "HI_RES" || loaded.lookup("tidal").TimeoutSeconds != 60 {
+		t.Errorf("limits not persisted: %+v", loaded)
+	}
+
+	if err := store.Set(FallbackPolicy{Services: []ServicePolicy{{Service: "napster", Enabled: true}}}); err == nil {
+		t.Error("expected error for unknown service")
+	}
+	if err := store.Set(FallbackPolicy{Services: []ServicePolicy{{Service: "tidal", MaxQuality: "MP3"}}}); err == nil {
+		t.Error("expected error for invalid quality cap")
+	}
+}
+
+func TestServicePolicyEnabledByDefault(t *testing.T) {
+	var policy FallbackPolicy
+	data := `{"services": [{"service": "qobuz", "max_quality": "LOSSLESS"}, {"service": "tidal", "enabled": false}]}`
+	if err := json.Unmarshal([]byte(data), &policy); err != nil {
+		t.Fatal(err)
+	}
+	if !policy.Services[0].Enabled || policy.Services[1].Enabled {
+		t.Errorf("services = %+v", policy.Services)
+	}
+}
+
+func TestDownloadAbortKeepsUserCancel(t *testing.T) {
+	const itemID = "abort-test"
+	defer clearDownloadCancel(itemID)
+
+	// A timeout abort alone clears the flag for the next provider
+	beginDownloadAbort(itemID)
+	abortDownloadAttempt(itemID)
+	if endDownloadAbort(itemID) || isDownloadCancelled(itemID) {
+		t.Fatal("timeout abort left the item cancelled")
+	}
+
+	// A user cancel during the abort survives the provider resetting its entry
+	beginDownloadAbort(itemID)
+	abortDownloadAttempt(itemID)
+	cancelDownload(itemID)
+	initDownloadCancel(itemID)
+	if !endDownloadAbort(itemID) || !isDownloadCancelled(itemID) {
+		t.Error("user cancel lost during abort")
+	}
+}
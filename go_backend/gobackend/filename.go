@@ -2,6 +2,7 @@ package gobackend
 
 import (
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
@@ -94,3 +95,16 @@ func extractYear(date string) string {
 	}
 	return date
 }
+
+// buildTrackOutputPath returns the .flac path the built-in services write a request to
+func buildTrackOutputPath(req DownloadRequest) string {
+	filename := buildFilenameFromTemplate(req.FilenameFormat, map[string]interface{}{
+		"title":  req.TrackName,
+		"artist": req.ArtistName,
+		"album":  req.AlbumName,
+		"track":  req.TrackNumber,
+		"year":   extractYear(req.ReleaseDate),
+		"disc":   req.DiscNumber,
+	})
+	return filepath.Join(req.OutputDir, sanitizeFilename(filename)+".flac")
+}
@@ -0,0 +1,464 @@
+package gobackend
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// errNotFLACInMP4 is returned when a fragmented MP4 carries a codec other
+// than FLAC, e.g. AAC, and must be converted by FFmpeg instead
+var errNotFLACInMP4 = errors.New("MP4 stream is not FLAC")
+
+const (
+	flacBlockTypeStreamInfo = 0
+	flacStreamInfoSize      = 34
+)
+
+// fmp4Box is a box parsed from an in-memory buffer
+type fmp4Box struct {
+	typ     string
+	payload []byte
+}
+
+// parseFMP4Boxes splits data into consecutive boxes
+func parseFMP4Boxes(data []byte) ([]fmp4Box, error) {
+	var boxes []fmp4Box
+	pos := 0
+	for pos+8 <= len(data) {
+		size := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		typ := string(data[pos+4 : pos+8])
+		headerSize := 8
+		switch size {
+		case 0:
+			size = len(data) - pos
+		case 1:
+			if pos+16 > len(data) {
+				return nil, fmt.Errorf("truncated %s box", typ)
+			}
+			size = int(binary.BigEndian.Uint64(data[pos+8 : pos+16]))
+			headerSize = 16
+		}
+		if size < headerSize || pos+size > len(data) {
+			return nil, fmt.Errorf("invalid %s box size", typ)
+		}
+		boxes = append(boxes, fmp4Box{typ: typ, payload: data[pos+headerSize : pos+size]})
+		pos += size
+	}
+	return boxes, nil
+}
+
+// findFMP4Box follows a path of box types, e.g. "trak", "mdia", "mdhd"
+func findFMP4Box(data []byte, path ...string) ([]byte, bool) {
+	for _, typ := range path {
+		boxes, err := parseFMP4Boxes(data)
+		if err != nil {
+			return nil, false
+		}
+		found := false
+		for _, b := range boxes {
+			if b.typ == typ {
+				data = b.payload
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return data, true
+}
+
+// fmp4FLACTrack is what the remuxer needs from moov
+type fmp4FLACTrack struct {
+	trackID         uint32
+	timescale       uint32
+	metadataBlocks  []byte // FLAC metadata blocks from dfLa, STREAMINFO first
+	defaultDuration uint32 // From trex
+	defaultSize     uint32 // From trex
+}
+
+// parseFMP4Moov finds the FLAC track in moov and its dfLa metadata
+func parseFMP4Moov(moov []byte) (fmp4FLACTrack, error) {
+	var track fmp4FLACTrack
+
+	boxes, err := parseFMP4Boxes(moov)
+	if err != nil {
+		return track, err
+	}
+
+	found := false
+	for _, b := range boxes {
+		if b.typ != "trak" {
+			continue
+		}
+		stsd, ok := findFMP4Box(b.payload, "mdia", "minf", "stbl", "stsd")
+		if !ok || len(stsd) < 8 {
+			continue
+		}
+		// stsd: version/flags, entry count, then sample entries
+		entries, err := parseFMP4Boxes(stsd[8:])
+		if err != nil || len(entries) == 0 {
+			continue
+		}
+		if entries[0].typ != "fLaC" {
+			return track, fmt.Errorf("%w (sample entry %q)", errNotFLACInMP4, entries[0].typ)
+		}
+
+		// AudioSampleEntry fields take 28 bytes before the child boxes
+		if len(entries[0].payload) < 28 {
+			return track, fmt.Errorf("truncated fLaC sample entry")
+		}
+		dfLa, ok := findFMP4Box(entries[0].payload[28:], "dfLa")
+		if !ok || len(dfLa) < 4+4+flacStreamInfoSize {
+			return track, fmt.Errorf("dfLa box missing or truncated")
+		}
+		track.metadataBlocks = dfLa[4:] // Skip version/flags
+		if track.metadataBlocks[0]&0x7F != flacBlockTypeStreamInfo {
+			return track, fmt.Errorf("dfLa does not start with STREAMINFO")
+		}
+
+		// tkhd and mdhd share the layout up to the field after the timestamps:
+		// track ID in tkhd, timescale in mdhd
+		if tkhd, ok := findFMP4Box(b.payload, "tkhd"); ok {
+			track.trackID = fmp4FieldAfterTimestamps(tkhd)
+		}
+		if mdhd, ok := findFMP4Box(b.payload, "mdia", "mdhd"); ok {
+			track.timescale = fmp4FieldAfterTimestamps(mdhd)
+		}
+		found = true
+		break
+	}
+	if !found {
+		return track, fmt.Errorf("no audio track found in moov")
+	}
+
+	readFMP4TrackDefaults(moov, &track)
+	return track, nil
+}
+
+// readFMP4TrackDefaults fills in the trex sample defaults of track
+func readFMP4TrackDefaults(moov []byte, track *fmp4FLACTrack) {
+	mvex, ok := findFMP4Box(moov, "mvex")
+	if !ok {
+		return
+	}
+	exBoxes, _ := parseFMP4Boxes(mvex)
+	for _, b := range exBoxes {
+		// trex: version/flags, track ID, description index, duration, size, flags
+		if b.typ != "trex" || len(b.payload) < 24 {
+			continue
+		}
+		if binary.BigEndian.Uint32(b.payload[4:8]) == track.trackID {
+			track.defaultDuration = binary.BigEndian.Uint32(b.payload[12:16])
+			track.defaultSize = binary.BigEndian.Uint32(b.payload[16:20])
+		}
+	}
+}
+
+// fmp4FieldAfterTimestamps reads the 32-bit field following the creation and
+// modification times of a versioned header box (32-bit in v0, 64-bit in v1)
+func fmp4FieldAfterTimestamps(payload []byte) uint32 {
+	pos := 12
+	if len(payload) > 0 && payload[0] == 1 {
+		pos = 20
+	}
+	if len(payload) < pos+4 {
+		return 0
+	}
+	return binary.BigEndian.Uint32(payload[pos : pos+4])
+}
+
+// fmp4Sample is one FLAC frame located in the file
+type fmp4Sample struct {
+	offset   int64
+	size     uint32
+	duration uint32
+}
+
+// byteReader reads big-endian fields and records running past the end
+type byteReader struct {
+	data []byte
+	pos  int
+	err  bool
+}
+
+func (r *byteReader) u32() uint32 {
+	if r.pos+4 > len(r.data) {
+		r.err = true
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.data[r.pos:])
+	r.pos += 4
+	return v
+}
+
+func (r *byteReader) u64() uint64 {
+	if r.pos+8 > len(r.data) {
+		r.err = true
+		return 0
+	}
+	v := binary.BigEndian.Uint64(r.data[r.pos:])
+	r.pos += 8
+	return v
+}
+
+// parseFMP4Moof lists the samples of the FLAC track in one fragment.
+// moofOffset is the absolute file offset of the moof box.
+func parseFMP4Moof(moof []byte, moofOffset int64, track fmp4FLACTrack) ([]fmp4Sample, error) {
+	boxes, err := parseFMP4Boxes(moof)
+	if err != nil {
+		return nil, err
+	}
+
+	var samples []fmp4Sample
+	for _, traf := range boxes {
+		if traf.typ != "traf" {
+			continue
+		}
+		children, err := parseFMP4Boxes(traf.payload)
+		if err != nil {
+			return nil, err
+		}
+
+		var trackID uint32
+		baseOffset := moofOffset
+		defaultDuration := track.defaultDuration
+		defaultSize := track.defaultSize
+		for _, c := range children {
+			if c.typ != "tfhd" {
+				continue
+			}
+			r := &byteReader{data: c.payload}
+			flags := r.u32() & 0xFFFFFF
+			trackID = r.u32()
+			if flags&0x01 != 0 {
+				baseOffset = int64(r.u64())
+			}
+			if flags&0x02 != 0 {
+				r.u32() // sample description index
+			}
+			if flags&0x08 != 0 {
+				defaultDuration = r.u32()
+			}
+			if flags&0x10 != 0 {
+				defaultSize = r.u32()
+			}
+			if r.err {
+				return nil, fmt.Errorf("truncated tfhd box")
+			}
+		}
+		if track.trackID != 0 && trackID != track.trackID {
+			continue
+		}
+
+		dataOffset := baseOffset
+		for _, c := range children {
+			if c.typ != "trun" {
+				continue
+			}
+			r := &byteReader{data: c.payload}
+			flags := r.u32() & 0xFFFFFF
+			count := r.u32()
+			if flags&0x01 != 0 {
+				dataOffset = baseOffset + int64(int32(r.u32()))
+			}
+			if flags&0x04 != 0 {
+				r.u32() // first sample flags
+			}
+			for range count {
+				sample := fmp4Sample{offset: dataOffset, size: defaultSize, duration: defaultDuration}
+				if flags&0x100 != 0 {
+					sample.duration = r.u32()
+				}
+				if flags&0x200 != 0 {
+					sample.size = r.u32()
+				}
+				if flags&0x400 != 0 {
+					r.u32() // sample flags
+				}
+				if flags&0x800 != 0 {
+					r.u32() // composition time offset
+				}
+				if r.err {
+					return nil, fmt.Errorf("truncated trun box")
+				}
+				samples = append(samples, sample)
+				dataOffset += int64(sample.size)
+			}
+		}
+	}
+	return samples, nil
+}
+
+// buildFLACHeader returns "fLaC" followed by the dfLa metadata blocks, with
+// STREAMINFO's total samples and frame sizes filled in from the fragments.
+// The MD5 is left as-is; zero means unknown and is valid.
+func buildFLACHeader(track fmp4FLACTrack, samples []fmp4Sample) ([]byte, error) {
+	blocks := append([]byte(nil), track.metadataBlocks...)
+
+	// Walk the blocks to validate lengths and set the last-block flag on the final one
+	pos := 0
+	lastHeader := -1
+	for pos+4 <= len(blocks) {
+		length := int(blocks[pos+1])<<16 | int(blocks[pos+2])<<8 | int(blocks[pos+3])
+		if pos+4+length > len(blocks) {
+			break
+		}
+		blocks[pos] &^= 0x80
+		lastHeader = pos
+		pos += 4 + length
+	}
+	if lastHeader < 0 {
+		return nil, fmt.Errorf("no FLAC metadata blocks in dfLa")
+	}
+	blocks = blocks[:pos]
+	blocks[lastHeader] |= 0x80
+
+	streamInfo := blocks[4 : 4+flacStreamInfoSize]
+	sampleRate := uint32(streamInfo[10])<<12 | uint32(streamInfo[11])<<4 | uint32(streamInfo[12])>>4
+
+	var minFrame, maxFrame uint32
+	var totalDuration uint64
+	for i, s := range samples {
+		if i == 0 || s.size < minFrame {
+			minFrame = s.size
+		}
+		maxFrame = max(maxFrame, s.size)
+		totalDuration += uint64(s.duration)
+	}
+
+	putUint24(streamInfo[4:7], minFrame)
+	putUint24(streamInfo[7:10], maxFrame)
+
+	totalSamples := totalDuration
+	if track.timescale != 0 && sampleRate != 0 && track.timescale != sampleRate {
+		totalSamples = totalDuration * uint64(sampleRate) / uint64(track.timescale)
+	}
+	if totalSamples >= 1<<36 {
+		totalSamples = 0 // Unknown
+	}
+	streamInfo[13] = streamInfo[13]&0xF0 | byte(totalSamples>>32)&0x0F
+	binary.BigEndian.PutUint32(streamInfo[14:18], uint32(totalSamples))
+
+	return append([]byte("fLaC"), blocks...), nil
+}
+
+func putUint24(b []byte, v uint32) {
+	if v >= 1<<24 {
+		v = 0 // Unknown
+	}
+	b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+}
+
+// remuxFMP4ToFLAC rewrites a fragmented MP4 with a FLAC track (Tidal's
+// lossless DASH stream) as a native FLAC file, without re-encoding.
+// Returns errNotFLACInMP4 for other codecs.
+func remuxFMP4ToFLAC(inputPath, outputPath string) error {
+	in, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+
+	// First pass: parse moov and every moof, skipping mdat payloads
+	var track fmp4FLACTrack
+	haveMoov := false
+	var samples []fmp4Sample
+	for pos := int64(0); pos+8 <= fileSize; {
+		header, err := readAtomHeaderAt(in, pos, fileSize)
+		if err != nil {
+			return fmt.Errorf("failed to read box at %d: %w", pos, err)
+		}
+		if header.size == 0 {
+			header.size = fileSize - pos
+		}
+		if header.size < header.headerSize || pos+header.size > fileSize {
+			return fmt.Errorf("invalid %s box size", header.typ)
+		}
+
+		switch header.typ {
+		case "moov", "moof":
+			buf := make([]byte, header.size-header.headerSize)
+			if _, err := in.ReadAt(buf, pos+header.headerSize); err != nil {
+				return fmt.Errorf("failed to read %s: %w", header.typ, err)
+			}
+			if header.typ == "moov" {
+				if track, err = parseFMP4Moov(buf); err != nil {
+					return err
+				}
+				haveMoov = true
+				break
+			}
+			if !haveMoov {
+				return fmt.Errorf("moof before moov")
+			}
+			fragment, err := parseFMP4Moof(buf, pos, track)
+			if err != nil {
+				return err
+			}
+			samples = append(samples, fragment...)
+		}
+		pos += header.size
+	}
+	if !haveMoov {
+		return fmt.Errorf("moov box not found")
+	}
+	if len(samples) == 0 {
+		return fmt.Errorf("no audio frames found")
+	}
+	for _, s := range samples {
+		if s.offset < 0 || s.offset+int64(s.size) > fileSize {
+			return fmt.Errorf("audio frame outside file bounds")
+		}
+	}
+
+	flacHeader, err := buildFLACHeader(track, samples)
+	if err != nil {
+		return err
+	}
+
+	// Second pass: write the header and copy frames in order
+	tmpPath := outputPath + ".remux.tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to create FLAC file: %w", err)
+	}
+	w := bufio.NewWriterSize(out, 256*1024)
+	writeErr := func() error {
+		if _, err := w.Write(flacHeader); err != nil {
+			return err
+		}
+		for _, s := range samples {
+			if _, err := io.Copy(w, io.NewSectionReader(in, s.offset, int64(s.size))); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	}()
+	closeErr := out.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write FLAC file: %w", writeErr)
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	GoLog("[FLAC] Remuxed %d frames from %s\n", len(samples), inputPath)
+	return nil
+}
@@ -0,0 +1,132 @@
+package gobackend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-flac/go-flac"
+)
+
+func testBox(typ string, parts ...[]byte) []byte {
+	payload := bytes.Join(parts, nil)
+	b := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(b, uint32(8+len(payload)))
+	copy(b[4:], typ)
+	return append(b, payload...)
+}
+
+func testU32(values ...uint32) []byte {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.BigEndian.PutUint32(b[4*i:], v)
+	}
+	return b
+}
+
+// testStreamInfo builds a STREAMINFO block for 44.1kHz stereo 16-bit with
+// unknown totals
+func testStreamInfo() []byte {
+	si := make([]byte, flacStreamInfoSize)
+	binary.BigEndian.PutUint16(si[0:], 4096)
+	binary.BigEndian.PutUint16(si[2:], 4096)
+	sampleRate := uint32(44100)
+	si[10] = byte(sampleRate >> 12)
+	si[11] = byte(sampleRate >> 4)
+	si[12] = byte(sampleRate<<4) | 1<<1 // 2 channels
+	si[13] = 15 << 4                    // 16 bits per sample
+	return append([]byte{0x80 | flacBlockTypeStreamInfo, 0, 0, flacStreamInfoSize}, si...)
+}
+
+func testFLACFMP4(sampleEntry string, frames [][]byte) []byte {
+	audioEntry := make([]byte, 28)
+	entry := testBox(sampleEntry, audioEntry, testBox("dfLa", testU32(0), testStreamInfo()))
+	stsd := testBox("stsd", testU32(0, 1), entry)
+	moov := testBox("moov",
+		testBox("trak",
+			testBox("tkhd", testU32(0, 0, 0, 1, 0)),
+			testBox("mdia",
+				testBox("mdhd", testU32(0, 0, 0, 44100, 0)),
+				testBox("minf", testBox("stbl", stsd)))),
+		testBox("mvex", testBox("trex", testU32(0, 1, 1, 4096, 0, 0))))
+
+	var fragments []byte
+	for i := 0; i < len(frames); i += 2 {
+		group := frames[i:min(i+2, len(frames))]
+		// trun with data offset and per-sample sizes; duration comes from trex
+		trunFields := []uint32{0x000201, uint32(len(group)), 0}
+		for _, f := range group {
+			trunFields = append(trunFields, uint32(len(f)))
+		}
+		build := func(dataOffset uint32) []byte {
+			trunFields[2] = dataOffset
+			return testBox("moof",
+				testBox("mfhd", testU32(0, uint32(i/2+1))),
+				testBox("traf",
+					testBox("tfhd", testU32(0x020000, 1)),
+					testBox("trun", testU32(trunFields...))))
+		}
+		moof := build(0)
+		moof = build(uint32(len(moof) + 8))
+		fragments = append(fragments, moof...)
+		fragments = append(fragments, testBox("mdat", group...)...)
+	}
+
+	return append(append(testBox("ftyp", []byte("iso6"), testU32(0)), moov...), fragments...)
+}
+
+func TestRemuxFMP4ToFLAC(t *testing.T) {
+	dir := t.TempDir()
+	frames := [][]byte{
+		bytes.Repeat([]byte{0xFF, 0xF8, 1}, 100),
+		bytes.Repeat([]byte{0xFF, 0xF8, 2}, 80),
+		bytes.Repeat([]byte{0xFF, 0xF8, 3}, 120),
+	}
+	input := filepath.Join(dir, "track.m4a")
+	if err := os.WriteFile(input, testFLACFMP4("fLaC", frames), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := filepath.Join(dir, "track.flac")
+	if err := remuxFMP4ToFLAC(input, output); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := append([]byte("fLaC"), testStreamInfo()...)
+	if !bytes.HasPrefix(data, []byte("fLaC")) || len(data) != len(header)+300+240+360 {
+		t.Fatalf("unexpected output size %d", len(data))
+	}
+	if !bytes.Equal(data[len(header):], bytes.Join(frames, nil)) {
+		t.Error("audio frames not copied in order")
+	}
+
+	si := data[8 : 8+flacStreamInfoSize]
+	minFrame := uint32(si[4])<<16 | uint32(si[5])<<8 | uint32(si[6])
+	maxFrame := uint32(si[7])<<16 | uint32(si[8])<<8 | uint32(si[9])
+	totalSamples := uint64(si[13]&0x0F)<<32 | uint64(binary.BigEndian.Uint32(si[14:18]))
+	if minFrame != 240 || maxFrame != 360 || totalSamples != 3*4096 {
+		t.Errorf("STREAMINFO min=%d max=%d total=%d", minFrame, maxFrame, totalSamples)
+	}
+
+	if _, err := flac.ParseFile(output); err != nil {
+		t.Errorf("remuxed file is not parseable FLAC: %v", err)
+	}
+}
+
+func TestRemuxFMP4ToFLAC_RejectsOtherCodecs(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "track.m4a")
+	os.WriteFile(input, testFLACFMP4("mp4a", [][]byte{{1, 2, 3}}), 0644)
+
+	err := remuxFMP4ToFLAC(input, filepath.Join(dir, "track.flac"))
+	if !errors.Is(err, errNotFLACInMP4) {
+		t.Fatalf("expected errNotFLACInMP4, got %v", err)
+	}
+}
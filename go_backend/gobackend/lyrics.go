@@ -119,16 +119,18 @@ type LyricsResponse struct {
 
 type LyricsClient struct {
 	httpClient *http.Client
+	baseURL    string
 }
 
 func NewLyricsClient() *LyricsClient {
 	return &LyricsClient{
 		httpClient: NewHTTPClientWithTimeout(15 * time.Second),
+		baseURL:    Endpoints().LRCLIB,
 	}
 }
 
 func (c *LyricsClient) FetchLyricsWithMetadata(artist, track string) (*LyricsResponse, error) {
-	baseURL := "https://lrclib.net/api/get"
+	baseURL := c.baseURL + "/get"
 	params := url.Values{}
 	params.Set("artist_name", artist)
 	params.Set("track_name", track)
@@ -164,7 +166,7 @@ func (c *LyricsClient) FetchLyricsWithMetadata(artist, track string) (*LyricsRes
 }
 
 func (c *LyricsClient) FetchLyricsFromLRCLibSearch(query string, durationSec float64) (*LyricsResponse, error) {
-	baseURL := "https://lrclib.net/api/search"
+	baseURL := c.baseURL + "/search"
 	params := url.Values{}
 	params.Set("q", query)
 
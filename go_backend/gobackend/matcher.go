@@ -0,0 +1,721 @@
+package gobackend
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Weights of the match components. Components that cannot be compared, such
+// as a duration or ISRC only one side knows, are left out and the remaining
+// weights renormalised.
+const (
+	matchWeightTitle    = 0.30
+	matchWeightArtist   = 0.25
+	matchWeightDuration = 0.15
+	matchWeightISRC     = 0.15
+	matchWeightVersion  = 0.10
+	matchWeightAlbum    = 0.05
+)
+
+// Rejection reasons produced only by the scored matcher
+const (
+	RejectVersionMismatch = "version_mismatch"
+	RejectLowConfidence   = "low_confidence"
+)
+
+// MatchThresholds decide when a scored candidate is accepted
+type MatchThresholds struct {
+	AcceptScore          float64 `json:"accept_score"`           // Minimum weighted score
+	MinTitleScore        float64 `json:"min_title_score"`        // Minimum title score unless the ISRC matches
+	MinArtistScore       float64 `json:"min_artist_score"`       // Minimum artist score
+	DurationToleranceSec int     `json:"duration_tolerance_sec"` // Largest accepted duration difference
+}
+
+// MatchConfig holds the default thresholds and per-provider overrides. Zero
+// fields in an override keep the default.
+type MatchConfig struct {
+	Default   MatchThresholds            `json:"default"`
+	Providers map[string]MatchThresholds `json:"providers,omitempty"`
+}
+
+// DefaultMatchConfig returns the built-in thresholds. Qobuz durations are
+// rounded differently, so it keeps its wider duration tolerance.
+func DefaultMatchConfig() MatchConfig {
+	return MatchConfig{
+		Default: MatchThresholds{
+			AcceptScore:          0.70,
+			MinTitleScore:        0.75,
+			MinArtistScore:       0.75,
+			DurationToleranceSec: 3,
+		},
+		Providers: map[string]MatchThresholds{
+			"qobuz": {DurationToleranceSec: 10},
+		},
+	}
+}
+
+func (t MatchThresholds) validate() error {
+	for name, v := range map[string]float64{
+		"accept_score":     t.AcceptScore,
+		"min_title_score":  t.MinTitleScore,
+		"min_artist_score": t.MinArtistScore,
+	} {
+		if v < 0 || v > 1 {
+			return fmt.Errorf("%s must be between 0 and 1, got %v", name, v)
+		}
+	}
+	if t.DurationToleranceSec < 0 || t.DurationToleranceSec > 60 {
+		return fmt.Errorf("duration_tolerance_sec must be between 0 and 60, got %d", t.DurationToleranceSec)
+	}
+	return nil
+}
+
+// overlay returns t with the non-zero fields of override applied
+func (t MatchThresholds) overlay(override MatchThresholds) MatchThresholds {
+	if override.AcceptScore > 0 {
+		t.AcceptScore = override.AcceptScore
+	}
+	if override.MinTitleScore > 0 {
+		t.MinTitleScore = override.MinTitleScore
+	}
+	if override.MinArtistScore > 0 {
+		t.MinArtistScore = override.MinArtistScore
+	}
+	if override.DurationToleranceSec > 0 {
+		t.DurationToleranceSec = override.DurationToleranceSec
+	}
+	return t
+}
+
+var (
+	matchConfig   = DefaultMatchConfig()
+	matchConfigMu sync.RWMutex
+)
+
+// GetMatchConfig returns the current matching thresholds
+func GetMatchConfig() MatchConfig {
+	matchConfigMu.RLock()
+	defer matchConfigMu.RUnlock()
+	return matchConfig
+}
+
+// SetMatchConfig validates and applies matching thresholds
+func SetMatchConfig(config MatchConfig) error {
+	if err := config.Default.validate(); err != nil {
+		return err
+	}
+	for provider, t := range config.Providers {
+		if err := t.validate(); err != nil {
+			return fmt.Errorf("%s: %w", provider, err)
+		}
+	}
+
+	matchConfigMu.Lock()
+	defer matchConfigMu.Unlock()
+	matchConfig = config
+	GoLog("[Matcher] Thresholds set: %+v (%d provider overrides)\n", config.Default, len(config.Providers))
+	return nil
+}
+
+// matchThresholdsFor returns the thresholds used for a provider
+func matchThresholdsFor(provider string) MatchThresholds {
+	config := GetMatchConfig()
+	if override, ok := config.Providers[provider]; ok {
+		return config.Default.overlay(override)
+	}
+	return config.Default
+}
+
+// TrackMatchInfo is one side of a comparison. Empty fields are not compared.
+type TrackMatchInfo struct {
+	Title      string `json:"title"`
+	Artist     string `json:"artist"` // One or more artists, as displayed
+	Album      string `json:"album,omitempty"`
+	DurationMS int    `json:"duration_ms,omitempty"`
+	ISRC       string `json:"isrc,omitempty"`
+}
+
+// MatchComponent is the score of one compared property
+type MatchComponent struct {
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Weight float64 `json:"weight"`
+	Detail string  `json:"detail"`
+}
+
+// MatchResult is a scored comparison of a candidate with the expected track
+type MatchResult struct {
+	Score       float64          `json:"score"`
+	Accepted    bool             `json:"accepted"`
+	Rejection   string           `json:"rejection,omitempty"`
+	Components  []MatchComponent `json:"components"`
+	Explanation string           `json:"explanation"`
+}
+
+func (r MatchResult) component(name string) (MatchComponent, bool) {
+	for _, c := range r.Components {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return MatchComponent{}, false
+}
+
+// ScoreTrackMatch compares a candidate with the expected track using the
+// thresholds configured for provider
+func ScoreTrackMatch(provider string, expected, found TrackMatchInfo) MatchResult {
+	return scoreTrackMatch(expected, found, matchThresholdsFor(provider))
+}
+
+// requestMatchInfo describes the requested track for ScoreTrackMatch
+func requestMatchInfo(req DownloadRequest) TrackMatchInfo {
+	return TrackMatchInfo{
+		Title:      req.TrackName,
+		Artist:     req.ArtistName,
+		Album:      req.AlbumName,
+		DurationMS: req.DurationMS,
+		ISRC:       req.ISRC,
+	}
+}
+
+// matchRequest scores a provider's candidate against a download request and
+// logs the explanation
+func matchRequest(provider, source string, req DownloadRequest, found TrackMatchInfo) MatchResult {
+	result := ScoreTrackMatch(provider, requestMatchInfo(req), found)
+	if result.Accepted {
+		GoLog("[Matcher] %s %s: '%s' by '%s' accepted: %s\n", provider, source, found.Title, found.Artist, result.Explanation)
+	} else {
+		GoLog("[Matcher] %s %s: '%s' by '%s' rejected: %s\n", provider, source, found.Title, found.Artist, result.Explanation)
+	}
+	return result
+}
+
+func scoreTrackMatch(expected, found TrackMatchInfo, t MatchThresholds) MatchResult {
+	var result MatchResult
+	add := func(name string, weight, score float64, detail string) {
+		result.Components = append(result.Components, MatchComponent{
+			Name: name, Score: math.Round(score*1000) / 1000, Weight: weight, Detail: detail,
+		})
+	}
+
+	isrcMatches := false
+	if expected.ISRC != "" && found.ISRC != "" {
+		isrcMatches = strings.EqualFold(expected.ISRC, found.ISRC)
+		if isrcMatches {
+			add("isrc", matchWeightISRC, 1, "same ISRC")
+		} else {
+			add("isrc", matchWeightISRC, 0, fmt.Sprintf("ISRC %s differs from %s", found.ISRC, expected.ISRC))
+		}
+	}
+	if expected.Title != "" && found.Title != "" {
+		score, detail := scoreTitles(expected.Title, found.Title)
+		add("title", matchWeightTitle, score, detail)
+	}
+	if expected.Artist != "" && found.Artist != "" {
+		score, detail := scoreArtists(expected.Artist, found.Artist)
+		add("artist", matchWeightArtist, score, detail)
+	}
+	durationOK := true
+	if expected.DurationMS > 0 && found.DurationMS > 0 {
+		// Compared in whole seconds, as catalogues report them
+		diff := expected.DurationMS/1000 - found.DurationMS/1000
+		if diff < 0 {
+			diff = -diff
+		}
+		durationOK = diff <= t.DurationToleranceSec
+		if durationOK {
+			add("duration", matchWeightDuration, 1, fmt.Sprintf("%ds apart, within %ds", diff, t.DurationToleranceSec))
+		} else {
+			add("duration", matchWeightDuration, 0, fmt.Sprintf("%ds apart, over %ds", diff, t.DurationToleranceSec))
+		}
+	}
+	versionOK := true
+	if expected.Title != "" && found.Title != "" {
+		score, significant, detail := scoreVersions(expected.Title, found.Title)
+		if detail != "" {
+			add("version", matchWeightVersion, score, detail)
+			versionOK = !significant
+		}
+	}
+	if expected.Album != "" && found.Album != "" {
+		score, detail := scoreTitles(expected.Album, found.Album)
+		add("album", matchWeightAlbum, score, detail)
+	}
+
+	var weighted, totalWeight float64
+	for _, c := range result.Components {
+		weighted += c.Weight * c.Score
+		totalWeight += c.Weight
+	}
+	if totalWeight > 0 {
+		result.Score = math.Round(weighted/totalWeight*1000) / 1000
+	}
+
+	// A matching ISRC identifies the recording, so title wording and version
+	// tags may differ
+	title, hasTitle := result.component("title")
+	artist, hasArtist := result.component("artist")
+	switch {
+	case hasTitle && !isrcMatches && title.Score < t.MinTitleScore:
+		result.Rejection = RejectTitleMismatch
+	case hasArtist && artist.Score < t.MinArtistScore:
+		result.Rejection = RejectArtistMismatch
+	case !durationOK:
+		result.Rejection = RejectDurationMismatch
+	case !versionOK && !isrcMatches:
+		result.Rejection = RejectVersionMismatch
+	case totalWeight == 0 || result.Score < t.AcceptScore:
+		result.Rejection = RejectLowConfidence
+	}
+	result.Accepted = result.Rejection == ""
+
+	parts := make([]string, 0, len(result.Components)+1)
+	for _, c := range result.Components {
+		parts = append(parts, fmt.Sprintf("%s %.2f (%s)", c.Name, c.Score, c.Detail))
+	}
+	verdict := fmt.Sprintf("score %.2f, accepted", result.Score)
+	if !result.Accepted {
+		verdict = fmt.Sprintf("score %.2f, rejected: %s", result.Score, result.Rejection)
+	}
+	result.Explanation = strings.Join(append(parts, verdict), "; ")
+	return result
+}
+
+// titlesMatch reports whether two titles pass the default title threshold
+func titlesMatch(expectedTitle, foundTitle string) bool {
+	score, _ := scoreTitles(expectedTitle, foundTitle)
+	return score >= GetMatchConfig().Default.MinTitleScore
+}
+
+// artistsMatch reports whether two artist strings pass the default artist
+// threshold
+func artistsMatch(expectedArtist, foundArtist string) bool {
+	score, _ := scoreArtists(expectedArtist, foundArtist)
+	return score >= GetMatchConfig().Default.MinArtistScore
+}
+
+// durationsMatch reports whether two durations in seconds are within the
+// provider's tolerance. Unknown durations match.
+func durationsMatch(provider string, expectedSec, foundSec int) bool {
+	if expectedSec <= 0 || foundSec <= 0 {
+		return true
+	}
+	diff := expectedSec - foundSec
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff <= matchThresholdsFor(provider).DurationToleranceSec
+}
+
+// scoreTitles scores two titles from identical (1) down to their edit
+// similarity
+func scoreTitles(expected, found string) (float64, string) {
+	normExpected := strings.ToLower(strings.TrimSpace(expected))
+	normFound := strings.ToLower(strings.TrimSpace(found))
+
+	if normExpected == normFound {
+		return 1, "identical"
+	}
+
+	cleanExpected := cleanTitle(normExpected)
+	cleanFound := cleanTitle(normFound)
+	if cleanExpected != "" && cleanExpected == cleanFound {
+		return 0.95, "same apart from version tags"
+	}
+
+	coreExpected := extractCoreTitle(normExpected)
+	coreFound := extractCoreTitle(normFound)
+	if coreExpected != "" && coreExpected == coreFound {
+		return 0.9, "same core title"
+	}
+
+	if strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected) ||
+		(cleanExpected != "" && cleanFound != "" &&
+			(strings.Contains(cleanExpected, cleanFound) || strings.Contains(cleanFound, cleanExpected))) {
+		return 0.85, "one contains the other"
+	}
+
+	if isLatinScript(expected) != isLatinScript(found) {
+		return 0.8, "different scripts, assumed transliteration"
+	}
+
+	similarity := calculateStringSimilarity(cleanExpected, cleanFound)
+	return similarity, fmt.Sprintf("%.0f%% similar", similarity*100)
+}
+
+// scoreArtists scores two artist strings by their best matching pair of
+// artists, with a smaller share for how many expected artists were found
+func scoreArtists(expected, found string) (float64, string) {
+	normExpected := strings.ToLower(strings.TrimSpace(expected))
+	normFound := strings.ToLower(strings.TrimSpace(found))
+
+	if normExpected == normFound {
+		return 1, "identical"
+	}
+
+	expectedArtists := splitArtists(normExpected)
+	foundArtists := splitArtists(normFound)
+
+	best := 0.0
+	bestDetail := ""
+	covered := 0
+	for _, exp := range expectedArtists {
+		expBest := 0.0
+		for _, fnd := range foundArtists {
+			score, detail := scoreArtistPair(exp, fnd)
+			if score > best {
+				best, bestDetail = score, detail
+			}
+			expBest = max(expBest, score)
+		}
+		if expBest >= 0.9 {
+			covered++
+		}
+	}
+
+	if best < 0.9 && (strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected)) {
+		best, bestDetail = 0.9, "one contains the other"
+	}
+	if best < 0.8 && isLatinScript(expected) != isLatinScript(found) {
+		return 0.8, "different scripts, assumed transliteration"
+	}
+
+	coverage := 0.0
+	if len(expectedArtists) > 0 {
+		coverage = float64(covered) / float64(len(expectedArtists))
+	}
+	score := 0.8*best + 0.2*coverage
+	if best >= 0.9 {
+		// Any artist in common is enough to pass; coverage only ranks
+		score = max(score, 0.8)
+	}
+	return score, fmt.Sprintf("%s, %d of %d artists found", bestDetail, covered, len(expectedArtists))
+}
+
+func scoreArtistPair(expected, found string) (float64, string) {
+	switch {
+	case expected == found:
+		return 1, "same artist"
+	case sameWordsUnordered(expected, found):
+		return 0.95, "same words in different order"
+	case strings.Contains(expected, found) || strings.Contains(found, expected):
+		return 0.9, "one contains the other"
+	}
+	similarity := calculateStringSimilarity(expected, found)
+	return similarity, fmt.Sprintf("%.0f%% similar", similarity*100)
+}
+
+// Version markers found in title decorations. Significant ones mean a
+// different recording; minor ones are usually the same audio.
+var (
+	significantVersionMarkers = []string{
+		"live", "acoustic", "remix", "instrumental", "karaoke", "demo",
+		"a cappella", "acapella", "sped up", "slowed", "cover", "unplugged",
+	}
+	minorVersionMarkers = []string{
+		"remaster", "radio edit", "edit", "extended", "mono", "stereo",
+		"single version", "album version", "explicit", "clean",
+	}
+	versionDecorationPattern = regexp.MustCompile(`\(([^)]*)\)|\[([^\]]*)\]| - (.*)$`)
+)
+
+// versionMarkers returns the markers in the parenthesised, bracketed or
+// dash-separated parts of a title. The bare title is ignored so "Live
+// Forever" is not a live version.
+func versionMarkers(title string) map[string]bool {
+	markers := make(map[string]bool)
+	for _, m := range versionDecorationPattern.FindAllStringSubmatch(strings.ToLower(title), -1) {
+		// Whole words only, so "edition" is not an edit; "remastered" and
+		// "remixes" still count
+		words := strings.FieldsFunc(strings.Join(m[1:], " "), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		decoration := " " + strings.Join(words, " ") + " "
+		for _, list := range [][]string{significantVersionMarkers, minorVersionMarkers} {
+			for _, marker := range list {
+				if marker == "edit" && markers["radio edit"] {
+					continue
+				}
+				for _, form := range []string{marker, marker + "ed", marker + "s"} {
+					if strings.Contains(decoration, " "+form+" ") {
+						markers[marker] = true
+					}
+				}
+			}
+		}
+	}
+	return markers
+}
+
+// scoreVersions compares version markers. The detail is empty when neither
+// title carries any.
+func scoreVersions(expected, found string) (float64, bool, string) {
+	expectedMarkers := versionMarkers(expected)
+	foundMarkers := versionMarkers(found)
+	if len(expectedMarkers) == 0 && len(foundMarkers) == 0 {
+		return 1, false, ""
+	}
+
+	var differing []string
+	score := 1.0
+	significant := false
+	check := func(marker string, weight float64, isSignificant bool) {
+		if expectedMarkers[marker] != foundMarkers[marker] {
+			differing = append(differing, marker)
+			score -= weight
+			significant = significant || isSignificant
+		}
+	}
+	for _, marker := range significantVersionMarkers {
+		check(marker, 0.5, true)
+	}
+	for _, marker := range minorVersionMarkers {
+		check(marker, 0.15, false)
+	}
+
+	if len(differing) == 0 {
+		return 1, false, "same version tags"
+	}
+	return max(score, 0), significant, "differs in " + strings.Join(differing, ", ")
+}
+
+// splitArtists splits a credit such as "A feat. B & C" into its artists
+func splitArtists(artists string) []string {
+	normalized := artists
+	normalized = strings.ReplaceAll(normalized, " feat. ", "|")
+	normalized = strings.ReplaceAll(normalized, " feat ", "|")
+	normalized = strings.ReplaceAll(normalized, " ft. ", "|")
+	normalized = strings.ReplaceAll(normalized, " ft ", "|")
+	normalized = strings.ReplaceAll(normalized, " & ", "|")
+	normalized = strings.ReplaceAll(normalized, " and ", "|")
+	normalized = strings.ReplaceAll(normalized, ", ", "|")
+	normalized = strings.ReplaceAll(normalized, " x ", "|")
+
+	parts := strings.Split(normalized, "|")
+	result := make([]string, 0, len(parts))
+	for _, p := range parts {
+		trimmed := strings.TrimSpace(p)
+		if trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+// sameWordsUnordered reports whether two names have the same words in any
+// order, as in "Last First" and "First Last"
+func sameWordsUnordered(a, b string) bool {
+	wordsA := strings.Fields(a)
+	wordsB := strings.Fields(b)
+
+	// Must have same number of words
+	if len(wordsA) != len(wordsB) || len(wordsA) == 0 {
+		return false
+	}
+
+	sortedA := make([]string, len(wordsA))
+	sortedB := make([]string, len(wordsB))
+	copy(sortedA, wordsA)
+	copy(sortedB, wordsB)
+
+	for i := 0; i < len(sortedA)-1; i++ {
+		for j := i + 1; j < len(sortedA); j++ {
+			if sortedA[i] > sortedA[j] {
+				sortedA[i], sortedA[j] = sortedA[j], sortedA[i]
+			}
+			if sortedB[i] > sortedB[j] {
+				sortedB[i], sortedB[j] = sortedB[j], sortedB[i]
+			}
+		}
+	}
+
+	for i := range sortedA {
+		if sortedA[i] != sortedB[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// extractCoreTitle returns the title before any parenthesised, bracketed or
+// dash-separated decoration
+func extractCoreTitle(title string) string {
+	parenIdx := strings.Index(title, "(")
+	bracketIdx := strings.Index(title, "[")
+	dashIdx := strings.Index(title, " - ")
+
+	cutIdx := len(title)
+	if parenIdx > 0 && parenIdx < cutIdx {
+		cutIdx = parenIdx
+	}
+	if bracketIdx > 0 && bracketIdx < cutIdx {
+		cutIdx = bracketIdx
+	}
+	if dashIdx > 0 && dashIdx < cutIdx {
+		cutIdx = dashIdx
+	}
+
+	return strings.TrimSpace(title[:cutIdx])
+}
+
+// cleanTitle removes version decorations such as "(Remastered)" or
+// " - Live" from a title
+func cleanTitle(title string) string {
+	cleaned := title
+
+	versionPatterns := []string{
+		"remaster", "remastered", "deluxe", "bonus", "single",
+		"album version", "radio edit", "original mix", "extended",
+		"club mix", "remix", "live", "acoustic", "demo",
+	}
+
+	for {
+		startParen := strings.LastIndex(cleaned, "(")
+		endParen := strings.LastIndex(cleaned, ")")
+		if startParen >= 0 && endParen > startParen {
+			content := strings.ToLower(cleaned[startParen+1 : endParen])
+			isVersionIndicator := false
+			for _, pattern := range versionPatterns {
+				if strings.Contains(content, pattern) {
+					isVersionIndicator = true
+					break
+				}
+			}
+			if isVersionIndicator {
+				cleaned = strings.TrimSpace(cleaned[:startParen]) + cleaned[endParen+1:]
+				continue
+			}
+		}
+		break
+	}
+
+	for {
+		startBracket := strings.LastIndex(cleaned, "[")
+		endBracket := strings.LastIndex(cleaned, "]")
+		if startBracket >= 0 && endBracket > startBracket {
+			content := strings.ToLower(cleaned[startBracket+1 : endBracket])
+			isVersionIndicator := false
+			for _, pattern := range versionPatterns {
+				if strings.Contains(content, pattern) {
+					isVersionIndicator = true
+					break
+				}
+			}
+			if isVersionIndicator {
+				cleaned = strings.TrimSpace(cleaned[:startBracket]) + cleaned[endBracket+1:]
+				continue
+			}
+		}
+		break
+	}
+
+	dashPatterns := []string{
+		" - remaster", " - remastered", " - single version", " - radio edit",
+		" - live", " - acoustic", " - demo", " - remix",
+	}
+	for _, pattern := range dashPatterns {
+		if strings.HasSuffix(strings.ToLower(cleaned), pattern) {
+			cleaned = cleaned[:len(cleaned)-len(pattern)]
+		}
+	}
+
+	for strings.Contains(cleaned, "  ") {
+		cleaned = strings.ReplaceAll(cleaned, "  ", " ")
+	}
+
+	return strings.TrimSpace(cleaned)
+}
+
+// isLatinScript checks if a string is primarily Latin script
+// Returns true for ASCII and Latin Extended characters (European languages)
+// Returns false for CJK, Arabic, Cyrillic, etc.
+func isLatinScript(s string) bool {
+	for _, r := range s {
+		// Skip common punctuation and numbers
+		if r < 128 {
+			continue
+		}
+		// Latin Extended-A: U+0100 to U+017F (Polish, Czech, etc.)
+		// Latin Extended-B: U+0180 to U+024F
+		// Latin Extended Additional: U+1E00 to U+1EFF
+		// Latin Extended-C/D/E: various ranges
+		if (r >= 0x0100 && r <= 0x024F) || // Latin Extended A & B
+			(r >= 0x1E00 && r <= 0x1EFF) || // Latin Extended Additional
+			(r >= 0x00C0 && r <= 0x00FF) { // Latin-1 Supplement (accented chars)
+			continue
+		}
+		// CJK ranges - definitely different script
+		if (r >= 0x4E00 && r <= 0x9FFF) || // CJK Unified Ideographs
+			(r >= 0x3040 && r <= 0x309F) || // Hiragana
+			(r >= 0x30A0 && r <= 0x30FF) || // Katakana
+			(r >= 0xAC00 && r <= 0xD7AF) || // Hangul (Korean)
+			(r >= 0x0600 && r <= 0x06FF) || // Arabic
+			(r >= 0x0400 && r <= 0x04FF) { // Cyrillic
+			return false
+		}
+	}
+	return true
+}
+
+// calculateStringSimilarity calculates similarity between two strings (0-1)
+func calculateStringSimilarity(s1, s2 string) float64 {
+	len1 := utf8.RuneCountInString(s1)
+	len2 := utf8.RuneCountInString(s2)
+	if len1 == 0 && len2 == 0 {
+		return 1.0
+	}
+	if len1 == 0 || len2 == 0 {
+		return 0.0
+	}
+
+	// Use Levenshtein distance
+	distance := levenshteinDistance(s1, s2)
+	maxLen := max(len1, len2)
+
+	return 1.0 - float64(distance)/float64(maxLen)
+}
+
+// levenshteinDistance calculates the Levenshtein distance between two strings
+// in runes, so accented and non-Latin characters count once
+func levenshteinDistance(a, b string) int {
+	s1 := []rune(a)
+	s2 := []rune(b)
+	if len(s1) == 0 {
+		return len(s2)
+	}
+	if len(s2) == 0 {
+		return len(s1)
+	}
+
+	// Create matrix
+	matrix := make([][]int, len(s1)+1)
+	for i := range matrix {
+		matrix[i] = make([]int, len(s2)+1)
+		matrix[i][0] = i
+	}
+	for j := range matrix[0] {
+		matrix[0][j] = j
+	}
+
+	// Fill matrix
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			matrix[i][j] = min(
+				matrix[i-1][j]+1,      // deletion
+				matrix[i][j-1]+1,      // insertion
+				matrix[i-1][j-1]+cost, // substitution
+			)
+		}
+	}
+
+	return matrix[len(s1)][len(s2)]
+}
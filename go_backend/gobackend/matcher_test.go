@@ -0,0 +1,125 @@
+package gobackend
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestScoreTrackMatch(t *testing.T) {
+	expected := TrackMatchInfo{
+		Title:      "Bohemian Rhapsody",
+		Artist:     "Queen",
+		Album:      "A Night at the Opera",
+		DurationMS: 354000,
+		ISRC:       "GBUM71029604",
+	}
+
+	tests := []struct {
+		name      string
+		found     TrackMatchInfo
+		rejection string
+	}{
+		{"exact", expected, ""},
+		{"remaster tag", TrackMatchInfo{Title: "Bohemian Rhapsody (Remastered 2011)", Artist: "Queen", DurationMS: 355000}, ""},
+		{"other title", TrackMatchInfo{Title: "Another One Bites the Dust", Artist: "Queen", DurationMS: 354000}, RejectTitleMismatch},
+		{"other artist", TrackMatchInfo{Title: "Bohemian Rhapsody", Artist: "Panic! At The Disco", DurationMS: 354000}, RejectArtistMismatch},
+		{"too long", TrackMatchInfo{Title: "Bohemian Rhapsody", Artist: "Queen", DurationMS: 370000}, RejectDurationMismatch},
+		{"live version", TrackMatchInfo{Title: "Bohemian Rhapsody (Live at Wembley)", Artist: "Queen", DurationMS: 355000}, RejectVersionMismatch},
+		{"live, same ISRC", TrackMatchInfo{Title: "Bohemian Rhapsody - Live", Artist: "Queen", ISRC: "GBUM71029604"}, ""},
+		{"featured artist", TrackMatchInfo{Title: "Bohemian Rhapsody", Artist: "Queen, David Bowie"}, ""},
+		{"nothing to compare", TrackMatchInfo{}, RejectLowConfidence},
+	}
+
+	for _, tt := range tests {
+		result := scoreTrackMatch(expected, tt.found, DefaultMatchConfig().Default)
+		if result.Rejection != tt.rejection || result.Accepted != (tt.rejection == "") {
+			t.Errorf("%s: rejection %q (accepted %v), want %q\n%s",
+				tt.name, result.Rejection, result.Accepted, tt.rejection, result.Explanation)
+		}
+		if result.Explanation == "" {
+			t.Errorf("%s: empty explanation", tt.name)
+		}
+	}
+}
+
+func TestScoreTrackMatchWeights(t *testing.T) {
+	expected := TrackMatchInfo{Title: "Song", Artist: "Artist", DurationMS: 200000, ISRC: "USAAA0000001"}
+	found := TrackMatchInfo{Title: "Song", Artist: "Artist", DurationMS: 201000, ISRC: "GBBBB0000002"}
+
+	// Only the ISRC differs; no album or version to compare: 1 - 0.15/0.85
+	result := scoreTrackMatch(expected, found, DefaultMatchConfig().Default)
+	if !result.Accepted || result.Score < 0.82 || result.Score > 0.83 {
+		t.Errorf("score = %v (accepted %v), want ~0.824\n%s", result.Score, result.Accepted, result.Explanation)
+	}
+	if !strings.Contains(result.Explanation, "isrc 0.00") {
+		t.Errorf("explanation does not mention the ISRC: %s", result.Explanation)
+	}
+
+	strict := DefaultMatchConfig().Default
+	strict.AcceptScore = 0.9
+	if result := scoreTrackMatch(expected, found, strict); result.Rejection != RejectLowConfidence {
+		t.Errorf("strict thresholds: rejection %q, want %q", result.Rejection, RejectLowConfidence)
+	}
+}
+
+func TestVersionMarkers(t *testing.T) {
+	tests := []struct {
+		title string
+		want  []string
+	}{
+		{"Live Forever", nil},
+		{"Live Forever (Live)", []string{"live"}},
+		{"Song - Radio Edit", []string{"radio edit"}},
+		{"Song [Acoustic] (2011 Remaster)", []string{"acoustic", "remaster"}},
+		{"Song (Deliverance Mix)", nil},
+		{"Song (Deluxe Edition)", nil},
+		{"Song (Remastered 2011)", []string{"remaster"}},
+	}
+
+	for _, tt := range tests {
+		got := versionMarkers(tt.title)
+		if len(got) != len(tt.want) {
+			t.Errorf("%q: markers %v, want %v", tt.title, got, tt.want)
+			continue
+		}
+		for _, marker := range tt.want {
+			if !got[marker] {
+				t.Errorf("%q: markers %v, want %v", tt.title, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestMatchConfigOverrides(t *testing.T) {
+	defer SetMatchConfig(DefaultMatchConfig())
+
+	if !durationsMatch("qobuz", 200, 208) || durationsMatch("tidal", 200, 208) {
+		t.Error("qobuz should keep its 10s duration tolerance, tidal its 3s")
+	}
+
+	config := DefaultMatchConfig()
+	config.Providers["tidal"] = MatchThresholds{DurationToleranceSec: 10}
+	if err := SetMatchConfig(config); err != nil {
+		t.Fatal(err)
+	}
+	if !durationsMatch("tidal", 200, 208) {
+		t.Error("tidal override not applied")
+	}
+	if got := matchThresholdsFor("tidal"); got.AcceptScore != config.Default.AcceptScore {
+		t.Errorf("unset override field: accept score %v, want default %v", got.AcceptScore, config.Default.AcceptScore)
+	}
+
+	config.Default.AcceptScore = 1.5
+	if err := SetMatchConfig(config); err == nil {
+		t.Error("accept score above 1 was accepted")
+	}
+}
+
+func TestLevenshteinDistanceRunes(t *testing.T) {
+	if got := levenshteinDistance("Beyoncé", "Beyonce"); got != 1 {
+		t.Errorf("distance = %d, want 1", got)
+	}
+	if got := calculateStringSimilarity("東京", "東京"); got != 1 {
+		t.Errorf("similarity = %v, want 1", got)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"strconv"
 	"strings"
@@ -23,12 +24,20 @@ type Metadata struct {
 	TrackNumber int
 	TotalTracks int
 	DiscNumber  int
+	TotalDiscs  int
 	ISRC        string
 	Description string
 	Lyrics      string
 	Genre       string
 	Label       string
 	Copyright   string
+	UPC         string
+
+	MusicBrainzTrackID   string   // Recording MBID
+	MusicBrainzAlbumID   string   // Release MBID
+	MusicBrainzArtistIDs []string // Artist MBIDs, one tag each
+	OriginalDate         string
+	ReleaseTypes         []string // e.g. "album", "live"
 }
 
 func EmbedMetadata(filePath string, metadata Metadata, coverPath string) error {
@@ -70,7 +79,11 @@ func EmbedMetadata(filePath string, metadata Metadata, coverPath string) error {
 	}
 
 	if metadata.DiscNumber > 0 {
-		setComment(cmt, "DISCNUMBER", strconv.Itoa(metadata.DiscNumber))
+		if metadata.TotalDiscs > 0 {
+			setComment(cmt, "DISCNUMBER", fmt.Sprintf("%d/%d", metadata.DiscNumber, metadata.TotalDiscs))
+		} else {
+			setComment(cmt, "DISCNUMBER", strconv.Itoa(metadata.DiscNumber))
+		}
 	}
 
 	if metadata.ISRC != "" {
@@ -98,6 +111,12 @@ func EmbedMetadata(filePath string, metadata Metadata, coverPath string) error {
 		setComment(cmt, "COPYRIGHT", metadata.Copyright)
 	}
 
+	if metadata.UPC != "" {
+		setComment(cmt, "UPC", metadata.UPC)
+	}
+
+	setMusicBrainzComments(cmt, metadata)
+
 	cmtBlock := cmt.Marshal()
 	if cmtIdx >= 0 {
 		f.Meta[cmtIdx] = &cmtBlock
@@ -178,7 +197,11 @@ func EmbedMetadataWithCoverData(filePath string, metadata Metadata, coverData []
 	}
 
 	if metadata.DiscNumber > 0 {
-		setComment(cmt, "DISCNUMBER", strconv.Itoa(metadata.DiscNumber))
+		if metadata.TotalDiscs > 0 {
+			setComment(cmt, "DISCNUMBER", fmt.Sprintf("%d/%d", metadata.DiscNumber, metadata.TotalDiscs))
+		} else {
+			setComment(cmt, "DISCNUMBER", strconv.Itoa(metadata.DiscNumber))
+		}
 	}
 
 	if metadata.ISRC != "" {
@@ -206,6 +229,12 @@ func EmbedMetadataWithCoverData(filePath string, metadata Metadata, coverData []
 		setComment(cmt, "COPYRIGHT", metadata.Copyright)
 	}
 
+	if metadata.UPC != "" {
+		setComment(cmt, "UPC", metadata.UPC)
+	}
+
+	setMusicBrainzComments(cmt, metadata)
+
 	cmtBlock := cmt.Marshal()
 	if cmtIdx >= 0 {
 		f.Meta[cmtIdx] = &cmtBlock
@@ -260,6 +289,9 @@ func ReadMetadata(filePath string) (*Metadata, error) {
 			metadata.AlbumArtist = getComment(cmt, "ALBUMARTIST")
 			metadata.Date = getComment(cmt, "DATE")
 			metadata.ISRC = getComment(cmt, "ISRC")
+			metadata.MusicBrainzTrackID = getComment(cmt, "MUSICBRAINZ_TRACKID")
+			metadata.MusicBrainzAlbumID = getComment(cmt, "MUSICBRAINZ_ALBUMID")
+			metadata.OriginalDate = getComment(cmt, "ORIGINALDATE")
 			metadata.Description = getComment(cmt, "DESCRIPTION")
 
 			metadata.Lyrics = getComment(cmt, "LYRICS")
@@ -318,6 +350,29 @@ func setComment(cmt *flacvorbis.MetaDataBlockVorbisComment, key, value string) {
 	cmt.Comments = append(cmt.Comments, key+"="+value)
 }
 
+// setComments replaces key with one comment per value, as Vorbis comments
+// carry multi-valued tags
+func setComments(cmt *flacvorbis.MetaDataBlockVorbisComment, key string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	setComment(cmt, key, values[0])
+	for _, value := range values[1:] {
+		if value != "" {
+			cmt.Comments = append(cmt.Comments, key+"="+value)
+		}
+	}
+}
+
+// setMusicBrainzComments writes the MusicBrainz tags under the names Picard uses
+func setMusicBrainzComments(cmt *flacvorbis.MetaDataBlockVorbisComment, metadata Metadata) {
+	setComment(cmt, "MUSICBRAINZ_TRACKID", metadata.MusicBrainzTrackID)
+	setComment(cmt, "MUSICBRAINZ_ALBUMID", metadata.MusicBrainzAlbumID)
+	setComments(cmt, "MUSICBRAINZ_ARTISTID", metadata.MusicBrainzArtistIDs)
+	setComment(cmt, "ORIGINALDATE", metadata.OriginalDate)
+	setComments(cmt, "RELEASETYPE", metadata.ReleaseTypes)
+}
+
 func getComment(cmt *flacvorbis.MetaDataBlockVorbisComment, key string) string {
 	keyUpper := strings.ToUpper(key) + "="
 	for _, comment := range cmt.Comments {
@@ -726,7 +781,7 @@ func buildMetaAtom(metadata Metadata, coverData []byte) []byte {
 	}
 
 	if metadata.DiscNumber > 0 {
-		ilst = append(ilst, buildDiscNumberAtom(metadata.DiscNumber, 0)...)
+		ilst = append(ilst, buildDiscNumberAtom(metadata.DiscNumber, metadata.TotalDiscs)...)
 	}
 
 	if metadata.Lyrics != "" {
@@ -917,7 +972,116 @@ func GetM4AQuality(filePath string) (AudioQuality, error) {
 		bitDepth = 24
 	}
 
-	return AudioQuality{BitDepth: bitDepth, SampleRate: sampleRate}, nil
+	var totalSamples int64
+	if duration, timescale, ok := readM4ADuration(f, moovHeader, fileSize); ok && sampleRate > 0 {
+		totalSamples = int64(float64(duration) * float64(sampleRate) / float64(timescale))
+	}
+
+	return AudioQuality{BitDepth: bitDepth, SampleRate: sampleRate, TotalSamples: totalSamples}, nil
+}
+
+// readM4ADuration returns the duration and its timescale. Fragmented files
+// are timed by their fragments, see readFragmentedM4ADuration; others by mvhd.
+func readM4ADuration(f *os.File, moov atomHeader, fileSize int64) (uint64, uint32, bool) {
+	contentStart := moov.offset + moov.headerSize
+	contentSize := moov.size - moov.headerSize
+
+	if _, found, err := findAtomInRange(f, contentStart, contentSize, "mvex", fileSize); err == nil && found {
+		return readFragmentedM4ADuration(f, moov, fileSize)
+	}
+
+	mvhd, found, err := findAtomInRange(f, contentStart, contentSize, "mvhd", fileSize)
+	if err != nil || !found {
+		return 0, 0, false
+	}
+	buf := make([]byte, min(32, mvhd.size-mvhd.headerSize))
+	if len(buf) < 20 {
+		return 0, 0, false
+	}
+	if _, err := f.ReadAt(buf, mvhd.offset+mvhd.headerSize); err != nil {
+		return 0, 0, false
+	}
+
+	var timescale uint32
+	var duration uint64
+	if buf[0] == 1 {
+		if len(buf) < 32 {
+			return 0, 0, false
+		}
+		timescale = binary.BigEndian.Uint32(buf[20:24])
+		duration = binary.BigEndian.Uint64(buf[24:32])
+		if duration == math.MaxUint64 {
+			duration = 0
+		}
+	} else {
+		timescale = binary.BigEndian.Uint32(buf[12:16])
+		duration = uint64(binary.BigEndian.Uint32(buf[16:20]))
+		if duration == math.MaxUint32 {
+			duration = 0
+		}
+	}
+	if timescale == 0 || duration == 0 {
+		return 0, 0, false
+	}
+	return duration, timescale, true
+}
+
+// Largest moov or moof box read into memory to time a fragmented file
+const maxFMP4IndexBoxSize = 16 << 20
+
+// readFragmentedM4ADuration sums the sample durations of the first track over
+// the fragments whose samples are all in the file. mvhd and mehd only state
+// the intended length, which a download that stopped early still reports.
+func readFragmentedM4ADuration(f *os.File, moov atomHeader, fileSize int64) (uint64, uint32, bool) {
+	if moov.size > maxFMP4IndexBoxSize {
+		return 0, 0, false
+	}
+	moovData := make([]byte, moov.size-moov.headerSize)
+	if _, err := f.ReadAt(moovData, moov.offset+moov.headerSize); err != nil {
+		return 0, 0, false
+	}
+
+	// Durations are in the track's mdhd timescale, not the movie's
+	var track fmp4FLACTrack
+	if tkhd, ok := findFMP4Box(moovData, "trak", "tkhd"); ok {
+		track.trackID = fmp4FieldAfterTimestamps(tkhd)
+	}
+	if mdhd, ok := findFMP4Box(moovData, "trak", "mdia", "mdhd"); ok {
+		track.timescale = fmp4FieldAfterTimestamps(mdhd)
+	}
+	if track.timescale == 0 {
+		return 0, 0, false
+	}
+	readFMP4TrackDefaults(moovData, &track)
+
+	var duration uint64
+	for pos := int64(0); pos+8 <= fileSize; {
+		header, err := readAtomHeaderAt(f, pos, fileSize)
+		if err != nil || header.size < header.headerSize || pos+header.size > fileSize {
+			break
+		}
+		if header.typ == "moof" {
+			if header.size > maxFMP4IndexBoxSize {
+				break
+			}
+			moofData := make([]byte, header.size-header.headerSize)
+			if _, err := f.ReadAt(moofData, pos+header.headerSize); err != nil {
+				break
+			}
+			samples, err := parseFMP4Moof(moofData, pos, track)
+			if err != nil {
+				break
+			}
+			for _, sample := range samples {
+				if sample.offset+int64(sample.size) > fileSize {
+					return duration, track.timescale, duration > 0
+				}
+				duration += uint64(sample.duration)
+			}
+		}
+		pos += header.size
+	}
+	return duration, track.timescale, duration > 0
 }
 
 type atomHeader struct {
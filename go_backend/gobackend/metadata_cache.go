@@ -0,0 +1,507 @@
+package gobackend
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metadataCacheDirName = "metadata_cache"
+
+// Changes to single-file namespaces are saved this long after the first one,
+// so a batch of lookups rewrites the file once
+const metadataCacheFlushDelay = 5 * time.Second
+
+// Metadata cache namespaces
+const (
+	CacheSpotifySearch = "spotify_search"
+	CacheSpotifyAlbum  = "spotify_album"
+	CacheSpotifyArtist = "spotify_artist"
+	CacheSpotifyISRC   = "spotify_isrc" // Spotify track ID -> ISRC
+	CacheDeezerSearch  = "deezer_search"
+	CacheDeezerAlbum   = "deezer_album"
+	CacheDeezerArtist  = "deezer_artist"
+	CacheDeezerAlbumEx = "deezer_album_extended" // Genre and label
+	CacheDeezerISRC    = "deezer_isrc"           // Deezer track ID -> ISRC
+)
+
+// CacheNamespaceConfig limits one namespace. Entries expire after TTL; past
+// MaxEntries or MaxBytes the least recently used are evicted. SingleFile
+// namespaces hold many small entries and are saved as one file rather than
+// a file per entry.
+type CacheNamespaceConfig struct {
+	TTL        time.Duration
+	MaxEntries int
+	MaxBytes   int64
+	SingleFile bool
+}
+
+// metadataCacheNamespaces sets the limits of every namespace. Catalogue
+// pages change rarely, search results more often, and ISRCs never.
+var metadataCacheNamespaces = map[string]CacheNamespaceConfig{
+	CacheSpotifySearch: {TTL: time.Hour, MaxEntries: 200, MaxBytes: 4 << 20},
+	CacheSpotifyAlbum:  {TTL: 7 * 24 * time.Hour, MaxEntries: 500, MaxBytes: 16 << 20},
+	CacheSpotifyArtist: {TTL: 24 * time.Hour, MaxEntries: 200, MaxBytes: 8 << 20},
+	CacheSpotifyISRC:   {TTL: 90 * 24 * time.Hour, MaxEntries: 50000, MaxBytes: 4 << 20, SingleFile: true},
+	CacheDeezerSearch:  {TTL: time.Hour, MaxEntries: 200, MaxBytes: 4 << 20},
+	CacheDeezerAlbum:   {TTL: 7 * 24 * time.Hour, MaxEntries: 500, MaxBytes: 16 << 20},
+	CacheDeezerArtist:  {TTL: 24 * time.Hour, MaxEntries: 200, MaxBytes: 8 << 20},
+	CacheDeezerAlbumEx: {TTL: 7 * 24 * time.Hour, MaxEntries: 2000, MaxBytes: 1 << 20},
+	CacheDeezerISRC:    {TTL: 90 * 24 * time.Hour, MaxEntries: 50000, MaxBytes: 4 << 20, SingleFile: true},
+}
+
+// metadataCacheRecord is one cached value, in memory and as its file
+type metadataCacheRecord struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	ExpiresAt int64           `json:"expires_at"` // Unix milliseconds
+}
+
+func (r *metadataCacheRecord) expired(now time.Time) bool {
+	return now.UnixMilli() >= r.ExpiresAt
+}
+
+type metadataCacheNamespace struct {
+	config  CacheNamespaceConfig
+	entries map[string]*list.Element // Values are *metadataCacheRecord
+	lru     *list.List               // Most recently used first
+	bytes   int64
+	dirty   bool // A SingleFile namespace changed since it was saved
+
+	hits, misses, evictions int64
+}
+
+// CacheNamespaceStats describes one namespace for GetMetadataCacheStatsJSON
+type CacheNamespaceStats struct {
+	Entries    int   `json:"entries"`
+	Bytes      int64 `json:"bytes"`
+	MaxEntries int   `json:"max_entries"`
+	MaxBytes   int64 `json:"max_bytes"`
+	TTLSeconds int64 `json:"ttl_seconds"`
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Evictions  int64 `json:"evictions"`
+}
+
+// MetadataCache is a key/value cache of metadata API responses shared by the
+// Spotify and Deezer clients. Values are kept in memory and, once SetDataDir
+// has been called, each is also written to its own file so the cache
+// survives restarts. SingleFile namespaces are written as a whole by Flush,
+// which runs shortly after they change.
+type MetadataCache struct {
+	mu             sync.Mutex
+	flushMu        sync.Mutex // Keeps an older snapshot from overwriting a newer one
+	dir            string
+	namespaces     map[string]*metadataCacheNamespace
+	flushScheduled bool
+}
+
+var (
+	globalMetadataCache     *MetadataCache
+	globalMetadataCacheOnce sync.Once
+)
+
+// GetMetadataCache returns the global metadata cache. It is memory-only
+// until SetDataDir is called.
+func GetMetadataCache() *MetadataCache {
+	globalMetadataCacheOnce.Do(func() {
+		globalMetadataCache = newMetadataCache(metadataCacheNamespaces)
+	})
+	return globalMetadataCache
+}
+
+func newMetadataCache(configs map[string]CacheNamespaceConfig) *MetadataCache {
+	c := &MetadataCache{namespaces: make(map[string]*metadataCacheNamespace, len(configs))}
+	for name, config := range configs {
+		c.namespaces[name] = &metadataCacheNamespace{
+			config:  config,
+			entries: make(map[string]*list.Element),
+			lru:     list.New(),
+		}
+	}
+	return c
+}
+
+// metadataCacheFileName returns the file of a key; keys may hold any characters
+func metadataCacheFileName(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:16]) + ".json"
+}
+
+// filePathLocked returns the file of one entry, or "" when entries have no
+// file of their own
+func (c *MetadataCache) filePathLocked(namespace, key string) string {
+	if c.dir == "" || c.namespaces[namespace].config.SingleFile {
+		return ""
+	}
+	return filepath.Join(c.dir, namespace, metadataCacheFileName(key))
+}
+
+func (c *MetadataCache) namespaceFilePath(namespace string) string {
+	return filepath.Join(c.dir, namespace+".json")
+}
+
+// markDirtyLocked records a change to a SingleFile namespace and schedules
+// a Flush
+func (c *MetadataCache) markDirtyLocked(ns *metadataCacheNamespace) {
+	if !ns.config.SingleFile || c.dir == "" {
+		return
+	}
+	ns.dirty = true
+	if !c.flushScheduled {
+		c.flushScheduled = true
+		time.AfterFunc(metadataCacheFlushDelay, c.Flush)
+	}
+}
+
+// SetDataDir stores the cache under dataDir and loads the entries saved there
+func (c *MetadataCache) SetDataDir(dataDir string) error {
+	dir := filepath.Join(dataDir, metadataCacheDirName)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.dir = dir
+	now := time.Now()
+	loaded := 0
+	for name, ns := range c.namespaces {
+		if ns.config.SingleFile {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create cache directory: %w", err)
+			}
+			loaded += c.loadNamespaceFileLocked(name, ns, now)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			return fmt.Errorf("failed to create cache directory: %w", err)
+		}
+		loaded += c.loadNamespaceLocked(name, ns, now)
+	}
+	GoLog("[MetadataCache] Loaded %d entries from %s\n", loaded, dir)
+	return nil
+}
+
+// loadNamespaceLocked reads a namespace's files, least recently used first
+// by modification time, and drops expired or unreadable ones
+func (c *MetadataCache) loadNamespaceLocked(name string, ns *metadataCacheNamespace, now time.Time) int {
+	nsDir := filepath.Join(c.dir, name)
+	dirEntries, err := os.ReadDir(nsDir)
+	if err != nil {
+		return 0
+	}
+
+	type savedFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []savedFile
+	for _, entry := range dirEntries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, savedFile{filepath.Join(nsDir, entry.Name()), info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	loaded := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file.path)
+		if err != nil {
+			continue
+		}
+		var record metadataCacheRecord
+		if json.Unmarshal(data, &record) != nil || record.Key == "" || record.expired(now) {
+			os.Remove(file.path)
+			continue
+		}
+		ns.putLocked(&record)
+		loaded++
+	}
+	for _, evicted := range ns.evictLocked() {
+		os.Remove(filepath.Join(nsDir, metadataCacheFileName(evicted)))
+	}
+	return loaded
+}
+
+// loadNamespaceFileLocked reads a SingleFile namespace, which is saved least
+// recently used first
+func (c *MetadataCache) loadNamespaceFileLocked(name string, ns *metadataCacheNamespace, now time.Time) int {
+	data, err := os.ReadFile(c.namespaceFilePath(name))
+	if err != nil {
+		return 0
+	}
+	var records []*metadataCacheRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		GoLog("[MetadataCache] Ignoring unreadable %s file: %v\n", name, err)
+		return 0
+	}
+
+	loaded := 0
+	for _, record := range records {
+		if record == nil || record.Key == "" || record.expired(now) {
+			ns.dirty = true
+			continue
+		}
+		ns.putLocked(record)
+		loaded++
+	}
+	if len(ns.evictLocked()) > 0 {
+		ns.dirty = true
+	}
+	return loaded
+}
+
+func (r *metadataCacheRecord) size() int64 {
+	return int64(len(r.Key) + len(r.Value))
+}
+
+// putLocked stores a record as the most recently used
+func (ns *metadataCacheNamespace) putLocked(record *metadataCacheRecord) {
+	if elem, ok := ns.entries[record.Key]; ok {
+		ns.removeLocked(elem)
+	}
+	ns.entries[record.Key] = ns.lru.PushFront(record)
+	ns.bytes += record.size()
+}
+
+func (ns *metadataCacheNamespace) removeLocked(elem *list.Element) {
+	record := ns.lru.Remove(elem).(*metadataCacheRecord)
+	delete(ns.entries, record.Key)
+	ns.bytes -= record.size()
+}
+
+// evictLocked drops least recently used entries until the namespace is within
+// its limits and returns their keys
+func (ns *metadataCacheNamespace) evictLocked() []string {
+	var evicted []string
+	for ns.lru.Len() > 0 &&
+		((ns.config.MaxEntries > 0 && ns.lru.Len() > ns.config.MaxEntries) ||
+			(ns.config.MaxBytes > 0 && ns.bytes > ns.config.MaxBytes)) {
+		oldest := ns.lru.Back()
+		evicted = append(evicted, oldest.Value.(*metadataCacheRecord).Key)
+		ns.removeLocked(oldest)
+		ns.evictions++
+	}
+	return evicted
+}
+
+// Get decodes the cached value of key into dst and reports whether it was
+// found and not expired
+func (c *MetadataCache) Get(namespace, key string, dst interface{}) bool {
+	c.mu.Lock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		c.mu.Unlock()
+		return false
+	}
+
+	elem, ok := ns.entries[key]
+	if !ok {
+		ns.misses++
+		c.mu.Unlock()
+		return false
+	}
+	record := elem.Value.(*metadataCacheRecord)
+	filePath := c.filePathLocked(namespace, key)
+	if record.expired(time.Now()) {
+		ns.removeLocked(elem)
+		ns.misses++
+		c.markDirtyLocked(ns)
+		c.mu.Unlock()
+		if filePath != "" {
+			os.Remove(filePath)
+		}
+		return false
+	}
+	ns.lru.MoveToFront(elem)
+	ns.hits++
+	value := record.Value
+	c.mu.Unlock()
+
+	if err := json.Unmarshal(value, dst); err != nil {
+		GoLog("[MetadataCache] Dropping unreadable %s entry %q: %v\n", namespace, key, err)
+		c.Delete(namespace, key)
+		return false
+	}
+	// The modification time keeps the recency order across restarts.
+	// SingleFile namespaces save the order with their next change.
+	if filePath != "" {
+		now := time.Now()
+		os.Chtimes(filePath, now, now)
+	}
+	return true
+}
+
+// Set caches value under key for the namespace's TTL
+func (c *MetadataCache) Set(namespace, key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		GoLog("[MetadataCache] Failed to encode %s entry %q: %v\n", namespace, key, err)
+		return
+	}
+
+	c.mu.Lock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		c.mu.Unlock()
+		GoLog("[MetadataCache] Unknown namespace %s\n", namespace)
+		return
+	}
+	record := &metadataCacheRecord{
+		Key:       key,
+		Value:     data,
+		ExpiresAt: time.Now().Add(ns.config.TTL).UnixMilli(),
+	}
+	ns.putLocked(record)
+	evicted := ns.evictLocked()
+	filePath := c.filePathLocked(namespace, key)
+	c.markDirtyLocked(ns)
+	dir := c.dir
+	c.mu.Unlock()
+
+	if filePath == "" {
+		return
+	}
+	for _, evictedKey := range evicted {
+		os.Remove(filepath.Join(dir, namespace, metadataCacheFileName(evictedKey)))
+	}
+	fileData, err := json.Marshal(record)
+	if err == nil {
+		err = writeFileAtomic(filePath, fileData)
+	}
+	if err != nil {
+		GoLog("[MetadataCache] Failed to save %s entry: %v\n", namespace, err)
+	}
+}
+
+// Delete removes one entry
+func (c *MetadataCache) Delete(namespace, key string) {
+	c.mu.Lock()
+	ns, ok := c.namespaces[namespace]
+	if !ok {
+		c.mu.Unlock()
+		return
+	}
+	if elem, ok := ns.entries[key]; ok {
+		ns.removeLocked(elem)
+		c.markDirtyLocked(ns)
+	}
+	filePath := c.filePathLocked(namespace, key)
+	c.mu.Unlock()
+
+	if filePath != "" {
+		os.Remove(filePath)
+	}
+}
+
+// Clear empties one namespace, or every namespace when namespace is ""
+func (c *MetadataCache) Clear(namespace string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if namespace != "" {
+		if _, ok := c.namespaces[namespace]; !ok {
+			return fmt.Errorf("unknown cache namespace: %s", namespace)
+		}
+	}
+
+	for name, ns := range c.namespaces {
+		if namespace != "" && name != namespace {
+			continue
+		}
+		ns.entries = make(map[string]*list.Element)
+		ns.lru.Init()
+		ns.bytes = 0
+		ns.dirty = false
+		if c.dir == "" {
+			continue
+		}
+		if ns.config.SingleFile {
+			if err := os.Remove(c.namespaceFilePath(name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to clear %s: %w", name, err)
+			}
+			continue
+		}
+		nsDir := filepath.Join(c.dir, name)
+		if err := os.RemoveAll(nsDir); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", name, err)
+		}
+		if err := os.MkdirAll(nsDir, 0755); err != nil {
+			return err
+		}
+	}
+	GoLog("[MetadataCache] Cleared %q\n", namespace)
+	return nil
+}
+
+// Flush saves the SingleFile namespaces that changed since they were last
+// saved
+func (c *MetadataCache) Flush() {
+	c.flushMu.Lock()
+	defer c.flushMu.Unlock()
+
+	type snapshot struct {
+		name    string
+		records []*metadataCacheRecord
+	}
+	var pending []snapshot
+
+	c.mu.Lock()
+	c.flushScheduled = false
+	dir := c.dir
+	for name, ns := range c.namespaces {
+		if !ns.dirty || dir == "" {
+			continue
+		}
+		records := make([]*metadataCacheRecord, 0, ns.lru.Len())
+		for elem := ns.lru.Back(); elem != nil; elem = elem.Prev() {
+			records = append(records, elem.Value.(*metadataCacheRecord))
+		}
+		pending = append(pending, snapshot{name, records})
+		ns.dirty = false
+	}
+	c.mu.Unlock()
+
+	for _, p := range pending {
+		data, err := json.Marshal(p.records)
+		if err == nil {
+			err = writeFileAtomic(filepath.Join(dir, p.name+".json"), data)
+		}
+		if err != nil {
+			GoLog("[MetadataCache] Failed to save %s: %v\n", p.name, err)
+		}
+	}
+}
+
+// Stats returns entry counts, sizes and hit rates per namespace
+func (c *MetadataCache) Stats() map[string]CacheNamespaceStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := make(map[string]CacheNamespaceStats, len(c.namespaces))
+	for name, ns := range c.namespaces {
+		stats[name] = CacheNamespaceStats{
+			Entries:    ns.lru.Len(),
+			Bytes:      ns.bytes,
+			MaxEntries: ns.config.MaxEntries,
+			MaxBytes:   ns.config.MaxBytes,
+			TTLSeconds: int64(ns.config.TTL / time.Second),
+			Hits:       ns.hits,
+			Misses:     ns.misses,
+			Evictions:  ns.evictions,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,157 @@
+package gobackend
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMetadataCachePersists(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]CacheNamespaceConfig{CacheSpotifyAlbum: {TTL: time.Hour, MaxEntries: 10}}
+
+	album := &AlbumResponsePayload{
+		AlbumInfo: AlbumInfoMetadata{Name: "Album", TotalTracks: 2},
+		TrackList: []AlbumTrackMetadata{{SpotifyID: "t1", Name: "One", ISRC: "USRC17607839"}},
+	}
+	cache := newMetadataCache(configs)
+	if err := cache.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set(CacheSpotifyAlbum, "album:1", album)
+
+	// A new session reads the entry back from disk
+	reloaded := newMetadataCache(configs)
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	var got AlbumResponsePayload
+	if !reloaded.Get(CacheSpotifyAlbum, "album:1", &got) {
+		t.Fatal("entry not loaded from disk")
+	}
+	if !reflect.DeepEqual(&got, album) {
+		t.Errorf("got %+v, want %+v", got, album)
+	}
+
+	if err := reloaded.Clear(""); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded.Get(CacheSpotifyAlbum, "album:1", &got) {
+		t.Error("entry survived Clear")
+	}
+	files, _ := os.ReadDir(filepath.Join(dir, metadataCacheDirName, CacheSpotifyAlbum))
+	if len(files) != 0 {
+		t.Errorf("%d files left after Clear", len(files))
+	}
+}
+
+func TestMetadataCacheExpiry(t *testing.T) {
+	dir := t.TempDir()
+	cache := newMetadataCache(map[string]CacheNamespaceConfig{CacheDeezerISRC: {TTL: time.Hour}})
+	if err := cache.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set(CacheDeezerISRC, "1", "USRC17607839")
+
+	// Age the entry past its TTL
+	cache.namespaces[CacheDeezerISRC].entries["1"].Value.(*metadataCacheRecord).ExpiresAt = time.Now().Add(-time.Second).UnixMilli()
+	var isrc string
+	if cache.Get(CacheDeezerISRC, "1", &isrc) {
+		t.Error("expired entry returned")
+	}
+	if _, err := os.Stat(filepath.Join(dir, metadataCacheDirName, CacheDeezerISRC, metadataCacheFileName("1"))); !os.IsNotExist(err) {
+		t.Error("expired entry file not removed")
+	}
+	if stats := cache.Stats()[CacheDeezerISRC]; stats.Misses != 1 || stats.Entries != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestMetadataCacheLRUEviction(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]CacheNamespaceConfig{CacheDeezerSearch: {TTL: time.Hour, MaxEntries: 2}}
+	cache := newMetadataCache(configs)
+	if err := cache.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var v string
+	cache.Set(CacheDeezerSearch, "a", "A")
+	cache.Set(CacheDeezerSearch, "b", "B")
+	cache.Get(CacheDeezerSearch, "a", &v) // "b" is now least recently used
+	cache.Set(CacheDeezerSearch, "c", "C")
+
+	if cache.Get(CacheDeezerSearch, "b", &v) {
+		t.Error("least recently used entry was kept")
+	}
+	for _, key := range []string{"a", "c"} {
+		if !cache.Get(CacheDeezerSearch, key, &v) {
+			t.Errorf("%s was evicted", key)
+		}
+	}
+	stats := cache.Stats()[CacheDeezerSearch]
+	if stats.Entries != 2 || stats.Evictions != 1 || stats.Hits != 3 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if _, err := os.Stat(filepath.Join(dir, metadataCacheDirName, CacheDeezerSearch, metadataCacheFileName("b"))); !os.IsNotExist(err) {
+		t.Error("evicted entry file not removed")
+	}
+
+	// Byte caps evict too
+	small := newMetadataCache(map[string]CacheNamespaceConfig{CacheDeezerSearch: {TTL: time.Hour, MaxBytes: 20}})
+	small.Set(CacheDeezerSearch, "a", "0123456789")
+	small.Set(CacheDeezerSearch, "b", "0123456789")
+	if small.Get(CacheDeezerSearch, "a", &v) || !small.Get(CacheDeezerSearch, "b", &v) {
+		t.Error("byte cap should evict the older entry only")
+	}
+
+	if err := cache.Clear("unknown"); err == nil {
+		t.Error("expected an error for an unknown namespace")
+	}
+}
+
+func TestMetadataCacheSingleFileNamespace(t *testing.T) {
+	dir := t.TempDir()
+	configs := map[string]CacheNamespaceConfig{CacheSpotifyISRC: {TTL: time.Hour, MaxEntries: 100, SingleFile: true}}
+	cache := newMetadataCache(configs)
+	if err := cache.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	cache.Set(CacheSpotifyISRC, "t1", "USRC17607839")
+	cache.Set(CacheSpotifyISRC, "t2", "GBAYE0601498")
+	cache.Set(CacheSpotifyISRC, "t3", "USUM71703861")
+	cache.Delete(CacheSpotifyISRC, "t2")
+	cache.Flush()
+
+	// All entries share one file
+	if _, err := os.Stat(filepath.Join(dir, metadataCacheDirName, CacheSpotifyISRC+".json")); err != nil {
+		t.Fatalf("namespace file not written: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, metadataCacheDirName, CacheSpotifyISRC)); !os.IsNotExist(err) {
+		t.Error("per-entry directory created for a single-file namespace")
+	}
+
+	reloaded := newMetadataCache(configs)
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	var isrc string
+	if !reloaded.Get(CacheSpotifyISRC, "t3", &isrc) || isrc != "USUM71703861" {
+		t.Errorf("t3 = %q", isrc)
+	}
+	if reloaded.Get(CacheSpotifyISRC, "t2", &isrc) {
+		t.Error("deleted entry was saved")
+	}
+	if stats := reloaded.Stats()[CacheSpotifyISRC]; stats.Entries != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if err := reloaded.Clear(CacheSpotifyISRC); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, metadataCacheDirName, CacheSpotifyISRC+".json")); !os.IsNotExist(err) {
+		t.Error("namespace file survived Clear")
+	}
+}
@@ -0,0 +1,409 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// Fields the metadata merge reconciles, as named in MetadataMergeConfig
+const (
+	MergeFieldTitle       = "title"
+	MergeFieldArtist      = "artist"
+	MergeFieldAlbum       = "album"
+	MergeFieldAlbumArtist = "album_artist"
+	MergeFieldReleaseDate = "release_date"
+	MergeFieldTrackNumber = "track_number"
+	MergeFieldTotalTracks = "total_tracks"
+	MergeFieldDiscNumber  = "disc_number"
+	MergeFieldISRC        = "isrc"
+	MergeFieldGenre       = "genre"
+	MergeFieldLabel       = "label"
+	MergeFieldCopyright   = "copyright"
+)
+
+var metadataMergeFields = []string{
+	MergeFieldTitle, MergeFieldArtist, MergeFieldAlbum, MergeFieldAlbumArtist,
+	MergeFieldReleaseDate, MergeFieldTrackNumber, MergeFieldTotalTracks, MergeFieldDiscNumber,
+	MergeFieldISRC, MergeFieldGenre, MergeFieldLabel, MergeFieldCopyright,
+}
+
+// Metadata sources besides the provider IDs ("spotify", "deezer", "qobuz", ...)
+// and extension IDs
+const (
+	// MetadataSourceRequest is the metadata a download was requested with
+	MetadataSourceRequest = "request"
+	// MetadataSourceExtension stands for any extension in a priority list
+	MetadataSourceExtension = "extension"
+)
+
+// MetadataValues is one source's view of a track's tags. Empty strings and
+// zero numbers mean the source has no value.
+type MetadataValues struct {
+	Title       string `json:"title,omitempty"`
+	Artist      string `json:"artist,omitempty"`
+	Album       string `json:"album,omitempty"`
+	AlbumArtist string `json:"album_artist,omitempty"`
+	ReleaseDate string `json:"release_date,omitempty"`
+	TrackNumber int    `json:"track_number,omitempty"`
+	TotalTracks int    `json:"total_tracks,omitempty"`
+	DiscNumber  int    `json:"disc_number,omitempty"`
+	ISRC        string `json:"isrc,omitempty"`
+	Genre       string `json:"genre,omitempty"`
+	Label       string `json:"label,omitempty"`
+	Copyright   string `json:"copyright,omitempty"`
+}
+
+// get returns a field as a string, "" when unset
+func (v MetadataValues) get(field string) string {
+	number := func(n int) string {
+		if n <= 0 {
+			return ""
+		}
+		return strconv.Itoa(n)
+	}
+
+	switch field {
+	case MergeFieldTitle:
+		return v.Title
+	case MergeFieldArtist:
+		return v.Artist
+	case MergeFieldAlbum:
+		return v.Album
+	case MergeFieldAlbumArtist:
+		return v.AlbumArtist
+	case MergeFieldReleaseDate:
+		return v.ReleaseDate
+	case MergeFieldTrackNumber:
+		return number(v.TrackNumber)
+	case MergeFieldTotalTracks:
+		return number(v.TotalTracks)
+	case MergeFieldDiscNumber:
+		return number(v.DiscNumber)
+	case MergeFieldISRC:
+		return v.ISRC
+	case MergeFieldGenre:
+		return v.Genre
+	case MergeFieldLabel:
+		return v.Label
+	case MergeFieldCopyright:
+		return v.Copyright
+	}
+	return ""
+}
+
+// set stores a value returned by get
+func (v *MetadataValues) set(field, value string) {
+	switch field {
+	case MergeFieldTitle:
+		v.Title = value
+	case MergeFieldArtist:
+		v.Artist = value
+	case MergeFieldAlbum:
+		v.Album = value
+	case MergeFieldAlbumArtist:
+		v.AlbumArtist = value
+	case MergeFieldReleaseDate:
+		v.ReleaseDate = value
+	case MergeFieldTrackNumber:
+		v.TrackNumber, _ = strconv.Atoi(value)
+	case MergeFieldTotalTracks:
+		v.TotalTracks, _ = strconv.Atoi(value)
+	case MergeFieldDiscNumber:
+		v.DiscNumber, _ = strconv.Atoi(value)
+	case MergeFieldISRC:
+		v.ISRC = value
+	case MergeFieldGenre:
+		v.Genre = value
+	case MergeFieldLabel:
+		v.Label = value
+	case MergeFieldCopyright:
+		v.Copyright = value
+	}
+}
+
+// MetadataCandidate is the metadata one source offers for a track
+type MetadataCandidate struct {
+	Source string         `json:"source"`
+	Values MetadataValues `json:"values"`
+}
+
+// MergedMetadata is the merged result and, for every field that got a value,
+// the source that supplied it
+type MergedMetadata struct {
+	MetadataValues
+	Sources map[string]string `json:"sources"`
+}
+
+// MetadataMergeConfig orders the sources each field is taken from. Fields
+// not listed, and sources a field's list leaves out, follow Default.
+// "extension" in a list matches any extension ID.
+type MetadataMergeConfig struct {
+	Default []string            `json:"default"`
+	Fields  map[string][]string `json:"fields,omitempty"`
+}
+
+// DefaultMetadataMergeConfig keeps the requested metadata unless a field is
+// better known elsewhere: extensions resolve real titles and ISRCs, and the
+// downloaded service knows its own album layout
+func DefaultMetadataMergeConfig() MetadataMergeConfig {
+	return MetadataMergeConfig{
+		Default: []string{MetadataSourceRequest, MetadataSourceExtension, "spotify", "deezer", "qobuz", "tidal", "amazon"},
+		Fields: map[string][]string{
+			MergeFieldTitle:       {MetadataSourceExtension},
+			MergeFieldArtist:      {MetadataSourceExtension},
+			MergeFieldISRC:        {MetadataSourceExtension},
+			MergeFieldAlbumArtist: {"qobuz"},
+			MergeFieldReleaseDate: {"qobuz"},
+			MergeFieldTrackNumber: {"qobuz", "tidal", "amazon"},
+			MergeFieldTotalTracks: {"qobuz"},
+			MergeFieldDiscNumber:  {"qobuz", "tidal", "amazon"},
+		},
+	}
+}
+
+func (c MetadataMergeConfig) validate() error {
+	for field, sources := range c.Fields {
+		if !isMetadataMergeField(field) {
+			return fmt.Errorf("unknown metadata field %q", field)
+		}
+		for _, source := range sources {
+			if source == "" {
+				return fmt.Errorf("empty source in %s priority", field)
+			}
+		}
+	}
+	for _, source := range c.Default {
+		if source == "" {
+			return fmt.Errorf("empty source in default priority")
+		}
+	}
+	return nil
+}
+
+func isMetadataMergeField(field string) bool {
+	for _, f := range metadataMergeFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// isExtensionMetadataSource reports whether a source is an extension rather
+// than a built-in metadata or download provider
+func isExtensionMetadataSource(source string) bool {
+	switch source {
+	case MetadataSourceRequest, "spotify", "deezer", MetadataProviderMusicBrainz:
+		return false
+	}
+	return !isBuiltInProvider(source)
+}
+
+// rank returns a source's position for a field, lower first. Unlisted
+// sources rank last.
+func (c MetadataMergeConfig) rank(field, source string) int {
+	order := append(append([]string(nil), c.Fields[field]...), c.Default...)
+	for i, s := range order {
+		if s == source || (s == MetadataSourceExtension && isExtensionMetadataSource(source)) {
+			return i
+		}
+	}
+	return len(order)
+}
+
+// merge takes each field from the highest ranked candidate that has it.
+// Candidates of equal rank keep their order.
+func (c MetadataMergeConfig) merge(candidates []MetadataCandidate) MergedMetadata {
+	merged := MergedMetadata{Sources: make(map[string]string)}
+	for _, field := range metadataMergeFields {
+		best, bestRank := -1, 0
+		for i, candidate := range candidates {
+			if candidate.Values.get(field) == "" {
+				continue
+			}
+			if rank := c.rank(field, candidate.Source); best < 0 || rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best >= 0 {
+			merged.set(field, candidates[best].Values.get(field))
+			merged.Sources[field] = candidates[best].Source
+		}
+	}
+	return merged
+}
+
+var (
+	metadataMergeConfig   = DefaultMetadataMergeConfig()
+	metadataMergeConfigMu sync.RWMutex
+)
+
+// GetMetadataMergeConfig returns the field priorities in use
+func GetMetadataMergeConfig() MetadataMergeConfig {
+	metadataMergeConfigMu.RLock()
+	defer metadataMergeConfigMu.RUnlock()
+	return metadataMergeConfig
+}
+
+// SetMetadataMergeConfig replaces the field priorities. An empty default
+// list keeps the built-in one.
+func SetMetadataMergeConfig(config MetadataMergeConfig) error {
+	if err := config.validate(); err != nil {
+		return err
+	}
+	if len(config.Default) == 0 {
+		config.Default = DefaultMetadataMergeConfig().Default
+	}
+
+	metadataMergeConfigMu.Lock()
+	defer metadataMergeConfigMu.Unlock()
+	metadataMergeConfig = config
+	GoLog("[Metadata] Merge priorities set: default %v, fields %v\n", config.Default, config.Fields)
+	return nil
+}
+
+// MergeMetadata merges candidates with the configured field priorities
+func MergeMetadata(candidates []MetadataCandidate) MergedMetadata {
+	return GetMetadataMergeConfig().merge(candidates)
+}
+
+func metadataValuesFromExtTrack(t *ExtTrackMetadata) MetadataValues {
+	return MetadataValues{
+		Title:       t.Name,
+		Artist:      t.Artists,
+		Album:       t.AlbumName,
+		AlbumArtist: t.AlbumArtist,
+		ReleaseDate: t.ReleaseDate,
+		TrackNumber: t.TrackNumber,
+		DiscNumber:  t.DiscNumber,
+		ISRC:        t.ISRC,
+		Genre:       t.Genre,
+		Label:       t.Label,
+		Copyright:   t.Copyright,
+	}
+}
+
+func metadataValuesFromRequest(req DownloadRequest) MetadataValues {
+	return MetadataValues{
+		Title:       req.TrackName,
+		Artist:      req.ArtistName,
+		Album:       req.AlbumName,
+		AlbumArtist: req.AlbumArtist,
+		ReleaseDate: req.ReleaseDate,
+		TrackNumber: req.TrackNumber,
+		TotalTracks: req.TotalTracks,
+		DiscNumber:  req.DiscNumber,
+		ISRC:        req.ISRC,
+		Genre:       req.Genre,
+		Label:       req.Label,
+		Copyright:   req.Copyright,
+	}
+}
+
+// metadataCandidateFromJSON reads a TrackMetadata or ExtTrackMetadata object,
+// whose JSON field names agree
+func metadataCandidateFromJSON(source string, data []byte) (MetadataCandidate, error) {
+	var track struct {
+		ExtTrackMetadata
+		TotalTracks int `json:"total_tracks"`
+	}
+	if err := json.Unmarshal(data, &track); err != nil {
+		return MetadataCandidate{}, err
+	}
+	values := metadataValuesFromExtTrack(&track.ExtTrackMetadata)
+	values.TotalTracks = track.TotalTracks
+	return MetadataCandidate{Source: source, Values: values}, nil
+}
+
+// requestCandidates splits a request's metadata back into the sources that
+// supplied it, so an earlier merge's provenance carries into the next
+func requestCandidates(req DownloadRequest) []MetadataCandidate {
+	values := metadataValuesFromRequest(req)
+	var candidates []MetadataCandidate
+	index := make(map[string]int)
+	for _, field := range metadataMergeFields {
+		value := values.get(field)
+		if value == "" {
+			continue
+		}
+		source := req.MetadataSources[field]
+		if source == "" {
+			source = MetadataSourceRequest
+		}
+		i, ok := index[source]
+		if !ok {
+			i = len(candidates)
+			index[source] = i
+			candidates = append(candidates, MetadataCandidate{Source: source})
+		}
+		candidates[i].Values.set(field, value)
+	}
+	return candidates
+}
+
+// mergeRequestMetadata merges other sources into a request's metadata
+func mergeRequestMetadata(req DownloadRequest, others ...MetadataCandidate) MergedMetadata {
+	return MergeMetadata(append(requestCandidates(req), others...))
+}
+
+// applyToRequest replaces a request's metadata with the merged values
+func (m MergedMetadata) applyToRequest(req *DownloadRequest) {
+	for field, source := range m.Sources {
+		if req.MetadataSources[field] != source && source != MetadataSourceRequest {
+			GoLog("[Metadata] %s from %s: %s\n", field, source, m.get(field))
+		}
+	}
+	req.TrackName = m.Title
+	req.ArtistName = m.Artist
+	req.AlbumName = m.Album
+	req.AlbumArtist = m.AlbumArtist
+	req.ReleaseDate = m.ReleaseDate
+	req.TrackNumber = m.TrackNumber
+	req.TotalTracks = m.TotalTracks
+	req.DiscNumber = m.DiscNumber
+	req.ISRC = m.ISRC
+	req.Genre = m.Genre
+	req.Label = m.Label
+	req.Copyright = m.Copyright
+	req.MetadataSources = m.Sources
+}
+
+// toMetadata returns the merged values as file tags
+func (m MergedMetadata) toMetadata() Metadata {
+	return Metadata{
+		Title:       m.Title,
+		Artist:      m.Artist,
+		Album:       m.Album,
+		AlbumArtist: m.AlbumArtist,
+		Date:        m.ReleaseDate,
+		TrackNumber: m.TrackNumber,
+		TotalTracks: m.TotalTracks,
+		DiscNumber:  m.DiscNumber,
+		ISRC:        m.ISRC,
+		Genre:       m.Genre,
+		Label:       m.Label,
+		Copyright:   m.Copyright,
+	}
+}
+
+// downloadResult reports the merged tags of a finished download
+func (m MergedMetadata) downloadResult(filePath string, bitDepth, sampleRate int) DownloadResult {
+	return DownloadResult{
+		FilePath:        filePath,
+		BitDepth:        bitDepth,
+		SampleRate:      sampleRate,
+		Title:           m.Title,
+		Artist:          m.Artist,
+		Album:           m.Album,
+		ReleaseDate:     m.ReleaseDate,
+		TrackNumber:     m.TrackNumber,
+		DiscNumber:      m.DiscNumber,
+		ISRC:            m.ISRC,
+		Genre:           m.Genre,
+		Label:           m.Label,
+		Copyright:       m.Copyright,
+		MetadataSources: m.Sources,
+	}
+}
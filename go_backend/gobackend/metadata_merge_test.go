@@ -0,0 +1,117 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergeMetadataFieldPriority(t *testing.T) {
+	config := MetadataMergeConfig{
+		Default: []string{"spotify", "deezer", "qobuz"},
+		Fields: map[string][]string{
+			MergeFieldGenre: {"deezer"},
+			MergeFieldLabel: {"qobuz"},
+			MergeFieldISRC:  {"spotify"},
+		},
+	}
+	candidates := []MetadataCandidate{
+		{Source: "qobuz", Values: MetadataValues{Title: "Song (Remastered)", ISRC: "QOBUZ0000001", Label: "Qobuz Label", TrackNumber: 4}},
+		{Source: "deezer", Values: MetadataValues{Title: "Song", Genre: "Rock", Label: "Deezer Label", ISRC: "DEEZER000001"}},
+		{Source: "spotify", Values: MetadataValues{Title: "Song", Artist: "Artist", Genre: "", ISRC: "SPOTIFY00001"}},
+	}
+
+	merged := config.merge(candidates)
+	want := MetadataValues{Title: "Song", Artist: "Artist", ISRC: "SPOTIFY00001", Genre: "Rock", Label: "Qobuz Label", TrackNumber: 4}
+	if merged.MetadataValues != want {
+		t.Errorf("merged = %+v\nwant %+v", merged.MetadataValues, want)
+	}
+	wantSources := map[string]string{
+		MergeFieldTitle:       "spotify",
+		MergeFieldArtist:      "spotify",
+		MergeFieldISRC:        "spotify",
+		MergeFieldGenre:       "deezer",
+		MergeFieldLabel:       "qobuz",
+		MergeFieldTrackNumber: "qobuz",
+	}
+	if !reflect.DeepEqual(merged.Sources, wantSources) {
+		t.Errorf("sources = %v, want %v", merged.Sources, wantSources)
+	}
+}
+
+func TestMergeMetadataExtensionSource(t *testing.T) {
+	config := DefaultMetadataMergeConfig()
+	candidates := []MetadataCandidate{
+		{Source: MetadataSourceRequest, Values: MetadataValues{Title: "song", ISRC: "FAKE", Genre: "Pop"}},
+		{Source: "my-ext", Values: MetadataValues{Title: "Song", ISRC: "USRC17607839", Genre: "Dance", Label: "Label"}},
+	}
+
+	merged := config.merge(candidates)
+	// Extensions win titles and ISRCs by default, the request keeps the rest
+	if merged.Title != "Song" || merged.ISRC != "USRC17607839" || merged.Sources[MergeFieldISRC] != "my-ext" {
+		t.Errorf("title %q, isrc %q from %s", merged.Title, merged.ISRC, merged.Sources[MergeFieldISRC])
+	}
+	if merged.Genre != "Pop" || merged.Label != "Label" || merged.Sources[MergeFieldLabel] != "my-ext" {
+		t.Errorf("genre %q, label %q from %s", merged.Genre, merged.Label, merged.Sources[MergeFieldLabel])
+	}
+}
+
+func TestMergeMetadataKeepsRequestIdentity(t *testing.T) {
+	req := DownloadRequest{TrackName: "Song", ArtistName: "Artist", ISRC: "USAAA0000001"}
+	for _, service := range []string{"qobuz", "tidal", "amazon"} {
+		merged := mergeRequestMetadata(req, MetadataCandidate{Source: service, Values: MetadataValues{Title: "Song (Live)", ISRC: "GBBBB0000002"}})
+		// Files are indexed by their tagged ISRC, so it must stay the requested one
+		if merged.ISRC != req.ISRC || merged.Title != req.TrackName || merged.Sources[MergeFieldISRC] != MetadataSourceRequest {
+			t.Errorf("%s: title %q, isrc %q from %s", service, merged.Title, merged.ISRC, merged.Sources[MergeFieldISRC])
+		}
+	}
+}
+
+func TestRequestCandidatesKeepProvenance(t *testing.T) {
+	req := DownloadRequest{TrackName: "Song", ArtistName: "Artist", ISRC: "USRC17607839", TrackNumber: 3}
+	merged := mergeRequestMetadata(req, MetadataCandidate{Source: "deezer", Values: MetadataValues{Genre: "Rock", Label: "Label"}})
+	merged.applyToRequest(&req)
+
+	if req.Genre != "Rock" || req.MetadataSources[MergeFieldGenre] != "deezer" || req.MetadataSources[MergeFieldTitle] != MetadataSourceRequest {
+		t.Fatalf("req = %+v", req)
+	}
+
+	// A service merged afterwards sees the Deezer genre as Deezer's
+	candidates := requestCandidates(req)
+	if len(candidates) != 2 || candidates[1].Source != "deezer" || candidates[1].Values.Genre != "Rock" || candidates[0].Values.TrackNumber != 3 {
+		t.Errorf("candidates = %+v", candidates)
+	}
+	final := mergeRequestMetadata(req, MetadataCandidate{Source: "tidal", Values: MetadataValues{Title: "Song", TrackNumber: 5}})
+	if final.TrackNumber != 5 || final.Sources[MergeFieldTrackNumber] != "tidal" || final.Sources[MergeFieldLabel] != "deezer" {
+		t.Errorf("final = %+v", final)
+	}
+}
+
+func TestSetMetadataMergeConfig(t *testing.T) {
+	defer SetMetadataMergeConfig(DefaultMetadataMergeConfig())
+
+	if err := SetMetadataMergeConfig(MetadataMergeConfig{Fields: map[string][]string{"mood": {"deezer"}}}); err == nil {
+		t.Error("expected an error for an unknown field")
+	}
+	if err := SetMetadataMergeConfigJSON(`{"fields": {"genre": ["deezer"]}}`); err != nil {
+		t.Fatal(err)
+	}
+	if config := GetMetadataMergeConfig(); !reflect.DeepEqual(config.Default, DefaultMetadataMergeConfig().Default) {
+		t.Errorf("empty default should keep the built-in order, got %v", config.Default)
+	}
+
+	out, err := MergeMetadataJSON(`[
+		{"source": "spotify", "track": {"name": "Song", "artists": "Artist", "isrc": "USRC17607839", "total_tracks": 10}},
+		{"source": "deezer", "track": {"name": "Song", "genre": "Rock"}}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var merged MergedMetadata
+	if err := json.Unmarshal([]byte(out), &merged); err != nil {
+		t.Fatal(err)
+	}
+	if merged.Genre != "Rock" || merged.TotalTracks != 10 || merged.Sources[MergeFieldGenre] != "deezer" || merged.Sources[MergeFieldTitle] != "spotify" {
+		t.Errorf("MergeMetadataJSON = %s", out)
+	}
+}
@@ -0,0 +1,308 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	mirrorHealthFileName = "mirror_health.json"
+
+	// mirrorTopK is how many of the healthiest mirrors are queried first
+	mirrorTopK = 3
+	// mirrorCircuitThreshold consecutive failures open a mirror's circuit
+	mirrorCircuitThreshold    = 3
+	mirrorCircuitBaseCooldown = 2 * time.Minute
+	mirrorCircuitMaxCooldown  = time.Hour
+	// mirrorLatencyWeight is the weight of the newest sample in the latency average
+	mirrorLatencyWeight = 0.3
+)
+
+// MirrorHealth is the recorded reliability of one API mirror
+type MirrorHealth struct {
+	URL                 string  `json:"url"`
+	Successes           int64   `json:"successes"`
+	Failures            int64   `json:"failures"`
+	Previews            int64   `json:"previews"`     // Responses that only offered a preview
+	TrackMisses         int64   `json:"track_misses"` // Previews and 404s; these say nothing about the mirror
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	Trips               int     `json:"trips"`                  // Times the circuit opened since the last success
+	AvgLatencyMS        float64 `json:"avg_latency_ms"`         // Moving average of successful responses
+	LastSuccess         int64   `json:"last_success,omitempty"` // Unix milliseconds
+	LastFailure         int64   `json:"last_failure,omitempty"` // Unix milliseconds
+	LastError           string  `json:"last_error,omitempty"`
+	CircuitOpenUntil    int64   `json:"circuit_open_until,omitempty"` // Unix milliseconds
+
+	// Computed when listing
+	Score       float64 `json:"score"`
+	CircuitOpen bool    `json:"circuit_open"`
+}
+
+// successRate is smoothed so untried mirrors start at 0.5
+func (m *MirrorHealth) successRate() float64 {
+	return float64(m.Successes+1) / float64(m.Successes+m.Failures+2)
+}
+
+// score ranks mirrors: reliable and fast is best. Latency halves the score
+// at two seconds.
+func (m *MirrorHealth) score() float64 {
+	latency := m.AvgLatencyMS
+	if m.Successes == 0 {
+		latency = 2000
+	}
+	return m.successRate() / (1 + latency/2000)
+}
+
+func (m *MirrorHealth) circuitOpen(now time.Time) bool {
+	return m.CircuitOpenUntil > now.UnixMilli()
+}
+
+// MirrorHealthStore tracks API mirror health and persists it across sessions
+type MirrorHealthStore struct {
+	mu       sync.Mutex
+	filePath string
+	mirrors  map[string]*MirrorHealth
+	dirty    bool
+}
+
+var (
+	globalMirrorHealthStore     *MirrorHealthStore
+	globalMirrorHealthStoreOnce sync.Once
+)
+
+// GetMirrorHealthStore returns the global mirror health store. Scores are kept
+// in memory until SetDataDir enables persistence.
+func GetMirrorHealthStore() *MirrorHealthStore {
+	globalMirrorHealthStoreOnce.Do(func() {
+		globalMirrorHealthStore = &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	})
+	return globalMirrorHealthStore
+}
+
+// SetDataDir sets the directory of the health file and loads saved scores
+func (s *MirrorHealthStore) SetDataDir(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create mirror health directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filePath = filepath.Join(dataDir, mirrorHealthFileName)
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var saved []MirrorHealth
+	if err := json.Unmarshal(data, &saved); err != nil {
+		GoLog("[MirrorHealth] Ignoring unreadable health file: %v\n", err)
+		return nil
+	}
+	for i := range saved {
+		m := saved[i]
+		s.mirrors[m.URL] = &m
+	}
+	GoLog("[MirrorHealth] Loaded health for %d mirrors\n", len(saved))
+	return nil
+}
+
+func (s *MirrorHealthStore) getLocked(url string) *MirrorHealth {
+	m, ok := s.mirrors[url]
+	if !ok {
+		m = &MirrorHealth{URL: url}
+		s.mirrors[url] = m
+	}
+	return m
+}
+
+// RecordSuccess records a usable response from a mirror
+func (s *MirrorHealthStore) RecordSuccess(url string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.getLocked(url)
+	ms := float64(latency.Milliseconds())
+	if m.Successes == 0 {
+		m.AvgLatencyMS = ms
+	} else {
+		m.AvgLatencyMS = mirrorLatencyWeight*ms + (1-mirrorLatencyWeight)*m.AvgLatencyMS
+	}
+	m.Successes++
+	m.ConsecutiveFailures = 0
+	m.Trips = 0
+	m.CircuitOpenUntil = 0
+	m.LastSuccess = time.Now().UnixMilli()
+	s.dirty = true
+}
+
+// RecordFailure records an error or unusable response from the mirror.
+// mirrorCircuitThreshold consecutive failures open the mirror's circuit, for
+// longer each time it trips again without a success in between. Once the
+// cooldown has passed the mirror is half-open: its next failure counts as the
+// first strike of a new run, not as one more on top of the old run.
+func (s *MirrorHealthStore) RecordFailure(url string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	m := s.getLocked(url)
+	m.Failures++
+	if m.CircuitOpenUntil != 0 && !m.circuitOpen(now) {
+		m.ConsecutiveFailures = 0
+		m.CircuitOpenUntil = 0
+	}
+	m.ConsecutiveFailures++
+	m.LastFailure = now.UnixMilli()
+	if err != nil {
+		m.LastError = err.Error()
+	}
+
+	if m.ConsecutiveFailures >= mirrorCircuitThreshold && !m.circuitOpen(now) {
+		m.Trips++
+		cooldown := time.Duration(float64(mirrorCircuitBaseCooldown) * math.Pow(2, float64(m.Trips-1)))
+		cooldown = min(cooldown, mirrorCircuitMaxCooldown)
+		m.CircuitOpenUntil = now.Add(cooldown).UnixMilli()
+		GoLog("[MirrorHealth] Circuit open for %s for %v (%d consecutive failures)\n", url, cooldown, m.ConsecutiveFailures)
+	}
+	s.dirty = true
+}
+
+// RecordTrackMiss records a response that only concerns the requested track,
+// such as a preview of a region-locked track or a 404. The mirror answered, so
+// this neither counts as a failure nor moves it towards an open circuit.
+func (s *MirrorHealthStore) RecordTrackMiss(url string, err error, preview bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := s.getLocked(url)
+	m.TrackMisses++
+	if preview {
+		m.Previews++
+	}
+	if err != nil {
+		m.LastError = err.Error()
+	}
+	s.dirty = true
+}
+
+// RankMirrors orders mirrors into query tiers: the topK healthiest with a
+// closed circuit, the remaining closed ones, then open-circuit mirrors as a
+// last resort. Empty tiers are omitted.
+func (s *MirrorHealthStore) RankMirrors(urls []string, topK int) [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	type ranked struct {
+		url   string
+		score float64
+		open  bool
+	}
+	var closed, open []ranked
+	for _, url := range urls {
+		m := s.getLocked(url)
+		r := ranked{url: url, score: m.score(), open: m.circuitOpen(now)}
+		if r.open {
+			open = append(open, r)
+		} else {
+			closed = append(closed, r)
+		}
+	}
+	byScore := func(list []ranked) {
+		sort.SliceStable(list, func(i, j int) bool { return list[i].score > list[j].score })
+	}
+	byScore(closed)
+	byScore(open)
+
+	urlsOf := func(list []ranked) []string {
+		result := make([]string, len(list))
+		for i, r := range list {
+			result[i] = r.url
+		}
+		return result
+	}
+
+	var tiers [][]string
+	if topK <= 0 || topK > len(closed) {
+		topK = len(closed)
+	}
+	if topK > 0 {
+		tiers = append(tiers, urlsOf(closed[:topK]))
+	}
+	if len(closed) > topK {
+		tiers = append(tiers, urlsOf(closed[topK:]))
+	}
+	if len(open) > 0 {
+		tiers = append(tiers, urlsOf(open))
+	}
+	return tiers
+}
+
+// Snapshot returns the health of the given mirrors, best first
+func (s *MirrorHealthStore) Snapshot(urls []string) []MirrorHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	result := make([]MirrorHealth, 0, len(urls))
+	for _, url := range urls {
+		m := *s.getLocked(url)
+		m.Score = math.Round(m.score()*1000) / 1000
+		m.CircuitOpen = m.circuitOpen(now)
+		result = append(result, m)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result
+}
+
+// Flush saves recorded changes if persistence is enabled
+func (s *MirrorHealthStore) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty || s.filePath == "" {
+		return
+	}
+
+	mirrors := make([]MirrorHealth, 0, len(s.mirrors))
+	for _, m := range s.mirrors {
+		mirrors = append(mirrors, *m)
+	}
+	sort.Slice(mirrors, func(i, j int) bool { return mirrors[i].URL < mirrors[j].URL })
+
+	data, err := json.MarshalIndent(mirrors, "", "  ")
+	if err != nil {
+		return
+	}
+	if err := writeFileAtomic(s.filePath, data); err != nil {
+		GoLog("[MirrorHealth] Failed to save mirror health: %v\n", err)
+		return
+	}
+	s.dirty = false
+}
+
+// Reset forgets all recorded health
+func (s *MirrorHealthStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.mirrors = make(map[string]*MirrorHealth)
+	s.dirty = false
+	if s.filePath == "" {
+		return nil
+	}
+	if err := os.Remove(s.filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
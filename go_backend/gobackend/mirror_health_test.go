@@ -0,0 +1,113 @@
+package gobackend
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMirrorHealthStore_RankingAndCircuit(t *testing.T) {
+	store := &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	mirrors := []string{"https://a", "https://b", "https://c", "https://d"}
+
+	store.RecordSuccess("https://c", 200*time.Millisecond)
+	store.RecordSuccess("https://b", 1500*time.Millisecond)
+	for range mirrorCircuitThreshold {
+		store.RecordFailure("https://a", errors.New("HTTP 500"))
+	}
+	store.RecordTrackMiss("https://d", errors.New("returned PREVIEW instead of FULL"), true)
+
+	tiers := store.RankMirrors(mirrors, 2)
+	want := [][]string{{"https://c", "https://b"}, {"https://d"}, {"https://a"}}
+	if !reflect.DeepEqual(tiers, want) {
+		t.Fatalf("RankMirrors = %v, want %v", tiers, want)
+	}
+
+	snapshot := store.Snapshot(mirrors)
+	if snapshot[0].URL != "https://c" || !snapshot[3].CircuitOpen || snapshot[3].URL != "https://a" {
+		t.Errorf("unexpected snapshot order: %+v", snapshot)
+	}
+	for _, m := range snapshot {
+		if m.URL == "https://d" && m.Previews != 1 {
+			t.Errorf("preview not recorded: %+v", m)
+		}
+	}
+
+	// A success closes the circuit again
+	store.RecordSuccess("https://a", 100*time.Millisecond)
+	if tiers := store.RankMirrors(mirrors, 0); len(tiers) != 1 || len(tiers[0]) != 4 {
+		t.Errorf("expected a single tier after recovery, got %v", tiers)
+	}
+}
+
+func TestMirrorHealthStore_TrackMissesKeepCircuitClosed(t *testing.T) {
+	store := &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	mirrors := []string{"https://a", "https://b"}
+
+	// A region-locked track queried on every mirror, again and again
+	for range mirrorCircuitThreshold * 2 {
+		for _, url := range mirrors {
+			store.RecordTrackMiss(url, errors.New("returned PREVIEW instead of FULL"), true)
+		}
+	}
+	if tiers := store.RankMirrors(mirrors, 0); len(tiers) != 1 || len(tiers[0]) != 2 {
+		t.Errorf("track misses opened a circuit: %v", tiers)
+	}
+	snapshot := store.Snapshot(mirrors)
+	if snapshot[0].ConsecutiveFailures != 0 || snapshot[0].Failures != 0 || snapshot[0].TrackMisses != 6 {
+		t.Errorf("snapshot = %+v", snapshot[0])
+	}
+}
+
+func TestMirrorHealthStore_HalfOpen(t *testing.T) {
+	store := &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	for range mirrorCircuitThreshold {
+		store.RecordFailure("https://a", errors.New("HTTP 500"))
+	}
+	m := store.mirrors["https://a"]
+	if !m.circuitOpen(time.Now()) || m.Trips != 1 {
+		t.Fatalf("circuit not open: %+v", m)
+	}
+
+	// After the cooldown a single failed probe is one strike, not a new trip
+	m.CircuitOpenUntil = time.Now().Add(-time.Second).UnixMilli()
+	store.RecordFailure("https://a", errors.New("HTTP 500"))
+	if m.circuitOpen(time.Now()) || m.ConsecutiveFailures != 1 {
+		t.Fatalf("half-open probe reopened the circuit: %+v", m)
+	}
+
+	// A full run of failures trips it again, for twice as long
+	for range mirrorCircuitThreshold - 1 {
+		store.RecordFailure("https://a", errors.New("HTTP 500"))
+	}
+	cooldown := time.Until(time.UnixMilli(m.CircuitOpenUntil))
+	if m.Trips != 2 || cooldown <= mirrorCircuitBaseCooldown {
+		t.Errorf("second trip: trips %d, cooldown %v", m.Trips, cooldown)
+	}
+
+	store.RecordSuccess("https://a", time.Second)
+	if m.Trips != 0 || m.ConsecutiveFailures != 0 || m.circuitOpen(time.Now()) {
+		t.Errorf("success did not reset the circuit: %+v", m)
+	}
+}
+
+func TestMirrorHealthStore_Persistence(t *testing.T) {
+	dir := t.TempDir()
+	store := &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	if err := store.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	store.RecordSuccess("https://a", 300*time.Millisecond)
+	store.RecordFailure("https://b", errors.New("timeout"))
+	store.Flush()
+
+	reloaded := &MirrorHealthStore{mirrors: make(map[string]*MirrorHealth)}
+	if err := reloaded.SetDataDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	snapshot := reloaded.Snapshot([]string{"https://a", "https://b"})
+	if snapshot[0].URL != "https://a" || snapshot[0].AvgLatencyMS != 300 || snapshot[1].LastError != "timeout" {
+		t.Errorf("health not persisted: %+v", snapshot)
+	}
+}
@@ -0,0 +1,429 @@
+package gobackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MetadataProviderMusicBrainz is the metadata provider priority ID that
+	// turns on MusicBrainz tagging for downloads
+	MetadataProviderMusicBrainz = "musicbrainz"
+
+	musicBrainzCacheTTL = 30 * time.Minute
+	// MusicBrainz asks clients to identify themselves with a contact URL
+	musicBrainzUserAgent = "SpotiFLAC-Mobile/3 ( https://github.com/zarzet/SpotiFLAC-Mobile )"
+)
+
+// MusicBrainzClient looks up recordings and releases on MusicBrainz
+type MusicBrainzClient struct {
+	httpClient *http.Client
+	limiter    *RateLimiter
+	cache      map[string]*cacheEntry
+	cacheMu    sync.RWMutex
+}
+
+var (
+	musicBrainzClient     *MusicBrainzClient
+	musicBrainzClientOnce sync.Once
+)
+
+func GetMusicBrainzClient() *MusicBrainzClient {
+	musicBrainzClientOnce.Do(func() {
+		musicBrainzClient = &MusicBrainzClient{
+			httpClient: NewHTTPClientWithTimeout(15 * time.Second),
+			limiter:    GetMusicBrainzRateLimiter(),
+			cache:      make(map[string]*cacheEntry),
+		}
+	})
+	return musicBrainzClient
+}
+
+type mbArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+type mbReleaseGroup struct {
+	ID               string   `json:"id"`
+	PrimaryType      string   `json:"primary-type"`
+	SecondaryTypes   []string `json:"secondary-types"`
+	FirstReleaseDate string   `json:"first-release-date"`
+}
+
+type mbRecording struct {
+	ID               string           `json:"id"`
+	Title            string           `json:"title"`
+	Length           int              `json:"length"` // in milliseconds
+	FirstReleaseDate string           `json:"first-release-date"`
+	ISRCs            []string         `json:"isrcs"`
+	ArtistCredit     []mbArtistCredit `json:"artist-credit"`
+	Releases         []mbRelease      `json:"releases"`
+}
+
+type mbRelease struct {
+	ID           string           `json:"id"`
+	Score        int              `json:"score"` // Search relevance, 0-100
+	Title        string           `json:"title"`
+	Status       string           `json:"status"`
+	Date         string           `json:"date"`
+	Barcode      string           `json:"barcode"`
+	ArtistCredit []mbArtistCredit `json:"artist-credit"`
+	ReleaseGroup mbReleaseGroup   `json:"release-group"`
+	Media        []struct {
+		Position int `json:"position"`
+		Tracks   []struct {
+			ID        string      `json:"id"`
+			Position  int         `json:"position"`
+			Title     string      `json:"title"`
+			Length    int         `json:"length"`
+			Recording mbRecording `json:"recording"`
+		} `json:"tracks"`
+	} `json:"media"`
+}
+
+// MusicBrainzMetadata holds the MusicBrainz identifiers and release details
+// found for one track
+type MusicBrainzMetadata struct {
+	RecordingID    string   `json:"recording_id"`
+	ReleaseID      string   `json:"release_id,omitempty"`
+	ReleaseGroupID string   `json:"release_group_id,omitempty"`
+	ArtistIDs      []string `json:"artist_ids,omitempty"`
+	Title          string   `json:"title"`
+	Artist         string   `json:"artist"`
+	Album          string   `json:"album,omitempty"`
+	Barcode        string   `json:"barcode,omitempty"`
+	ReleaseDate    string   `json:"release_date,omitempty"`
+	OriginalDate   string   `json:"original_date,omitempty"`
+	ReleaseTypes   []string `json:"release_types,omitempty"` // e.g. ["album", "live"]
+	DurationMS     int      `json:"duration_ms,omitempty"`
+}
+
+// artistCreditString renders a credit the way MusicBrainz displays it
+func artistCreditString(credits []mbArtistCredit) string {
+	var sb strings.Builder
+	for _, c := range credits {
+		sb.WriteString(c.Name)
+		sb.WriteString(c.JoinPhrase)
+	}
+	return sb.String()
+}
+
+func artistCreditIDs(credits []mbArtistCredit) []string {
+	ids := make([]string, 0, len(credits))
+	for _, c := range credits {
+		if c.Artist.ID != "" {
+			ids = append(ids, c.Artist.ID)
+		}
+	}
+	return ids
+}
+
+// releaseTypes returns the primary and secondary types of a release group in
+// the lower case Picard writes
+func (g mbReleaseGroup) releaseTypes() []string {
+	var types []string
+	if g.PrimaryType != "" {
+		types = append(types, strings.ToLower(g.PrimaryType))
+	}
+	for _, t := range g.SecondaryTypes {
+		types = append(types, strings.ToLower(t))
+	}
+	return types
+}
+
+// waitForSlot waits for the 1 request per second MusicBrainz allows
+func (c *MusicBrainzClient) waitForSlot(ctx context.Context) error {
+	for !c.limiter.TryAcquire() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+	return nil
+}
+
+func (c *MusicBrainzClient) getJSON(ctx context.Context, path string, params url.Values, dst interface{}) error {
+	cacheKey := path + "?" + params.Encode()
+	c.cacheMu.RLock()
+	if entry, ok := c.cache[cacheKey]; ok && !entry.isExpired() {
+		c.cacheMu.RUnlock()
+		return json.Unmarshal(entry.data.([]byte), dst)
+	}
+	c.cacheMu.RUnlock()
+
+	if err := c.waitForSlot(ctx); err != nil {
+		return err
+	}
+
+	params.Set("fmt", "json")
+	endpoint := Endpoints().MusicBrainz + path + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", musicBrainzUserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("not found on MusicBrainz")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("musicbrainz API returned status %d", resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, dst); err != nil {
+		return err
+	}
+
+	c.cacheMu.Lock()
+	c.cache[cacheKey] = &cacheEntry{data: body, expiresAt: time.Now().Add(musicBrainzCacheTTL)}
+	c.cacheMu.Unlock()
+	return nil
+}
+
+// LookupISRC returns the recordings with an ISRC, with their releases
+func (c *MusicBrainzClient) LookupISRC(ctx context.Context, isrc string) ([]mbRecording, error) {
+	var resp struct {
+		Recordings []mbRecording `json:"recordings"`
+	}
+	params := url.Values{"inc": {"artists+releases+release-groups"}}
+	if err := c.getJSON(ctx, "/isrc/"+url.PathEscape(strings.ToUpper(isrc)), params, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Recordings) == 0 {
+		return nil, fmt.Errorf("no recording found for ISRC: %s", isrc)
+	}
+	return resp.Recordings, nil
+}
+
+// SearchReleases finds releases by barcode, or by title and artist when no
+// barcode is given
+func (c *MusicBrainzClient) SearchReleases(ctx context.Context, upc, title, artist string) ([]mbRelease, error) {
+	var query string
+	switch {
+	case upc != "":
+		query = "barcode:" + luceneQuote(strings.TrimLeft(upc, "0"))
+		// Barcodes are stored as printed, with or without the leading zero
+		if trimmed := strings.TrimLeft(upc, "0"); trimmed != upc {
+			query = fmt.Sprintf("barcode:%s OR barcode:%s", luceneQuote(upc), luceneQuote(trimmed))
+		}
+	case title != "":
+		query = "release:" + luceneQuote(title)
+		if artist != "" {
+			query += " AND artist:" + luceneQuote(artist)
+		}
+	default:
+		return nil, fmt.Errorf("a barcode or title is required")
+	}
+
+	var resp struct {
+		Releases []mbRelease `json:"releases"`
+	}
+	if err := c.getJSON(ctx, "/release", url.Values{"query": {query}, "limit": {"10"}}, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Releases) == 0 {
+		return nil, fmt.Errorf("no release found for %s", query)
+	}
+	return resp.Releases, nil
+}
+
+// GetRelease fetches a release with its track list and release group
+func (c *MusicBrainzClient) GetRelease(ctx context.Context, releaseID string) (*mbRelease, error) {
+	var release mbRelease
+	params := url.Values{"inc": {"recordings+artist-credits+release-groups"}}
+	if err := c.getJSON(ctx, "/release/"+url.PathEscape(releaseID), params, &release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+// luceneQuote quotes a search term for the MusicBrainz query syntax
+func luceneQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+// chooseRelease picks the release a track most likely came from: the one with
+// the requested barcode, else an official release named like the album, else
+// the earliest official release
+func chooseRelease(releases []mbRelease, albumName, upc string) *mbRelease {
+	var best *mbRelease
+	bestScore := -1
+	for i := range releases {
+		r := &releases[i]
+		if upc != "" && r.Barcode != "" && strings.TrimLeft(r.Barcode, "0") == strings.TrimLeft(upc, "0") {
+			return r
+		}
+		score := 0
+		if albumName != "" && titlesMatch(albumName, r.Title) {
+			score += 2
+		}
+		if r.Status == "Official" {
+			score++
+		}
+		if score > bestScore || (score == bestScore && r.Date != "" && (best.Date == "" || r.Date < best.Date)) {
+			best, bestScore = r, score
+		}
+	}
+	return best
+}
+
+// earliestDate returns the earliest non-empty date
+func earliestDate(dates ...string) string {
+	earliest := ""
+	for _, d := range dates {
+		if d != "" && (earliest == "" || d < earliest) {
+			earliest = d
+		}
+	}
+	return earliest
+}
+
+// newMusicBrainzMetadata combines a recording with the release it was chosen
+// from, which may be nil
+func newMusicBrainzMetadata(rec *mbRecording, release *mbRelease) *MusicBrainzMetadata {
+	meta := &MusicBrainzMetadata{
+		RecordingID:  rec.ID,
+		ArtistIDs:    artistCreditIDs(rec.ArtistCredit),
+		Title:        rec.Title,
+		Artist:       artistCreditString(rec.ArtistCredit),
+		OriginalDate: rec.FirstReleaseDate,
+		DurationMS:   rec.Length,
+	}
+	if release == nil {
+		return meta
+	}
+
+	meta.ReleaseID = release.ID
+	meta.ReleaseGroupID = release.ReleaseGroup.ID
+	meta.Album = release.Title
+	meta.Barcode = release.Barcode
+	meta.ReleaseDate = release.Date
+	meta.ReleaseTypes = release.ReleaseGroup.releaseTypes()
+	if meta.OriginalDate == "" {
+		meta.OriginalDate = earliestDate(release.ReleaseGroup.FirstReleaseDate, release.Date)
+	}
+	return meta
+}
+
+// GetTrackMetadata identifies a track on MusicBrainz, first by ISRC and then
+// through its release, found by UPC or by album and artist name
+func (c *MusicBrainzClient) GetTrackMetadata(ctx context.Context, isrc, trackName, artistName, albumName, upc string) (*MusicBrainzMetadata, error) {
+	if isrc != "" {
+		recordings, err := c.LookupISRC(ctx, isrc)
+		if err == nil {
+			rec := &recordings[0]
+			for i := range recordings {
+				if trackName != "" && titlesMatch(trackName, recordings[i].Title) {
+					rec = &recordings[i]
+					break
+				}
+			}
+			return newMusicBrainzMetadata(rec, chooseRelease(rec.Releases, albumName, upc)), nil
+		}
+		GoLog("[MusicBrainz] ISRC lookup failed for %s: %v\n", isrc, err)
+	}
+
+	if upc == "" && albumName == "" {
+		return nil, fmt.Errorf("track not found on MusicBrainz")
+	}
+	releases, err := c.SearchReleases(ctx, upc, albumName, artistName)
+	if err != nil {
+		return nil, err
+	}
+	candidate := chooseRelease(releases, albumName, upc)
+	if upc == "" && candidate.Score < 90 {
+		return nil, fmt.Errorf("no confident release match for %q (score %d)", albumName, candidate.Score)
+	}
+
+	release, err := c.GetRelease(ctx, candidate.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, medium := range release.Media {
+		for _, track := range medium.Tracks {
+			if titlesMatch(trackName, track.Title) || (isrc != "" && slices.Contains(track.Recording.ISRCs, isrc)) {
+				rec := track.Recording
+				if len(rec.ArtistCredit) == 0 {
+					rec.ArtistCredit = release.ArtistCredit
+				}
+				return newMusicBrainzMetadata(&rec, release), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("%q not found on release %s", trackName, release.ID)
+}
+
+// musicBrainzEnabled reports whether MusicBrainz is in the metadata provider
+// priority
+func musicBrainzEnabled() bool {
+	return slices.Contains(GetMetadataProviderPriority(), MetadataProviderMusicBrainz)
+}
+
+// startMusicBrainzLookup identifies the requested track on MusicBrainz in the
+// background while it downloads. The returned function waits for the result,
+// which is nil when MusicBrainz is not selected or has no match.
+func startMusicBrainzLookup(req DownloadRequest) func() *MusicBrainzMetadata {
+	if !musicBrainzEnabled() || (req.ISRC == "" && req.AlbumName == "") {
+		return func() *MusicBrainzMetadata { return nil }
+	}
+
+	var result *MusicBrainzMetadata
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		meta, err := GetMusicBrainzClient().GetTrackMetadata(ctx, req.ISRC, req.TrackName, req.ArtistName, req.AlbumName, "")
+		if err != nil {
+			GoLog("[MusicBrainz] No match for %s - %s: %v\n", req.ArtistName, req.TrackName, err)
+			return
+		}
+		GoLog("[MusicBrainz] Matched recording %s (release %s)\n", meta.RecordingID, meta.ReleaseID)
+		result = meta
+	}()
+	return func() *MusicBrainzMetadata {
+		<-done
+		return result
+	}
+}
+
+// applyMusicBrainzMetadata adds MusicBrainz identifiers to the tags. Titles
+// and names stay as the download service wrote them.
+func applyMusicBrainzMetadata(metadata *Metadata, mb *MusicBrainzMetadata) {
+	if mb == nil {
+		return
+	}
+	metadata.MusicBrainzTrackID = mb.RecordingID
+	metadata.MusicBrainzAlbumID = mb.ReleaseID
+	metadata.MusicBrainzArtistIDs = mb.ArtistIDs
+	metadata.OriginalDate = mb.OriginalDate
+	metadata.ReleaseTypes = mb.ReleaseTypes
+	if metadata.UPC == "" {
+		metadata.UPC = mb.Barcode
+	}
+}
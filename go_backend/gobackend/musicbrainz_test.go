@@ -0,0 +1,242 @@
+package gobackend
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-flac/flacvorbis"
+	"github.com/go-flac/go-flac"
+)
+
+const testISRCResponse = `{
+  "isrc": "GBAYE0601498",
+  "recordings": [{
+    "id": "rec-1",
+    "title": "Song",
+    "length": 215000,
+    "first-release-date": "1971-11-08",
+    "artist-credit": [
+      {"name": "Artist", "joinphrase": " & ", "artist": {"id": "art-1", "name": "Artist"}},
+      {"name": "Guest", "joinphrase": "", "artist": {"id": "art-2", "name": "Guest"}}
+    ],
+    "releases": [
+      {"id": "rel-comp", "title": "Greatest Hits", "status": "Official", "date": "1990-01-01",
+       "release-group": {"id": "rg-comp", "primary-type": "Album", "secondary-types": ["Compilation"]}},
+      {"id": "rel-bootleg", "title": "Album", "status": "Bootleg", "date": "1969-01-01",
+       "release-group": {"id": "rg-boot", "primary-type": "Album"}},
+      {"id": "rel-album", "title": "Album", "status": "Official", "date": "1971-11-08", "barcode": "0075678263927",
+       "release-group": {"id": "rg-album", "primary-type": "Album", "first-release-date": "1971-11-08"}}
+    ]
+  }]
+}`
+
+const testReleaseSearchResponse = `{
+  "releases": [
+    {"id": "rel-album", "score": 100, "title": "Album", "status": "Official", "date": "1971-11-08"}
+  ]
+}`
+
+const testReleaseResponse = `{
+  "id": "rel-album",
+  "title": "Album",
+  "status": "Official",
+  "date": "1971-11-08",
+  "barcode": "075678263927",
+  "artist-credit": [{"name": "Artist", "joinphrase": "", "artist": {"id": "art-1", "name": "Artist"}}],
+  "release-group": {"id": "rg-album", "primary-type": "Album", "secondary-types": ["Live"], "first-release-date": "1971-11-08"},
+  "media": [{"position": 1, "tracks": [
+    {"id": "t-1", "position": 1, "title": "Intro", "recording": {"id": "rec-0", "title": "Intro"}},
+    {"id": "t-2", "position": 2, "title": "Song (Live)", "recording": {"id": "rec-2", "title": "Song (Live)", "length": 301000}}
+  ]}]
+}`
+
+func TestChooseRelease(t *testing.T) {
+	releases := []mbRelease{
+		{ID: "bootleg", Title: "Album", Status: "Bootleg", Date: "1969"},
+		{ID: "reissue", Title: "Album", Status: "Official", Date: "2011", Barcode: "0012345678905"},
+		{ID: "original", Title: "Album", Status: "Official", Date: "1971"},
+		{ID: "compilation", Title: "Hits", Status: "Official", Date: "1960"},
+	}
+
+	tests := []struct {
+		name, album, upc, want string
+	}{
+		{"earliest official matching album", "Album", "", "original"},
+		{"barcode wins", "Album", "12345678905", "reissue"},
+		{"no album name", "", "", "compilation"},
+	}
+	for _, tt := range tests {
+		if got := chooseRelease(releases, tt.album, tt.upc); got == nil || got.ID != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.name, got, tt.want)
+		}
+	}
+	if chooseRelease(nil, "Album", "") != nil {
+		t.Error("expected nil for no releases")
+	}
+}
+
+func TestLuceneQuote(t *testing.T) {
+	if got := luceneQuote(`Say "Hi" \ Bye`); got != `"Say \"Hi\" \\ Bye"` {
+		t.Errorf("luceneQuote = %s", got)
+	}
+}
+
+func newTestMusicBrainzClient(t *testing.T, handler http.HandlerFunc) *MusicBrainzClient {
+	t.Helper()
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+
+	store := GetEndpointStore()
+	if err := store.SetServiceOverride(EndpointMusicBrainz, []string{server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Reset() })
+
+	return &MusicBrainzClient{
+		httpClient: server.Client(),
+		limiter:    NewRateLimiter(100, time.Second),
+		cache:      make(map[string]*cacheEntry),
+	}
+}
+
+func TestMusicBrainzGetTrackMetadataByISRC(t *testing.T) {
+	requests := 0
+	client := newTestMusicBrainzClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/isrc/GBAYE0601498" || r.URL.Query().Get("fmt") != "json" {
+			http.NotFound(w, r)
+			return
+		}
+		if !strings.Contains(r.Header.Get("User-Agent"), "https://") {
+			t.Errorf("User-Agent %q has no contact URL", r.Header.Get("User-Agent"))
+		}
+		w.Write([]byte(testISRCResponse))
+	})
+
+	meta, err := client.GetTrackMetadata(context.Background(), "gbaye0601498", "Song", "Artist", "Album", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &MusicBrainzMetadata{
+		RecordingID:    "rec-1",
+		ReleaseID:      "rel-album",
+		ReleaseGroupID: "rg-album",
+		ArtistIDs:      []string{"art-1", "art-2"},
+		Title:          "Song",
+		Artist:         "Artist & Guest",
+		Album:          "Album",
+		Barcode:        "0075678263927",
+		ReleaseDate:    "1971-11-08",
+		OriginalDate:   "1971-11-08",
+		ReleaseTypes:   []string{"album"},
+		DurationMS:     215000,
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("got %+v\nwant %+v", meta, want)
+	}
+
+	// A second lookup is served from the cache
+	if _, err := client.GetTrackMetadata(context.Background(), "GBAYE0601498", "Song", "Artist", "Album", ""); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests, want 1", requests)
+	}
+}
+
+func TestMusicBrainzGetTrackMetadataByRelease(t *testing.T) {
+	client := newTestMusicBrainzClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/release":
+			if q := r.URL.Query().Get("query"); q != `release:"Album" AND artist:"Artist"` {
+				t.Errorf("query = %s", q)
+			}
+			w.Write([]byte(testReleaseSearchResponse))
+		case "/release/rel-album":
+			w.Write([]byte(testReleaseResponse))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	meta, err := client.GetTrackMetadata(context.Background(), "", "Song (Live)", "Artist", "Album", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.RecordingID != "rec-2" || meta.ReleaseID != "rel-album" {
+		t.Errorf("got recording %s on release %s", meta.RecordingID, meta.ReleaseID)
+	}
+	// The track carries no credit of its own, so the release credit is used
+	if !reflect.DeepEqual(meta.ArtistIDs, []string{"art-1"}) {
+		t.Errorf("artist IDs = %v", meta.ArtistIDs)
+	}
+	if !reflect.DeepEqual(meta.ReleaseTypes, []string{"album", "live"}) || meta.OriginalDate != "1971-11-08" {
+		t.Errorf("release types %v, original date %q", meta.ReleaseTypes, meta.OriginalDate)
+	}
+
+	if _, err := client.GetTrackMetadata(context.Background(), "", "Missing", "Artist", "Album", ""); err == nil {
+		t.Error("expected an error for a track not on the release")
+	}
+}
+
+func TestEmbedMusicBrainzTags(t *testing.T) {
+	path := writeTestFLAC(t, 5, "Song", "")
+	metadata := Metadata{Title: "Song", Artist: "Artist"}
+	applyMusicBrainzMetadata(&metadata, &MusicBrainzMetadata{
+		RecordingID:  "rec-1",
+		ReleaseID:    "rel-1",
+		ArtistIDs:    []string{"art-1", "art-2"},
+		OriginalDate: "1971-11-08",
+		ReleaseTypes: []string{"album", "live"},
+		Barcode:      "075678263927",
+	})
+	if err := EmbedMetadata(path, metadata, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := flac.ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var comments []string
+	for _, meta := range f.Meta {
+		if meta.Type == flac.VorbisComment {
+			cmt, err := flacvorbis.ParseFromMetaDataBlock(*meta)
+			if err != nil {
+				t.Fatal(err)
+			}
+			comments = cmt.Comments
+		}
+	}
+	for _, want := range []string{
+		"MUSICBRAINZ_TRACKID=rec-1",
+		"MUSICBRAINZ_ALBUMID=rel-1",
+		"MUSICBRAINZ_ARTISTID=art-1",
+		"MUSICBRAINZ_ARTISTID=art-2",
+		"ORIGINALDATE=1971-11-08",
+		"RELEASETYPE=album",
+		"RELEASETYPE=live",
+		"UPC=075678263927",
+	} {
+		found := false
+		for _, c := range comments {
+			found = found || c == want
+		}
+		if !found {
+			t.Errorf("missing %s in %v", want, comments)
+		}
+	}
+
+	read, err := ReadMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.MusicBrainzTrackID != "rec-1" || read.MusicBrainzAlbumID != "rel-1" || read.OriginalDate != "1971-11-08" {
+		t.Errorf("ReadMetadata = %+v", read)
+	}
+}
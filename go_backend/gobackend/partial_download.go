@@ -0,0 +1,289 @@
+package gobackend
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	partialSuffix      = ".part"
+	partialStateSuffix = ".part.json"
+)
+
+// partialState records what a .part file was downloaded against so a later
+// attempt can verify the server is still serving the same content before
+// resuming it with a Range request. Download URLs are signed per request, so
+// the URL itself is not compared.
+type partialState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+	TotalSize    int64  `json:"total_size"`
+}
+
+func loadPartialState(outputPath string) (*partialState, int64) {
+	info, err := os.Stat(outputPath + partialSuffix)
+	if err != nil || info.Size() == 0 {
+		return nil, 0
+	}
+
+	data, err := os.ReadFile(outputPath + partialStateSuffix)
+	if err != nil {
+		return nil, 0
+	}
+
+	var state partialState
+	if err := json.Unmarshal(data, &state); err != nil || state.TotalSize <= 0 {
+		return nil, 0
+	}
+	if info.Size() > state.TotalSize {
+		return nil, 0
+	}
+
+	return &state, info.Size()
+}
+
+func savePartialState(outputPath string, state partialState) {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(outputPath+partialStateSuffix, data, 0644); err != nil {
+		GoLog("[Download] Failed to save partial state: %v\n", err)
+	}
+}
+
+// RemovePartialDownload deletes any .part file and resume state for outputPath
+func RemovePartialDownload(outputPath string) {
+	os.Remove(outputPath + partialSuffix)
+	os.Remove(outputPath + partialStateSuffix)
+}
+
+// parseContentRange parses "bytes start-end/total"
+func parseContentRange(header string) (start, total int64, ok bool) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes ") {
+		return 0, 0, false
+	}
+	spec := strings.TrimPrefix(header, "bytes ")
+	slash := strings.Index(spec, "/")
+	dash := strings.Index(spec, "-")
+	if slash < 0 || dash < 0 || dash > slash {
+		return 0, 0, false
+	}
+
+	start, err := strconv.ParseInt(spec[:dash], 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	totalStr := spec[slash+1:]
+	if totalStr == "*" {
+		return start, -1, true
+	}
+	total, err = strconv.ParseInt(totalStr, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return start, total, true
+}
+
+// isStrongETag reports whether an ETag can be used with If-Range
+func isStrongETag(etag string) bool {
+	return etag != "" && !strings.HasPrefix(etag, "W/")
+}
+
+// downloadToFileResumable downloads downloadURL into outputPath through a
+// .part file. If a matching partial from an earlier attempt exists and the
+// server honours Range requests, the download continues from where it
+// stopped. On failure the partial is kept for network errors when the server
+// supports resuming, and on cancellation only if keepPartialOnCancel is set.
+// When expectedDurationMS is set, a stream that turns out to be a preview
+// clip is discarded with a *PreviewError instead of replacing outputPath.
+func downloadToFileResumable(ctx context.Context, client *http.Client, downloadURL, outputPath, itemID string, keepPartialOnCancel bool, expectedDurationMS int) (int64, error) {
+	return downloadToFilePart(ctx, client, downloadURL, outputPath, itemID, keepPartialOnCancel, expectedDurationMS, false)
+}
+
+// downloadToFilePart is one attempt of downloadToFileResumable. A partial that
+// no longer matches the server is discarded and the download restarted once.
+func downloadToFilePart(ctx context.Context, client *http.Client, downloadURL, outputPath, itemID string, keepPartialOnCancel bool, expectedDurationMS int, restarted bool) (int64, error) {
+	partPath := outputPath + partialSuffix
+
+	state, offset := loadPartialState(outputPath)
+	if state == nil {
+		RemovePartialDownload(outputPath)
+	}
+
+	resp, err := requestDownloadRange(ctx, client, downloadURL, state, offset)
+	if err != nil {
+		if isDownloadCancelled(itemID) {
+			return 0, ErrDownloadCancelled
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var totalSize int64
+	resumable := strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		start, total, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if state == nil || offset == 0 {
+			// No Range was requested; some servers answer a plain GET with a
+			// 206 for the whole file, which is the same as a 200
+			if !ok || start != 0 {
+				RemovePartialDownload(outputPath)
+				return 0, fmt.Errorf("download failed: unexpected partial response %q", resp.Header.Get("Content-Range"))
+			}
+			offset = 0
+			totalSize = resp.ContentLength
+			break
+		}
+		etag := resp.Header.Get("ETag")
+		if !ok || start != offset || total != state.TotalSize ||
+			(isStrongETag(state.ETag) && etag != "" && etag != state.ETag) {
+			resp.Body.Close()
+			RemovePartialDownload(outputPath)
+			if restarted {
+				return 0, fmt.Errorf("download failed: server returned mismatched partial content")
+			}
+			// Server content changed since the partial was written, start over
+			GoLog("[Download] Partial does not match server content, restarting from zero\n")
+			return downloadToFilePart(ctx, client, downloadURL, outputPath, itemID, keepPartialOnCancel, expectedDurationMS, true)
+		}
+		GoLog("[Download] Resuming from byte %d of %d\n", offset, total)
+		totalSize = total
+		resumable = true
+	case http.StatusOK:
+		if offset > 0 {
+			GoLog("[Download] Server ignored Range request, restarting from zero\n")
+		}
+		offset = 0
+		totalSize = resp.ContentLength
+	case http.StatusRequestedRangeNotSatisfiable:
+		if state != nil && offset == state.TotalSize {
+			if err := os.Rename(partPath, outputPath); err != nil {
+				return 0, fmt.Errorf("failed to finalize file: %w", err)
+			}
+			os.Remove(outputPath + partialStateSuffix)
+			return offset, nil
+		}
+		RemovePartialDownload(outputPath)
+		return 0, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
+	}
+
+	if err := checkPreviewSize(totalSize, expectedDurationMS); err != nil {
+		GoLog("[Download] Rejecting stream: %v\n", err)
+		RemovePartialDownload(outputPath)
+		return 0, err
+	}
+
+	if totalSize > 0 && itemID != "" {
+		SetItemBytesTotal(itemID, totalSize)
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if offset > 0 {
+		flags |= os.O_APPEND
+	} else {
+		flags |= os.O_TRUNC
+	}
+	out, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	if resumable && totalSize > 0 {
+		savePartialState(outputPath, partialState{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			TotalSize:    totalSize,
+		})
+	}
+
+	bufWriter := bufio.NewWriterSize(out, 256*1024)
+
+	var written int64
+	if itemID != "" {
+		progressWriter := NewItemProgressWriter(bufWriter, itemID)
+		progressWriter.current = offset
+		progressWriter.lastBytes = offset
+		written, err = io.Copy(progressWriter, resp.Body)
+	} else {
+		written, err = io.Copy(bufWriter, resp.Body)
+	}
+
+	flushErr := bufWriter.Flush()
+	closeErr := out.Close()
+	received := offset + written
+
+	keepPartial := resumable && totalSize > 0
+	if err != nil {
+		cancelled := isDownloadCancelled(itemID) || errors.Is(err, ErrDownloadCancelled)
+		if !keepPartial || (cancelled && !keepPartialOnCancel) {
+			RemovePartialDownload(outputPath)
+		} else {
+			GoLog("[Download] Keeping partial download (%d/%d bytes) for resume\n", received, totalSize)
+		}
+		if cancelled {
+			return received, ErrDownloadCancelled
+		}
+		return received, fmt.Errorf("download interrupted: %w", err)
+	}
+	if flushErr != nil {
+		RemovePartialDownload(outputPath)
+		return received, fmt.Errorf("failed to flush buffer: %w", flushErr)
+	}
+	if closeErr != nil {
+		RemovePartialDownload(outputPath)
+		return received, fmt.Errorf("failed to close file: %w", closeErr)
+	}
+
+	if totalSize > 0 && received != totalSize {
+		if !keepPartial || received > totalSize {
+			RemovePartialDownload(outputPath)
+		}
+		return received, fmt.Errorf("incomplete download: expected %d bytes, got %d bytes", totalSize, received)
+	}
+
+	if err := checkPreviewFile(partPath, expectedDurationMS); err != nil {
+		GoLog("[Download] Rejecting download: %v\n", err)
+		RemovePartialDownload(outputPath)
+		return received, err
+	}
+
+	if err := os.Rename(partPath, outputPath); err != nil {
+		RemovePartialDownload(outputPath)
+		return received, fmt.Errorf("failed to finalize file: %w", err)
+	}
+	os.Remove(outputPath + partialStateSuffix)
+
+	return received, nil
+}
+
+func requestDownloadRange(ctx context.Context, client *http.Client, downloadURL string, state *partialState, offset int64) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if state != nil && offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		if isStrongETag(state.ETag) {
+			req.Header.Set("If-Range", state.ETag)
+		} else if state.LastModified != "" {
+			req.Header.Set("If-Range", state.LastModified)
+		}
+	}
+
+	return DoRequestWithUserAgent(client, req)
+}
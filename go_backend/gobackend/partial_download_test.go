@@ -0,0 +1,137 @@
+package gobackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadToFileResumable_ResumesPartial(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	var rangeHeaders []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rangeHeaders = append(rangeHeaders, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "track.flac", time.Unix(0, 0), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "track.flac")
+	if err := os.WriteFile(outputPath+partialSuffix, content[:4000], 0644); err != nil {
+		t.Fatal(err)
+	}
+	savePartialState(outputPath, partialState{ETag: `"v1"`, TotalSize: int64(len(content))})
+
+	client := NewHTTPClientWithTimeout(10 * time.Second)
+	n, err := downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 0)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Errorf("expected %d bytes, got %d", len(content), n)
+	}
+
+	got, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Error("resumed file content does not match")
+	}
+	if len(rangeHeaders) != 1 || rangeHeaders[0] != "bytes=4000-" {
+		t.Errorf("expected a single Range request from byte 4000, got %v", rangeHeaders)
+	}
+	if _, err := os.Stat(outputPath + partialStateSuffix); !os.IsNotExist(err) {
+		t.Error("partial state should be removed after completion")
+	}
+}
+
+func TestDownloadToFileResumable_RestartsOnChangedContent(t *testing.T) {
+	content := []byte(strings.Repeat("new content ", 200))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		http.ServeContent(w, r, "track.flac", time.Unix(0, 0), bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "track.flac")
+	os.WriteFile(outputPath+partialSuffix, []byte("stale partial"), 0644)
+	savePartialState(outputPath, partialState{ETag: `"v1"`, TotalSize: int64(len(content))})
+
+	client := NewHTTPClientWithTimeout(10 * time.Second)
+	if _, err := downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 0); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(outputPath)
+	if !bytes.Equal(got, content) {
+		t.Error("expected full fresh download when ETag changed")
+	}
+}
+
+func TestDownloadToFileResumable_PartialContentWithoutRange(t *testing.T) {
+	content := []byte(strings.Repeat("whole file ", 100))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			t.Errorf("unexpected Range header %q", r.Header.Get("Range"))
+		}
+		// A CDN that answers a plain GET with 206 for the whole file
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes 0-%d/%d", len(content)-1, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(content)
+	}))
+	defer server.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "track.flac")
+	client := NewHTTPClientWithTimeout(10 * time.Second)
+	if _, err := downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 0); err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	if got, _ := os.ReadFile(outputPath); !bytes.Equal(got, content) {
+		t.Error("206 to a plain GET should be saved like a 200")
+	}
+}
+
+func TestDownloadToFileResumable_RestartsOnlyOnce(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		// Always answers with a range that matches no partial
+		w.Header().Set("Content-Range", "bytes 5-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("56789"))
+	}))
+	defer server.Close()
+
+	outputPath := filepath.Join(t.TempDir(), "track.flac")
+	os.WriteFile(outputPath+partialSuffix, []byte("0123"), 0644)
+	savePartialState(outputPath, partialState{TotalSize: 10})
+
+	client := NewHTTPClientWithTimeout(10 * time.Second)
+	if _, err := downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 0); err == nil {
+		t.Fatal("expected an error for a server that never sends the requested range")
+	}
+	if requests != 2 {
+		t.Errorf("expected the original request and one restart, got %d requests", requests)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	start, total, ok := parseContentRange("bytes 100-199/1000")
+	if !ok || start != 100 || total != 1000 {
+		t.Errorf("unexpected parse: %d %d %v", start, total, ok)
+	}
+	if _, _, ok := parseContentRange("items 0-1/2"); ok {
+		t.Error("expected non-bytes unit to be rejected")
+	}
+}
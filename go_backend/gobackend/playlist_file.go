@@ -0,0 +1,284 @@
+package gobackend
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Supported playlist file formats
+const (
+	PlaylistFormatM3U8 = "m3u8"
+	PlaylistFormatPLS  = "pls"
+	PlaylistFormatXSPF = "xspf"
+)
+
+// PlaylistFileEntry is one track slot of a playlist file. Entries without a
+// FilePath (not downloaded yet, or failed) are left out of the written file;
+// M3U8 keeps a comment in their place so the gap is visible.
+type PlaylistFileEntry struct {
+	FilePath   string `json:"file_path,omitempty"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	DurationMS int    `json:"duration_ms,omitempty"`
+}
+
+// playlistFileWriter keeps playlist files in track order and rewrites them
+// every time a slot is filled, so players see the playlist grow while a
+// batch is still downloading.
+type playlistFileWriter struct {
+	dir      string
+	baseName string
+	title    string
+	formats  []string
+	entries  []PlaylistFileEntry
+	mu       sync.Mutex
+}
+
+// newPlaylistFileWriter prepares playlist files named after title inside dir.
+// Unknown formats are ignored; an empty list means M3U8 only.
+func newPlaylistFileWriter(dir, title string, formats []string, total int) *playlistFileWriter {
+	w := &playlistFileWriter{
+		dir:      dir,
+		baseName: sanitizeFilename(title),
+		title:    title,
+		entries:  make([]PlaylistFileEntry, total),
+	}
+
+	if len(formats) == 0 {
+		formats = []string{PlaylistFormatM3U8}
+	}
+	seen := make(map[string]bool)
+	for _, format := range formats {
+		format = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(format), "."))
+		if format == "m3u" {
+			format = PlaylistFormatM3U8
+		}
+		switch format {
+		case PlaylistFormatM3U8, PlaylistFormatPLS, PlaylistFormatXSPF:
+			if !seen[format] {
+				seen[format] = true
+				w.formats = append(w.formats, format)
+			}
+		default:
+			GoLog("[PlaylistFile] Ignoring unsupported format: %s\n", format)
+		}
+	}
+	return w
+}
+
+// Paths returns the playlist file paths this writer maintains
+func (w *playlistFileWriter) Paths() []string {
+	paths := make([]string, len(w.formats))
+	for i, format := range w.formats {
+		paths[i] = filepath.Join(w.dir, w.baseName+"."+format)
+	}
+	return paths
+}
+
+// Set fills slot index and rewrites the playlist files
+func (w *playlistFileWriter) Set(index int, entry PlaylistFileEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if index < 0 || index >= len(w.entries) {
+		return fmt.Errorf("playlist index %d out of range", index)
+	}
+	w.entries[index] = entry
+	return w.writeLocked()
+}
+
+// Fill sets slot index without writing; call Write afterwards
+func (w *playlistFileWriter) Fill(index int, entry PlaylistFileEntry) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if index >= 0 && index < len(w.entries) {
+		w.entries[index] = entry
+	}
+}
+
+// Write rewrites all playlist files from the current slots
+func (w *playlistFileWriter) Write() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.writeLocked()
+}
+
+func (w *playlistFileWriter) writeLocked() error {
+	var firstErr error
+	for _, format := range w.formats {
+		var content string
+		switch format {
+		case PlaylistFormatM3U8:
+			content = renderM3U8(w.entries, w.dir)
+		case PlaylistFormatPLS:
+			content = renderPLS(w.entries, w.dir)
+		case PlaylistFormatXSPF:
+			content = renderXSPF(w.entries, w.dir, w.title)
+		}
+
+		path := filepath.Join(w.dir, w.baseName+"."+format)
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			GoLog("[PlaylistFile] Failed to write %s: %v\n", path, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// writeFileAtomic replaces path with data via a temporary file
+func writeFileAtomic(path string, data []byte) error {
+	// A unique temp file keeps concurrent writers of one path apart
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmpPath, 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// playlistRelativePath returns filePath relative to the playlist directory
+// with forward slashes, falling back to the absolute path on other volumes
+func playlistRelativePath(playlistDir, filePath string) string {
+	rel, err := filepath.Rel(playlistDir, filePath)
+	if err != nil {
+		return filepath.ToSlash(filePath)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func playlistEntryLabel(entry PlaylistFileEntry) string {
+	if entry.Artist == "" {
+		return entry.Title
+	}
+	return entry.Artist + " - " + entry.Title
+}
+
+// playlistDurationSeconds returns the EXTINF/PLS length, -1 when unknown
+func playlistDurationSeconds(entry PlaylistFileEntry) int {
+	if entry.DurationMS <= 0 {
+		return -1
+	}
+	return (entry.DurationMS + 500) / 1000
+}
+
+// renderM3U8 renders an extended M3U playlist (UTF-8)
+func renderM3U8(entries []PlaylistFileEntry, playlistDir string) string {
+	var sb strings.Builder
+	sb.WriteString("#EXTM3U\n")
+	for _, entry := range entries {
+		label := strings.ReplaceAll(playlistEntryLabel(entry), "\n", " ")
+		if entry.FilePath == "" {
+			if label != "" {
+				sb.WriteString("# Missing: " + label + "\n")
+			}
+			continue
+		}
+		sb.WriteString(fmt.Sprintf("#EXTINF:%d,%s\n", playlistDurationSeconds(entry), label))
+		sb.WriteString(playlistRelativePath(playlistDir, entry.FilePath) + "\n")
+	}
+	return sb.String()
+}
+
+// renderPLS renders a PLS playlist
+func renderPLS(entries []PlaylistFileEntry, playlistDir string) string {
+	var sb strings.Builder
+	sb.WriteString("[playlist]\n")
+	n := 0
+	for _, entry := range entries {
+		if entry.FilePath == "" {
+			continue
+		}
+		n++
+		sb.WriteString(fmt.Sprintf("File%d=%s\n", n, playlistRelativePath(playlistDir, entry.FilePath)))
+		sb.WriteString(fmt.Sprintf("Title%d=%s\n", n, playlistEntryLabel(entry)))
+		sb.WriteString(fmt.Sprintf("Length%d=%d\n", n, playlistDurationSeconds(entry)))
+	}
+	sb.WriteString(fmt.Sprintf("NumberOfEntries=%d\nVersion=2\n", n))
+	return sb.String()
+}
+
+type xspfPlaylist struct {
+	XMLName   xml.Name    `xml:"playlist"`
+	Version   string      `xml:"version,attr"`
+	Namespace string      `xml:"xmlns,attr"`
+	Title     string      `xml:"title,omitempty"`
+	Tracks    []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Duration int    `xml:"duration,omitempty"`
+}
+
+// renderXSPF renders an XSPF playlist with relative URI locations
+func renderXSPF(entries []PlaylistFileEntry, playlistDir, title string) string {
+	playlist := xspfPlaylist{
+		Version:   "1",
+		Namespace: "http://xspf.org/ns/0/",
+		Title:     title,
+	}
+	for _, entry := range entries {
+		if entry.FilePath == "" {
+			continue
+		}
+		location := (&url.URL{Path: playlistRelativePath(playlistDir, entry.FilePath)}).EscapedPath()
+		playlist.Tracks = append(playlist.Tracks, xspfTrack{
+			Location: location,
+			Title:    entry.Title,
+			Creator:  entry.Artist,
+			Duration: entry.DurationMS,
+		})
+	}
+
+	data, err := xml.MarshalIndent(playlist, "", "  ")
+	if err != nil {
+		return ""
+	}
+	return xml.Header + string(data) + "\n"
+}
+
+// playlistEntryFromResponse builds a playlist slot from a finished download.
+// Tracks that already existed point at the existing file; failed tracks keep
+// only their label.
+func playlistEntryFromResponse(track DownloadRequest, resp *DownloadResponse) PlaylistFileEntry {
+	entry := PlaylistFileEntry{
+		Title:      track.TrackName,
+		Artist:     track.ArtistName,
+		DurationMS: track.DurationMS,
+	}
+	if resp == nil || !resp.Success {
+		return entry
+	}
+	if resp.Title != "" {
+		entry.Title = resp.Title
+	}
+	if resp.Artist != "" {
+		entry.Artist = resp.Artist
+	}
+	entry.FilePath = resp.FilePath
+	return entry
+}
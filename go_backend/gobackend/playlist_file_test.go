@@ -0,0 +1,87 @@
+package gobackend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRenderM3U8(t *testing.T) {
+	dir := t.TempDir()
+	entries := []PlaylistFileEntry{
+		{FilePath: filepath.Join(dir, "Album", "01 First.flac"), Title: "First", Artist: "Artist", DurationMS: 185400},
+		{Title: "Failed", Artist: "Artist"},
+		{FilePath: filepath.Join(dir, "Second.flac"), Title: "Second"},
+	}
+
+	got := renderM3U8(entries, dir)
+	want := "#EXTM3U\n" +
+		"#EXTINF:185,Artist - First\n" +
+		"Album/01 First.flac\n" +
+		"# Missing: Artist - Failed\n" +
+		"#EXTINF:-1,Second\n" +
+		"Second.flac\n"
+	if got != want {
+		t.Errorf("unexpected M3U8:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestPlaylistFileWriter_IncrementalUpdate(t *testing.T) {
+	dir := t.TempDir()
+	writer := newPlaylistFileWriter(dir, "My: Playlist", []string{"m3u8", "pls", "xspf", "wpl"}, 2)
+	if len(writer.Paths()) != 3 {
+		t.Fatalf("expected 3 formats, got %v", writer.Paths())
+	}
+
+	if err := writer.Set(1, PlaylistFileEntry{FilePath: filepath.Join(dir, "b.flac"), Title: "B"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Set(0, PlaylistFileEntry{FilePath: filepath.Join(dir, "a.flac"), Title: "A"}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(writer.Paths()[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Index(string(data), "a.flac") > strings.Index(string(data), "b.flac") {
+		t.Errorf("entries should keep playlist order:\n%s", data)
+	}
+
+	pls, _ := os.ReadFile(writer.Paths()[1])
+	if !strings.Contains(string(pls), "NumberOfEntries=2") {
+		t.Errorf("unexpected PLS:\n%s", pls)
+	}
+}
+
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := writeFileAtomic(path, []byte(strings.Repeat("x", i+1))); err != nil {
+				t.Errorf("writer %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// One writer wins as a whole and no temp files are left behind
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || strings.Trim(string(data), "x") != "" {
+		t.Errorf("content = %q, %v", data, err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("%d files left, want 1", len(entries))
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().Perm() != 0644 {
+		t.Errorf("mode = %v, want 0644", info.Mode().Perm())
+	}
+}
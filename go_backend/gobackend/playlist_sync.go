@@ -0,0 +1,371 @@
+package gobackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultRemovedFolder = "removed"
+
+// PlaylistSyncRequest describes a playlist to mirror into a local directory.
+// The output directory is assumed to be dedicated to this playlist: with
+// MoveRemoved set, every indexed track that is no longer in the playlist is
+// moved into the removed folder.
+type PlaylistSyncRequest struct {
+	Source               string   `json:"source"`      // "spotify", "deezer" or an extension ID
+	PlaylistID           string   `json:"playlist_id"` // ID or URL
+	OutputDir            string   `json:"output_dir"`
+	FilenameFormat       string   `json:"filename_format"`
+	Quality              string   `json:"quality"`
+	Service              string   `json:"service,omitempty"`
+	Mode                 string   `json:"mode,omitempty"`
+	EmbedLyrics          bool     `json:"embed_lyrics"`
+	EmbedMaxQualityCover bool     `json:"embed_max_quality_cover"`
+	LyricsMode           string   `json:"lyrics_mode,omitempty"`
+	Concurrency          int      `json:"concurrency,omitempty"`
+	MoveRemoved          bool     `json:"move_removed"`
+	RemovedFolder        string   `json:"removed_folder,omitempty"` // Relative to OutputDir, defaults to "removed"
+	DryRun               bool     `json:"dry_run,omitempty"`        // Only compute the diff
+	ItemIDPrefix         string   `json:"item_id_prefix,omitempty"`
+	PlaylistFormats      []string `json:"playlist_formats,omitempty"` // "m3u8", "pls", "xspf"; written in playlist order
+}
+
+// PlaylistSyncMovedTrack is a local file moved into or out of the removed folder
+type PlaylistSyncMovedTrack struct {
+	ISRC     string `json:"isrc"`
+	FilePath string `json:"file_path"`
+	MovedTo  string `json:"moved_to,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// PlaylistSyncResult is the diff summary of a playlist sync
+type PlaylistSyncResult struct {
+	Success       bool                     `json:"success"`
+	DryRun        bool                     `json:"dry_run,omitempty"`
+	PlaylistName  string                   `json:"playlist_name"`
+	Owner         string                   `json:"owner,omitempty"`
+	OutputDir     string                   `json:"output_dir"`
+	Total         int                      `json:"total"`
+	Present       int                      `json:"present"` // Already in the library
+	Missing       int                      `json:"missing"` // Not in the library (includes tracks without ISRC)
+	NoISRC        int                      `json:"no_isrc"` // Tracks that could not be diffed by ISRC
+	Downloaded    int                      `json:"downloaded"`
+	Skipped       int                      `json:"skipped"`
+	Failed        int                      `json:"failed"`
+	Cancelled     int                      `json:"cancelled"`
+	Restored      []PlaylistSyncMovedTrack `json:"restored,omitempty"` // Moved back out of the removed folder
+	Removed       []PlaylistSyncMovedTrack `json:"removed,omitempty"`  // No longer in the playlist
+	Tracks        []TrackDownloadReport    `json:"tracks"`             // Missing tracks and their outcome
+	PlaylistFiles []string                 `json:"playlist_files,omitempty"`
+	DurationMS    int64                    `json:"duration_ms"`
+}
+
+// playlistDiff is the result of comparing a playlist with an ISRC index
+type playlistDiff struct {
+	Present int
+	NoISRC  int
+	Missing []int             // Positions of tracks to download
+	Restore map[string]string // ISRC -> path inside the removed folder
+	Removed map[string]string // ISRC -> path no longer in the playlist
+}
+
+// diffPlaylistAgainstIndex compares playlist tracks with the indexed files.
+// Files under removedDir never count as present; if their track is back in
+// the playlist they are restored instead of downloaded again.
+func diffPlaylistAgainstIndex(tracks []DownloadRequest, indexed map[string]string, removedDir string) playlistDiff {
+	diff := playlistDiff{
+		Restore: make(map[string]string),
+		Removed: make(map[string]string),
+	}
+
+	wanted := make(map[string]bool, len(tracks))
+	for i, track := range tracks {
+		if track.ISRC == "" {
+			diff.NoISRC++
+			diff.Missing = append(diff.Missing, i)
+			continue
+		}
+
+		isrc := strings.ToUpper(track.ISRC)
+		if wanted[isrc] {
+			continue
+		}
+		wanted[isrc] = true
+
+		path, exists := indexed[isrc]
+		switch {
+		case !exists:
+			diff.Missing = append(diff.Missing, i)
+		case isPathInDir(path, removedDir):
+			diff.Restore[isrc] = path
+			diff.Present++
+		default:
+			diff.Present++
+		}
+	}
+
+	for isrc, path := range indexed {
+		if !wanted[isrc] && !isPathInDir(path, removedDir) {
+			diff.Removed[isrc] = path
+		}
+	}
+
+	return diff
+}
+
+// isPathInDir reports whether path is inside dir
+func isPathInDir(path, dir string) bool {
+	if dir == "" {
+		return false
+	}
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvedPlaylist is a playlist normalized from any metadata source
+type resolvedPlaylist struct {
+	Name   string
+	Owner  string
+	Tracks []DownloadRequest
+}
+
+// resolvePlaylist fetches playlist tracks from Spotify (with Deezer fallback), Deezer or an extension
+func resolvePlaylist(source, playlistID string) (*resolvedPlaylist, error) {
+	switch source {
+	case "", "spotify", "deezer":
+		var payload *PlaylistResponsePayload
+		if source == "deezer" {
+			deezerID := playlistID
+			if strings.Contains(playlistID, "deezer") {
+				resourceType, id, err := parseDeezerURL(playlistID)
+				if err != nil {
+					return nil, err
+				}
+				if resourceType != "playlist" {
+					return nil, fmt.Errorf("not a Deezer playlist URL: %s", playlistID)
+				}
+				deezerID = id
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			defer cancel()
+			var err error
+			payload, err = GetDeezerClient().GetPlaylist(ctx, deezerID)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			playlistURL := playlistID
+			if !strings.Contains(playlistID, "spotify") {
+				playlistURL = "https://open.spotify.com/playlist/" + playlistID
+			}
+			payloadJSON, err := GetSpotifyMetadataWithDeezerFallback(playlistURL)
+			if err != nil {
+				return nil, err
+			}
+			payload = &PlaylistResponsePayload{}
+			if err := json.Unmarshal([]byte(payloadJSON), payload); err != nil {
+				return nil, fmt.Errorf("unexpected playlist payload: %w", err)
+			}
+		}
+
+		playlist := &resolvedPlaylist{
+			Name:  payload.PlaylistInfo.Owner.Name,
+			Owner: payload.PlaylistInfo.Owner.DisplayName,
+		}
+		for _, t := range payload.TrackList {
+			playlist.Tracks = append(playlist.Tracks, trackMetadataToRequest(t))
+		}
+		return playlist, nil
+	default:
+		payloadJSON, err := GetPlaylistWithExtensionJSON(source, playlistID)
+		if err != nil {
+			return nil, err
+		}
+		var payload struct {
+			Name   string               `json:"name"`
+			Owner  string               `json:"owner"`
+			Tracks []extensionTrackJSON `json:"tracks"`
+		}
+		if err := json.Unmarshal([]byte(payloadJSON), &payload); err != nil {
+			return nil, fmt.Errorf("unexpected playlist payload: %w", err)
+		}
+
+		playlist := &resolvedPlaylist{Name: payload.Name, Owner: payload.Owner}
+		for _, t := range payload.Tracks {
+			playlist.Tracks = append(playlist.Tracks, t.toDownloadRequest(source))
+		}
+		return playlist, nil
+	}
+}
+
+// moveTrackFile moves an audio file and its .lrc sidecar into targetDir
+func moveTrackFile(path, targetDir string) (string, error) {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	target := filepath.Join(targetDir, filepath.Base(path))
+	if _, err := os.Stat(target); err == nil {
+		return "", fmt.Errorf("target already exists: %s", target)
+	}
+	if err := os.Rename(path, target); err != nil {
+		return "", err
+	}
+
+	lrcPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".lrc"
+	if _, err := os.Stat(lrcPath); err == nil {
+		os.Rename(lrcPath, filepath.Join(targetDir, filepath.Base(lrcPath)))
+	}
+	return target, nil
+}
+
+// SyncPlaylist diffs a playlist against the ISRC index of the output directory,
+// downloads only the missing tracks and optionally moves tracks that left the
+// playlist into the removed folder.
+func SyncPlaylist(req PlaylistSyncRequest) (*PlaylistSyncResult, error) {
+	startTime := time.Now()
+
+	req.OutputDir = strings.TrimSpace(req.OutputDir)
+	if req.OutputDir == "" {
+		return nil, fmt.Errorf("output directory is required")
+	}
+	if req.PlaylistID == "" {
+		return nil, fmt.Errorf("playlist ID is required")
+	}
+
+	playlist, err := resolvePlaylist(req.Source, req.PlaylistID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve playlist: %w", err)
+	}
+
+	if err := os.MkdirAll(req.OutputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	AddAllowedDownloadDir(req.OutputDir)
+
+	removedFolder := req.RemovedFolder
+	if removedFolder == "" {
+		removedFolder = defaultRemovedFolder
+	}
+	removedDir := filepath.Join(req.OutputDir, sanitizeFilename(removedFolder))
+
+	// Always diff against a fresh scan, a cached index may predate manual changes
+	InvalidateISRCCache(req.OutputDir)
+	indexed := GetISRCIndex(req.OutputDir).snapshot()
+	diff := diffPlaylistAgainstIndex(playlist.Tracks, indexed, removedDir)
+
+	GoLog("[PlaylistSync] '%s': %d tracks, %d present, %d missing, %d to restore, %d no longer in playlist\n",
+		playlist.Name, len(playlist.Tracks), diff.Present, len(diff.Missing), len(diff.Restore), len(diff.Removed))
+
+	result := &PlaylistSyncResult{
+		DryRun:       req.DryRun,
+		PlaylistName: playlist.Name,
+		Owner:        playlist.Owner,
+		OutputDir:    req.OutputDir,
+		Total:        len(playlist.Tracks),
+		Present:      diff.Present,
+		Missing:      len(diff.Missing),
+		NoISRC:       diff.NoISRC,
+	}
+
+	for isrc, path := range diff.Restore {
+		moved := PlaylistSyncMovedTrack{ISRC: isrc, FilePath: path}
+		if !req.DryRun {
+			target, moveErr := moveTrackFile(path, req.OutputDir)
+			if moveErr != nil {
+				moved.Error = moveErr.Error()
+			} else {
+				moved.MovedTo = target
+				indexed[isrc] = target
+			}
+		}
+		result.Restored = append(result.Restored, moved)
+	}
+
+	for isrc, path := range diff.Removed {
+		moved := PlaylistSyncMovedTrack{ISRC: isrc, FilePath: path}
+		if req.MoveRemoved && !req.DryRun {
+			target, moveErr := moveTrackFile(path, removedDir)
+			if moveErr != nil {
+				moved.Error = moveErr.Error()
+			} else {
+				moved.MovedTo = target
+			}
+		}
+		result.Removed = append(result.Removed, moved)
+	}
+
+	if len(result.Restored) > 0 || (req.MoveRemoved && len(result.Removed) > 0) {
+		InvalidateISRCCache(req.OutputDir)
+	}
+
+	tracks := make([]DownloadRequest, len(diff.Missing))
+	for i, position := range diff.Missing {
+		track := playlist.Tracks[position]
+		track.OutputDir = req.OutputDir
+		track.FilenameFormat = req.FilenameFormat
+		track.Quality = req.Quality
+		track.Service = req.Service
+		track.EmbedLyrics = req.EmbedLyrics
+		track.EmbedMaxQualityCover = req.EmbedMaxQualityCover
+		track.LyricsMode = req.LyricsMode
+		tracks[i] = track
+	}
+
+	if req.DryRun {
+		result.Tracks = make([]TrackDownloadReport, len(tracks))
+		for i, track := range tracks {
+			result.Tracks[i] = TrackDownloadReport{
+				Index:       i,
+				Title:       track.TrackName,
+				Artist:      track.ArtistName,
+				ISRC:        track.ISRC,
+				TrackNumber: track.TrackNumber,
+				DiscNumber:  track.DiscNumber,
+				DurationMS:  track.DurationMS,
+				Status:      "missing",
+			}
+		}
+		result.Success = true
+		result.DurationMS = time.Since(startTime).Milliseconds()
+		return result, nil
+	}
+
+	mode := req.Mode
+	if mode == "" {
+		mode = defaultBatchMode(req.Source)
+	}
+
+	var onTrackDone func(int, DownloadRequest, *DownloadResponse)
+	if len(req.PlaylistFormats) > 0 {
+		writer := newPlaylistFileWriter(req.OutputDir, playlist.Name, req.PlaylistFormats, len(playlist.Tracks))
+		for i, track := range playlist.Tracks {
+			entry := playlistEntryFromResponse(track, nil)
+			if path, ok := indexed[strings.ToUpper(track.ISRC)]; ok && track.ISRC != "" && !isPathInDir(path, removedDir) {
+				entry.FilePath = path
+			}
+			writer.Fill(i, entry)
+		}
+		writer.Write()
+		result.PlaylistFiles = writer.Paths()
+		onTrackDone = func(index int, track DownloadRequest, resp *DownloadResponse) {
+			writer.Set(diff.Missing[index], playlistEntryFromResponse(track, resp))
+		}
+	}
+
+	result.Tracks = downloadTracksConcurrently(tracks, mode, req.Concurrency, req.ItemIDPrefix, "PlaylistSync", onTrackDone)
+
+	result.Downloaded, result.Skipped, result.Failed, result.Cancelled = tallyTrackReports(result.Tracks)
+	result.Success = result.Failed == 0 && result.Cancelled == 0
+	result.DurationMS = time.Since(startTime).Milliseconds()
+
+	GoLog("[PlaylistSync] Done: %d downloaded, %d skipped, %d failed, %d restored, %d removed in %v\n",
+		result.Downloaded, result.Skipped, result.Failed, len(result.Restored), len(result.Removed), time.Since(startTime).Round(time.Second))
+
+	return result, nil
+}
@@ -0,0 +1,42 @@
+package gobackend
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDiffPlaylistAgainstIndex(t *testing.T) {
+	root := t.TempDir()
+	removedDir := filepath.Join(root, "removed")
+
+	tracks := []DownloadRequest{
+		{ISRC: "usabc0000001", TrackName: "Present"},
+		{ISRC: "USABC0000002", TrackName: "Missing"},
+		{ISRC: "USABC0000003", TrackName: "Back in playlist"},
+		{TrackName: "No ISRC"},
+	}
+	indexed := map[string]string{
+		"USABC0000001": filepath.Join(root, "Present.flac"),
+		"USABC0000003": filepath.Join(removedDir, "Back in playlist.flac"),
+		"USABC0000004": filepath.Join(root, "Dropped.flac"),
+		"USABC0000005": filepath.Join(removedDir, "Dropped earlier.flac"),
+	}
+
+	diff := diffPlaylistAgainstIndex(tracks, indexed, removedDir)
+
+	if diff.Present != 2 {
+		t.Errorf("expected 2 present, got %d", diff.Present)
+	}
+	if diff.NoISRC != 1 {
+		t.Errorf("expected 1 track without ISRC, got %d", diff.NoISRC)
+	}
+	if len(diff.Missing) != 2 || diff.Missing[0] != 1 || diff.Missing[1] != 3 {
+		t.Errorf("unexpected missing tracks: %+v", diff.Missing)
+	}
+	if _, ok := diff.Restore["USABC0000003"]; !ok || len(diff.Restore) != 1 {
+		t.Errorf("expected only USABC0000003 to be restored, got %v", diff.Restore)
+	}
+	if _, ok := diff.Removed["USABC0000004"]; !ok || len(diff.Removed) != 1 {
+		t.Errorf("expected only USABC0000004 to be removed, got %v", diff.Removed)
+	}
+}
@@ -0,0 +1,95 @@
+package gobackend
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const (
+	// Catalogue previews run between 30 and 90 seconds
+	previewMaxSeconds = 90.0
+	// A clip shorter than this share of the track is not the full track
+	previewMaxRatio = 0.6
+	// No full-length stream a provider serves is below 64 kbps, so a smaller
+	// announced size means a clip
+	previewMinBytesPerSecond = 8 * 1024
+)
+
+// PreviewError reports a stream or file that is a preview clip instead of the
+// full track. Fallback moves on to the next provider.
+type PreviewError struct {
+	ActualSec   float64 // Clip length, 0 when only the size was known
+	ExpectedSec float64 // Requested track length, 0 when unknown
+	Size        int64   // Announced stream size, 0 when the length was read
+}
+
+func (e *PreviewError) Error() string {
+	switch {
+	case e.ActualSec > 0:
+		return fmt.Sprintf("got a %.0fs preview instead of the %.0fs track", e.ActualSec, e.ExpectedSec)
+	case e.Size > 0:
+		return fmt.Sprintf("stream of %d bytes is too small for the %.0fs track, likely a preview", e.Size, e.ExpectedSec)
+	}
+	return "only a preview is available"
+}
+
+// isPreviewError reports whether err is or wraps a PreviewError
+func isPreviewError(err error) bool {
+	var previewErr *PreviewError
+	return errors.As(err, &previewErr)
+}
+
+// looksLikePreview reports whether a clip of actualSec is a preview of a track
+// expected to run expectedSec
+func looksLikePreview(actualSec, expectedSec float64) bool {
+	return actualSec > 0 && expectedSec > 0 &&
+		actualSec <= previewMaxSeconds && actualSec < expectedSec*previewMaxRatio
+}
+
+// checkPreviewSize rejects a stream whose announced size is too small to hold
+// the expected duration, before any of it is downloaded
+func checkPreviewSize(size int64, expectedMS int) error {
+	if size <= 0 || expectedMS <= 0 {
+		return nil
+	}
+	expectedSec := float64(expectedMS) / 1000
+	if float64(size) < expectedSec*previewMinBytesPerSecond {
+		return &PreviewError{ExpectedSec: expectedSec, Size: size}
+	}
+	return nil
+}
+
+// checkPreviewFile rejects a downloaded FLAC or M4A whose real length is that
+// of a preview. Files whose length cannot be read pass.
+func checkPreviewFile(filePath string, expectedMS int) error {
+	if expectedMS <= 0 {
+		return nil
+	}
+	actualSec, ok := fileDurationSeconds(filePath)
+	if !ok {
+		return nil
+	}
+	expectedSec := float64(expectedMS) / 1000
+	if looksLikePreview(actualSec, expectedSec) {
+		return &PreviewError{ActualSec: actualSec, ExpectedSec: expectedSec}
+	}
+	return nil
+}
+
+// rejectPreviewDownload removes a finished download that is a preview clip
+// and returns the preview error, so fallback moves on to the next provider
+func rejectPreviewDownload(filePath string, expectedMS int) error {
+	if filePath == "" {
+		return nil
+	}
+	err := checkPreviewFile(filePath, expectedMS)
+	if err == nil {
+		return nil
+	}
+	GoLog("[Download] Rejecting %s: %v\n", filePath, err)
+	if removeErr := os.Remove(filePath); removeErr != nil && !os.IsNotExist(removeErr) {
+		GoLog("[Download] Failed to remove preview clip: %v\n", removeErr)
+	}
+	return err
+}
@@ -0,0 +1,82 @@
+package gobackend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLooksLikePreview(t *testing.T) {
+	tests := []struct {
+		actual, expected float64
+		want             bool
+	}{
+		{30, 200, true},
+		{30.5, 61, true},
+		{60, 240, true},
+		{199, 200, false},
+		{30, 35, false},   // Short track, not a clip of it
+		{120, 400, false}, // Too long for a preview
+		{30, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := looksLikePreview(tt.actual, tt.expected); got != tt.want {
+			t.Errorf("looksLikePreview(%v, %v) = %v, want %v", tt.actual, tt.expected, got, tt.want)
+		}
+	}
+}
+
+func TestDownloadToFileResumableRejectsPreview(t *testing.T) {
+	clip, err := os.ReadFile(writeTestFLAC(t, 30, "Song", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pad the clip so only its STREAMINFO length gives it away
+	clip = append(clip, bytes.Repeat([]byte{0}, 2<<20)...)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "track.flac", time.Unix(0, 0), bytes.NewReader(clip))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClientWithTimeout(10 * time.Second)
+	outputPath := filepath.Join(t.TempDir(), "track.flac")
+
+	_, err = downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 200000)
+	var previewErr *PreviewError
+	if !errors.As(err, &previewErr) || previewErr.ActualSec < 29 || previewErr.ActualSec > 31 {
+		t.Fatalf("err = %v, want a 30s preview error", err)
+	}
+	for _, path := range []string{outputPath, outputPath + partialSuffix} {
+		if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+			t.Errorf("%s should not exist after a rejected preview", path)
+		}
+	}
+
+	// Announced size alone rules out a 10 minute track
+	_, err = downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 600000)
+	if !errors.As(err, &previewErr) || previewErr.Size != int64(len(clip)) {
+		t.Errorf("err = %v, want a size-based preview error", err)
+	}
+
+	// Without an expected duration the clip is kept
+	if _, err := downloadToFileResumable(context.Background(), client, server.URL, outputPath, "", false, 0); err != nil {
+		t.Errorf("no expected duration: %v", err)
+	}
+}
+
+func TestPreviewAttemptRejection(t *testing.T) {
+	err := stageError(StageDownload, "", fmt.Errorf("download failed: %w", &PreviewError{ActualSec: 30, ExpectedSec: 200}))
+	attempt := newDownloadAttempt("qobuz", time.Now(), err)
+	if attempt.Rejection != RejectPreview || attempt.ErrorType != "preview" {
+		t.Errorf("rejection %q, error type %q; want preview", attempt.Rejection, attempt.ErrorType)
+	}
+}
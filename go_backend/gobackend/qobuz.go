@@ -1,9 +1,7 @@
 package gobackend
 
 import (
-	"bufio"
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -11,7 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -20,7 +17,6 @@ import (
 type QobuzDownloader struct {
 	client *http.Client
 	appID  string
-	apiURL string
 }
 
 var (
@@ -31,12 +27,15 @@ var (
 type QobuzTrack struct {
 	ID                  int64   `json:"id"`
 	Title               string  `json:"title"`
+	Version             string  `json:"version"` // e.g. "Live" or "2011 Remaster"
 	ISRC                string  `json:"isrc"`
 	Duration            int     `json:"duration"`
 	TrackNumber         int     `json:"track_number"`
+	MediaNumber         int     `json:"media_number"` // Disc number
 	MaximumBitDepth     int     `json:"maximum_bit_depth"`
 	MaximumSamplingRate float64 `json:"maximum_sampling_rate"`
 	Album               struct {
+		ID          string `json:"id"`
 		Title       string `json:"title"`
 		ReleaseDate string `json:"release_date_original"`
 		Image       struct {
@@ -48,270 +47,6 @@ type QobuzTrack struct {
 	} `json:"performer"`
 }
 
-func qobuzArtistsMatch(expectedArtist, foundArtist string) bool {
-	normExpected := strings.ToLower(strings.TrimSpace(expectedArtist))
-	normFound := strings.ToLower(strings.TrimSpace(foundArtist))
-
-	// Exact match
-	if normExpected == normFound {
-		return true
-	}
-
-	// Check if one contains the other
-	if strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected) {
-		return true
-	}
-
-	expectedArtists := qobuzSplitArtists(normExpected)
-	foundArtists := qobuzSplitArtists(normFound)
-
-	for _, exp := range expectedArtists {
-		for _, fnd := range foundArtists {
-			if exp == fnd {
-				return true
-			}
-			if strings.Contains(exp, fnd) || strings.Contains(fnd, exp) {
-				return true
-			}
-			if qobuzSameWordsUnordered(exp, fnd) {
-				GoLog("[Qobuz] Artist names have same words in different order: '%s' vs '%s'\n", exp, fnd)
-				return true
-			}
-		}
-	}
-
-	expectedLatin := qobuzIsLatinScript(expectedArtist)
-	foundLatin := qobuzIsLatinScript(foundArtist)
-	if expectedLatin != foundLatin {
-		GoLog("[Qobuz] Artist names in different scripts, assuming match: '%s' vs '%s'\n", expectedArtist, foundArtist)
-		return true
-	}
-
-	return false
-}
-
-func qobuzSplitArtists(artists string) []string {
-	normalized := artists
-	normalized = strings.ReplaceAll(normalized, " feat. ", "|")
-	normalized = strings.ReplaceAll(normalized, " feat ", "|")
-	normalized = strings.ReplaceAll(normalized, " ft. ", "|")
-	normalized = strings.ReplaceAll(normalized, " ft ", "|")
-	normalized = strings.ReplaceAll(normalized, " & ", "|")
-	normalized = strings.ReplaceAll(normalized, " and ", "|")
-	normalized = strings.ReplaceAll(normalized, ", ", "|")
-	normalized = strings.ReplaceAll(normalized, " x ", "|")
-
-	parts := strings.Split(normalized, "|")
-	result := make([]string, 0, len(parts))
-	for _, p := range parts {
-		trimmed := strings.TrimSpace(p)
-		if trimmed != "" {
-			result = append(result, trimmed)
-		}
-	}
-	return result
-}
-
-// qobuzSameWordsUnordered checks if two strings have the same words regardless of order
-// Useful for Japanese names: "Sawano Hiroyuki" vs "Hiroyuki Sawano"
-func qobuzSameWordsUnordered(a, b string) bool {
-	wordsA := strings.Fields(a)
-	wordsB := strings.Fields(b)
-
-	// Must have same number of words
-	if len(wordsA) != len(wordsB) || len(wordsA) == 0 {
-		return false
-	}
-
-	// Sort and compare
-	sortedA := make([]string, len(wordsA))
-	sortedB := make([]string, len(wordsB))
-	copy(sortedA, wordsA)
-	copy(sortedB, wordsB)
-
-	// Simple bubble sort (usually just 2-3 words)
-	for i := 0; i < len(sortedA)-1; i++ {
-		for j := i + 1; j < len(sortedA); j++ {
-			if sortedA[i] > sortedA[j] {
-				sortedA[i], sortedA[j] = sortedA[j], sortedA[i]
-			}
-			if sortedB[i] > sortedB[j] {
-				sortedB[i], sortedB[j] = sortedB[j], sortedB[i]
-			}
-		}
-	}
-
-	for i := range sortedA {
-		if sortedA[i] != sortedB[i] {
-			return false
-		}
-	}
-	return true
-}
-
-func qobuzTitlesMatch(expectedTitle, foundTitle string) bool {
-	normExpected := strings.ToLower(strings.TrimSpace(expectedTitle))
-	normFound := strings.ToLower(strings.TrimSpace(foundTitle))
-
-	// Exact match
-	if normExpected == normFound {
-		return true
-	}
-
-	if strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected) {
-		return true
-	}
-
-	cleanExpected := qobuzCleanTitle(normExpected)
-	cleanFound := qobuzCleanTitle(normFound)
-
-	if cleanExpected == cleanFound {
-		return true
-	}
-
-	if cleanExpected != "" && cleanFound != "" {
-		if strings.Contains(cleanExpected, cleanFound) || strings.Contains(cleanFound, cleanExpected) {
-			return true
-		}
-	}
-
-	coreExpected := qobuzExtractCoreTitle(normExpected)
-	coreFound := qobuzExtractCoreTitle(normFound)
-
-	if coreExpected != "" && coreFound != "" && coreExpected == coreFound {
-		return true
-	}
-
-	// If scripts are TRULY different (Latin vs CJK/Arabic/Cyrillic), assume match (transliteration)
-	// Don't treat Latin Extended (Polish, French, etc.) as different script
-	expectedLatin := qobuzIsLatinScript(expectedTitle)
-	foundLatin := qobuzIsLatinScript(foundTitle)
-	if expectedLatin != foundLatin {
-		GoLog("[Qobuz] Titles in different scripts, assuming match: '%s' vs '%s'\n", expectedTitle, foundTitle)
-		return true
-	}
-
-	return false
-}
-
-// qobuzExtractCoreTitle extracts the main title before any parentheses or brackets
-func qobuzExtractCoreTitle(title string) string {
-	// Find first occurrence of ( or [
-	parenIdx := strings.Index(title, "(")
-	bracketIdx := strings.Index(title, "[")
-	dashIdx := strings.Index(title, " - ")
-
-	cutIdx := len(title)
-	if parenIdx > 0 && parenIdx < cutIdx {
-		cutIdx = parenIdx
-	}
-	if bracketIdx > 0 && bracketIdx < cutIdx {
-		cutIdx = bracketIdx
-	}
-	if dashIdx > 0 && dashIdx < cutIdx {
-		cutIdx = dashIdx
-	}
-
-	return strings.TrimSpace(title[:cutIdx])
-}
-
-func qobuzCleanTitle(title string) string {
-	cleaned := title
-
-	versionPatterns := []string{
-		"remaster", "remastered", "deluxe", "bonus", "single",
-		"album version", "radio edit", "original mix", "extended",
-		"club mix", "remix", "live", "acoustic", "demo",
-	}
-
-	for {
-		startParen := strings.LastIndex(cleaned, "(")
-		endParen := strings.LastIndex(cleaned, ")")
-		if startParen >= 0 && endParen > startParen {
-			content := strings.ToLower(cleaned[startParen+1 : endParen])
-			isVersionIndicator := false
-			for _, pattern := range versionPatterns {
-				if strings.Contains(content, pattern) {
-					isVersionIndicator = true
-					break
-				}
-			}
-			if isVersionIndicator {
-				cleaned = strings.TrimSpace(cleaned[:startParen]) + cleaned[endParen+1:]
-				continue
-			}
-		}
-		break
-	}
-
-	for {
-		startBracket := strings.LastIndex(cleaned, "[")
-		endBracket := strings.LastIndex(cleaned, "]")
-		if startBracket >= 0 && endBracket > startBracket {
-			content := strings.ToLower(cleaned[startBracket+1 : endBracket])
-			isVersionIndicator := false
-			for _, pattern := range versionPatterns {
-				if strings.Contains(content, pattern) {
-					isVersionIndicator = true
-					break
-				}
-			}
-			if isVersionIndicator {
-				cleaned = strings.TrimSpace(cleaned[:startBracket]) + cleaned[endBracket+1:]
-				continue
-			}
-		}
-		break
-	}
-
-	dashPatterns := []string{
-		" - remaster", " - remastered", " - single version", " - radio edit",
-		" - live", " - acoustic", " - demo", " - remix",
-	}
-	for _, pattern := range dashPatterns {
-		if strings.HasSuffix(strings.ToLower(cleaned), pattern) {
-			cleaned = cleaned[:len(cleaned)-len(pattern)]
-		}
-	}
-
-	for strings.Contains(cleaned, "  ") {
-		cleaned = strings.ReplaceAll(cleaned, "  ", " ")
-	}
-
-	return strings.TrimSpace(cleaned)
-}
-
-// qobuzIsLatinScript checks if a string is primarily Latin script
-// Returns true for ASCII and Latin Extended characters (European languages)
-// Returns false for CJK, Arabic, Cyrillic, etc.
-func qobuzIsLatinScript(s string) bool {
-	for _, r := range s {
-		// Skip common punctuation and numbers
-		if r < 128 {
-			continue
-		}
-		// Latin Extended-A: U+0100 to U+017F (Polish, Czech, etc.)
-		// Latin Extended-B: U+0180 to U+024F
-		// Latin Extended Additional: U+1E00 to U+1EFF
-		// Latin Extended-C/D/E: various ranges
-		if (r >= 0x0100 && r <= 0x024F) || // Latin Extended A & B
-			(r >= 0x1E00 && r <= 0x1EFF) || // Latin Extended Additional
-			(r >= 0x00C0 && r <= 0x00FF) { // Latin-1 Supplement (accented chars)
-			continue
-		}
-		// CJK ranges - definitely different script
-		if (r >= 0x4E00 && r <= 0x9FFF) || // CJK Unified Ideographs
-			(r >= 0x3040 && r <= 0x309F) || // Hiragana
-			(r >= 0x30A0 && r <= 0x30FF) || // Katakana
-			(r >= 0xAC00 && r <= 0xD7AF) || // Hangul (Korean)
-			(r >= 0x0600 && r <= 0x06FF) || // Arabic
-			(r >= 0x0400 && r <= 0x04FF) { // Cyrillic
-			return false
-		}
-	}
-	return true
-}
-
 // qobuzIsASCIIString checks if a string contains only ASCII characters
 // Kept for potential future use
 // func qobuzIsASCIIString(s string) bool {
@@ -345,8 +80,8 @@ func NewQobuzDownloader() *QobuzDownloader {
 
 func (q *QobuzDownloader) GetTrackByID(trackID int64) (*QobuzTrack, error) {
 	// Qobuz API: /track/get?track_id=XXX
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMi90cmFjay9nZXQ/dHJhY2tfaWQ9")
-	trackURL := fmt.Sprintf("%s%d&app_id=%s", string(apiBase), trackID, q.appID)
+	apiBase := Endpoints().QobuzAPI + "/track/get?track_id="
+	trackURL := fmt.Sprintf("%s%d&app_id=%s", apiBase, trackID, q.appID)
 
 	req, err := http.NewRequest("GET", trackURL, nil)
 	if err != nil {
@@ -371,31 +106,15 @@ func (q *QobuzDownloader) GetTrackByID(trackID int64) (*QobuzTrack, error) {
 	return &track, nil
 }
 
-// GetAvailableAPIs returns list of available Qobuz APIs
-// Uses same APIs as PC version for compatibility
+// GetAvailableAPIs returns list of available Qobuz stream APIs from the
+// endpoint config. Defaults are the same APIs as PC version for compatibility.
 func (q *QobuzDownloader) GetAvailableAPIs() []string {
-	// Same APIs as PC version (referensi/backend/qobuz.go)
-	// Primary: dab.yeet.su, Fallback: dabmusic.xyz
-	encodedAPIs := []string{
-		"ZGFiLnllZXQuc3UvYXBpL3N0cmVhbT90cmFja0lkPQ==", // dab.yeet.su/api/stream?trackId= (PRIMARY - same as PC)
-		"ZGFibXVzaWMueHl6L2FwaS9zdHJlYW0/dHJhY2tJZD0=", // dabmusic.xyz/api/stream?trackId= (FALLBACK - same as PC)
-	}
-
-	var apis []string
-	for _, encoded := range encodedAPIs {
-		decoded, err := base64.StdEncoding.DecodeString(encoded)
-		if err != nil {
-			continue
-		}
-		apis = append(apis, "https://"+string(decoded))
-	}
-
-	return apis
+	return Endpoints().Qobuz
 }
 
 func (q *QobuzDownloader) SearchTrackByISRC(isrc string) (*QobuzTrack, error) {
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMi90cmFjay9zZWFyY2g/cXVlcnk9")
-	searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", string(apiBase), url.QueryEscape(isrc), q.appID)
+	apiBase := Endpoints().QobuzAPI + "/track/search?query="
+	searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", apiBase, url.QueryEscape(isrc), q.appID)
 
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
@@ -439,8 +158,8 @@ func (q *QobuzDownloader) SearchTrackByISRC(isrc string) (*QobuzTrack, error) {
 func (q *QobuzDownloader) SearchTrackByISRCWithDuration(isrc string, expectedDurationSec int) (*QobuzTrack, error) {
 	GoLog("[Qobuz] Searching by ISRC: %s\n", isrc)
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMi90cmFjay9zZWFyY2g/cXVlcnk9")
-	searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", string(apiBase), url.QueryEscape(isrc), q.appID)
+	apiBase := Endpoints().QobuzAPI + "/track/search?query="
+	searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", apiBase, url.QueryEscape(isrc), q.appID)
 
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
@@ -482,11 +201,7 @@ func (q *QobuzDownloader) SearchTrackByISRCWithDuration(isrc string, expectedDur
 		if expectedDurationSec > 0 {
 			var durationVerifiedMatches []*QobuzTrack
 			for _, track := range isrcMatches {
-				durationDiff := track.Duration - expectedDurationSec
-				if durationDiff < 0 {
-					durationDiff = -durationDiff
-				}
-				if durationDiff <= 10 {
+				if durationsMatch("qobuz", expectedDurationSec, track.Duration) {
 					durationVerifiedMatches = append(durationVerifiedMatches, track)
 				}
 			}
@@ -525,7 +240,7 @@ func (q *QobuzDownloader) SearchTrackByMetadata(trackName, artistName string) (*
 // Now includes romaji conversion for Japanese text (same as Tidal)
 // Also includes title verification to prevent wrong song downloads
 func (q *QobuzDownloader) SearchTrackByMetadataWithDuration(trackName, artistName string, expectedDurationSec int) (*QobuzTrack, error) {
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMi90cmFjay9zZWFyY2g/cXVlcnk9")
+	apiBase := Endpoints().QobuzAPI + "/track/search?query="
 
 	// Try multiple search strategies (same as Tidal/PC version)
 	queries := []string{}
@@ -587,7 +302,7 @@ func (q *QobuzDownloader) SearchTrackByMetadataWithDuration(trackName, artistNam
 
 		GoLog("[Qobuz] Searching for: %s\n", cleanQuery)
 
-		searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", string(apiBase), url.QueryEscape(cleanQuery), q.appID)
+		searchURL := fmt.Sprintf("%s%s&limit=50&app_id=%s", apiBase, url.QueryEscape(cleanQuery), q.appID)
 
 		req, err := http.NewRequest("GET", searchURL, nil)
 		if err != nil {
@@ -630,7 +345,7 @@ func (q *QobuzDownloader) SearchTrackByMetadataWithDuration(trackName, artistNam
 	var titleMatches []*QobuzTrack
 	for i := range allTracks {
 		track := &allTracks[i]
-		if qobuzTitlesMatch(trackName, track.Title) {
+		if titlesMatch(trackName, track.Title) {
 			titleMatches = append(titleMatches, track)
 		}
 	}
@@ -650,11 +365,7 @@ func (q *QobuzDownloader) SearchTrackByMetadataWithDuration(trackName, artistNam
 	if expectedDurationSec > 0 {
 		var durationMatches []*QobuzTrack
 		for _, track := range tracksToCheck {
-			durationDiff := track.Duration - expectedDurationSec
-			if durationDiff < 0 {
-				durationDiff = -durationDiff
-			}
-			if durationDiff <= 10 {
+			if durationsMatch("qobuz", expectedDurationSec, track.Duration) {
 				durationMatches = append(durationMatches, track)
 			}
 		}
@@ -820,7 +531,7 @@ func (q *QobuzDownloader) GetDownloadURL(trackID int64, quality string) (string,
 }
 
 // DownloadFile downloads a file from URL with User-Agent and progress tracking
-func (q *QobuzDownloader) DownloadFile(downloadURL, outputPath, itemID string) error {
+func (q *QobuzDownloader) DownloadFile(downloadURL, outputPath, itemID string, keepPartial bool, expectedDurationMS int) error {
 	ctx := context.Background()
 
 	// Initialize item progress (required for all downloads)
@@ -835,99 +546,60 @@ func (q *QobuzDownloader) DownloadFile(downloadURL, outputPath, itemID string) e
 		return ErrDownloadCancelled
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := DoRequestWithUserAgent(q.client, req)
-	if err != nil {
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
-	}
-
-	expectedSize := resp.ContentLength
-	if expectedSize > 0 && itemID != "" {
-		SetItemBytesTotal(itemID, expectedSize)
-	}
-
-	out, err := os.Create(outputPath)
+	_, err := downloadToFileResumable(ctx, q.client, downloadURL, outputPath, itemID, keepPartial, expectedDurationMS)
 	if err != nil {
 		return err
 	}
 
-	bufWriter := bufio.NewWriterSize(out, 256*1024)
-
-	var written int64
-	if itemID != "" {
-		progressWriter := NewItemProgressWriter(bufWriter, itemID)
-		written, err = io.Copy(progressWriter, resp.Body)
-	} else {
-		written, err = io.Copy(bufWriter, resp.Body)
-	}
-
-	// Flush buffer before checking for errors
-	flushErr := bufWriter.Flush()
-	closeErr := out.Close()
-
-	if err != nil {
-		os.Remove(outputPath)
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
-		return fmt.Errorf("download interrupted: %w", err)
-	}
-	if flushErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to flush buffer: %w", flushErr)
-	}
-	if closeErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to close file: %w", closeErr)
-	}
-
-	// Verify file size if Content-Length was provided
-	if expectedSize > 0 && written != expectedSize {
-		os.Remove(outputPath)
-		return fmt.Errorf("incomplete download: expected %d bytes, got %d bytes", expectedSize, written)
-	}
-
 	return nil
 }
 
 type QobuzDownloadResult struct {
-	FilePath    string
-	BitDepth    int
-	SampleRate  int
-	Title       string
-	Artist      string
-	Album       string
-	ReleaseDate string
-	TrackNumber int
-	DiscNumber  int
-	ISRC        string
+	FilePath        string
+	BitDepth        int
+	SampleRate      int
+	Title           string
+	Artist          string
+	Album           string
+	ReleaseDate     string
+	TrackNumber     int
+	DiscNumber      int
+	ISRC            string
+	Genre           string
+	Label           string
+	Copyright       string
+	MetadataSources map[string]string // Field -> source of the tag written
+	MatchedID       string            // Provider's ID of the track it downloaded
+	Matched         TrackMatchInfo    // Provider's own details of that track, checked by verifyDownload
 }
 
-func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
-	downloader := NewQobuzDownloader()
-
-	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
-		return QobuzDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+// matchInfo describes the track for ScoreTrackMatch, with the version added
+// back to the title the way other catalogues show it
+func (t *QobuzTrack) matchInfo() TrackMatchInfo {
+	title := t.Title
+	if t.Version != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(t.Version)) {
+		title += " (" + t.Version + ")"
+	}
+	return TrackMatchInfo{
+		Title:      title,
+		Artist:     t.Performer.Name,
+		Album:      t.Album.Title,
+		DurationMS: t.Duration * 1000,
+		ISRC:       t.ISRC,
 	}
+}
 
+// resolveQobuzTrack finds the Qobuz track for a request: Odesli ID, cached
+// ID, ISRC search and finally metadata search, with artist/title checks.
+func resolveQobuzTrack(downloader *QobuzDownloader, req DownloadRequest) (*QobuzTrack, error) {
 	expectedDurationSec := req.DurationMS / 1000
 
 	var track *QobuzTrack
 	var err error
+	var stage, rejection string
 
 	if req.QobuzID != "" {
+		stage = StageProviderID
 		GoLog("[Qobuz] Using Qobuz ID from Odesli enrichment: %s\n", req.QobuzID)
 		var trackID int64
 		if _, parseErr := fmt.Sscanf(req.QobuzID, "%d", &trackID); parseErr == nil && trackID > 0 {
@@ -943,6 +615,7 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 
 	// OPTIMIZATION: Check cache first for track ID
 	if track == nil && req.ISRC != "" {
+		stage = StageProviderID
 		if cached := GetTrackIDCache().Get(req.ISRC); cached != nil && cached.QobuzTrackID > 0 {
 			GoLog("[Qobuz] Cache hit! Using cached track ID: %d\n", cached.QobuzTrackID)
 			// For Qobuz we need to search again to get full track info, but we can use the ID
@@ -956,30 +629,26 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 
 	// Strategy 1: Search by ISRC with duration verification
 	if track == nil && req.ISRC != "" {
+		stage = StageISRCSearch
 		GoLog("[Qobuz] Trying ISRC search: %s\n", req.ISRC)
 		track, err = downloader.SearchTrackByISRCWithDuration(req.ISRC, expectedDurationSec)
-		// Verify artist AND title
 		if track != nil {
-			if !qobuzArtistsMatch(req.ArtistName, track.Performer.Name) {
-				GoLog("[Qobuz] Artist mismatch from ISRC search: expected '%s', got '%s'. Rejecting.\n",
-					req.ArtistName, track.Performer.Name)
-				track = nil
-			} else if !qobuzTitlesMatch(req.TrackName, track.Title) {
-				GoLog("[Qobuz] Title mismatch from ISRC search: expected '%s', got '%s'. Rejecting.\n",
-					req.TrackName, track.Title)
+			if match := matchRequest("qobuz", "ISRC search", req, track.matchInfo()); !match.Accepted {
 				track = nil
+				rejection = match.Rejection
 			}
 		}
 	}
 
 	// Strategy 2: Search by metadata with duration verification (includes title verification)
 	if track == nil {
+		stage = StageMetadataSearch
 		track, err = downloader.SearchTrackByMetadataWithDuration(req.TrackName, req.ArtistName, expectedDurationSec)
-		// Verify artist (title already verified in SearchTrackByMetadataWithDuration)
-		if track != nil && !qobuzArtistsMatch(req.ArtistName, track.Performer.Name) {
-			GoLog("[Qobuz] Artist mismatch from metadata search: expected '%s', got '%s'. Rejecting.\n",
-				req.ArtistName, track.Performer.Name)
-			track = nil
+		if track != nil {
+			if match := matchRequest("qobuz", "metadata search", req, track.matchInfo()); !match.Accepted {
+				track = nil
+				rejection = match.Rejection
+			}
 		}
 	}
 
@@ -988,7 +657,7 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 		if err != nil {
 			errMsg = err.Error()
 		}
-		return QobuzDownloadResult{}, fmt.Errorf("qobuz search failed: %s", errMsg)
+		return nil, stageError(stage, rejection, fmt.Errorf("qobuz search failed: %s", errMsg))
 	}
 
 	// Log match found and cache the track ID
@@ -997,33 +666,92 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 		GetTrackIDCache().SetQobuz(req.ISRC, track.ID)
 	}
 
-	filename := buildFilenameFromTemplate(req.FilenameFormat, map[string]interface{}{
-		"title":  req.TrackName,
-		"artist": req.ArtistName,
-		"album":  req.AlbumName,
-		"track":  req.TrackNumber,
-		"year":   extractYear(req.ReleaseDate),
-		"disc":   req.DiscNumber,
-	})
-	filename = sanitizeFilename(filename) + ".flac"
-	outputPath := filepath.Join(req.OutputDir, filename)
+	return track, nil
+}
+
+// qobuzQualityCode maps the Tidal-style quality names to Qobuz format IDs.
+// Tidal: LOSSLESS (16-bit), HI_RES (24-bit), HI_RES_LOSSLESS (24-bit hi-res)
+// Qobuz: 5 (MP3 320), 6 (16-bit), 7 (24-bit 96kHz), 27 (24-bit 192kHz)
+func qobuzQualityCode(quality string) string {
+	switch quality {
+	case "LOSSLESS":
+		return "6" // 16-bit FLAC
+	case "HI_RES":
+		return "7" // 24-bit 96kHz
+	default:
+		return "27" // Default to highest quality (24-bit 192kHz)
+	}
+}
+
+// probeQobuzQuality resolves the track and reports its maximum quality,
+// capped by the requested quality tier, without downloading audio
+func probeQobuzQuality(req DownloadRequest) (QualityProbe, error) {
+	track, err := resolveQobuzTrack(NewQobuzDownloader(), req)
+	if err != nil {
+		return QualityProbe{}, err
+	}
+
+	bitDepth := track.MaximumBitDepth
+	sampleRate := int(track.MaximumSamplingRate * 1000)
+	switch qobuzQualityCode(req.Quality) {
+	case "6":
+		bitDepth, sampleRate = min(bitDepth, 16), min(sampleRate, 44100)
+	case "7":
+		bitDepth, sampleRate = min(bitDepth, 24), min(sampleRate, 96000)
+	}
+
+	return QualityProbe{
+		TrackID:    fmt.Sprintf("%d", track.ID),
+		Title:      track.Title,
+		Artist:     track.Performer.Name,
+		BitDepth:   bitDepth,
+		SampleRate: sampleRate,
+	}, nil
+}
+
+func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
+	downloader := NewQobuzDownloader()
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return QobuzDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+	}
+
+	track, err := resolveQobuzTrack(downloader, req)
+	if err != nil {
+		return QobuzDownloadResult{}, err
+	}
+	return downloadQobuzTrack(downloader, req, track)
+}
+
+// downloadProbedQobuz downloads the track probeQobuzQuality matched
+func downloadProbedQobuz(req DownloadRequest, probe QualityProbe) (QobuzDownloadResult, error) {
+	downloader := NewQobuzDownloader()
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return QobuzDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+	}
+
+	var trackID int64
+	if _, err := fmt.Sscanf(probe.TrackID, "%d", &trackID); err != nil || trackID <= 0 {
+		return QobuzDownloadResult{}, fmt.Errorf("invalid Qobuz track ID from quality probe: %q", probe.TrackID)
+	}
+	track, err := downloader.GetTrackByID(trackID)
+	if err != nil {
+		return QobuzDownloadResult{}, stageError(StageProviderID, "", fmt.Errorf("failed to get probed track %d: %w", trackID, err))
+	}
+	GoLog("[Qobuz] Using track %d matched by the quality probe\n", track.ID)
+	return downloadQobuzTrack(downloader, req, track)
+}
+
+// downloadQobuzTrack downloads and tags a resolved track
+func downloadQobuzTrack(downloader *QobuzDownloader, req DownloadRequest, track *QobuzTrack) (QobuzDownloadResult, error) {
+	outputPath := buildTrackOutputPath(req)
 
 	if fileInfo, statErr := os.Stat(outputPath); statErr == nil && fileInfo.Size() > 0 {
 		return QobuzDownloadResult{FilePath: "EXISTS:" + outputPath}, nil
 	}
 
-	// Map quality from Tidal format to Qobuz format
-	// Tidal: LOSSLESS (16-bit), HI_RES (24-bit), HI_RES_LOSSLESS (24-bit hi-res)
-	// Qobuz: 5 (MP3 320), 6 (16-bit), 7 (24-bit 96kHz), 27 (24-bit 192kHz)
-	qobuzQuality := "27" // Default to highest quality
-	switch req.Quality {
-	case "LOSSLESS":
-		qobuzQuality = "6" // 16-bit FLAC
-	case "HI_RES":
-		qobuzQuality = "7" // 24-bit 96kHz
-	case "HI_RES_LOSSLESS":
-		qobuzQuality = "27" // 24-bit 192kHz
-	}
+	qobuzQuality := qobuzQualityCode(req.Quality)
 	GoLog("[Qobuz] Using quality: %s (mapped from %s)\n", qobuzQuality, req.Quality)
 
 	actualBitDepth := track.MaximumBitDepth
@@ -1032,10 +760,22 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 
 	downloadURL, err := downloader.GetDownloadURL(track.ID, qobuzQuality)
 	if err != nil {
-		return QobuzDownloadResult{}, fmt.Errorf("failed to get download URL: %w", err)
+		return QobuzDownloadResult{}, stageError(StageDownloadURL, "", fmt.Errorf("failed to get download URL: %w", err))
 	}
 
-	// START PARALLEL: Fetch cover and lyrics while downloading audio
+	musicBrainzResult := startMusicBrainzLookup(req)
+
+	// START PARALLEL: Fetch cover, lyrics and album context while downloading audio
+	var album *QobuzAlbum
+	albumDone := make(chan struct{})
+	go func() {
+		defer close(albumDone)
+		var albumErr error
+		if album, albumErr = downloader.GetAlbum(track.Album.ID); albumErr != nil {
+			GoLog("[Qobuz] Warning: album context unavailable: %v\n", albumErr)
+		}
+	}()
+
 	var parallelResult *ParallelDownloadResult
 	parallelDone := make(chan struct{})
 	go func() {
@@ -1052,40 +792,26 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 	}()
 
 	// Download audio file with item ID for progress tracking
-	if err := downloader.DownloadFile(downloadURL, outputPath, req.ItemID); err != nil {
+	if err := downloader.DownloadFile(downloadURL, outputPath, req.ItemID, req.KeepPartial, req.DurationMS); err != nil {
 		if errors.Is(err, ErrDownloadCancelled) {
 			return QobuzDownloadResult{}, ErrDownloadCancelled
 		}
-		return QobuzDownloadResult{}, fmt.Errorf("download failed: %w", err)
+		return QobuzDownloadResult{}, stageError(StageDownload, "", fmt.Errorf("download failed: %w", err))
 	}
 
 	// Wait for parallel operations to complete
 	<-parallelDone
+	<-albumDone
 
 	if req.ItemID != "" {
 		SetItemProgress(req.ItemID, 1.0, 0, 0)
 		SetItemFinalizing(req.ItemID)
 	}
 
-	albumName := track.Album.Title
-	if req.AlbumName != "" {
-		albumName = req.AlbumName
-	}
-
-	metadata := Metadata{
-		Title:       track.Title,
-		Artist:      track.Performer.Name,
-		Album:       albumName,
-		AlbumArtist: req.AlbumArtist, // Qobuz track struct might not have this handy, keep req or check album struct
-		Date:        track.Album.ReleaseDate,
-		TrackNumber: track.TrackNumber,
-		TotalTracks: req.TotalTracks,
-		DiscNumber:  req.DiscNumber, // QobuzTrack struct usually doesn't have disc info in simple search result
-		ISRC:        track.ISRC,
-		Genre:       req.Genre,     // From Deezer album metadata
-		Label:       req.Label,     // From Deezer album metadata
-		Copyright:   req.Copyright, // From Deezer album metadata
-	}
+	merged := mergeRequestMetadata(req, MetadataCandidate{Source: "qobuz", Values: qobuzMetadataValues(track, album)})
+	metadata := merged.toMetadata()
+	applyQobuzAlbumContext(&metadata, album)
+	applyMusicBrainzMetadata(&metadata, musicBrainzResult())
 
 	var coverData []byte
 	if parallelResult != nil && parallelResult.CoverData != nil {
@@ -1127,16 +853,8 @@ func downloadFromQobuz(req DownloadRequest) (QobuzDownloadResult, error) {
 	// Add to ISRC index for fast duplicate checking
 	AddToISRCIndex(req.OutputDir, req.ISRC, outputPath)
 
-	return QobuzDownloadResult{
-		FilePath:    outputPath,
-		BitDepth:    actualBitDepth,
-		SampleRate:  actualSampleRate,
-		Title:       track.Title,
-		Artist:      track.Performer.Name,
-		Album:       track.Album.Title,
-		ReleaseDate: track.Album.ReleaseDate,
-		TrackNumber: track.TrackNumber,
-		DiscNumber:  req.DiscNumber, // Qobuz track struct limitations
-		ISRC:        track.ISRC,
-	}, nil
+	result := merged.downloadResult(outputPath, actualBitDepth, actualSampleRate)
+	result.MatchedID = fmt.Sprintf("%d", track.ID)
+	result.Matched = track.matchInfo()
+	return QobuzDownloadResult(result), nil
 }
@@ -0,0 +1,189 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+// qobuzAlbumCacheSize bounds the album cache; album downloads look up the
+// same album for every track
+const qobuzAlbumCacheSize = 64
+
+// QobuzAlbum is a Qobuz release with its track list
+type QobuzAlbum struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	UPC         string `json:"upc"`
+	ReleaseDate string `json:"release_date_original"`
+	MediaCount  int    `json:"media_count"` // Number of discs
+	TracksCount int    `json:"tracks_count"`
+	Copyright   string `json:"copyright"`
+	Artist      struct {
+		Name string `json:"name"`
+	} `json:"artist"`
+	Label struct {
+		Name string `json:"name"`
+	} `json:"label"`
+	Genre struct {
+		Name string `json:"name"`
+	} `json:"genre"`
+	Image struct {
+		Large string `json:"large"`
+	} `json:"image"`
+	Tracks struct {
+		Items []QobuzTrack `json:"items"`
+	} `json:"tracks"`
+}
+
+var (
+	qobuzAlbumCache      = make(map[string]*QobuzAlbum)
+	qobuzAlbumCacheOrder []string // Album IDs, oldest first
+	qobuzAlbumCacheMu    sync.Mutex
+)
+
+// GetAlbum fetches an album with its tracks. Results are cached for the
+// session.
+func (q *QobuzDownloader) GetAlbum(albumID string) (*QobuzAlbum, error) {
+	if albumID == "" {
+		return nil, fmt.Errorf("album ID is empty")
+	}
+
+	qobuzAlbumCacheMu.Lock()
+	cached, ok := qobuzAlbumCache[albumID]
+	qobuzAlbumCacheMu.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	// Qobuz API: /album/get?album_id=XXX
+	albumURL := fmt.Sprintf("%s/album/get?album_id=%s&limit=500&app_id=%s", Endpoints().QobuzAPI, url.QueryEscape(albumID), q.appID)
+
+	req, err := http.NewRequest("GET", albumURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := DoRequestWithUserAgent(q.client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("get album failed: HTTP %d", resp.StatusCode)
+	}
+
+	var album QobuzAlbum
+	if err := json.NewDecoder(resp.Body).Decode(&album); err != nil {
+		return nil, err
+	}
+
+	cacheQobuzAlbum(albumID, &album)
+	return &album, nil
+}
+
+// cacheQobuzAlbum stores an album, evicting the oldest one when the cache is
+// full
+func cacheQobuzAlbum(albumID string, album *QobuzAlbum) {
+	qobuzAlbumCacheMu.Lock()
+	defer qobuzAlbumCacheMu.Unlock()
+
+	if _, ok := qobuzAlbumCache[albumID]; !ok {
+		if len(qobuzAlbumCacheOrder) >= qobuzAlbumCacheSize {
+			delete(qobuzAlbumCache, qobuzAlbumCacheOrder[0])
+			qobuzAlbumCacheOrder = qobuzAlbumCacheOrder[1:]
+		}
+		qobuzAlbumCacheOrder = append(qobuzAlbumCacheOrder, albumID)
+	}
+	qobuzAlbumCache[albumID] = album
+}
+
+// findTrack returns the album's entry for a track ID
+func (a *QobuzAlbum) findTrack(trackID int64) *QobuzTrack {
+	for i := range a.Tracks.Items {
+		if a.Tracks.Items[i].ID == trackID {
+			return &a.Tracks.Items[i]
+		}
+	}
+	return nil
+}
+
+// discNumber returns the disc of a track, preferring the track's own media
+// number and falling back to the album track list. Single-disc albums are
+// always disc 1.
+func (a *QobuzAlbum) discNumber(track *QobuzTrack) int {
+	if track.MediaNumber > 0 {
+		return track.MediaNumber
+	}
+	if albumTrack := a.findTrack(track.ID); albumTrack != nil && albumTrack.MediaNumber > 0 {
+		return albumTrack.MediaNumber
+	}
+	if a.MediaCount == 1 {
+		return 1
+	}
+	return 0
+}
+
+// discTrackCount returns the number of tracks on a disc, or 0 when the disc
+// is unknown or the track list is incomplete
+func (a *QobuzAlbum) discTrackCount(disc int) int {
+	if a.MediaCount <= 1 {
+		return a.TracksCount
+	}
+	if disc <= 0 || len(a.Tracks.Items) < a.TracksCount {
+		return 0
+	}
+	count := 0
+	for _, track := range a.Tracks.Items {
+		if track.MediaNumber == disc {
+			count++
+		}
+	}
+	return count
+}
+
+// qobuzMetadataValues returns Qobuz's own tags for a track, with album-level
+// values from the matched release when it could be fetched
+func qobuzMetadataValues(track *QobuzTrack, album *QobuzAlbum) MetadataValues {
+	values := MetadataValues{
+		Title:       track.Title,
+		Artist:      track.Performer.Name,
+		Album:       track.Album.Title,
+		ReleaseDate: track.Album.ReleaseDate,
+		TrackNumber: track.TrackNumber,
+		DiscNumber:  track.MediaNumber,
+		ISRC:        track.ISRC,
+	}
+	if album == nil {
+		return values
+	}
+
+	values.DiscNumber = album.discNumber(track)
+	// Track totals count the tracks of the track's own disc
+	values.TotalTracks = album.discTrackCount(values.DiscNumber)
+	values.AlbumArtist = album.Artist.Name
+	if album.ReleaseDate != "" {
+		values.ReleaseDate = album.ReleaseDate
+	}
+	values.Genre = album.Genre.Name
+	values.Label = album.Label.Name
+	values.Copyright = album.Copyright
+	return values
+}
+
+// applyQobuzAlbumContext fills the album tags the metadata merge does not
+// cover from the matched Qobuz release
+func applyQobuzAlbumContext(metadata *Metadata, album *QobuzAlbum) {
+	if album == nil {
+		return
+	}
+	if album.MediaCount > 0 {
+		metadata.TotalDiscs = album.MediaCount
+	}
+	if album.UPC != "" {
+		metadata.UPC = album.UPC
+	}
+}
@@ -0,0 +1,135 @@
+package gobackend
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+const qobuzAlbumFixture = `{
+	"id": "0060254785627",
+	"title": "Deluxe Edition",
+	"upc": "0060254785627",
+	"release_date_original": "2021-05-14",
+	"media_count": 2,
+	"tracks_count": 3,
+	"copyright": "(P) 2021 Label",
+	"artist": {"name": "Album Artist"},
+	"label": {"name": "Qobuz Label"},
+	"genre": {"name": "Pop"},
+	"tracks": {"items": [
+		{"id": 101, "title": "Opener", "track_number": 1, "media_number": 1},
+		{"id": 102, "title": "Closer", "track_number": 2, "media_number": 1},
+		{"id": 213, "title": "Bonus", "track_number": 1, "media_number": 2}
+	]}
+}`
+
+func TestQobuzMetadataValues(t *testing.T) {
+	var album QobuzAlbum
+	if err := json.Unmarshal([]byte(qobuzAlbumFixture), &album); err != nil {
+		t.Fatal(err)
+	}
+
+	// Search results may omit media_number; the album track list fills it in
+	track := &QobuzTrack{ID: 213, Title: "Bonus", TrackNumber: 1}
+	values := qobuzMetadataValues(track, &album)
+
+	if values.DiscNumber != 2 || values.TotalTracks != 1 {
+		t.Errorf("disc %d, total tracks %d; want 2, 1", values.DiscNumber, values.TotalTracks)
+	}
+	if values.AlbumArtist != "Album Artist" || values.ReleaseDate != "2021-05-14" {
+		t.Errorf("AlbumArtist/ReleaseDate = %q/%q", values.AlbumArtist, values.ReleaseDate)
+	}
+	if values.Genre != "Pop" || values.Label != "Qobuz Label" || values.Copyright != "(P) 2021 Label" {
+		t.Errorf("Genre/Label/Copyright = %q/%q/%q", values.Genre, values.Label, values.Copyright)
+	}
+
+	// The merge keeps the request's genre but takes the release layout from Qobuz
+	req := DownloadRequest{TrackName: "Bonus", AlbumArtist: "Extension Artist", DiscNumber: 1, TotalTracks: 12, Genre: "Dance"}
+	metadata := mergeRequestMetadata(req, MetadataCandidate{Source: "qobuz", Values: values}).toMetadata()
+	applyQobuzAlbumContext(&metadata, &album)
+
+	if metadata.DiscNumber != 2 || metadata.TotalDiscs != 2 || metadata.TotalTracks != 1 {
+		t.Errorf("disc = %d/%d, total tracks %d; want 2/2, 1", metadata.DiscNumber, metadata.TotalDiscs, metadata.TotalTracks)
+	}
+	if metadata.AlbumArtist != "Album Artist" || metadata.UPC != "0060254785627" {
+		t.Errorf("AlbumArtist/UPC = %q/%q", metadata.AlbumArtist, metadata.UPC)
+	}
+	if metadata.Genre != "Dance" || metadata.Label != "Qobuz Label" {
+		t.Errorf("Genre/Label = %q/%q, want request genre and Qobuz label", metadata.Genre, metadata.Label)
+	}
+}
+
+func TestQobuzAlbumDiscNumber(t *testing.T) {
+	var album QobuzAlbum
+	if err := json.Unmarshal([]byte(qobuzAlbumFixture), &album); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := album.discNumber(&QobuzTrack{ID: 999, MediaNumber: 2}); got != 2 {
+		t.Errorf("track media number: got %d, want 2", got)
+	}
+	if got := album.discNumber(&QobuzTrack{ID: 999}); got != 0 {
+		t.Errorf("unknown track on multi-disc album: got %d, want 0", got)
+	}
+
+	album.MediaCount = 1
+	if got := album.discNumber(&QobuzTrack{ID: 999}); got != 1 {
+		t.Errorf("single-disc album: got %d, want 1", got)
+	}
+
+	// Without album context the track's own media number is still used
+	if values := qobuzMetadataValues(&QobuzTrack{MediaNumber: 3}, nil); values.DiscNumber != 3 {
+		t.Errorf("no album: disc = %d, want 3", values.DiscNumber)
+	}
+}
+
+func TestQobuzAlbumDiscTrackCount(t *testing.T) {
+	var album QobuzAlbum
+	if err := json.Unmarshal([]byte(qobuzAlbumFixture), &album); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := album.discTrackCount(1); got != 2 {
+		t.Errorf("disc 1: got %d, want 2", got)
+	}
+	if got := album.discTrackCount(0); got != 0 {
+		t.Errorf("unknown disc: got %d, want 0", got)
+	}
+
+	// A truncated track list cannot be counted per disc
+	album.TracksCount = 30
+	if got := album.discTrackCount(1); got != 0 {
+		t.Errorf("incomplete track list: got %d, want 0", got)
+	}
+
+	album.MediaCount = 1
+	if got := album.discTrackCount(1); got != 30 {
+		t.Errorf("single-disc album: got %d, want 30", got)
+	}
+}
+
+func TestQobuzAlbumCacheEvictsOldest(t *testing.T) {
+	qobuzAlbumCacheMu.Lock()
+	savedCache, savedOrder := qobuzAlbumCache, qobuzAlbumCacheOrder
+	qobuzAlbumCache, qobuzAlbumCacheOrder = make(map[string]*QobuzAlbum), nil
+	qobuzAlbumCacheMu.Unlock()
+	defer func() {
+		qobuzAlbumCacheMu.Lock()
+		qobuzAlbumCache, qobuzAlbumCacheOrder = savedCache, savedOrder
+		qobuzAlbumCacheMu.Unlock()
+	}()
+
+	for i := range qobuzAlbumCacheSize + 1 {
+		cacheQobuzAlbum(fmt.Sprint(i), &QobuzAlbum{})
+	}
+	if _, ok := qobuzAlbumCache["0"]; ok {
+		t.Error("oldest album was kept")
+	}
+	if len(qobuzAlbumCache) != qobuzAlbumCacheSize {
+		t.Errorf("cache holds %d albums, want %d", len(qobuzAlbumCache), qobuzAlbumCacheSize)
+	}
+	if _, ok := qobuzAlbumCache["1"]; !ok {
+		t.Error("only the oldest album should be evicted")
+	}
+}
@@ -95,3 +95,10 @@ var songLinkRateLimiter = NewRateLimiter(9, time.Minute)
 func GetSongLinkRateLimiter() *RateLimiter {
 	return songLinkRateLimiter
 }
+
+// Global MusicBrainz rate limiter - 1 request per second as their API requires
+var musicBrainzRateLimiter = NewRateLimiter(1, time.Second)
+
+func GetMusicBrainzRateLimiter() *RateLimiter {
+	return musicBrainzRateLimiter
+}
@@ -0,0 +1,146 @@
+package gobackend
+
+import (
+	"os"
+	"strings"
+	"sync"
+)
+
+const maxResolveConcurrency = 4
+
+// ServiceResolution is what one built-in service would do for a request
+type ServiceResolution struct {
+	Service            string `json:"service"`
+	Matched            bool   `json:"matched"`
+	RemoteTrackID      string `json:"remote_track_id,omitempty"`
+	RemoteTitle        string `json:"remote_title,omitempty"`
+	RemoteArtist       string `json:"remote_artist,omitempty"`
+	ExpectedBitDepth   int    `json:"expected_bit_depth,omitempty"` // 0 when only known after download
+	ExpectedSampleRate int    `json:"expected_sample_rate,omitempty"`
+	Error              string `json:"error,omitempty"`
+	ErrorType          string `json:"error_type,omitempty"`
+}
+
+// DownloadResolution previews a download without fetching audio
+type DownloadResolution struct {
+	TrackName    string              `json:"track_name"`
+	ArtistName   string              `json:"artist_name"`
+	ISRC         string              `json:"isrc,omitempty"`
+	Service      string              `json:"service,omitempty"` // Service the fallback would download from
+	OutputPath   string              `json:"output_path"`
+	WouldSkip    bool                `json:"would_skip"`
+	SkipReason   string              `json:"skip_reason,omitempty"` // "isrc_index" or "file_exists"
+	ExistingPath string              `json:"existing_path,omitempty"`
+	PartialPath  string              `json:"partial_path,omitempty"` // Leftover .m4a of an interrupted Tidal DASH download or remux
+	Services     []ServiceResolution `json:"services"`               // Services checked, in fallback order
+	Error        string              `json:"error,omitempty"`
+}
+
+// existingDownloadPath reports whether the duplicate checks of the built-in
+// services would skip the request, and why
+func existingDownloadPath(req DownloadRequest, outputPath string) (string, string, bool) {
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return existingFile, "isrc_index", true
+	}
+	if fileInfo, err := os.Stat(outputPath); err == nil && fileInfo.Size() > 0 {
+		return outputPath, "file_exists", true
+	}
+	return "", "", false
+}
+
+// leftoverM4APath returns the .m4a a Tidal DASH download writes before
+// remuxing it to outputPath, if one was left behind
+func leftoverM4APath(outputPath string) string {
+	m4aPath := strings.TrimSuffix(outputPath, ".flac") + ".m4a"
+	if fileInfo, err := os.Stat(m4aPath); err == nil && fileInfo.Size() > 0 {
+		return m4aPath
+	}
+	return ""
+}
+
+// ResolveDownload runs the duplicate check and the same track matching as
+// DownloadWithFallback, stopping at the first service that matches, without
+// fetching audio. Services follow the fallback policy; disabled ones are
+// not checked.
+func ResolveDownload(req DownloadRequest) *DownloadResolution {
+	req.TrackName = strings.TrimSpace(req.TrackName)
+	req.ArtistName = strings.TrimSpace(req.ArtistName)
+	req.AlbumName = strings.TrimSpace(req.AlbumName)
+	req.OutputDir = strings.TrimSpace(req.OutputDir)
+
+	outputPath := buildTrackOutputPath(req)
+	resolution := &DownloadResolution{
+		TrackName:  req.TrackName,
+		ArtistName: req.ArtistName,
+		ISRC:       req.ISRC,
+		OutputPath: outputPath,
+		Services:   []ServiceResolution{},
+	}
+
+	if existingPath, reason, exists := existingDownloadPath(req, outputPath); exists {
+		resolution.WouldSkip = true
+		resolution.SkipReason = reason
+		resolution.ExistingPath = existingPath
+		return resolution
+	}
+	resolution.PartialPath = leftoverM4APath(outputPath)
+
+	// Same order, enabled services and quality caps as DownloadWithFallback
+	policy := GetFallbackPolicyStore().Get()
+	for _, service := range policy.serviceOrder(req.Service) {
+		provider, _ := GetDownloadProvider(service)
+		prober, ok := provider.(QualityProber)
+		if !ok {
+			continue
+		}
+
+		probeReq := req
+		probeReq.Service = service
+		probeReq.Quality = capQuality(probeReq.Quality, policy.lookup(service).MaxQuality)
+		probe, err := prober.ProbeQuality(probeReq)
+		if err != nil {
+			resolution.Services = append(resolution.Services, ServiceResolution{
+				Service:   service,
+				Error:     err.Error(),
+				ErrorType: classifyErrorType(err.Error()),
+			})
+			continue
+		}
+
+		resolution.Services = append(resolution.Services, ServiceResolution{
+			Service:            service,
+			Matched:            true,
+			RemoteTrackID:      probe.TrackID,
+			RemoteTitle:        probe.Title,
+			RemoteArtist:       probe.Artist,
+			ExpectedBitDepth:   probe.BitDepth,
+			ExpectedSampleRate: probe.SampleRate,
+		})
+		resolution.Service = service
+		return resolution
+	}
+
+	resolution.Error = "no service matched the track"
+	return resolution
+}
+
+// ResolveDownloads resolves a batch of requests with bounded parallelism,
+// keeping the input order
+func ResolveDownloads(reqs []DownloadRequest) []*DownloadResolution {
+	results := make([]*DownloadResolution, len(reqs))
+	sem := make(chan struct{}, maxResolveConcurrency)
+	var wg sync.WaitGroup
+
+	for i, req := range reqs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, req DownloadRequest) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = ResolveDownload(req)
+		}(i, req)
+	}
+	wg.Wait()
+
+	return results
+}
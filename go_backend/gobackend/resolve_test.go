@@ -0,0 +1,125 @@
+package gobackend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestResolveDownload_ExistingFileWouldSkip(t *testing.T) {
+	dir := t.TempDir()
+	req := DownloadRequest{
+		TrackName:      "Song",
+		ArtistName:     "Artist",
+		OutputDir:      dir,
+		FilenameFormat: "{artist} - {title}",
+	}
+
+	expected := filepath.Join(dir, "Artist - Song.flac")
+	if err := os.WriteFile(expected, []byte("fLaC"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolution := ResolveDownload(req)
+	if resolution.OutputPath != expected {
+		t.Errorf("expected output path %s, got %s", expected, resolution.OutputPath)
+	}
+	if !resolution.WouldSkip || resolution.SkipReason != "file_exists" || resolution.ExistingPath != expected {
+		t.Errorf("expected skip for existing file, got %+v", resolution)
+	}
+	if len(resolution.Services) != 0 {
+		t.Errorf("no service should be queried for a skipped track, got %+v", resolution.Services)
+	}
+}
+
+func TestResolveDownload_LeftoverM4AIsPartial(t *testing.T) {
+	saved, _ := GetDownloadProvider("tidal")
+	t.Cleanup(func() { RegisterDownloadProvider(saved) })
+	RegisterDownloadProvider(fakeProber{id: "tidal", qualities: map[string]string{}})
+	setTestFallbackPolicy(t, FallbackPolicy{Services: []ServicePolicy{
+		{Service: "tidal", Enabled: true},
+		{Service: "qobuz", Enabled: false},
+		{Service: "amazon", Enabled: false},
+	}})
+
+	dir := t.TempDir()
+	m4aPath := filepath.Join(dir, "Artist - Song.m4a")
+	if err := os.WriteFile(m4aPath, []byte("ftyp"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	resolution := ResolveDownload(DownloadRequest{TrackName: "Song", ArtistName: "Artist", OutputDir: dir, FilenameFormat: "{artist} - {title}"})
+	if resolution.WouldSkip || resolution.PartialPath != m4aPath {
+		t.Errorf("leftover .m4a should be reported as partial, got %+v", resolution)
+	}
+	if len(resolution.Services) != 1 || !resolution.Services[0].Matched {
+		t.Errorf("services should still be resolved, got %+v", resolution.Services)
+	}
+}
+
+// fakeProber stands in for a built-in service and records the quality it
+// was probed with
+type fakeProber struct {
+	id        string
+	err       error
+	qualities map[string]string
+}
+
+func (p fakeProber) ID() string { return p.id }
+
+func (p fakeProber) Download(req DownloadRequest) (DownloadResult, error) {
+	return DownloadResult{}, errors.New("not implemented")
+}
+
+func (p fakeProber) DownloadProbed(req DownloadRequest, probe QualityProbe) (DownloadResult, error) {
+	return DownloadResult{}, errors.New("not implemented")
+}
+
+func (p fakeProber) ProbeQuality(req DownloadRequest) (QualityProbe, error) {
+	p.qualities[p.id] = req.Quality
+	if p.err != nil {
+		return QualityProbe{}, p.err
+	}
+	return QualityProbe{TrackID: p.id + "-1", BitDepth: 24, SampleRate: 96000}, nil
+}
+
+func TestResolveDownload_FollowsFallbackPolicy(t *testing.T) {
+	qualities := map[string]string{}
+	for _, id := range []string{"tidal", "qobuz", "amazon"} {
+		saved, _ := GetDownloadProvider(id)
+		t.Cleanup(func() { RegisterDownloadProvider(saved) })
+	}
+	RegisterDownloadProvider(fakeProber{id: "tidal", qualities: qualities})
+	RegisterDownloadProvider(fakeProber{id: "qobuz", qualities: qualities})
+	RegisterDownloadProvider(fakeProber{id: "amazon", err: errors.New("track not found"), qualities: qualities})
+
+	setTestFallbackPolicy(t, FallbackPolicy{Services: []ServicePolicy{
+		{Service: "amazon", Enabled: true},
+		{Service: "tidal", Enabled: false},
+		{Service: "qobuz", Enabled: true, MaxQuality: "LOSSLESS"},
+	}})
+
+	resolution := ResolveDownload(DownloadRequest{
+		TrackName:  "Song",
+		ArtistName: "Artist",
+		OutputDir:  t.TempDir(),
+		Service:    "tidal",
+		Quality:    "HI_RES_LOSSLESS",
+	})
+
+	var checked []string
+	for _, s := range resolution.Services {
+		checked = append(checked, s.Service)
+	}
+	if !reflect.DeepEqual(checked, []string{"amazon", "qobuz"}) || resolution.Service != "qobuz" {
+		t.Errorf("checked %v, chose %q; want amazon then qobuz", checked, resolution.Service)
+	}
+	if _, probed := qualities["tidal"]; probed {
+		t.Error("disabled service was probed")
+	}
+	if qualities["qobuz"] != "LOSSLESS" {
+		t.Errorf("qobuz probed at %q, want the LOSSLESS cap", qualities["qobuz"])
+	}
+}
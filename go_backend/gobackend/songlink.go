@@ -52,8 +52,8 @@ func (s *SongLinkClient) CheckTrackAvailability(spotifyTrackID string, isrc stri
 	spotifyBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9vcGVuLnNwb3RpZnkuY29tL3RyYWNrLw==")
 	spotifyURL := fmt.Sprintf("%s%s", string(spotifyBase), spotifyTrackID)
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9hcGkuc29uZy5saW5rL3YxLWFscGhhLjEvbGlua3M/dXJsPQ==")
-	apiURL := fmt.Sprintf("%s%s", string(apiBase), url.QueryEscape(spotifyURL))
+	apiBase := Endpoints().SongLink + "/links?url="
+	apiURL := fmt.Sprintf("%s%s", apiBase, url.QueryEscape(spotifyURL))
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -143,8 +143,8 @@ func checkQobuzAvailability(isrc string) bool {
 	client := NewHTTPClientWithTimeout(10 * time.Second)
 	appID := "798273057"
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly93d3cucW9idXouY29tL2FwaS5qc29uLzAuMi90cmFjay9zZWFyY2g/cXVlcnk9")
-	searchURL := fmt.Sprintf("%s%s&limit=1&app_id=%s", string(apiBase), isrc, appID)
+	apiBase := Endpoints().QobuzAPI + "/track/search?query="
+	searchURL := fmt.Sprintf("%s%s&limit=1&app_id=%s", apiBase, isrc, appID)
 
 	req, err := http.NewRequest("GET", searchURL, nil)
 	if err != nil {
@@ -215,8 +215,8 @@ func (s *SongLinkClient) CheckAlbumAvailability(spotifyAlbumID string) (*AlbumAv
 	spotifyBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9vcGVuLnNwb3RpZnkuY29tL2FsYnVtLw==")
 	spotifyURL := fmt.Sprintf("%s%s", string(spotifyBase), spotifyAlbumID)
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9hcGkuc29uZy5saW5rL3YxLWFscGhhLjEvbGlua3M/dXJsPQ==")
-	apiURL := fmt.Sprintf("%s%s", string(apiBase), url.QueryEscape(spotifyURL))
+	apiBase := Endpoints().SongLink + "/links?url="
+	apiURL := fmt.Sprintf("%s%s", apiBase, url.QueryEscape(spotifyURL))
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -286,8 +286,8 @@ func (s *SongLinkClient) CheckAvailabilityFromDeezer(deezerTrackID string) (*Tra
 
 	deezerURL := fmt.Sprintf("https://www.deezer.com/track/%s", deezerTrackID)
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9hcGkuc29uZy5saW5rL3YxLWFscGhhLjEvbGlua3M/dXJsPQ==")
-	apiURL := fmt.Sprintf("%s%s&userCountry=US", string(apiBase), url.QueryEscape(deezerURL))
+	apiBase := Endpoints().SongLink + "/links?url="
+	apiURL := fmt.Sprintf("%s%s&userCountry=US", apiBase, url.QueryEscape(deezerURL))
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -375,7 +375,8 @@ func (s *SongLinkClient) CheckAvailabilityByPlatform(platform, entityType, entit
 
 	// Build API URL using platform, type, and id parameters (as per API docs)
 	// https://api.song.link/v1-alpha.1/links?platform=deezer&type=song&id=123456
-	apiURL := fmt.Sprintf("https://api.song.link/v1-alpha.1/links?platform=%s&type=%s&id=%s&userCountry=US",
+	apiURL := fmt.Sprintf("%s/links?platform=%s&type=%s&id=%s&userCountry=US",
+		Endpoints().SongLink,
 		url.QueryEscape(platform),
 		url.QueryEscape(entityType),
 		url.QueryEscape(entityID))
@@ -498,3 +499,92 @@ func (s *SongLinkClient) GetAmazonURLFromDeezer(deezerTrackID string) (string, e
 	
 	return availability.AmazonURL, nil
 }
+
+// SongLinkEntity is what SongLink identified a pasted music link as, with the
+// matching Spotify and Deezer IDs when it knows them
+type SongLinkEntity struct {
+	Type      string `json:"type"` // "track" or "album"
+	Title     string `json:"title,omitempty"`
+	Artist    string `json:"artist,omitempty"`
+	SpotifyID string `json:"spotify_id,omitempty"`
+	DeezerID  string `json:"deezer_id,omitempty"`
+}
+
+// ResolveURL looks up a track or album link from any platform SongLink
+// supports (Apple Music, YouTube Music, Tidal, song.link pages, ...)
+func (s *SongLinkClient) ResolveURL(musicURL string) (*SongLinkEntity, error) {
+	songLinkRateLimiter.WaitForSlot()
+
+	apiURL := fmt.Sprintf("%s/links?url=%s&userCountry=US", Endpoints().SongLink, url.QueryEscape(musicURL))
+
+	req, err := http.NewRequest("GET", apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	retryConfig := DefaultRetryConfig()
+	resp, err := DoRequestWithRetry(s.client, req, retryConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == 400 || resp.StatusCode == 404 {
+		return nil, fmt.Errorf("URL not recognized by SongLink")
+	}
+	if resp.StatusCode == 429 {
+		return nil, fmt.Errorf("SongLink rate limit exceeded")
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("SongLink API returned status %d", resp.StatusCode)
+	}
+
+	body, err := ReadResponseBody(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var songLinkResp struct {
+		EntityUniqueID     string `json:"entityUniqueId"`
+		EntitiesByUniqueID map[string]struct {
+			Type       string `json:"type"`
+			Title      string `json:"title"`
+			ArtistName string `json:"artistName"`
+		} `json:"entitiesByUniqueId"`
+		LinksByPlatform map[string]struct {
+			URL string `json:"url"`
+		} `json:"linksByPlatform"`
+	}
+
+	if err := json.Unmarshal(body, &songLinkResp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	source, ok := songLinkResp.EntitiesByUniqueID[songLinkResp.EntityUniqueID]
+	if !ok {
+		return nil, fmt.Errorf("SongLink returned no entity for URL")
+	}
+
+	entity := &SongLinkEntity{
+		Type:   "track",
+		Title:  source.Title,
+		Artist: source.ArtistName,
+	}
+	if source.Type == "album" {
+		entity.Type = "album"
+	}
+
+	if spotifyLink, ok := songLinkResp.LinksByPlatform["spotify"]; ok && spotifyLink.URL != "" {
+		if parsed, err := parseSpotifyURI(spotifyLink.URL); err == nil && parsed.Type == entity.Type {
+			entity.SpotifyID = parsed.ID
+		}
+	}
+
+	if deezerLink, ok := songLinkResp.LinksByPlatform["deezer"]; ok && deezerLink.URL != "" {
+		if resourceType, resourceID, err := parseDeezerURL(deezerLink.URL); err == nil && resourceType == entity.Type {
+			entity.DeezerID = resourceID
+		}
+	}
+
+	return entity, nil
+}
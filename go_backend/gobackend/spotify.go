@@ -23,10 +23,6 @@ const (
 	artistBaseURL   = "https://api.spotify.com/v1/artists/%s"
 	artistAlbumsURL = "https://api.spotify.com/v1/artists/%s/albums"
 	searchBaseURL   = "https://api.spotify.com/v1/search"
-
-	artistCacheTTL = 10 * time.Minute
-	searchCacheTTL = 5 * time.Minute
-	albumCacheTTL  = 10 * time.Minute
 )
 
 var errInvalidSpotifyURL = errors.New("invalid or unsupported Spotify URL")
@@ -50,11 +46,6 @@ type SpotifyMetadataClient struct {
 	rng            *rand.Rand
 	rngMu          sync.Mutex
 	userAgent      string
-
-	artistCache map[string]*cacheEntry
-	searchCache map[string]*cacheEntry
-	albumCache  map[string]*cacheEntry
-	cacheMu     sync.RWMutex
 }
 
 var (
@@ -121,9 +112,6 @@ func NewSpotifyMetadataClient() (*SpotifyMetadataClient, error) {
 		clientID:     clientID,
 		clientSecret: clientSecret,
 		rng:          rand.New(src),
-		artistCache:  make(map[string]*cacheEntry),
-		searchCache:  make(map[string]*cacheEntry),
-		albumCache:   make(map[string]*cacheEntry),
 	}
 	c.userAgent = c.randomUserAgent()
 	return c, nil
@@ -299,6 +287,14 @@ func (c *SpotifyMetadataClient) GetFilteredData(ctx context.Context, spotifyURL
 		return nil, err
 	}
 
+	// Private and collaborative playlists need the user's own token, which
+	// only requires a Client ID
+	if parsed.Type == "playlist" {
+		if userToken, err := GetSpotifyAuthStore().AccessToken(ctx); err == nil {
+			return c.fetchPlaylist(ctx, parsed.ID, userToken)
+		}
+	}
+
 	token, err := c.getAccessToken(ctx)
 	if err != nil {
 		return nil, err
@@ -367,12 +363,10 @@ func (c *SpotifyMetadataClient) SearchTracks(ctx context.Context, query string,
 func (c *SpotifyMetadataClient) SearchAll(ctx context.Context, query string, trackLimit, artistLimit int) (*SearchAllResult, error) {
 	cacheKey := fmt.Sprintf("all:%s:%d:%d", query, trackLimit, artistLimit)
 
-	c.cacheMu.RLock()
-	if entry, ok := c.searchCache[cacheKey]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*SearchAllResult), nil
+	var cached SearchAllResult
+	if GetMetadataCache().Get(CacheSpotifySearch, cacheKey, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	token, err := c.getAccessToken(ctx)
 	if err != nil {
@@ -442,12 +436,7 @@ func (c *SpotifyMetadataClient) SearchAll(ctx context.Context, query string, tra
 		})
 	}
 
-	c.cacheMu.Lock()
-	c.searchCache[cacheKey] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(searchCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheSpotifySearch, cacheKey, result)
 
 	return result, nil
 }
@@ -478,12 +467,10 @@ func (c *SpotifyMetadataClient) fetchTrack(ctx context.Context, trackID, token s
 }
 
 func (c *SpotifyMetadataClient) fetchAlbum(ctx context.Context, albumID, token string) (*AlbumResponsePayload, error) {
-	c.cacheMu.RLock()
-	if entry, ok := c.albumCache[albumID]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*AlbumResponsePayload), nil
+	var cached AlbumResponsePayload
+	if GetMetadataCache().Get(CacheSpotifyAlbum, albumID, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	type trackItem struct {
 		ID          string      `json:"id"`
@@ -573,12 +560,7 @@ func (c *SpotifyMetadataClient) fetchAlbum(ctx context.Context, albumID, token s
 		TrackList: tracks,
 	}
 
-	c.cacheMu.Lock()
-	c.albumCache[albumID] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(albumCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheSpotifyAlbum, albumID, result)
 
 	return result, nil
 }
@@ -721,12 +703,10 @@ func (c *SpotifyMetadataClient) fetchPlaylist(ctx context.Context, playlistID, t
 }
 
 func (c *SpotifyMetadataClient) fetchArtist(ctx context.Context, artistID, token string) (*ArtistResponsePayload, error) {
-	c.cacheMu.RLock()
-	if entry, ok := c.artistCache[artistID]; ok && !entry.isExpired() {
-		c.cacheMu.RUnlock()
-		return entry.data.(*ArtistResponsePayload), nil
+	var cached ArtistResponsePayload
+	if GetMetadataCache().Get(CacheSpotifyArtist, artistID, &cached) {
+		return &cached, nil
 	}
-	c.cacheMu.RUnlock()
 
 	var artistData struct {
 		ID        string  `json:"id"`
@@ -804,23 +784,26 @@ func (c *SpotifyMetadataClient) fetchArtist(ctx context.Context, artistID, token
 		Albums:     albums,
 	}
 
-	c.cacheMu.Lock()
-	c.artistCache[artistID] = &cacheEntry{
-		data:      result,
-		expiresAt: time.Now().Add(artistCacheTTL),
-	}
-	c.cacheMu.Unlock()
+	GetMetadataCache().Set(CacheSpotifyArtist, artistID, result)
 
 	return result, nil
 }
 
 func (c *SpotifyMetadataClient) fetchTrackISRC(ctx context.Context, trackID, token string) string {
+	var isrc string
+	if GetMetadataCache().Get(CacheSpotifyISRC, trackID, &isrc) {
+		return isrc
+	}
+
 	var data struct {
 		ExternalID externalID `json:"external_ids"`
 	}
 	if err := c.getJSON(ctx, fmt.Sprintf(trackBaseURL, trackID), token, &data); err != nil {
 		return ""
 	}
+	if data.ExternalID.ISRC != "" {
+		GetMetadataCache().Set(CacheSpotifyISRC, trackID, data.ExternalID.ISRC)
+	}
 	return data.ExternalID.ISRC
 }
 
@@ -828,6 +811,10 @@ func (c *SpotifyMetadataClient) getAccessToken(ctx context.Context) (string, err
 	if c.cachedToken != "" && time.Now().Before(c.tokenExpiresAt) {
 		return c.cachedToken, nil
 	}
+	if c.clientID == "" || c.clientSecret == "" {
+		// Clients for user tokens carry no client credentials
+		return "", ErrNoSpotifyCredentials
+	}
 
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
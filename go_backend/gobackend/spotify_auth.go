@@ -0,0 +1,424 @@
+package gobackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spotifyAuthorizeURL = "https://accounts.spotify.com/authorize"
+	spotifyAuthFileName = "spotify_auth.json"
+)
+
+// Scopes requested for the user library exports and private playlists
+var spotifyUserScopes = []string{
+	"user-library-read",
+	"user-follow-read",
+	"playlist-read-private",
+	"playlist-read-collaborative",
+}
+
+// ErrSpotifyNotAuthorized is returned by user library calls before a Spotify
+// account has been connected, or after its refresh token was revoked
+var ErrSpotifyNotAuthorized = errors.New("Spotify account not connected. Please log in to Spotify in Settings")
+
+// spotifyUserToken is a user token issued by the authorization code flow
+type spotifyUserToken struct {
+	ClientID     string    `json:"client_id"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	Scope        string    `json:"scope,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// spotifyPendingAuth holds the PKCE verifier between StartAuthorization and
+// CompleteAuthorization. It is persisted so that the flow survives the app
+// being killed while the browser is open
+type spotifyPendingAuth struct {
+	State       string `json:"state"`
+	Verifier    string `json:"verifier"`
+	ClientID    string `json:"client_id"`
+	RedirectURI string `json:"redirect_uri"`
+}
+
+type spotifyAuthFile struct {
+	Token   *spotifyUserToken   `json:"token,omitempty"`
+	Pending *spotifyPendingAuth `json:"pending,omitempty"`
+}
+
+// SpotifyAuthStatus describes the connected Spotify account
+type SpotifyAuthStatus struct {
+	Authorized bool     `json:"authorized"`
+	Scopes     []string `json:"scopes,omitempty"`
+	ExpiresAt  int64    `json:"expires_at,omitempty"` // Unix milliseconds of the current access token
+	Pending    bool     `json:"pending"`              // A login was started and not completed yet
+}
+
+// SpotifyAuthStart is returned when a login is started
+type SpotifyAuthStart struct {
+	AuthURL string `json:"auth_url"`
+	State   string `json:"state"`
+}
+
+// SpotifyAuthStore keeps the user token of the authorization code flow and
+// refreshes it when it expires
+type SpotifyAuthStore struct {
+	mu sync.Mutex
+	// refreshMu serializes refreshes. The HTTP request runs without holding
+	// mu so that Status and Logout never wait on the network.
+	refreshMu  sync.Mutex
+	filePath   string
+	token      *spotifyUserToken
+	pending    *spotifyPendingAuth
+	httpClient *http.Client
+	tokenURL   string
+}
+
+var (
+	globalSpotifyAuthStore *SpotifyAuthStore
+	spotifyAuthStoreOnce   sync.Once
+)
+
+func GetSpotifyAuthStore() *SpotifyAuthStore {
+	spotifyAuthStoreOnce.Do(func() {
+		globalSpotifyAuthStore = &SpotifyAuthStore{
+			httpClient: NewHTTPClientWithTimeout(15 * time.Second),
+			tokenURL:   spotifyTokenURL,
+		}
+	})
+	return globalSpotifyAuthStore
+}
+
+// getSpotifyClientID returns the configured client ID. PKCE needs no client
+// secret, so unlike getCredentials a missing secret is not an error
+func getSpotifyClientID() (string, error) {
+	credentialsMu.RLock()
+	defer credentialsMu.RUnlock()
+
+	if customClientID != "" {
+		return customClientID, nil
+	}
+	if clientID := os.Getenv("SPOTIFY_CLIENT_ID"); clientID != "" {
+		return clientID, nil
+	}
+	return "", ErrNoSpotifyCredentials
+}
+
+// SetDataDir sets the directory of the token file and loads the saved token
+func (s *SpotifyAuthStore) SetDataDir(dataDir string) error {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return fmt.Errorf("failed to create auth directory: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.filePath = filepath.Join(dataDir, spotifyAuthFileName)
+	data, err := os.ReadFile(s.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var saved spotifyAuthFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		GoLog("[SpotifyAuth] Ignoring unreadable token file: %v\n", err)
+		return nil
+	}
+	s.token = saved.Token
+	s.pending = saved.Pending
+	if s.token != nil {
+		GoLog("[SpotifyAuth] Loaded saved Spotify login\n")
+	}
+	return nil
+}
+
+func (s *SpotifyAuthStore) saveLocked() error {
+	if s.filePath == "" {
+		return nil
+	}
+	if s.token == nil && s.pending == nil {
+		if err := os.Remove(s.filePath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.MarshalIndent(spotifyAuthFile{Token: s.token, Pending: s.pending}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.filePath, data)
+}
+
+// StartAuthorization generates a PKCE verifier and returns the URL the user
+// has to open. Spotify redirects to redirectURI with the code afterwards
+func (s *SpotifyAuthStore) StartAuthorization(redirectURI string) (*SpotifyAuthStart, error) {
+	if redirectURI == "" {
+		return nil, fmt.Errorf("redirect URI is required")
+	}
+	clientID, err := getSpotifyClientID()
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := generatePKCEVerifier(64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate PKCE verifier: %w", err)
+	}
+	state, err := generatePKCEVerifier(43)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate state: %w", err)
+	}
+
+	params := url.Values{}
+	params.Set("client_id", clientID)
+	params.Set("response_type", "code")
+	params.Set("redirect_uri", redirectURI)
+	params.Set("code_challenge_method", "S256")
+	params.Set("code_challenge", generatePKCEChallenge(verifier))
+	params.Set("scope", strings.Join(spotifyUserScopes, " "))
+	params.Set("state", state)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = &spotifyPendingAuth{
+		State:       state,
+		Verifier:    verifier,
+		ClientID:    clientID,
+		RedirectURI: redirectURI,
+	}
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+
+	return &SpotifyAuthStart{
+		AuthURL: spotifyAuthorizeURL + "?" + params.Encode(),
+		State:   state,
+	}, nil
+}
+
+// CompleteAuthorization exchanges the code in the redirect URL Spotify sent
+// the user back to for a user token
+func (s *SpotifyAuthStore) CompleteAuthorization(ctx context.Context, callbackURL string) error {
+	parsed, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("invalid callback URL: %w", err)
+	}
+	query := parsed.Query()
+	if errParam := query.Get("error"); errParam != "" {
+		return fmt.Errorf("Spotify authorization failed: %s", errParam)
+	}
+	code := query.Get("code")
+	if code == "" {
+		return fmt.Errorf("callback URL has no authorization code")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := s.pending
+	if pending == nil {
+		return fmt.Errorf("no Spotify login in progress")
+	}
+	if query.Get("state") != pending.State {
+		return fmt.Errorf("state mismatch in Spotify callback")
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", pending.ClientID)
+	form.Set("code", code)
+	form.Set("redirect_uri", pending.RedirectURI)
+	form.Set("code_verifier", pending.Verifier)
+
+	token, err := s.requestToken(ctx, form)
+	if err != nil {
+		return err
+	}
+	token.ClientID = pending.ClientID
+
+	s.token = token
+	s.pending = nil
+	GoLog("[SpotifyAuth] Spotify account connected\n")
+	return s.saveLocked()
+}
+
+// AccessToken returns a valid user access token, refreshing it first when it
+// is about to expire
+func (s *SpotifyAuthStore) AccessToken(ctx context.Context) (string, error) {
+	if token, current, err := s.currentToken(); err != nil || current {
+		return token, err
+	}
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	// Another caller may have refreshed while this one waited
+	s.mu.Lock()
+	if s.token == nil {
+		s.mu.Unlock()
+		return "", ErrSpotifyNotAuthorized
+	}
+	if time.Now().Before(s.token.ExpiresAt.Add(-time.Minute)) {
+		token := s.token.AccessToken
+		s.mu.Unlock()
+		return token, nil
+	}
+	old := *s.token
+	s.mu.Unlock()
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", old.RefreshToken)
+	form.Set("client_id", old.ClientID)
+
+	refreshed, err := s.requestToken(ctx, form)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A logout or new login during the request wins over its result
+	if s.token == nil || s.token.RefreshToken != old.RefreshToken {
+		if s.token == nil {
+			return "", ErrSpotifyNotAuthorized
+		}
+		return s.token.AccessToken, nil
+	}
+
+	if err != nil {
+		var tokenErr *spotifyTokenError
+		if errors.As(err, &tokenErr) && tokenErr.Code == "invalid_grant" {
+			GoLog("[SpotifyAuth] Refresh token revoked, logging out\n")
+			s.token = nil
+			if saveErr := s.saveLocked(); saveErr != nil {
+				GoLog("[SpotifyAuth] Failed to remove token file: %v\n", saveErr)
+			}
+			return "", ErrSpotifyNotAuthorized
+		}
+		return "", err
+	}
+
+	// Spotify may rotate the refresh token; keep the old one if it did not
+	refreshed.ClientID = old.ClientID
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = old.RefreshToken
+	}
+	if refreshed.Scope == "" {
+		refreshed.Scope = old.Scope
+	}
+	s.token = refreshed
+	if err := s.saveLocked(); err != nil {
+		GoLog("[SpotifyAuth] Failed to save refreshed token: %v\n", err)
+	}
+	return refreshed.AccessToken, nil
+}
+
+// currentToken returns the access token and whether it is still usable
+// without a refresh
+func (s *SpotifyAuthStore) currentToken() (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == nil {
+		return "", false, ErrSpotifyNotAuthorized
+	}
+	if time.Now().Before(s.token.ExpiresAt.Add(-time.Minute)) {
+		return s.token.AccessToken, true, nil
+	}
+	return "", false, nil
+}
+
+// Logout forgets the user token and any login in progress
+func (s *SpotifyAuthStore) Logout() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.token = nil
+	s.pending = nil
+	return s.saveLocked()
+}
+
+// Status reports whether a Spotify account is connected
+func (s *SpotifyAuthStore) Status() SpotifyAuthStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := SpotifyAuthStatus{Pending: s.pending != nil}
+	if s.token != nil {
+		status.Authorized = true
+		status.Scopes = strings.Fields(s.token.Scope)
+		status.ExpiresAt = s.token.ExpiresAt.UnixMilli()
+	}
+	return status
+}
+
+// spotifyTokenError is an OAuth error returned by the token endpoint
+type spotifyTokenError struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (e *spotifyTokenError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("Spotify token request failed (%d): %s: %s", e.Status, e.Code, e.Description)
+	}
+	return fmt.Sprintf("Spotify token request failed (%d): %s", e.Status, e.Code)
+}
+
+func (s *SpotifyAuthStore) requestToken(ctx context.Context, form url.Values) (*spotifyUserToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokenResp struct {
+		AccessToken      string `json:"access_token"`
+		RefreshToken     string `json:"refresh_token"`
+		Scope            string `json:"scope"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResp); err != nil && resp.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || tokenResp.Error != "" {
+		return nil, &spotifyTokenError{Status: resp.StatusCode, Code: tokenResp.Error, Description: tokenResp.ErrorDescription}
+	}
+	if tokenResp.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no access token")
+	}
+
+	return &spotifyUserToken{
+		AccessToken:  tokenResp.AccessToken,
+		RefreshToken: tokenResp.RefreshToken,
+		Scope:        tokenResp.Scope,
+		ExpiresAt:    time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second),
+	}, nil
+}
@@ -0,0 +1,323 @@
+package gobackend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestSpotifyAuthStore(t *testing.T, handler http.HandlerFunc) *SpotifyAuthStore {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	store := &SpotifyAuthStore{httpClient: server.Client(), tokenURL: server.URL}
+	if err := store.SetDataDir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestSpotifyAuthorizationCodeFlow(t *testing.T) {
+	SetSpotifyCredentials("client-1", "")
+	defer SetSpotifyCredentials("", "")
+
+	var challenge string
+	store := newTestSpotifyAuthStore(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "authorization_code" || r.Form.Get("code") != "code-1" || r.Form.Get("client_id") != "client-1" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+		if generatePKCEChallenge(r.Form.Get("code_verifier")) != challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"access-1","refresh_token":"refresh-1","scope":"user-library-read user-follow-read","expires_in":3600}`))
+	})
+
+	start, err := store.StartAuthorization("spotiflac://callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authURL, err := url.Parse(start.AuthURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	params := authURL.Query()
+	challenge = params.Get("code_challenge")
+	if params.Get("code_challenge_method") != "S256" || challenge == "" || params.Get("state") != start.State {
+		t.Fatalf("auth URL = %s", start.AuthURL)
+	}
+
+	if err := store.CompleteAuthorization(context.Background(), "spotiflac://callback?code=code-1&state=wrong"); err == nil {
+		t.Error("expected an error for a mismatched state")
+	}
+	if err := store.CompleteAuthorization(context.Background(), "spotiflac://callback?code=code-1&state="+url.QueryEscape(start.State)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new session reads the token back from disk
+	reloaded := &SpotifyAuthStore{httpClient: store.httpClient, tokenURL: store.tokenURL}
+	if err := reloaded.SetDataDir(filepath.Dir(store.filePath)); err != nil {
+		t.Fatal(err)
+	}
+	token, err := reloaded.AccessToken(context.Background())
+	if err != nil || token != "access-1" {
+		t.Fatalf("AccessToken = %q, %v", token, err)
+	}
+	if status := reloaded.Status(); !status.Authorized || status.Pending || len(status.Scopes) != 2 {
+		t.Errorf("status = %+v", status)
+	}
+
+	if err := reloaded.Logout(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.filePath); !os.IsNotExist(err) {
+		t.Error("token file not removed on logout")
+	}
+}
+
+func TestSpotifyAuthRefresh(t *testing.T) {
+	revoked := false
+	store := newTestSpotifyAuthStore(t, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" || revoked {
+			http.Error(w, `{"error":"invalid_grant","error_description":"Refresh token revoked"}`, http.StatusBadRequest)
+			return
+		}
+		// No refresh_token in the response: the old one stays valid
+		w.Write([]byte(`{"access_token":"access-2","expires_in":3600}`))
+	})
+	store.token = &spotifyUserToken{
+		ClientID:     "client-1",
+		AccessToken:  "access-1",
+		RefreshToken: "refresh-1",
+		Scope:        "user-library-read",
+		ExpiresAt:    time.Now().Add(30 * time.Second),
+	}
+
+	token, err := store.AccessToken(context.Background())
+	if err != nil || token != "access-2" {
+		t.Fatalf("AccessToken = %q, %v", token, err)
+	}
+	if store.token.RefreshToken != "refresh-1" || store.token.Scope != "user-library-read" {
+		t.Errorf("token after refresh = %+v", store.token)
+	}
+
+	store.token.ExpiresAt = time.Now()
+	revoked = true
+	if _, err := store.AccessToken(context.Background()); !errors.Is(err, ErrSpotifyNotAuthorized) {
+		t.Errorf("expected ErrSpotifyNotAuthorized, got %v", err)
+	}
+	if store.Status().Authorized {
+		t.Error("revoked login still reported as authorized")
+	}
+}
+
+func TestSpotifyAuthStatusDuringRefresh(t *testing.T) {
+	release := make(chan struct{})
+	store := newTestSpotifyAuthStore(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(`{"access_token":"access-2","expires_in":3600}`))
+	})
+	store.token = &spotifyUserToken{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresAt: time.Now()}
+
+	done := make(chan string)
+	go func() {
+		token, _ := store.AccessToken(context.Background())
+		done <- token
+	}()
+
+	// Status must answer while the refresh request is still in flight
+	statusDone := make(chan bool)
+	go func() { statusDone <- store.Status().Authorized }()
+	select {
+	case authorized := <-statusDone:
+		if !authorized {
+			t.Error("expected authorized status during refresh")
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Status blocked on the token refresh")
+	}
+
+	close(release)
+	if token := <-done; token != "access-2" {
+		t.Errorf("AccessToken = %q, want access-2", token)
+	}
+}
+
+// rewriteTransport sends every request to a test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newTestSpotifyUserClient logs the global auth store in with "user-token"
+// and returns a user client whose requests go to target
+func newTestSpotifyUserClient(t *testing.T, target *url.URL) *SpotifyMetadataClient {
+	t.Helper()
+	store := GetSpotifyAuthStore()
+	store.mu.Lock()
+	saved := store.token
+	store.token = &spotifyUserToken{AccessToken: "user-token", ExpiresAt: time.Now().Add(time.Hour)}
+	store.mu.Unlock()
+	t.Cleanup(func() {
+		store.mu.Lock()
+		store.token = saved
+		store.mu.Unlock()
+	})
+
+	client := newSpotifyUserClient()
+	client.httpClient = &http.Client{Transport: rewriteTransport{target: target}}
+	return client
+}
+
+func TestSpotifyLikedSongs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1/me":
+			w.Write([]byte(`{"display_name":"Listener"}`))
+		case r.URL.Path == "/v1/me/tracks" && r.URL.Query().Get("offset") == "":
+			w.Write([]byte(`{"total":2,"next":"https://api.spotify.com/v1/me/tracks?offset=1","items":[
+				{"track":{"id":"t1","name":"One","external_ids":{"isrc":"USRC17607839"},"artists":[{"name":"Artist"}],
+				 "album":{"id":"a1","name":"Album","album_type":"album","artists":[{"name":"Artist"}]}}}]}`))
+		case r.URL.Path == "/v1/me/tracks":
+			w.Write([]byte(`{"total":2,"next":null,"items":[{"track":{"id":"t2","name":"Two","artists":[{"name":"Guest"}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	client := newTestSpotifyUserClient(t, target)
+
+	liked, err := client.GetLikedSongs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if liked.PlaylistInfo.Owner.Name != "Liked Songs" || liked.PlaylistInfo.Owner.DisplayName != "Listener" || liked.PlaylistInfo.Tracks.Total != 2 {
+		t.Errorf("playlist info = %+v", liked.PlaylistInfo)
+	}
+	if len(liked.TrackList) != 2 || liked.TrackList[0].ISRC != "USRC17607839" || liked.TrackList[0].AlbumID != "a1" || liked.TrackList[1].Artists != "Guest" {
+		t.Errorf("tracks = %+v", liked.TrackList)
+	}
+}
+
+func TestSpotifySavedAlbums(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/v1/me/albums" && r.URL.Query().Get("offset") == "":
+			w.Write([]byte(`{"next":"https://api.spotify.com/v1/me/albums?offset=1","items":[
+				{"album":{"id":"a1","name":"First","album_type":"album","release_date":"2020-01-01","total_tracks":10,
+				 "images":[{"url":"https://i.scdn.co/image/a1"}],"artists":[{"name":"Artist"}]}}]}`))
+		case r.URL.Path == "/v1/me/albums":
+			w.Write([]byte(`{"next":null,"items":[{"album":{"id":"a2","name":"Second","album_type":"single","artists":[{"name":"Other"}]}}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	saved, err := newTestSpotifyUserClient(t, target).GetSavedAlbums(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.ArtistInfo.Name != "Saved Albums" {
+		t.Errorf("artist info = %+v", saved.ArtistInfo)
+	}
+	want := []ArtistAlbumMetadata{
+		{ID: "a1", Name: "First", ReleaseDate: "2020-01-01", TotalTracks: 10, Images: "https://i.scdn.co/image/a1", AlbumType: "album", Artists: "Artist"},
+		{ID: "a2", Name: "Second", AlbumType: "single", Artists: "Other"},
+	}
+	if !reflect.DeepEqual(saved.Albums, want) {
+		t.Errorf("albums = %+v, want %+v", saved.Albums, want)
+	}
+}
+
+func TestSpotifyFollowedArtists(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/me/following" || r.URL.Query().Get("type") != "artist" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Query().Get("after") == "" {
+			w.Write([]byte(`{"artists":{"total":2,"next":"https://api.spotify.com/v1/me/following?type=artist&after=ar1","items":[
+				{"id":"ar1","name":"First","popularity":50,"followers":{"total":1000},"images":[{"url":"https://i.scdn.co/image/ar1"}]}]}}`))
+			return
+		}
+		w.Write([]byte(`{"artists":{"total":2,"next":null,"items":[{"id":"ar2","name":"Second"}]}}`))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	followed, err := newTestSpotifyUserClient(t, target).GetFollowedArtists(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &FollowedArtistsPayload{
+		Artists: []SearchArtistResult{
+			{ID: "ar1", Name: "First", Images: "https://i.scdn.co/image/ar1", Followers: 1000, Popularity: 50},
+			{ID: "ar2", Name: "Second"},
+		},
+		Total: 2,
+	}
+	if !reflect.DeepEqual(followed, want) {
+		t.Errorf("followed = %+v, want %+v", followed, want)
+	}
+}
+
+func TestSpotifyPlaylistWithUserTokenOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer user-token" || r.URL.Path != "/v1/playlists/p1" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name":"Private","owner":{"display_name":"Listener"},"tracks":{"total":1,"items":[
+			{"track":{"id":"t1","name":"One","artists":[{"name":"Artist"}]}}]}}`))
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	// A user client has no client secret, so only the user token can work
+	client := newTestSpotifyUserClient(t, target)
+	data, err := client.GetFilteredData(context.Background(), "https://open.spotify.com/playlist/p1", false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	playlist, ok := data.(*PlaylistResponsePayload)
+	if !ok || playlist.PlaylistInfo.Owner.Name != "Private" || len(playlist.TrackList) != 1 {
+		t.Errorf("playlist = %+v", data)
+	}
+
+	if _, err := client.GetFilteredData(context.Background(), "https://open.spotify.com/album/a1", false, 0); !errors.Is(err, ErrNoSpotifyCredentials) {
+		t.Errorf("album without client credentials: expected ErrNoSpotifyCredentials, got %v", err)
+	}
+}
@@ -0,0 +1,209 @@
+package gobackend
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	spotifyMeURL           = "https://api.spotify.com/v1/me"
+	savedTracksURL         = "https://api.spotify.com/v1/me/tracks"
+	savedAlbumsURL         = "https://api.spotify.com/v1/me/albums"
+	followedArtistsURL     = "https://api.spotify.com/v1/me/following"
+	spotifyLibraryPageSize = 50
+)
+
+// FollowedArtistsPayload lists the artists the user follows
+type FollowedArtistsPayload struct {
+	Artists []SearchArtistResult `json:"artists"`
+	Total   int                  `json:"total"`
+}
+
+// newSpotifyUserClient returns a client for user library calls. These use the
+// user token of the authorization code flow, so no client secret is needed
+func newSpotifyUserClient() *SpotifyMetadataClient {
+	c := &SpotifyMetadataClient{
+		httpClient: NewHTTPClientWithTimeout(15 * time.Second),
+		rng:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	c.userAgent = c.randomUserAgent()
+	return c
+}
+
+func albumTrackFromFull(track *trackFull) AlbumTrackMetadata {
+	return AlbumTrackMetadata{
+		SpotifyID:   track.ID,
+		Artists:     joinArtists(track.Artists),
+		Name:        track.Name,
+		AlbumName:   track.Album.Name,
+		AlbumArtist: joinArtists(track.Album.Artists),
+		DurationMS:  track.DurationMS,
+		Images:      firstImageURL(track.Album.Images),
+		ReleaseDate: track.Album.ReleaseDate,
+		TrackNumber: track.TrackNumber,
+		TotalTracks: track.Album.TotalTracks,
+		DiscNumber:  track.DiscNumber,
+		ExternalURL: track.ExternalURL.Spotify,
+		ISRC:        track.ExternalID.ISRC,
+		AlbumID:     track.Album.ID,
+		AlbumURL:    track.Album.ExternalURL.Spotify,
+		AlbumType:   track.Album.AlbumType,
+	}
+}
+
+// GetLikedSongs returns the user's Liked Songs as a playlist payload
+func (c *SpotifyMetadataClient) GetLikedSongs(ctx context.Context) (*PlaylistResponsePayload, error) {
+	token, err := GetSpotifyAuthStore().AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var info PlaylistInfoMetadata
+	info.Owner.Name = "Liked Songs"
+
+	var me struct {
+		DisplayName string  `json:"display_name"`
+		Images      []image `json:"images"`
+	}
+	if err := c.getJSON(ctx, spotifyMeURL, token, &me); err == nil {
+		info.Owner.DisplayName = me.DisplayName
+	}
+
+	tracks := make([]AlbumTrackMetadata, 0)
+	nextURL := fmt.Sprintf("%s?limit=%d", savedTracksURL, spotifyLibraryPageSize)
+
+	for nextURL != "" {
+		var page struct {
+			Items []struct {
+				Track *trackFull `json:"track"`
+			} `json:"items"`
+			Total int    `json:"total"`
+			Next  string `json:"next"`
+		}
+
+		if err := c.getJSON(ctx, nextURL, token, &page); err != nil {
+			if len(tracks) == 0 {
+				return nil, err
+			}
+			GoLog("[Spotify] Warning: failed to fetch liked songs page, returning %d tracks: %v\n", len(tracks), err)
+			break
+		}
+
+		info.Tracks.Total = page.Total
+		for _, item := range page.Items {
+			if item.Track == nil {
+				continue
+			}
+			tracks = append(tracks, albumTrackFromFull(item.Track))
+		}
+		nextURL = page.Next
+	}
+
+	GoLog("[Spotify] Fetched %d liked songs (total: %d)\n", len(tracks), info.Tracks.Total)
+
+	return &PlaylistResponsePayload{
+		PlaylistInfo: info,
+		TrackList:    tracks,
+	}, nil
+}
+
+// GetSavedAlbums returns the user's saved albums in the artist payload shape,
+// with the album list under "albums"
+func (c *SpotifyMetadataClient) GetSavedAlbums(ctx context.Context) (*ArtistResponsePayload, error) {
+	token, err := GetSpotifyAuthStore().AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	albums := make([]ArtistAlbumMetadata, 0)
+	nextURL := fmt.Sprintf("%s?limit=%d", savedAlbumsURL, spotifyLibraryPageSize)
+
+	for nextURL != "" {
+		var page struct {
+			Items []struct {
+				Album albumSimplified `json:"album"`
+			} `json:"items"`
+			Next string `json:"next"`
+		}
+
+		if err := c.getJSON(ctx, nextURL, token, &page); err != nil {
+			if len(albums) == 0 {
+				return nil, err
+			}
+			GoLog("[Spotify] Warning: failed to fetch saved albums page, returning %d albums: %v\n", len(albums), err)
+			break
+		}
+
+		for _, item := range page.Items {
+			albums = append(albums, ArtistAlbumMetadata{
+				ID:          item.Album.ID,
+				Name:        item.Album.Name,
+				ReleaseDate: item.Album.ReleaseDate,
+				TotalTracks: item.Album.TotalTracks,
+				Images:      firstImageURL(item.Album.Images),
+				AlbumType:   item.Album.AlbumType,
+				Artists:     joinArtists(item.Album.Artists),
+			})
+		}
+		nextURL = page.Next
+	}
+
+	return &ArtistResponsePayload{
+		ArtistInfo: ArtistInfoMetadata{Name: "Saved Albums"},
+		Albums:     albums,
+	}, nil
+}
+
+// GetFollowedArtists returns the artists the user follows
+func (c *SpotifyMetadataClient) GetFollowedArtists(ctx context.Context) (*FollowedArtistsPayload, error) {
+	token, err := GetSpotifyAuthStore().AccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FollowedArtistsPayload{Artists: make([]SearchArtistResult, 0)}
+	// Followed artists are paged by cursor rather than by offset
+	nextURL := fmt.Sprintf("%s?type=artist&limit=%d", followedArtistsURL, spotifyLibraryPageSize)
+
+	for nextURL != "" {
+		var page struct {
+			Artists struct {
+				Items []struct {
+					ID        string  `json:"id"`
+					Name      string  `json:"name"`
+					Images    []image `json:"images"`
+					Followers struct {
+						Total int `json:"total"`
+					} `json:"followers"`
+					Popularity int `json:"popularity"`
+				} `json:"items"`
+				Total int    `json:"total"`
+				Next  string `json:"next"`
+			} `json:"artists"`
+		}
+
+		if err := c.getJSON(ctx, nextURL, token, &page); err != nil {
+			if len(result.Artists) == 0 {
+				return nil, err
+			}
+			GoLog("[Spotify] Warning: failed to fetch followed artists page, returning %d artists: %v\n", len(result.Artists), err)
+			break
+		}
+
+		result.Total = page.Artists.Total
+		for _, artist := range page.Artists.Items {
+			result.Artists = append(result.Artists, SearchArtistResult{
+				ID:         artist.ID,
+				Name:       artist.Name,
+				Images:     firstImageURL(artist.Images),
+				Followers:  artist.Followers.Total,
+				Popularity: artist.Popularity,
+			})
+		}
+		nextURL = page.Artists.Next
+	}
+
+	return result, nil
+}
@@ -1,19 +1,15 @@
 package gobackend
 
 import (
-	"bufio"
 	"context"
 	"encoding/base64"
 	"encoding/json"
-	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
-	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +33,7 @@ var (
 type TidalTrack struct {
 	ID           int64  `json:"id"`
 	Title        string `json:"title"`
+	Version      string `json:"version"` // e.g. "Live" or "2011 Remaster"
 	ISRC         string `json:"isrc"`
 	AudioQuality string `json:"audioQuality"`
 	TrackNumber  int    `json:"trackNumber"`
@@ -80,26 +77,6 @@ type TidalBTSManifest struct {
 	URLs           []string `json:"urls"`
 }
 
-type MPD struct {
-	XMLName xml.Name `xml:"MPD"`
-	Period  struct {
-		AdaptationSet struct {
-			Representation struct {
-				SegmentTemplate struct {
-					Initialization string `xml:"initialization,attr"`
-					Media          string `xml:"media,attr"`
-					Timeline       struct {
-						Segments []struct {
-							Duration int `xml:"d,attr"`
-							Repeat   int `xml:"r,attr"`
-						} `xml:"S"`
-					} `xml:"SegmentTimeline"`
-				} `xml:"SegmentTemplate"`
-			} `xml:"Representation"`
-		} `xml:"AdaptationSet"`
-	} `xml:"Period"`
-}
-
 func NewTidalDownloader() *TidalDownloader {
 	tidalDownloaderOnce.Do(func() {
 		clientID, _ := base64.StdEncoding.DecodeString("NkJEU1JkcEs5aHFFQlRnVQ==")
@@ -119,29 +96,9 @@ func NewTidalDownloader() *TidalDownloader {
 	return globalTidalDownloader
 }
 
-// GetAvailableAPIs returns list of available Tidal APIs
+// GetAvailableAPIs returns list of available Tidal APIs from the endpoint config
 func (t *TidalDownloader) GetAvailableAPIs() []string {
-	encodedAPIs := []string{
-		"dGlkYWwua2lub3BsdXMub25saW5l",
-		"dGlkYWwtYXBpLmJpbmltdW0ub3Jn",
-		"dHJpdG9uLnNxdWlkLnd0Zg==",
-		"dm9nZWwucXFkbC5zaXRl",
-		"bWF1cy5xcWRsLnNpdGU=",
-		"aHVuZC5xcWRsLnNpdGU=",
-		"a2F0emUucXFkbC5zaXRl",
-		"d29sZi5xcWRsLnNpdGU=",
-	}
-
-	var apis []string
-	for _, encoded := range encodedAPIs {
-		decoded, err := base64.StdEncoding.DecodeString(encoded)
-		if err != nil {
-			continue
-		}
-		apis = append(apis, "https://"+string(decoded))
-	}
-
-	return apis
+	return Endpoints().Tidal
 }
 
 func (t *TidalDownloader) GetAccessToken() (string, error) {
@@ -196,8 +153,8 @@ func (t *TidalDownloader) GetTidalURLFromSpotify(spotifyTrackID string) (string,
 	spotifyBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9vcGVuLnNwb3RpZnkuY29tL3RyYWNrLw==")
 	spotifyURL := fmt.Sprintf("%s%s", string(spotifyBase), spotifyTrackID)
 
-	apiBase, _ := base64.StdEncoding.DecodeString("aHR0cHM6Ly9hcGkuc29uZy5saW5rL3YxLWFscGhhLjEvbGlua3M/dXJsPQ==")
-	apiURL := fmt.Sprintf("%s%s", string(apiBase), url.QueryEscape(spotifyURL))
+	apiBase := Endpoints().SongLink + "/links?url="
+	apiURL := fmt.Sprintf("%s%s", apiBase, url.QueryEscape(spotifyURL))
 
 	req, err := http.NewRequest("GET", apiURL, nil)
 	if err != nil {
@@ -438,11 +395,7 @@ func (t *TidalDownloader) SearchTrackByMetadataWithISRC(trackName, artistName, s
 					if result.Items[i].ISRC == spotifyISRC {
 						track := &result.Items[i]
 						if expectedDuration > 0 {
-							durationDiff := track.Duration - expectedDuration
-							if durationDiff < 0 {
-								durationDiff = -durationDiff
-							}
-							if durationDiff <= 3 {
+							if durationsMatch("tidal", expectedDuration, track.Duration) {
 								GoLog("[Tidal] ✓ ISRC match: '%s' (duration verified)\n", track.Title)
 								return track, nil
 							}
@@ -478,11 +431,7 @@ func (t *TidalDownloader) SearchTrackByMetadataWithISRC(trackName, artistName, s
 			if expectedDuration > 0 {
 				var durationVerifiedMatches []*TidalTrack
 				for _, track := range isrcMatches {
-					durationDiff := track.Duration - expectedDuration
-					if durationDiff < 0 {
-						durationDiff = -durationDiff
-					}
-					if durationDiff <= 3 {
+					if durationsMatch("tidal", expectedDuration, track.Duration) {
 						durationVerifiedMatches = append(durationVerifiedMatches, track)
 					}
 				}
@@ -508,16 +457,11 @@ func (t *TidalDownloader) SearchTrackByMetadataWithISRC(trackName, artistName, s
 	}
 
 	if expectedDuration > 0 {
-		tolerance := 3 // 3 seconds tolerance
 		var durationMatches []*TidalTrack
 
 		for i := range allTracks {
 			track := &allTracks[i]
-			durationDiff := track.Duration - expectedDuration
-			if durationDiff < 0 {
-				durationDiff = -durationDiff
-			}
-			if durationDiff <= tolerance {
+			if durationsMatch("tidal", expectedDuration, track.Duration) {
 				durationMatches = append(durationMatches, track)
 			}
 		}
@@ -582,115 +526,150 @@ type tidalAPIResult struct {
 	apiURL   string
 	info     TidalDownloadInfo
 	err      error
+	preview  bool // The mirror answered with a preview instead of the full track
+	notFound bool // The mirror does not have the track
 	duration time.Duration
 }
 
-// Returns the first successful result (supports both v1 and v2 API formats)
-func getDownloadURLParallel(apis []string, trackID int64, quality string) (string, TidalDownloadInfo, error) {
-	if len(apis) == 0 {
-		return "", TidalDownloadInfo{}, fmt.Errorf("no APIs available")
-	}
-
-	GoLog("[Tidal] Requesting download URL from %d APIs in parallel...\n", len(apis))
-
-	resultChan := make(chan tidalAPIResult, len(apis))
-	startTime := time.Now()
-
-	for _, apiURL := range apis {
-		go func(api string) {
-			reqStart := time.Now()
+// tidalMirrorClient is shared by all mirror requests
+var tidalMirrorClient = NewHTTPClientWithTimeout(15 * time.Second)
 
-			client := NewHTTPClientWithTimeout(15 * time.Second)
+// queryTidalMirror requests a download URL from one API mirror
+func queryTidalMirror(api string, trackID int64, quality string) tidalAPIResult {
+	reqStart := time.Now()
+	fail := func(err error) tidalAPIResult {
+		return tidalAPIResult{apiURL: api, err: err, duration: time.Since(reqStart)}
+	}
 
-			reqURL := fmt.Sprintf("%s/track/?id=%d&quality=%s", api, trackID, quality)
+	reqURL := fmt.Sprintf("%s/track/?id=%d&quality=%s", api, trackID, quality)
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return fail(err)
+	}
 
-			req, err := http.NewRequest("GET", reqURL, nil)
-			if err != nil {
-				resultChan <- tidalAPIResult{apiURL: api, err: err, duration: time.Since(reqStart)}
-				return
-			}
+	resp, err := tidalMirrorClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer resp.Body.Close()
 
-			resp, err := client.Do(req)
-			if err != nil {
-				resultChan <- tidalAPIResult{apiURL: api, err: err, duration: time.Since(reqStart)}
-				return
-			}
-			defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		result := fail(fmt.Errorf("HTTP %d", resp.StatusCode))
+		result.notFound = resp.StatusCode == http.StatusNotFound
+		return result
+	}
 
-			if resp.StatusCode != 200 {
-				resultChan <- tidalAPIResult{apiURL: api, err: fmt.Errorf("HTTP %d", resp.StatusCode), duration: time.Since(reqStart)}
-				return
-			}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fail(err)
+	}
 
-			body, err := io.ReadAll(resp.Body)
-			if err != nil {
-				resultChan <- tidalAPIResult{apiURL: api, err: err, duration: time.Since(reqStart)}
-				return
-			}
+	var v2Response TidalAPIResponseV2
+	if err := json.Unmarshal(body, &v2Response); err == nil && v2Response.Data.Manifest != "" {
+		if v2Response.Data.AssetPresentation == "PREVIEW" {
+			result := fail(fmt.Errorf("returned PREVIEW instead of FULL"))
+			result.preview = true
+			return result
+		}
 
-			var v2Response TidalAPIResponseV2
-			if err := json.Unmarshal(body, &v2Response); err == nil && v2Response.Data.Manifest != "" {
-						if v2Response.Data.AssetPresentation == "PREVIEW" {
-					resultChan <- tidalAPIResult{apiURL: api, err: fmt.Errorf("returned PREVIEW instead of FULL"), duration: time.Since(reqStart)}
-					return
-				}
+		info := TidalDownloadInfo{
+			URL:        "MANIFEST:" + v2Response.Data.Manifest,
+			BitDepth:   v2Response.Data.BitDepth,
+			SampleRate: v2Response.Data.SampleRate,
+		}
+		return tidalAPIResult{apiURL: api, info: info, duration: time.Since(reqStart)}
+	}
 
+	var v1Responses []struct {
+		OriginalTrackURL string `json:"OriginalTrackUrl"`
+	}
+	if err := json.Unmarshal(body, &v1Responses); err == nil {
+		for _, item := range v1Responses {
+			if item.OriginalTrackURL != "" {
 				info := TidalDownloadInfo{
-					URL:        "MANIFEST:" + v2Response.Data.Manifest,
-					BitDepth:   v2Response.Data.BitDepth,
-					SampleRate: v2Response.Data.SampleRate,
+					URL:        item.OriginalTrackURL,
+					BitDepth:   16,
+					SampleRate: 44100,
 				}
-				resultChan <- tidalAPIResult{apiURL: api, info: info, err: nil, duration: time.Since(reqStart)}
-				return
+				return tidalAPIResult{apiURL: api, info: info, duration: time.Since(reqStart)}
 			}
+		}
+	}
 
-			var v1Responses []struct {
-				OriginalTrackURL string `json:"OriginalTrackUrl"`
-			}
-			if err := json.Unmarshal(body, &v1Responses); err == nil {
-				for _, item := range v1Responses {
-					if item.OriginalTrackURL != "" {
-						info := TidalDownloadInfo{
-							URL:        item.OriginalTrackURL,
-							BitDepth:   16,
-							SampleRate: 44100,
-						}
-						resultChan <- tidalAPIResult{apiURL: api, info: info, err: nil, duration: time.Since(reqStart)}
-						return
-					}
-				}
-			}
+	return fail(fmt.Errorf("no download URL or manifest in response"))
+}
 
-			resultChan <- tidalAPIResult{apiURL: api, err: fmt.Errorf("no download URL or manifest in response"), duration: time.Since(reqStart)}
-		}(apiURL)
+// recordTidalMirrorResult feeds a mirror response into the health store.
+// Previews and 404s depend on the track, not on the mirror.
+func recordTidalMirrorResult(store *MirrorHealthStore, result tidalAPIResult) {
+	switch {
+	case result.err == nil:
+		store.RecordSuccess(result.apiURL, result.duration)
+	case result.preview || result.notFound:
+		store.RecordTrackMiss(result.apiURL, result.err, result.preview)
+	default:
+		store.RecordFailure(result.apiURL, result.err)
 	}
+}
 
-	var errors []string
-
-	for i := 0; i < len(apis); i++ {
-		result := <-resultChan
-		if result.err == nil {
-			GoLog("[Tidal] [Parallel] ✓ Got response from %s (%d-bit/%dHz) in %v\n",
-				result.apiURL, result.info.BitDepth, result.info.SampleRate, result.duration)
+// Returns the first successful result (supports both v1 and v2 API formats).
+// Mirrors are queried in health tiers: the healthiest few first, then the
+// rest, then mirrors whose circuit is open.
+func getDownloadURLParallel(apis []string, trackID int64, quality string) (string, TidalDownloadInfo, error) {
+	if len(apis) == 0 {
+		return "", TidalDownloadInfo{}, fmt.Errorf("no APIs available")
+	}
 
-			go func(remaining int) {
-				for j := 0; j < remaining; j++ {
-					<-resultChan
-				}
-			}(len(apis) - i - 1)
+	store := GetMirrorHealthStore()
+	startTime := time.Now()
+	var failures []string
+	previews := 0
+
+	for tier, tierAPIs := range store.RankMirrors(apis, mirrorTopK) {
+		GoLog("[Tidal] Requesting download URL from %d APIs in parallel (tier %d)...\n", len(tierAPIs), tier+1)
+
+		resultChan := make(chan tidalAPIResult, len(tierAPIs))
+		for _, apiURL := range tierAPIs {
+			go func(api string) {
+				resultChan <- queryTidalMirror(api, trackID, quality)
+			}(apiURL)
+		}
+
+		for i := 0; i < len(tierAPIs); i++ {
+			result := <-resultChan
+			recordTidalMirrorResult(store, result)
+			if result.err == nil {
+				GoLog("[Tidal] [Parallel] ✓ Got response from %s (%d-bit/%dHz) in %v\n",
+					result.apiURL, result.info.BitDepth, result.info.SampleRate, result.duration)
+
+				// Keep scoring the slower mirrors in the background
+				go func(remaining int) {
+					for j := 0; j < remaining; j++ {
+						recordTidalMirrorResult(store, <-resultChan)
+					}
+					store.Flush()
+				}(len(tierAPIs) - i - 1)
 
-			GoLog("[Tidal] [Parallel] Total time: %v (first success)\n", time.Since(startTime))
-			return result.apiURL, result.info, nil
-		}
-		errMsg := result.err.Error()
-		if len(errMsg) > 50 {
-			errMsg = errMsg[:50] + "..."
+				GoLog("[Tidal] [Parallel] Total time: %v (first success)\n", time.Since(startTime))
+				return result.apiURL, result.info, nil
+			}
+			if result.preview {
+				previews++
+			}
+			errMsg := result.err.Error()
+			if len(errMsg) > 50 {
+				errMsg = errMsg[:50] + "..."
+			}
+			failures = append(failures, fmt.Sprintf("%s: %s", result.apiURL, errMsg))
 		}
-		errors = append(errors, fmt.Sprintf("%s: %s", result.apiURL, errMsg))
 	}
+	store.Flush()
 
 	GoLog("[Tidal] [Parallel] All %d APIs failed in %v\n", len(apis), time.Since(startTime))
-	return "", TidalDownloadInfo{}, fmt.Errorf("all %d Tidal APIs failed. Errors: %v", len(apis), errors)
+	if previews > 0 && previews == len(failures) {
+		return "", TidalDownloadInfo{}, fmt.Errorf("all %d Tidal APIs failed: %w", len(apis), &PreviewError{})
+	}
+	return "", TidalDownloadInfo{}, fmt.Errorf("all %d Tidal APIs failed. Errors: %v", len(apis), failures)
 }
 
 func (t *TidalDownloader) GetDownloadURL(trackID int64, quality string) (TidalDownloadInfo, error) {
@@ -734,70 +713,31 @@ func parseManifest(manifestB64 string) (directURL string, initURL string, mediaU
 		return btsManifest.URLs[0], "", nil, nil
 	}
 
-	var mpd MPD
-	if err := xml.Unmarshal(manifestBytes, &mpd); err != nil {
-		return "", "", nil, fmt.Errorf("failed to parse manifest XML: %w", err)
-	}
-
-	segTemplate := mpd.Period.AdaptationSet.Representation.SegmentTemplate
-	initURL = segTemplate.Initialization
-	mediaTemplate := segTemplate.Media
-
-	if initURL == "" || mediaTemplate == "" {
-		initRe := regexp.MustCompile(`initialization="([^"]+)"`)
-		mediaRe := regexp.MustCompile(`media="([^"]+)"`)
-
-		if match := initRe.FindStringSubmatch(manifestStr); len(match) > 1 {
-			initURL = match[1]
-		}
-		if match := mediaRe.FindStringSubmatch(manifestStr); len(match) > 1 {
-			mediaTemplate = match[1]
-		}
-	}
-
-	if initURL == "" {
-		return "", "", nil, fmt.Errorf("no initialization URL found in manifest")
-	}
-
-	initURL = strings.ReplaceAll(initURL, "&amp;", "&")
-	mediaTemplate = strings.ReplaceAll(mediaTemplate, "&amp;", "&")
-
-	segmentCount := 0
-	GoLog("[Tidal] XML parsed segments: %d entries in timeline\n", len(segTemplate.Timeline.Segments))
-	for i, seg := range segTemplate.Timeline.Segments {
-		GoLog("[Tidal] Segment[%d]: d=%d, r=%d\n", i, seg.Duration, seg.Repeat)
-		segmentCount += seg.Repeat + 1
+	periods, err := parseMPD(manifestBytes, "")
+	if err != nil {
+		return "", "", nil, err
 	}
-	GoLog("[Tidal] Segment count from XML: %d\n", segmentCount)
 
-	if segmentCount == 0 {
-		fmt.Println("[Tidal] No segments from XML, trying regex...")
-		segRe := regexp.MustCompile(`<S\s+d="(\d+)"(?:\s+r="(\d+)")?`)
-		matches := segRe.FindAllStringSubmatch(manifestStr, -1)
-		GoLog("[Tidal] Regex found %d segment entries\n", len(matches))
-		for i, match := range matches {
-			repeat := 0
-			if len(match) > 2 && match[2] != "" {
-				fmt.Sscanf(match[2], "%d", &repeat)
-			}
-			if i < 5 || i == len(matches)-1 {
-				GoLog("[Tidal] Regex segment[%d]: d=%s, r=%d\n", i, match[1], repeat)
-			}
-			segmentCount += repeat + 1
+	// Periods are downloaded back to back behind the first period's
+	// initialization segment. A file has room for one moov, so every period
+	// must carry the same codec for its fragments to decode with it.
+	initURL = periods[0].InitURL
+	for i, period := range periods {
+		GoLog("[Tidal] Period %d: representation %q (%s, %d bps), %d segments\n",
+			i+1, period.RepresentationID, period.Codecs, period.Bandwidth, len(period.MediaURLs))
+		if !strings.EqualFold(period.Codecs, periods[0].Codecs) {
+			return "", "", nil, fmt.Errorf("manifest periods use different codecs (%q, %q)", periods[0].Codecs, period.Codecs)
 		}
-		GoLog("[Tidal] Total segments from regex: %d\n", segmentCount)
-	}
-
-	for i := 1; i <= segmentCount; i++ {
-		mediaURL := strings.ReplaceAll(mediaTemplate, "$Number$", fmt.Sprintf("%d", i))
-		mediaURLs = append(mediaURLs, mediaURL)
+		mediaURLs = append(mediaURLs, period.MediaURLs...)
 	}
 
 	return "", initURL, mediaURLs, nil
 }
 
 // DownloadFile downloads a file from URL with progress tracking
-func (t *TidalDownloader) DownloadFile(downloadURL, outputPath, itemID string) error {
+// keepPartial keeps an interrupted .part file on cancellation so a retry can resume it
+// expectedDurationMS, when set, rejects a preview clip before it is kept
+func (t *TidalDownloader) DownloadFile(downloadURL, outputPath, itemID string, keepPartial bool, expectedDurationMS int) error {
 	ctx := context.Background()
 
 	if strings.HasPrefix(downloadURL, "MANIFEST:") {
@@ -810,7 +750,7 @@ func (t *TidalDownloader) DownloadFile(downloadURL, outputPath, itemID string) e
 		if isDownloadCancelled(itemID) {
 			return ErrDownloadCancelled
 		}
-		return t.downloadFromManifest(ctx, strings.TrimPrefix(downloadURL, "MANIFEST:"), outputPath, itemID)
+		return t.downloadFromManifest(ctx, strings.TrimPrefix(downloadURL, "MANIFEST:"), outputPath, itemID, keepPartial, expectedDurationMS)
 	}
 
 	if itemID != "" {
@@ -824,72 +764,11 @@ func (t *TidalDownloader) DownloadFile(downloadURL, outputPath, itemID string) e
 		return ErrDownloadCancelled
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", downloadURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	resp, err := DoRequestWithUserAgent(t.client, req)
-	if err != nil {
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		return fmt.Errorf("download failed: HTTP %d", resp.StatusCode)
-	}
-
-	expectedSize := resp.ContentLength
-	if expectedSize > 0 && itemID != "" {
-		SetItemBytesTotal(itemID, expectedSize)
-	}
-
-	out, err := os.Create(outputPath)
-	if err != nil {
-		return err
-	}
-
-	bufWriter := bufio.NewWriterSize(out, 256*1024)
-
-	var written int64
-	if itemID != "" {
-		progressWriter := NewItemProgressWriter(bufWriter, itemID)
-		written, err = io.Copy(progressWriter, resp.Body)
-	} else {
-		written, err = io.Copy(bufWriter, resp.Body)
-	}
-
-	flushErr := bufWriter.Flush()
-	closeErr := out.Close()
-
-	if err != nil {
-		os.Remove(outputPath)
-		if isDownloadCancelled(itemID) {
-			return ErrDownloadCancelled
-		}
-		return fmt.Errorf("download interrupted: %w", err)
-	}
-	if flushErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to flush buffer: %w", flushErr)
-	}
-	if closeErr != nil {
-		os.Remove(outputPath)
-		return fmt.Errorf("failed to close file: %w", closeErr)
-	}
-
-	if expectedSize > 0 && written != expectedSize {
-		os.Remove(outputPath)
-		return fmt.Errorf("incomplete download: expected %d bytes, got %d bytes", expectedSize, written)
-	}
-
-	return nil
+	_, err := downloadToFileResumable(ctx, t.client, downloadURL, outputPath, itemID, keepPartial, expectedDurationMS)
+	return err
 }
 
-func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64, outputPath, itemID string) error {
+func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64, outputPath, itemID string, keepPartial bool, expectedDurationMS int) error {
 	fmt.Println("[Tidal] Parsing manifest...")
 	directURL, initURL, mediaURLs, err := parseManifest(manifestB64)
 	if err != nil {
@@ -907,63 +786,12 @@ func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64,
 			return ErrDownloadCancelled
 		}
 
-		req, err := http.NewRequestWithContext(ctx, "GET", directURL, nil)
-		if err != nil {
-			GoLog("[Tidal] BTS request creation failed: %v\n", err)
-			return fmt.Errorf("failed to create request: %w", err)
-		}
-
-		resp, err := client.Do(req)
-		if err != nil {
-			if isDownloadCancelled(itemID) {
+		if _, err := downloadToFileResumable(ctx, client, directURL, outputPath, itemID, keepPartial, expectedDurationMS); err != nil {
+			if errors.Is(err, ErrDownloadCancelled) {
 				return ErrDownloadCancelled
 			}
 			GoLog("[Tidal] BTS download failed: %v\n", err)
-			return fmt.Errorf("failed to download file: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != 200 {
-			GoLog("[Tidal] BTS download HTTP error: %d\n", resp.StatusCode)
-			return fmt.Errorf("download failed with status %d", resp.StatusCode)
-		}
-		GoLog("[Tidal] BTS response OK, Content-Length: %d\n", resp.ContentLength)
-
-		expectedSize := resp.ContentLength
-		if expectedSize > 0 && itemID != "" {
-			SetItemBytesTotal(itemID, expectedSize)
-		}
-
-		out, err := os.Create(outputPath)
-		if err != nil {
-			return fmt.Errorf("failed to create file: %w", err)
-		}
-
-		var written int64
-		if itemID != "" {
-			progressWriter := NewItemProgressWriter(out, itemID)
-			written, err = io.Copy(progressWriter, resp.Body)
-		} else {
-			written, err = io.Copy(out, resp.Body)
-		}
-
-		closeErr := out.Close()
-
-		if err != nil {
-			os.Remove(outputPath)
-			if isDownloadCancelled(itemID) {
-				return ErrDownloadCancelled
-			}
-			return fmt.Errorf("download interrupted: %w", err)
-		}
-		if closeErr != nil {
-			os.Remove(outputPath)
-			return fmt.Errorf("failed to close file: %w", closeErr)
-		}
-
-		if expectedSize > 0 && written != expectedSize {
-			os.Remove(outputPath)
-			return fmt.Errorf("incomplete download: expected %d bytes, got %d bytes", expectedSize, written)
+			return err
 		}
 
 		return nil
@@ -978,42 +806,37 @@ func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64,
 		return fmt.Errorf("failed to create M4A file: %w", err)
 	}
 
-	GoLog("[Tidal] Downloading init segment...\n")
-	if isDownloadCancelled(itemID) {
-		out.Close()
-		os.Remove(m4aPath)
-		return ErrDownloadCancelled
-	}
-	req, err := http.NewRequestWithContext(ctx, "GET", initURL, nil)
-	if err != nil {
-		out.Close()
-		os.Remove(m4aPath)
-		GoLog("[Tidal] Init segment request failed: %v\n", err)
-		return fmt.Errorf("failed to create init segment request: %w", err)
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		out.Close()
-		os.Remove(m4aPath)
+	retryConfig := segmentRetryConfig()
+	var initData []byte
+	if initURL != "" {
+		GoLog("[Tidal] Downloading init segment...\n")
 		if isDownloadCancelled(itemID) {
+			out.Close()
+			os.Remove(m4aPath)
 			return ErrDownloadCancelled
 		}
-		GoLog("[Tidal] Init segment download failed: %v\n", err)
-		return fmt.Errorf("failed to download init segment: %w", err)
+		initData, err = fetchSegment(ctx, client, initURL, retryConfig)
+		if err != nil {
+			out.Close()
+			os.Remove(m4aPath)
+			if isSegmentCancelled(err, itemID) {
+				return ErrDownloadCancelled
+			}
+			GoLog("[Tidal] Init segment download failed: %v\n", err)
+			return fmt.Errorf("failed to download init segment: %w", err)
+		}
 	}
-	if resp.StatusCode != 200 {
-		resp.Body.Close()
-		out.Close()
-		os.Remove(m4aPath)
-		GoLog("[Tidal] Init segment HTTP error: %d\n", resp.StatusCode)
-		return fmt.Errorf("init segment download failed with status %d", resp.StatusCode)
+
+	// Progress reports real bytes; the total is estimated from the average
+	// segment size so far since DASH manifests carry no byte sizes
+	var writer io.Writer = out
+	if itemID != "" {
+		writer = NewItemProgressWriter(out, itemID)
 	}
-	_, err = io.Copy(out, resp.Body)
-	resp.Body.Close()
-	if err != nil {
+	if _, err := writer.Write(initData); err != nil {
 		out.Close()
 		os.Remove(m4aPath)
-		if isDownloadCancelled(itemID) {
+		if isSegmentCancelled(err, itemID) {
 			return ErrDownloadCancelled
 		}
 		GoLog("[Tidal] Init segment write failed: %v\n", err)
@@ -1021,57 +844,28 @@ func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64,
 	}
 
 	totalSegments := len(mediaURLs)
-	for i, mediaURL := range mediaURLs {
-		if isDownloadCancelled(itemID) {
-			out.Close()
-			os.Remove(m4aPath)
-			return ErrDownloadCancelled
-		}
-
-		if i%10 == 0 || i == totalSegments-1 {
-			GoLog("[Tidal] Downloading segment %d/%d...\n", i+1, totalSegments)
+	initSize := int64(len(initData))
+	GoLog("[Tidal] Downloading %d segments (%d parallel)...\n", totalSegments, defaultSegmentConcurrency)
+	err = fetchSegmentsOrdered(ctx, client, mediaURLs, writer, defaultSegmentConcurrency, retryConfig, func(written int) {
+		if written%10 == 0 || written == totalSegments {
+			GoLog("[Tidal] Wrote segment %d/%d\n", written, totalSegments)
 		}
-
-		if itemID != "" {
-			progress := float64(i+1) / float64(totalSegments)
-			SetItemProgress(itemID, progress, 0, 0)
+		if itemID == "" {
+			return
 		}
-
-		req, err := http.NewRequestWithContext(ctx, "GET", mediaURL, nil)
-		if err != nil {
-			out.Close()
-			os.Remove(m4aPath)
-			GoLog("[Tidal] Segment %d request failed: %v\n", i+1, err)
-			return fmt.Errorf("failed to create segment %d request: %w", i+1, err)
+		if info, statErr := out.Stat(); statErr == nil {
+			mediaBytes := info.Size() - initSize
+			SetItemBytesTotal(itemID, initSize+mediaBytes*int64(totalSegments)/int64(written))
 		}
-		resp, err := client.Do(req)
-		if err != nil {
-			out.Close()
-			os.Remove(m4aPath)
-			if isDownloadCancelled(itemID) {
-				return ErrDownloadCancelled
-			}
-			GoLog("[Tidal] Segment %d download failed: %v\n", i+1, err)
-			return fmt.Errorf("failed to download segment %d: %w", i+1, err)
-		}
-		if resp.StatusCode != 200 {
-			resp.Body.Close()
-			out.Close()
-			os.Remove(m4aPath)
-			GoLog("[Tidal] Segment %d HTTP error: %d\n", i+1, resp.StatusCode)
-			return fmt.Errorf("segment %d download failed with status %d", i+1, resp.StatusCode)
-		}
-		_, err = io.Copy(out, resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			out.Close()
-			os.Remove(m4aPath)
-			if isDownloadCancelled(itemID) {
-				return ErrDownloadCancelled
-			}
-			GoLog("[Tidal] Segment %d write failed: %v\n", i+1, err)
-			return fmt.Errorf("failed to write segment %d: %w", i+1, err)
+	})
+	if err != nil {
+		out.Close()
+		os.Remove(m4aPath)
+		if isSegmentCancelled(err, itemID) {
+			return ErrDownloadCancelled
 		}
+		GoLog("[Tidal] Segment download failed: %v\n", err)
+		return fmt.Errorf("failed to download segments: %w", err)
 	}
 
 	if err := out.Close(); err != nil {
@@ -1081,285 +875,100 @@ func (t *TidalDownloader) downloadFromManifest(ctx context.Context, manifestB64,
 	}
 
 	GoLog("[Tidal] DASH download completed: %s\n", m4aPath)
-	return nil
-}
-
-type TidalDownloadResult struct {
-	FilePath    string
-	BitDepth    int
-	SampleRate  int
-	Title       string
-	Artist      string
-	Album       string
-	ReleaseDate string
-	TrackNumber int
-	DiscNumber  int
-	ISRC        string
-}
-
-func artistsMatch(spotifyArtist, tidalArtist string) bool {
-	normSpotify := strings.ToLower(strings.TrimSpace(spotifyArtist))
-	normTidal := strings.ToLower(strings.TrimSpace(tidalArtist))
-
-	if normSpotify == normTidal {
-		return true
-	}
 
-	// Check if one contains the other (for cases like "Artist" vs "Artist feat. Someone")
-	if strings.Contains(normSpotify, normTidal) || strings.Contains(normTidal, normSpotify) {
-		return true
+	// Segments are written in place, so a preview is caught once all are in
+	if err := rejectPreviewDownload(m4aPath, expectedDurationMS); err != nil {
+		return err
 	}
 
-	spotifyArtists := splitArtists(normSpotify)
-	tidalArtists := splitArtists(normTidal)
-
-	for _, exp := range spotifyArtists {
-		for _, fnd := range tidalArtists {
-			if exp == fnd {
-				return true
-			}
-			if strings.Contains(exp, fnd) || strings.Contains(fnd, exp) {
-				return true
-			}
-			if sameWordsUnordered(exp, fnd) {
-				GoLog("[Tidal] Artist names have same words in different order: '%s' vs '%s'\n", exp, fnd)
-				return true
-			}
+	// Lossless DASH streams are FLAC in fragmented MP4; remux them so the
+	// result can be tagged directly. Other codecs stay M4A for FFmpeg.
+	if err := remuxFMP4ToFLAC(m4aPath, outputPath); err != nil {
+		if errors.Is(err, errNotFLACInMP4) {
+			GoLog("[Tidal] Keeping M4A: %v\n", err)
+		} else {
+			GoLog("[Tidal] FLAC remux failed, keeping M4A: %v\n", err)
 		}
+		return nil
 	}
-
-	spotifyLatin := isLatinScript(spotifyArtist)
-	tidalLatin := isLatinScript(tidalArtist)
-	if spotifyLatin != tidalLatin {
-		GoLog("[Tidal] Artist names in different scripts, assuming match: '%s' vs '%s'\n", spotifyArtist, tidalArtist)
-		return true
-	}
-
-	return false
+	os.Remove(m4aPath)
+	GoLog("[Tidal] Remuxed DASH stream to FLAC: %s\n", outputPath)
+	return nil
 }
 
-func splitArtists(artists string) []string {
-	normalized := artists
-	normalized = strings.ReplaceAll(normalized, " feat. ", "|")
-	normalized = strings.ReplaceAll(normalized, " feat ", "|")
-	normalized = strings.ReplaceAll(normalized, " ft. ", "|")
-	normalized = strings.ReplaceAll(normalized, " ft ", "|")
-	normalized = strings.ReplaceAll(normalized, " & ", "|")
-	normalized = strings.ReplaceAll(normalized, " and ", "|")
-	normalized = strings.ReplaceAll(normalized, ", ", "|")
-	normalized = strings.ReplaceAll(normalized, " x ", "|")
-
-	parts := strings.Split(normalized, "|")
-	result := make([]string, 0, len(parts))
-	for _, p := range parts {
-		trimmed := strings.TrimSpace(p)
-		if trimmed != "" {
-			result = append(result, trimmed)
-		}
-	}
-	return result
+type TidalDownloadResult struct {
+	FilePath        string
+	BitDepth        int
+	SampleRate      int
+	Title           string
+	Artist          string
+	Album           string
+	ReleaseDate     string
+	TrackNumber     int
+	DiscNumber      int
+	ISRC            string
+	Genre           string
+	Label           string
+	Copyright       string
+	MetadataSources map[string]string // Field -> source of the tag written
+	MatchedID       string            // Provider's ID of the track it downloaded
+	Matched         TrackMatchInfo    // Provider's own details of that track, checked by verifyDownload
 }
 
-func sameWordsUnordered(a, b string) bool {
-	wordsA := strings.Fields(a)
-	wordsB := strings.Fields(b)
-
-	// Must have same number of words
-	if len(wordsA) != len(wordsB) || len(wordsA) == 0 {
-		return false
-	}
-
-	sortedA := make([]string, len(wordsA))
-	sortedB := make([]string, len(wordsB))
-	copy(sortedA, wordsA)
-	copy(sortedB, wordsB)
-
-	for i := 0; i < len(sortedA)-1; i++ {
-		for j := i + 1; j < len(sortedA); j++ {
-			if sortedA[i] > sortedA[j] {
-				sortedA[i], sortedA[j] = sortedA[j], sortedA[i]
-			}
-			if sortedB[i] > sortedB[j] {
-				sortedB[i], sortedB[j] = sortedB[j], sortedB[i]
-			}
-		}
+// artistNames returns all credited artists, or the main artist when the list
+// is missing
+func (t *TidalTrack) artistNames() string {
+	if len(t.Artists) == 0 {
+		return t.Artist.Name
 	}
-
-	for i := range sortedA {
-		if sortedA[i] != sortedB[i] {
-			return false
-		}
+	names := make([]string, 0, len(t.Artists))
+	for _, a := range t.Artists {
+		names = append(names, a.Name)
 	}
-	return true
+	return strings.Join(names, ", ")
 }
 
-func titlesMatch(expectedTitle, foundTitle string) bool {
-	normExpected := strings.ToLower(strings.TrimSpace(expectedTitle))
-	normFound := strings.ToLower(strings.TrimSpace(foundTitle))
-
-	// Exact match
-	if normExpected == normFound {
-		return true
-	}
-
-	if strings.Contains(normExpected, normFound) || strings.Contains(normFound, normExpected) {
-		return true
-	}
-
-	// Clean both titles and compare
-	cleanExpected := cleanTitle(normExpected)
-	cleanFound := cleanTitle(normFound)
-
-	if cleanExpected == cleanFound {
-		return true
-	}
-
-	if cleanExpected != "" && cleanFound != "" {
-		if strings.Contains(cleanExpected, cleanFound) || strings.Contains(cleanFound, cleanExpected) {
-			return true
-		}
+// metadataValues returns Tidal's own tags for the track
+func (t *TidalTrack) metadataValues() MetadataValues {
+	return MetadataValues{
+		Title:       t.Title,
+		Artist:      t.artistNames(),
+		Album:       t.Album.Title,
+		ReleaseDate: t.Album.ReleaseDate,
+		TrackNumber: t.TrackNumber,
+		DiscNumber:  t.VolumeNumber,
+		ISRC:        t.ISRC,
 	}
-
-	// Extract core title (before any parentheses/brackets)
-	coreExpected := extractCoreTitle(normExpected)
-	coreFound := extractCoreTitle(normFound)
-
-	if coreExpected != "" && coreFound != "" && coreExpected == coreFound {
-		return true
-	}
-
-	// Don't treat Latin Extended (Polish, French, etc.) as different script
-	expectedLatin := isLatinScript(expectedTitle)
-	foundLatin := isLatinScript(foundTitle)
-	if expectedLatin != foundLatin {
-		GoLog("[Tidal] Titles in different scripts, assuming match: '%s' vs '%s'\n", expectedTitle, foundTitle)
-		return true
-	}
-
-	return false
 }
 
-func extractCoreTitle(title string) string {
-	parenIdx := strings.Index(title, "(")
-	bracketIdx := strings.Index(title, "[")
-	dashIdx := strings.Index(title, " - ")
-
-	cutIdx := len(title)
-	if parenIdx > 0 && parenIdx < cutIdx {
-		cutIdx = parenIdx
-	}
-	if bracketIdx > 0 && bracketIdx < cutIdx {
-		cutIdx = bracketIdx
+// matchInfo describes the track for ScoreTrackMatch. Tidal keeps the version
+// apart from the title, so it is added back the way other catalogues show it.
+func (t *TidalTrack) matchInfo() TrackMatchInfo {
+	title := t.Title
+	if t.Version != "" && !strings.Contains(strings.ToLower(title), strings.ToLower(t.Version)) {
+		title += " (" + t.Version + ")"
 	}
-	if dashIdx > 0 && dashIdx < cutIdx {
-		cutIdx = dashIdx
+	return TrackMatchInfo{
+		Title:      title,
+		Artist:     t.artistNames(),
+		Album:      t.Album.Title,
+		DurationMS: t.Duration * 1000,
+		ISRC:       t.ISRC,
 	}
-
-	return strings.TrimSpace(title[:cutIdx])
 }
 
-func cleanTitle(title string) string {
-	cleaned := title
-
-	versionPatterns := []string{
-		"remaster", "remastered", "deluxe", "bonus", "single",
-		"album version", "radio edit", "original mix", "extended",
-		"club mix", "remix", "live", "acoustic", "demo",
-	}
-
-	for {
-		startParen := strings.LastIndex(cleaned, "(")
-		endParen := strings.LastIndex(cleaned, ")")
-		if startParen >= 0 && endParen > startParen {
-			content := strings.ToLower(cleaned[startParen+1 : endParen])
-			isVersionIndicator := false
-			for _, pattern := range versionPatterns {
-				if strings.Contains(content, pattern) {
-					isVersionIndicator = true
-					break
-				}
-			}
-			if isVersionIndicator {
-				cleaned = strings.TrimSpace(cleaned[:startParen]) + cleaned[endParen+1:]
-				continue
-			}
-		}
-		break
-	}
-
-	for {
-		startBracket := strings.LastIndex(cleaned, "[")
-		endBracket := strings.LastIndex(cleaned, "]")
-		if startBracket >= 0 && endBracket > startBracket {
-			content := strings.ToLower(cleaned[startBracket+1 : endBracket])
-			isVersionIndicator := false
-			for _, pattern := range versionPatterns {
-				if strings.Contains(content, pattern) {
-					isVersionIndicator = true
-					break
-				}
-			}
-			if isVersionIndicator {
-				cleaned = strings.TrimSpace(cleaned[:startBracket]) + cleaned[endBracket+1:]
-				continue
-			}
-		}
-		break
-	}
-
-	dashPatterns := []string{
-		" - remaster", " - remastered", " - single version", " - radio edit",
-		" - live", " - acoustic", " - demo", " - remix",
-	}
-	for _, pattern := range dashPatterns {
-		if strings.HasSuffix(strings.ToLower(cleaned), pattern) {
-			cleaned = cleaned[:len(cleaned)-len(pattern)]
-		}
-	}
-
-	for strings.Contains(cleaned, "  ") {
-		cleaned = strings.ReplaceAll(cleaned, "  ", " ")
-	}
-
-	return strings.TrimSpace(cleaned)
-}
-
-func isLatinScript(s string) bool {
-	for _, r := range s {
-		if r < 128 {
-			continue
-		}
-		if (r >= 0x0100 && r <= 0x024F) ||
-			(r >= 0x1E00 && r <= 0x1EFF) ||
-			(r >= 0x00C0 && r <= 0x00FF) {
-			continue
-		}
-		if (r >= 0x4E00 && r <= 0x9FFF) ||
-			(r >= 0x3040 && r <= 0x309F) ||
-			(r >= 0x30A0 && r <= 0x30FF) ||
-			(r >= 0xAC00 && r <= 0xD7AF) ||
-			(r >= 0x0600 && r <= 0x06FF) ||
-			(r >= 0x0400 && r <= 0x04FF) {
-			return false
-		}
-	}
-	return true
-}
-
-
-func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
-	downloader := NewTidalDownloader()
-
-	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
-		return TidalDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
-	}
-
+// resolveTidalTrack finds the Tidal track for a request: Odesli ID, cached ID,
+// ISRC search, SongLink and finally metadata search, each checked for artist
+// (and where available title/duration) mismatches.
+func resolveTidalTrack(downloader *TidalDownloader, req DownloadRequest) (*TidalTrack, error) {
 	expectedDurationSec := req.DurationMS / 1000
 
 	var track *TidalTrack
 	var err error
+	var stage, rejection string
 
 	if req.TidalID != "" {
+		stage = StageProviderID
 		GoLog("[Tidal] Using Tidal ID from Odesli enrichment: %s\n", req.TidalID)
 		var trackID int64
 		if _, parseErr := fmt.Sscanf(req.TidalID, "%d", &trackID); parseErr == nil && trackID > 0 {
@@ -1374,6 +983,7 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 	}
 
 	if track == nil && req.ISRC != "" {
+		stage = StageProviderID
 		if cached := GetTrackIDCache().Get(req.ISRC); cached != nil && cached.TidalTrackID > 0 {
 			GoLog("[Tidal] Cache hit! Using cached track ID: %d\n", cached.TidalTrackID)
 			track, err = downloader.GetTrackInfoByID(cached.TidalTrackID)
@@ -1385,27 +995,19 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 	}
 
 	if track == nil && req.ISRC != "" {
+		stage = StageISRCSearch
 		GoLog("[Tidal] Trying ISRC search: %s\n", req.ISRC)
 		track, err = downloader.SearchTrackByMetadataWithISRC(req.TrackName, req.ArtistName, req.ISRC, expectedDurationSec)
 		if track != nil {
-			// Verify artist only (ISRC match is already accurate)
-			tidalArtist := track.Artist.Name
-			if len(track.Artists) > 0 {
-				var artistNames []string
-				for _, a := range track.Artists {
-					artistNames = append(artistNames, a.Name)
-				}
-				tidalArtist = strings.Join(artistNames, ", ")
-			}
-			if !artistsMatch(req.ArtistName, tidalArtist) {
-				GoLog("[Tidal] Artist mismatch from ISRC search: expected '%s', got '%s'. Rejecting.\n",
-					req.ArtistName, tidalArtist)
+			if match := matchRequest("tidal", "ISRC search", req, track.matchInfo()); !match.Accepted {
 				track = nil
+				rejection = match.Rejection
 			}
 		}
 	}
 
 	if track == nil && req.SpotifyID != "" {
+		stage = StageSongLink
 		GoLog("[Tidal] ISRC search failed, trying SongLink...\n")
 		var tidalURL string
 		var slErr error
@@ -1424,31 +1026,9 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 			if idErr == nil {
 				track, err = downloader.GetTrackInfoByID(trackID)
 				if track != nil {
-					tidalArtist := track.Artist.Name
-					if len(track.Artists) > 0 {
-						var artistNames []string
-						for _, a := range track.Artists {
-							artistNames = append(artistNames, a.Name)
-						}
-						tidalArtist = strings.Join(artistNames, ", ")
-					}
-
-					if !artistsMatch(req.ArtistName, tidalArtist) {
-						GoLog("[Tidal] Artist mismatch from SongLink: expected '%s', got '%s'. Rejecting.\n",
-							req.ArtistName, tidalArtist)
+					if match := matchRequest("tidal", "SongLink", req, track.matchInfo()); !match.Accepted {
 						track = nil
-					}
-
-					if track != nil && expectedDurationSec > 0 {
-						durationDiff := track.Duration - expectedDurationSec
-						if durationDiff < 0 {
-							durationDiff = -durationDiff
-						}
-						if durationDiff > 3 {
-							GoLog("[Tidal] Duration mismatch from SongLink: expected %ds, got %ds. Rejecting.\n",
-								expectedDurationSec, track.Duration)
-							track = nil // Reject this match
-						}
+						rejection = match.Rejection
 					}
 				}
 			}
@@ -1456,26 +1036,13 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 	}
 
 	if track == nil {
+		stage = StageMetadataSearch
 		GoLog("[Tidal] Trying metadata search as last resort...\n")
 		track, err = downloader.SearchTrackByMetadataWithISRC(req.TrackName, req.ArtistName, "", expectedDurationSec)
 		if track != nil {
-			tidalArtist := track.Artist.Name
-			if len(track.Artists) > 0 {
-				var artistNames []string
-				for _, a := range track.Artists {
-					artistNames = append(artistNames, a.Name)
-				}
-				tidalArtist = strings.Join(artistNames, ", ")
-			}
-
-			if !titlesMatch(req.TrackName, track.Title) {
-				GoLog("[Tidal] Title mismatch from metadata search: expected '%s', got '%s'. Rejecting.\n",
-					req.TrackName, track.Title)
-				track = nil
-			} else if !artistsMatch(req.ArtistName, tidalArtist) {
-				GoLog("[Tidal] Artist mismatch from metadata search: expected '%s', got '%s'. Rejecting.\n",
-					req.ArtistName, tidalArtist)
+			if match := matchRequest("tidal", "metadata search", req, track.matchInfo()); !match.Accepted {
 				track = nil
+				rejection = match.Rejection
 			}
 		}
 	}
@@ -1485,33 +1052,83 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 		if err != nil {
 			errMsg = err.Error()
 		}
-		return TidalDownloadResult{}, fmt.Errorf("tidal search failed: %s", errMsg)
+		return nil, stageError(stage, rejection, fmt.Errorf("tidal search failed: %s", errMsg))
 	}
 
-	tidalArtist := track.Artist.Name
-	if len(track.Artists) > 0 {
-		var artistNames []string
-		for _, a := range track.Artists {
-			artistNames = append(artistNames, a.Name)
-		}
-		tidalArtist = strings.Join(artistNames, ", ")
-	}
-	GoLog("[Tidal] Match found: '%s' by '%s' (duration: %ds)\n", track.Title, tidalArtist, track.Duration)
+	GoLog("[Tidal] Match found: '%s' by '%s' (duration: %ds)\n", track.Title, track.artistNames(), track.Duration)
 
 	if req.ISRC != "" {
 		GetTrackIDCache().SetTidal(req.ISRC, track.ID)
 	}
 
-	filename := buildFilenameFromTemplate(req.FilenameFormat, map[string]interface{}{
-		"title":  req.TrackName,
-		"artist": req.ArtistName,
-		"album":  req.AlbumName,
-		"track":  req.TrackNumber,
-		"year":   extractYear(req.ReleaseDate),
-		"disc":   req.DiscNumber,
-	})
-	filename = sanitizeFilename(filename) + ".flac"
-	outputPath := filepath.Join(req.OutputDir, filename)
+	return track, nil
+}
+
+// probeTidalQuality resolves the track and asks the API which stream it would
+// serve for the requested quality, without downloading audio
+func probeTidalQuality(req DownloadRequest) (QualityProbe, error) {
+	downloader := NewTidalDownloader()
+	track, err := resolveTidalTrack(downloader, req)
+	if err != nil {
+		return QualityProbe{}, err
+	}
+
+	quality := req.Quality
+	if quality == "" {
+		quality = "LOSSLESS"
+	}
+	downloadInfo, err := downloader.GetDownloadURL(track.ID, quality)
+	if err != nil {
+		return QualityProbe{}, stageError(StageDownloadURL, "", fmt.Errorf("failed to get download URL: %w", err))
+	}
+
+	return QualityProbe{
+		TrackID:    fmt.Sprintf("%d", track.ID),
+		Title:      track.Title,
+		Artist:     track.Artist.Name,
+		BitDepth:   downloadInfo.BitDepth,
+		SampleRate: downloadInfo.SampleRate,
+	}, nil
+}
+
+func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
+	downloader := NewTidalDownloader()
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return TidalDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+	}
+
+	track, err := resolveTidalTrack(downloader, req)
+	if err != nil {
+		return TidalDownloadResult{}, err
+	}
+	return downloadTidalTrack(downloader, req, track)
+}
+
+// downloadProbedTidal downloads the track probeTidalQuality matched. The
+// stream URL is requested again since the probe's may have expired.
+func downloadProbedTidal(req DownloadRequest, probe QualityProbe) (TidalDownloadResult, error) {
+	downloader := NewTidalDownloader()
+
+	if existingFile, exists := checkISRCExistsInternal(req.OutputDir, req.ISRC); exists {
+		return TidalDownloadResult{FilePath: "EXISTS:" + existingFile}, nil
+	}
+
+	var trackID int64
+	if _, err := fmt.Sscanf(probe.TrackID, "%d", &trackID); err != nil || trackID <= 0 {
+		return TidalDownloadResult{}, fmt.Errorf("invalid Tidal track ID from quality probe: %q", probe.TrackID)
+	}
+	track, err := downloader.GetTrackInfoByID(trackID)
+	if err != nil {
+		return TidalDownloadResult{}, stageError(StageProviderID, "", fmt.Errorf("failed to get probed track %d: %w", trackID, err))
+	}
+	GoLog("[Tidal] Using track %d matched by the quality probe\n", track.ID)
+	return downloadTidalTrack(downloader, req, track)
+}
+
+// downloadTidalTrack downloads and tags a resolved track
+func downloadTidalTrack(downloader *TidalDownloader, req DownloadRequest, track *TidalTrack) (TidalDownloadResult, error) {
+	outputPath := buildTrackOutputPath(req)
 
 	if fileInfo, statErr := os.Stat(outputPath); statErr == nil && fileInfo.Size() > 0 {
 		return TidalDownloadResult{FilePath: "EXISTS:" + outputPath}, nil
@@ -1535,11 +1152,13 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 
 	downloadInfo, err := downloader.GetDownloadURL(track.ID, quality)
 	if err != nil {
-		return TidalDownloadResult{}, fmt.Errorf("failed to get download URL: %w", err)
+		return TidalDownloadResult{}, stageError(StageDownloadURL, "", fmt.Errorf("failed to get download URL: %w", err))
 	}
 
 	GoLog("[Tidal] Actual quality: %d-bit/%dHz\n", downloadInfo.BitDepth, downloadInfo.SampleRate)
 
+	musicBrainzResult := startMusicBrainzLookup(req)
+
 	var parallelResult *ParallelDownloadResult
 	parallelDone := make(chan struct{})
 	go func() {
@@ -1563,12 +1182,12 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 		return "Direct URL"
 	}())
 
-	if err := downloader.DownloadFile(downloadInfo.URL, outputPath, req.ItemID); err != nil {
+	if err := downloader.DownloadFile(downloadInfo.URL, outputPath, req.ItemID, req.KeepPartial, req.DurationMS); err != nil {
 		if errors.Is(err, ErrDownloadCancelled) {
 			return TidalDownloadResult{}, ErrDownloadCancelled
 		}
 		GoLog("[Tidal] Download failed with error: %v\n", err)
-		return TidalDownloadResult{}, fmt.Errorf("download failed: %w", err)
+		return TidalDownloadResult{}, stageError(StageDownload, "", fmt.Errorf("download failed: %w", err))
 	}
 	fmt.Println("[Tidal] Download completed successfully")
 
@@ -1587,26 +1206,9 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 		return TidalDownloadResult{}, fmt.Errorf("download completed but file not found at %s or %s", outputPath, m4aPath)
 	}
 
-	releaseDate := req.ReleaseDate
-	if releaseDate == "" && track.Album.ReleaseDate != "" {
-		releaseDate = track.Album.ReleaseDate
-		GoLog("[Tidal] Using release date from Tidal API: %s\n", releaseDate)
-	}
-
-	metadata := Metadata{
-		Title:       req.TrackName,
-		Artist:      req.ArtistName,
-		Album:       req.AlbumName,
-		AlbumArtist: req.AlbumArtist,
-		Date:        releaseDate,
-		TrackNumber: track.TrackNumber,
-		TotalTracks: req.TotalTracks,
-		DiscNumber:  track.VolumeNumber,
-		ISRC:        track.ISRC,
-		Genre:       req.Genre,
-		Label:       req.Label,
-		Copyright:   req.Copyright,
-	}
+	merged := mergeRequestMetadata(req, MetadataCandidate{Source: "tidal", Values: track.metadataValues()})
+	metadata := merged.toMetadata()
+	applyMusicBrainzMetadata(&metadata, musicBrainzResult())
 
 	var coverData []byte
 	if parallelResult != nil && parallelResult.CoverData != nil {
@@ -1651,16 +1253,8 @@ func downloadFromTidal(req DownloadRequest) (TidalDownloadResult, error) {
 
 	AddToISRCIndex(req.OutputDir, req.ISRC, actualOutputPath)
 
-	return TidalDownloadResult{
-		FilePath:    actualOutputPath,
-		BitDepth:    downloadInfo.BitDepth,
-		SampleRate:  downloadInfo.SampleRate,
-		Title:       track.Title,
-		Artist:      track.Artist.Name,
-		Album:       track.Album.Title,
-		ReleaseDate: track.Album.ReleaseDate,
-		TrackNumber: track.TrackNumber,
-		DiscNumber:  track.VolumeNumber,
-		ISRC:        track.ISRC,
-	}, nil
+	result := merged.downloadResult(actualOutputPath, downloadInfo.BitDepth, downloadInfo.SampleRate)
+	result.MatchedID = fmt.Sprintf("%d", track.ID)
+	result.Matched = track.matchInfo()
+	return TidalDownloadResult(result), nil
 }
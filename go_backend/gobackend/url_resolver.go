@@ -0,0 +1,307 @@
+package gobackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Source of a ResolvedURL payload besides the built-in metadata providers
+const ResolvedSourceExtension = "extension"
+
+// ResolvedURL is the result of resolveAnyURL. Data holds the payload the
+// matching per-service export returns: GetSpotifyMetadata for "spotify",
+// GetDeezerMetadata for "deezer" and HandleURLWithExtensionJSON for "extension"
+type ResolvedURL struct {
+	Source      string          `json:"source"`
+	Type        string          `json:"type"`
+	ID          string          `json:"id,omitempty"`
+	ExtensionID string          `json:"extension_id,omitempty"`
+	URL         string          `json:"url"` // The normalized input URL
+	Data        json.RawMessage `json:"data"`
+}
+
+// Query parameters added by share sheets that never identify the content
+var trackingQueryParams = map[string]bool{
+	"si":      true,
+	"feature": true,
+	"fbclid":  true,
+	"igshid":  true,
+	"context": true,
+}
+
+// normalizeMusicURL extracts the link from pasted share text, adds a missing
+// scheme and drops tracking parameters and fragments
+func normalizeMusicURL(input string) (string, error) {
+	text := strings.TrimSpace(input)
+	if text == "" {
+		return "", fmt.Errorf("empty URL")
+	}
+
+	// Share sheets paste text like "Listen to Song by Artist: https://..."
+	candidate := text
+	for _, field := range strings.Fields(text) {
+		lower := strings.ToLower(field)
+		if strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "spotify:") {
+			candidate = field
+			break
+		}
+	}
+	candidate = strings.TrimRight(candidate, ".,;!)\"'")
+
+	// spotify: URIs become open.spotify.com links so SongLink can read them
+	if strings.HasPrefix(strings.ToLower(candidate), "spotify:") {
+		parsed, err := parseSpotifyURI(candidate)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("https://open.spotify.com/%s/%s", parsed.Type, parsed.ID), nil
+	}
+	if !strings.Contains(candidate, "://") {
+		candidate = "https://" + candidate
+	}
+
+	parsed, err := url.Parse(candidate)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if parsed.Host == "" || !strings.Contains(parsed.Host, ".") {
+		return "", fmt.Errorf("invalid URL: %s", input)
+	}
+
+	parsed.Scheme = strings.ToLower(parsed.Scheme)
+	if parsed.Scheme == "http" {
+		parsed.Scheme = "https"
+	}
+	parsed.Host = strings.ToLower(parsed.Host)
+	parsed.Fragment = ""
+
+	query := parsed.Query()
+	for key := range query {
+		if trackingQueryParams[key] || strings.HasPrefix(key, "utm_") {
+			query.Del(key)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+// fetchResolvedMetadata fetches a Spotify or Deezer resource in the payload
+// shape of GetSpotifyMetadata / GetDeezerMetadata
+func fetchResolvedMetadata(ctx context.Context, source, resourceType, resourceID string) (interface{}, error) {
+	switch source {
+	case "spotify":
+		client, err := NewSpotifyMetadataClient()
+		if err != nil {
+			return nil, err
+		}
+		return client.GetFilteredData(ctx, fmt.Sprintf("spotify:%s:%s", resourceType, resourceID), false, 0)
+	case "deezer":
+		client := GetDeezerClient()
+		switch resourceType {
+		case "track":
+			return client.GetTrack(ctx, resourceID)
+		case "album":
+			return client.GetAlbum(ctx, resourceID)
+		case "artist":
+			return client.GetArtist(ctx, resourceID)
+		case "playlist":
+			return client.GetPlaylist(ctx, resourceID)
+		}
+		return nil, fmt.Errorf("unsupported Deezer resource type: %s", resourceType)
+	}
+	return nil, fmt.Errorf("unsupported metadata source: %s", source)
+}
+
+func newResolvedURL(normalized, source, resourceType, resourceID string, data interface{}) (*ResolvedURL, error) {
+	jsonBytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return &ResolvedURL{
+		Source: source,
+		Type:   resourceType,
+		ID:     resourceID,
+		URL:    normalized,
+		Data:   jsonBytes,
+	}, nil
+}
+
+// resolveViaSongLink maps a link from any platform to Spotify or Deezer,
+// trying them in metadata provider priority order
+func resolveViaSongLink(ctx context.Context, normalized string) (*ResolvedURL, error) {
+	entity, err := NewSongLinkClient().ResolveURL(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := map[string]string{"spotify": entity.SpotifyID, "deezer": entity.DeezerID}
+	var lastErr error
+	for _, source := range GetMetadataProviderPriority() {
+		id := ids[source]
+		if id == "" {
+			continue
+		}
+		delete(ids, source)
+
+		data, err := fetchResolvedMetadata(ctx, source, entity.Type, id)
+		if err != nil {
+			GoLog("[ResolveURL] %s lookup of %s %s failed: %v\n", source, entity.Type, id, err)
+			lastErr = err
+			continue
+		}
+		return newResolvedURL(normalized, source, entity.Type, id, data)
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("%s %q is not available on Spotify or Deezer", entity.Type, entity.Title)
+}
+
+// parseQobuzTrackURL returns the ID of a Qobuz track link such as
+// https://open.qobuz.com/track/123
+func parseQobuzTrackURL(normalized string) (int64, bool) {
+	parsed, err := url.Parse(normalized)
+	if err != nil || (parsed.Host != "qobuz.com" && !strings.HasSuffix(parsed.Host, ".qobuz.com")) {
+		return 0, false
+	}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	for i, part := range parts {
+		if part != "track" || i == len(parts)-1 {
+			continue
+		}
+		// www.qobuz.com puts a slug between the type and the ID
+		id, err := strconv.ParseInt(parts[len(parts)-1], 10, 64)
+		return id, err == nil && id > 0
+	}
+	return 0, false
+}
+
+// findTrackByISRC returns the Spotify or Deezer ID of the track with isrc
+func findTrackByISRC(ctx context.Context, source, isrc string) (string, error) {
+	switch source {
+	case "spotify":
+		client, err := NewSpotifyMetadataClient()
+		if err != nil {
+			return "", err
+		}
+		result, err := client.SearchTracks(ctx, "isrc:"+isrc, 1)
+		if err != nil {
+			return "", err
+		}
+		if len(result.Tracks) == 0 {
+			return "", fmt.Errorf("no track found for ISRC: %s", isrc)
+		}
+		return result.Tracks[0].SpotifyID, nil
+	case "deezer":
+		track, err := GetDeezerClient().SearchByISRC(ctx, isrc)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimPrefix(track.SpotifyID, "deezer:"), nil
+	}
+	return "", fmt.Errorf("unsupported metadata source: %s", source)
+}
+
+// resolveQobuzURL looks a Qobuz track up with the built-in Qobuz client and
+// finds its ISRC on Spotify or Deezer. SongLink does not cover Qobuz.
+func resolveQobuzURL(ctx context.Context, normalized string, trackID int64) (*ResolvedURL, error) {
+	track, err := NewQobuzDownloader().GetTrackByID(trackID)
+	if err != nil {
+		return nil, err
+	}
+	if track.ISRC == "" {
+		return nil, fmt.Errorf("Qobuz track %d has no ISRC", trackID)
+	}
+
+	var lastErr error
+	for _, source := range GetMetadataProviderPriority() {
+		if source != "spotify" && source != "deezer" {
+			continue
+		}
+		id, err := findTrackByISRC(ctx, source, track.ISRC)
+		if err == nil {
+			var data interface{}
+			if data, err = fetchResolvedMetadata(ctx, source, "track", id); err == nil {
+				return newResolvedURL(normalized, source, "track", id, data)
+			}
+		}
+		GoLog("[ResolveURL] %s lookup of ISRC %s failed: %v\n", source, track.ISRC, err)
+		lastErr = err
+	}
+
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("track %q is not available on Spotify or Deezer", track.Title)
+}
+
+// resolveAnyURL turns a music link from any platform into the track, album,
+// playlist or artist payload of the service that can describe it
+func resolveAnyURL(ctx context.Context, input string) (*ResolvedURL, error) {
+	normalized, err := normalizeMusicURL(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	// Spotify and Deezer links are fetched directly. If that fails, for
+	// example without Spotify credentials, SongLink can still map tracks and
+	// albums to the other service
+	if parsed, err := parseSpotifyURI(normalized); err == nil {
+		data, err := fetchResolvedMetadata(ctx, "spotify", parsed.Type, parsed.ID)
+		if err == nil {
+			return newResolvedURL(normalized, "spotify", parsed.Type, parsed.ID, data)
+		}
+		errs = append(errs, fmt.Errorf("spotify: %w", err))
+	} else if resourceType, resourceID, err := parseDeezerURL(normalized); err == nil {
+		data, err := fetchResolvedMetadata(ctx, "deezer", resourceType, resourceID)
+		if err == nil {
+			return newResolvedURL(normalized, "deezer", resourceType, resourceID, data)
+		}
+		errs = append(errs, fmt.Errorf("deezer: %w", err))
+	} else if trackID, ok := parseQobuzTrackURL(normalized); ok {
+		resolved, err := resolveQobuzURL(ctx, normalized, trackID)
+		if err == nil {
+			GoLog("[ResolveURL] Resolved %s via its ISRC to %s track %s\n", normalized, resolved.Source, resolved.ID)
+			return resolved, nil
+		}
+		errs = append(errs, fmt.Errorf("qobuz: %w", err))
+	}
+
+	resolved, err := resolveViaSongLink(ctx, normalized)
+	if err == nil {
+		GoLog("[ResolveURL] Resolved %s via SongLink to %s %s %s\n", normalized, resolved.Source, resolved.Type, resolved.ID)
+		return resolved, nil
+	}
+	errs = append(errs, fmt.Errorf("songlink: %w", err))
+
+	if handler := GetExtensionManager().FindURLHandler(normalized); handler != nil {
+		payload, err := HandleURLWithExtensionJSON(normalized)
+		if err == nil {
+			resolved := &ResolvedURL{
+				Source:      ResolvedSourceExtension,
+				ExtensionID: handler.extension.ID,
+				URL:         normalized,
+				Data:        json.RawMessage(payload),
+			}
+			var header struct {
+				Type string `json:"type"`
+			}
+			if json.Unmarshal(resolved.Data, &header) == nil {
+				resolved.Type = header.Type
+			}
+			return resolved, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", handler.extension.ID, err))
+	}
+
+	return nil, fmt.Errorf("could not resolve %s: %w", normalized, errors.Join(errs...))
+}
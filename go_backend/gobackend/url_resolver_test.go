@@ -0,0 +1,99 @@
+package gobackend
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeMusicURL(t *testing.T) {
+	tests := []struct {
+		input, want string
+	}{
+		{"https://open.spotify.com/track/abc123?si=xyz", "https://open.spotify.com/track/abc123"},
+		{"spotify:album:abc123", "https://open.spotify.com/album/abc123"},
+		{"Listen to Song by Artist on Apple Music. https://music.apple.com/us/album/song/123?i=456&utm_source=share", "https://music.apple.com/us/album/song/123?i=456"},
+		{"  HTTP://Music.YouTube.com/watch?v=dQw4w9WgXcQ&feature=share#t=10 ", "https://music.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{"tidal.com/browse/track/12345", "https://tidal.com/browse/track/12345"},
+		{"Check this out: https://song.link/s/abc123!", "https://song.link/s/abc123"},
+	}
+	for _, tt := range tests {
+		got, err := normalizeMusicURL(tt.input)
+		if err != nil {
+			t.Errorf("normalizeMusicURL(%q) error: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("normalizeMusicURL(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+
+	for _, input := range []string{"", "   ", "not a link"} {
+		if _, err := normalizeMusicURL(input); err == nil {
+			t.Errorf("normalizeMusicURL(%q) should fail", input)
+		}
+	}
+}
+
+func TestParseQobuzTrackURL(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int64
+		ok    bool
+	}{
+		{"https://open.qobuz.com/track/12345", 12345, true},
+		{"https://play.qobuz.com/track/12345", 12345, true},
+		{"https://www.qobuz.com/us-en/track/song-title/12345", 12345, true},
+		{"https://open.qobuz.com/album/0060253780890", 0, false},
+		{"https://open.qobuz.com/track/", 0, false},
+		{"https://notqobuz.com/track/12345", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseQobuzTrackURL(tt.input)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseQobuzTrackURL(%q) = %d, %v; want %d, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSongLinkResolveURL(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/links" || r.URL.Query().Get("url") != "https://music.apple.com/us/album/album/123" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{
+			"entityUniqueId": "ITUNES_ALBUM::123",
+			"entitiesByUniqueId": {
+				"ITUNES_ALBUM::123": {"type": "album", "title": "Album", "artistName": "Artist"}
+			},
+			"linksByPlatform": {
+				"spotify": {"url": "https://open.spotify.com/album/sp123"},
+				"deezer": {"url": "https://www.deezer.com/album/987"},
+				"appleMusic": {"url": "https://music.apple.com/us/album/album/123"}
+			}
+		}`))
+	}))
+	defer server.Close()
+
+	store := GetEndpointStore()
+	if err := store.SetServiceOverride(EndpointSongLink, []string{server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	defer store.Reset()
+
+	client := &SongLinkClient{client: server.Client()}
+	entity, err := client.ResolveURL("https://music.apple.com/us/album/album/123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SongLinkEntity{Type: "album", Title: "Album", Artist: "Artist", SpotifyID: "sp123", DeezerID: "987"}
+	if !reflect.DeepEqual(entity, want) {
+		t.Errorf("got %+v, want %+v", entity, want)
+	}
+
+	if _, err := client.ResolveURL("https://example.com/unknown"); err == nil {
+		t.Error("expected an error for an unknown URL")
+	}
+}
@@ -1793,9 +1793,14 @@ class DownloadQueueNotifier extends Notifier<DownloadQueueState> {
       if (result['success'] == true) {
         var filePath = result['file_path'] as String?;
         
-        final wasExisting = filePath != null && filePath.startsWith('EXISTS:');
-        if (wasExisting) {
+        // Go reports existing files with already_exists; older builds used
+        // an "EXISTS:" prefix on the path instead
+        var wasExisting = result['already_exists'] == true;
+        if (filePath != null && filePath.startsWith('EXISTS:')) {
           filePath = filePath.substring(7); // Remove "EXISTS:" prefix
+          wasExisting = true;
+        }
+        if (wasExisting) {
           _log.i('Using existing file: $filePath');
         }
         